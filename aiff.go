@@ -0,0 +1,308 @@
+package pulse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	aifcVersion1      = 0xA2805140
+	aiffUnknownLength = 0x7FFFFFFF
+)
+
+type aiffDecoder struct{}
+
+func (aiffDecoder) Sniff(header []byte) bool {
+	if len(header) >= 12 && string(header[0:4]) == `FORM` {
+		kind := string(header[8:12])
+		return kind == `AIFF` || kind == `AIFC`
+	}
+
+	return false
+}
+
+func (aiffDecoder) Decode(r io.Reader) (SampleSpec, io.Reader, error) {
+	var spec SampleSpec
+	var transform *byteTransform
+	var hasFormat bool
+	var form [12]byte
+
+	if _, err := io.ReadFull(r, form[:]); err != nil {
+		return SampleSpec{}, nil, err
+	} else if string(form[0:4]) != `FORM` {
+		return SampleSpec{}, nil, fmt.Errorf("Not an AIFF file")
+	}
+
+	compressed := (string(form[8:12]) == `AIFC`)
+
+	for {
+		var chunk [8]byte
+
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return SampleSpec{}, nil, fmt.Errorf("AIFF file has no SSND chunk")
+		}
+
+		size := binary.BigEndian.Uint32(chunk[4:8])
+
+		switch string(chunk[0:4]) {
+		case `COMM`:
+			body := make([]byte, size)
+
+			if _, err := io.ReadFull(r, body); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+			if s, t, err := parseAIFFCommon(body, compressed); err == nil {
+				spec = s
+				transform = t
+				hasFormat = true
+			} else {
+				return SampleSpec{}, nil, err
+			}
+
+			if err := skipBytes(r, int64(size&1)); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+		case `SSND`:
+			var header [8]byte
+
+			if !hasFormat {
+				return SampleSpec{}, nil, fmt.Errorf("AIFF SSND chunk precedes COMM chunk")
+			}
+
+			if _, err := io.ReadFull(r, header[:]); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+			offset := int64(binary.BigEndian.Uint32(header[0:4]))
+
+			if err := skipBytes(r, offset); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+			length := int64(size) - 8 - offset
+
+			if size == aiffUnknownLength || length < 0 {
+				length = unknownDataLength
+			}
+
+			return spec, newTransformReader(dataReader(r, length), transform), nil
+
+		default:
+			if err := skipBytes(r, int64(size)+int64(size&1)); err != nil {
+				return SampleSpec{}, nil, err
+			}
+		}
+	}
+}
+
+func parseAIFFCommon(body []byte, compressed bool) (SampleSpec, *byteTransform, error) {
+	var transform *byteTransform
+
+	if len(body) < 18 || (compressed && len(body) < 22) {
+		return SampleSpec{}, nil, fmt.Errorf("AIFF COMM chunk is too short")
+	}
+
+	spec := SampleSpec{
+		Format:      FormatInvalid,
+		NumChannels: int(binary.BigEndian.Uint16(body[0:2])),
+		SampleRate:  float80ToUint32(body[8:18]),
+	}
+
+	bits := int(binary.BigEndian.Uint16(body[6:8]))
+	compression := `NONE`
+
+	if compressed {
+		compression = string(body[18:22])
+	}
+
+	// sample data is left-justified in whole bytes, so the containing byte
+	// count is what determines the format
+	switch size := (bits + 7) / 8; compression {
+	case `NONE`, `twos`:
+		switch size {
+		case 1:
+			spec.Format = FormatPcmU8
+			transform = flipSign8
+		case 2:
+			spec.Format = FormatPcmS16BE
+		case 3:
+			spec.Format = FormatPcmS24PackedBE
+		case 4:
+			spec.Format = FormatPcmS32BE
+		}
+	case `sowt`:
+		switch size {
+		case 2:
+			spec.Format = FormatPcmS16LE
+		case 3:
+			spec.Format = FormatPcmS24PackedLE
+		case 4:
+			spec.Format = FormatPcmS32LE
+		}
+	case `fl32`, `FL32`:
+		spec.Format = FormatIEEEFloat32BE
+	case `ulaw`, `ULAW`:
+		spec.Format = FormatMuLaw8
+	case `alaw`, `ALAW`:
+		spec.Format = FormatALaw8
+	}
+
+	if spec.Format == FormatInvalid {
+		return SampleSpec{}, nil, fmt.Errorf("Unsupported AIFF compression %q with %d-bit samples", compression, bits)
+	}
+
+	return spec, transform, nil
+}
+
+func aiffTarget(format SampleFormat) (SampleFormat, *byteTransform, error) {
+	switch format {
+	case FormatALaw8, FormatMuLaw8, FormatPcmS16BE, FormatPcmS24PackedBE, FormatPcmS32BE, FormatIEEEFloat32BE:
+		return format, nil, nil
+	case FormatPcmU8:
+		return FormatPcmU8, flipSign8, nil
+	case FormatPcmS16LE:
+		return FormatPcmS16BE, swap16, nil
+	case FormatPcmS24PackedLE:
+		return FormatPcmS24PackedBE, swap24, nil
+	case FormatPcmS32LE:
+		return FormatPcmS32BE, swap32, nil
+	case FormatIEEEFloat32LE:
+		return FormatIEEEFloat32BE, swap32, nil
+	case FormatPcmS24Lsb32LE:
+		return FormatPcmS24PackedBE, s24_32leToBE, nil
+	case FormatPcmS24Lsb32BE:
+		return FormatPcmS24PackedBE, s24_32beToBE, nil
+	default:
+		return FormatInvalid, nil, fmt.Errorf("Cannot write sample format %v to an AIFF file", format)
+	}
+}
+
+func aiffHeader(spec SampleSpec, dataLength int64) ([]byte, error) {
+	var compression string
+	var body bytes.Buffer
+	var buf bytes.Buffer
+
+	be := func(w *bytes.Buffer, v interface{}) {
+		binary.Write(w, binary.BigEndian, v)
+	}
+
+	switch spec.Format {
+	case FormatIEEEFloat32BE:
+		compression = `fl32`
+	case FormatALaw8:
+		compression = `alaw`
+	case FormatMuLaw8:
+		compression = `ulaw`
+	}
+
+	frameSize := int64(frameSizeOf(spec))
+	frames := uint32(0)
+	ssndSize := uint32(aiffUnknownLength)
+
+	if dataLength >= 0 {
+		frames = uint32(dataLength / frameSize)
+		ssndSize = uint32(8 + dataLength)
+	}
+
+	// FVER chunk (AIFF-C only)
+	if compression != `` {
+		body.WriteString(`FVER`)
+		be(&body, uint32(4))
+		be(&body, uint32(aifcVersion1))
+	}
+
+	// COMM chunk
+	body.WriteString(`COMM`)
+
+	if compression != `` {
+		be(&body, uint32(24))
+	} else {
+		be(&body, uint32(18))
+	}
+
+	be(&body, uint16(spec.NumChannels))
+	be(&body, frames)
+	be(&body, uint16(8*spec.Format.PCM().SampleSize()))
+	body.Write(uint32ToFloat80(spec.SampleRate))
+
+	if compression != `` {
+		// compression type followed by an empty, padded Pascal-style name
+		body.WriteString(compression)
+		body.Write([]byte{0, 0})
+	}
+
+	// SSND chunk header, followed immediately by the sample data
+	body.WriteString(`SSND`)
+	be(&body, ssndSize)
+	be(&body, uint32(0))
+	be(&body, uint32(0))
+
+	formSize := uint32(aiffUnknownLength)
+
+	if dataLength >= 0 {
+		size := 4 + int64(body.Len()) + dataLength + dataLength%2
+
+		if size >= aiffUnknownLength {
+			return nil, fmt.Errorf("Cannot write %d bytes of audio to an AIFF file: too long", dataLength)
+		}
+
+		formSize = uint32(size)
+	}
+
+	buf.WriteString(`FORM`)
+	be(&buf, formSize)
+
+	if compression != `` {
+		buf.WriteString(`AIFC`)
+	} else {
+		buf.WriteString(`AIFF`)
+	}
+
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// Create a writer that encodes audio in the given spec as an AIFF file (or
+// AIFF-C for floating point and companded formats).
+func NewAIFFWriter(w io.Writer, spec SampleSpec) (*AudioWriter, error) {
+	return newAudioWriter(w, spec, aiffTarget, aiffHeader, true)
+}
+
+// decode an 80-bit IEEE 754 extended precision number, as used for AIFF sample rates
+func float80ToUint32(data []byte) uint32 {
+	exponent := int(binary.BigEndian.Uint16(data[0:2]) & 0x7FFF)
+	mantissa := binary.BigEndian.Uint64(data[2:10])
+
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+
+	return uint32(math.Ldexp(float64(mantissa), exponent-16383-63) + 0.5)
+}
+
+func uint32ToFloat80(value uint32) []byte {
+	out := make([]byte, 10)
+
+	if value == 0 {
+		return out
+	}
+
+	exponent := 16383 + 63
+	mantissa := uint64(value)
+
+	for mantissa&(1<<63) == 0 {
+		mantissa <<= 1
+		exponent--
+	}
+
+	binary.BigEndian.PutUint16(out[0:2], uint16(exponent))
+	binary.BigEndian.PutUint64(out[2:10], mantissa)
+
+	return out
+}
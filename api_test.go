@@ -0,0 +1,73 @@
+package pulse
+
+import (
+	"io"
+	"time"
+	"unsafe"
+)
+
+// The cgo and pure-Go backends each define the functions and methods below in
+// their own files.  Listing them here with their expected signatures means the
+// package's tests stop compiling under whichever build tag lets one drift; run
+// "go vet ./..." both with and without "-tags purego" to check both.
+var (
+	_ func(string, string) (*Conn, error) = NewWithServer
+	_ func(*Conn) *Operation              = NewOperation
+
+	_ func(*Conn) error                                   = (*Conn).Close
+	_ func(*Conn)                                         = (*Conn).Destroy
+	_ func(*Conn) error                                   = (*Conn).GetLastError
+	_ func(*Conn)                                         = (*Conn).Lock
+	_ func(*Conn, bool) error                             = (*Conn).SignalAll
+	_ func(*Conn) error                                   = (*Conn).Start
+	_ func(*Conn) error                                   = (*Conn).Stop
+	_ func(*Conn)                                         = (*Conn).Unlock
+	_ func(*Conn) unsafe.Pointer                          = (*Conn).Userdata
+	_ func(*Conn) error                                   = (*Conn).Wait
+	_ func(*Operation)                                    = (*Operation).Destroy
+	_ func(*Operation)                                    = (*Operation).Done
+	_ func(*Operation) error                              = (*Operation).Run
+	_ func(*Operation) unsafe.Pointer                     = (*Operation).Userdata
+	_ func(*Stream) error                                 = (*Stream).Cork
+	_ func(*Stream)                                       = (*Stream).Destroy
+	_ func(*Stream) error                                 = (*Stream).Drain
+	_ func(*Stream) error                                 = (*Stream).Flush
+	_ func(*Stream) bool                                  = (*Stream).IsCorked
+	_ func(*Stream) (SampleSpec, error)                   = (*Stream).NegotiatedSpec
+	_ func(*Stream) error                                 = (*Stream).Prebuf
+	_ func(*Stream) error                                 = (*Stream).Trigger
+	_ func(*Stream) error                                 = (*Stream).Uncork
+	_ func(*Stream, uint32) error                         = (*Stream).UpdateSampleRate
+	_ func(*Stream) unsafe.Pointer                        = (*Stream).Userdata
+	_ func(*Stream, []byte) (int, error)                  = (*Stream).Write
+	_ func(*Stream, []byte, int64, SeekMode) (int, error) = (*Stream).WriteSeek
+
+	_ Registerable = (*Conn)(nil)
+	_ Registerable = (*Operation)(nil)
+	_ Registerable = (*Stream)(nil)
+
+	_ = Conn{
+		ID:               ``,
+		Name:             ``,
+		Server:           ``,
+		OperationTimeout: time.Duration(0),
+	}
+
+	_ = Operation{
+		ID:       ``,
+		Index:    0,
+		Timeout:  time.Duration(0),
+		Payloads: []*Payload{},
+	}
+
+	_ = Stream{
+		BufferSize:  0,
+		ChannelMap:  ChannelMap{},
+		Destination: io.Writer(nil),
+		Flags:       StreamFlags(0),
+		ID:          ``,
+		Name:        ``,
+		Sampling:    SampleSpec{},
+		Source:      io.Reader(nil),
+	}
+)
@@ -0,0 +1,132 @@
+package pulse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	auEncodingMuLaw     = 1
+	auEncodingLinear8   = 2
+	auEncodingLinear16  = 3
+	auEncodingLinear24  = 4
+	auEncodingLinear32  = 5
+	auEncodingFloat     = 6
+	auEncodingALaw      = 27
+	auHeaderSize        = 24
+	auUnknownDataLength = 0xFFFFFFFF
+)
+
+type auDecoder struct{}
+
+func (auDecoder) Sniff(header []byte) bool {
+	return len(header) >= 4 && string(header[0:4]) == `.snd`
+}
+
+func (auDecoder) Decode(r io.Reader) (SampleSpec, io.Reader, error) {
+	var header [auHeaderSize]byte
+	var transform *byteTransform
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return SampleSpec{}, nil, err
+	} else if string(header[0:4]) != `.snd` {
+		return SampleSpec{}, nil, fmt.Errorf("Not a Sun AU file")
+	}
+
+	offset := int64(binary.BigEndian.Uint32(header[4:8]))
+	size := binary.BigEndian.Uint32(header[8:12])
+	encoding := binary.BigEndian.Uint32(header[12:16])
+
+	spec := SampleSpec{
+		Format:      FormatInvalid,
+		SampleRate:  binary.BigEndian.Uint32(header[16:20]),
+		NumChannels: int(binary.BigEndian.Uint32(header[20:24])),
+	}
+
+	switch encoding {
+	case auEncodingMuLaw:
+		spec.Format = FormatMuLaw8
+	case auEncodingLinear8:
+		spec.Format = FormatPcmU8
+		transform = flipSign8
+	case auEncodingLinear16:
+		spec.Format = FormatPcmS16BE
+	case auEncodingLinear24:
+		spec.Format = FormatPcmS24PackedBE
+	case auEncodingLinear32:
+		spec.Format = FormatPcmS32BE
+	case auEncodingFloat:
+		spec.Format = FormatIEEEFloat32BE
+	case auEncodingALaw:
+		spec.Format = FormatALaw8
+	default:
+		return SampleSpec{}, nil, fmt.Errorf("Unsupported AU encoding %d", encoding)
+	}
+
+	// skip the annotation field between the header and the data
+	if err := skipBytes(r, offset-auHeaderSize); err != nil {
+		return SampleSpec{}, nil, err
+	}
+
+	length := int64(size)
+
+	if size == auUnknownDataLength {
+		length = unknownDataLength
+	}
+
+	return spec, newTransformReader(dataReader(r, length), transform), nil
+}
+
+func auTarget(format SampleFormat) (SampleFormat, *byteTransform, error) {
+	// AU stores the same set of big-endian formats that AIFF does
+	return aiffTarget(format)
+}
+
+func auHeader(spec SampleSpec, dataLength int64) ([]byte, error) {
+	var encoding uint32
+	var buf bytes.Buffer
+
+	be := func(v interface{}) {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+
+	switch spec.Format {
+	case FormatMuLaw8:
+		encoding = auEncodingMuLaw
+	case FormatPcmU8:
+		encoding = auEncodingLinear8
+	case FormatPcmS16BE:
+		encoding = auEncodingLinear16
+	case FormatPcmS24PackedBE:
+		encoding = auEncodingLinear24
+	case FormatPcmS32BE:
+		encoding = auEncodingLinear32
+	case FormatIEEEFloat32BE:
+		encoding = auEncodingFloat
+	case FormatALaw8:
+		encoding = auEncodingALaw
+	}
+
+	size := uint32(auUnknownDataLength)
+
+	// lengths too large to record are written as unknown, which is valid
+	if dataLength >= 0 && dataLength < auUnknownDataLength {
+		size = uint32(dataLength)
+	}
+
+	buf.WriteString(`.snd`)
+	be(uint32(auHeaderSize))
+	be(size)
+	be(encoding)
+	be(spec.SampleRate)
+	be(uint32(spec.NumChannels))
+
+	return buf.Bytes(), nil
+}
+
+// Create a writer that encodes audio in the given spec as a Sun AU file.
+func NewAUWriter(w io.Writer, spec SampleSpec) (*AudioWriter, error) {
+	return newAudioWriter(w, spec, auTarget, auHeader, false)
+}
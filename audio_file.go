@@ -0,0 +1,417 @@
+package pulse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const unknownDataLength = -1
+
+// A Decoder recognizes an audio container format and parses its header into a
+// SampleSpec and a reader positioned at the start of the raw PCM data.
+type Decoder interface {
+	// Return whether the leading bytes of a file identify it as this format.
+	Sniff(header []byte) bool
+
+	// Parse the container header from the given reader.  The returned reader
+	// yields only the audio data described by the returned SampleSpec.
+	Decode(r io.Reader) (SampleSpec, io.Reader, error)
+}
+
+type namedDecoder struct {
+	Name    string
+	Decoder Decoder
+}
+
+var decoders []namedDecoder
+var decodersLock sync.RWMutex
+
+// Register a decoder under the given name, making it available to DecodeAudio,
+// OpenAudioFile, and PlayFile.  Registering a name a second time replaces the
+// existing decoder.
+func RegisterDecoder(name string, decoder Decoder) {
+	decodersLock.Lock()
+	defer decodersLock.Unlock()
+
+	for i, existing := range decoders {
+		if existing.Name == name {
+			decoders[i].Decoder = decoder
+			return
+		}
+	}
+
+	decoders = append(decoders, namedDecoder{
+		Name:    name,
+		Decoder: decoder,
+	})
+}
+
+// Return the names of all registered decoders.
+func DecoderNames() []string {
+	decodersLock.RLock()
+	defer decodersLock.RUnlock()
+
+	names := make([]string, len(decoders))
+
+	for i, d := range decoders {
+		names[i] = d.Name
+	}
+
+	return names
+}
+
+func init() {
+	RegisterDecoder(`wav`, wavDecoder{})
+	RegisterDecoder(`aiff`, aiffDecoder{})
+	RegisterDecoder(`au`, auDecoder{})
+}
+
+// Detect the container format of the given audio data and parse its header.
+func DecodeAudio(r io.Reader) (SampleSpec, io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(12)
+
+	decodersLock.RLock()
+	defer decodersLock.RUnlock()
+
+	for _, d := range decoders {
+		if d.Decoder.Sniff(header) {
+			return d.Decoder.Decode(buffered)
+		}
+	}
+
+	return SampleSpec{}, nil, fmt.Errorf("Unrecognized audio container format")
+}
+
+type audioFileReader struct {
+	io.Reader
+	io.Closer
+}
+
+// Open an audio file, returning the sample spec of its audio data and a reader
+// that yields that data.  The reader must be closed when no longer needed.
+func OpenAudioFile(path string) (SampleSpec, io.ReadCloser, error) {
+	if file, err := os.Open(path); err == nil {
+		if spec, data, err := DecodeAudio(file); err == nil {
+			return spec, &audioFileReader{
+				Reader: data,
+				Closer: file,
+			}, nil
+		} else {
+			file.Close()
+			return SampleSpec{}, nil, fmt.Errorf("%s: %v", path, err)
+		}
+	} else {
+		return SampleSpec{}, nil, err
+	}
+}
+
+// Play an audio file, blocking until playback has finished.  The stream is
+// named after the file.
+func PlayFile(conn *Conn, path string, flags ...StreamFlags) error {
+	if spec, data, err := OpenAudioFile(path); err == nil {
+		defer data.Close()
+		return Play(conn, filepath.Base(path), &spec, data, flags...)
+	} else {
+		return err
+	}
+}
+
+// Record from the named source device (or the default source if empty) into an
+// audio file for the given duration.  The container format is chosen from the
+// file's extension.
+func RecordFile(conn *Conn, path string, device string, spec SampleSpec, duration time.Duration, flags ...StreamFlags) error {
+	writer, err := CreateAudioFile(path, spec)
+
+	if err != nil {
+		return err
+	}
+
+	if stream, err := NewRecordStreamToDestination(conn, filepath.Base(path), device, &spec, writer, flags...); err == nil {
+		time.Sleep(duration)
+
+		// Destroy waits for a read callback that is already writing to finish
+		// (and the writer serializes against Close), so no data arrives once
+		// the file has been finalized
+		stream.Destroy()
+	} else {
+		writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+type headerFunc func(spec SampleSpec, dataLength int64) ([]byte, error)
+
+// An AudioWriter writes raw audio data into an audio container, converting the
+// byte layout of samples to one the container supports where needed.  If the
+// underlying writer is an io.Seeker, the header lengths are corrected when the
+// AudioWriter is closed; otherwise they are written as unknown.
+type AudioWriter struct {
+	Spec      SampleSpec
+	writer    io.Writer
+	closer    io.Closer
+	header    headerFunc
+	fileSpec  SampleSpec
+	transform *byteTransform
+	partial   []byte
+	written   int64
+	pad       bool
+	closed    bool
+	lock      sync.Mutex
+}
+
+func newAudioWriter(w io.Writer, spec SampleSpec, target func(SampleFormat) (SampleFormat, *byteTransform, error), header headerFunc, pad bool) (*AudioWriter, error) {
+	if spec.NumChannels <= 0 || spec.SampleRate == 0 {
+		return nil, fmt.Errorf("Invalid sample spec for audio file: %v", spec)
+	}
+
+	format, transform, err := target(spec.Format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &AudioWriter{
+		Spec:      spec,
+		writer:    w,
+		header:    header,
+		transform: transform,
+		pad:       pad,
+		fileSpec: SampleSpec{
+			Format:      format,
+			SampleRate:  spec.SampleRate,
+			NumChannels: spec.NumChannels,
+		},
+	}
+
+	if data, err := header(rv.fileSpec, unknownDataLength); err == nil {
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// Create an audio file for writing audio in the given spec.  The container
+// format is chosen from the file's extension (.wav, .aiff, .aifc, .aif, .au, or .snd).
+func CreateAudioFile(path string, spec SampleSpec) (*AudioWriter, error) {
+	var create func(io.Writer, SampleSpec) (*AudioWriter, error)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case `.wav`, `.wave`:
+		create = NewWAVWriter
+	case `.aif`, `.aiff`, `.aifc`:
+		create = NewAIFFWriter
+	case `.au`, `.snd`:
+		create = NewAUWriter
+	default:
+		return nil, fmt.Errorf("Cannot determine audio container format for %s", path)
+	}
+
+	if file, err := os.Create(path); err == nil {
+		if writer, err := create(file, spec); err == nil {
+			writer.closer = file
+			return writer, nil
+		} else {
+			file.Close()
+			os.Remove(path)
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Write raw audio data in the writer's Spec.
+func (self *AudioWriter) Write(data []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.closed {
+		return 0, fmt.Errorf("Cannot write to closed AudioWriter")
+	}
+
+	out := data
+
+	if self.transform != nil {
+		self.partial = append(self.partial, data...)
+		out, self.partial = self.transform.apply(self.partial)
+	}
+
+	n, err := self.writer.Write(out)
+	self.written += int64(n)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Finish writing the container, correcting the header if possible.  If the
+// AudioWriter was created with CreateAudioFile, the file is closed as well.
+func (self *AudioWriter) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.closed {
+		return nil
+	}
+
+	self.closed = true
+
+	err := func() error {
+		// chunks in both RIFF and IFF containers are padded to an even length
+		if self.pad && self.written%2 == 1 {
+			if _, err := self.writer.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+
+		if seeker, ok := self.writer.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			if data, err := self.header(self.fileSpec, self.written); err == nil {
+				if _, err := self.writer.Write(data); err != nil {
+					return err
+				}
+			} else {
+				return err
+			}
+
+			if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if self.closer != nil {
+		if cerr := self.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// A byteTransform rearranges the bytes of each sample, e.g.: to change
+// endianness or convert between signed and unsigned 8-bit samples.  Output
+// byte i of each sample is taken from input byte picks[i], then XORed with xor.
+type byteTransform struct {
+	inSize int
+	picks  []int
+	xor    byte
+}
+
+var (
+	swap16       = &byteTransform{inSize: 2, picks: []int{1, 0}}
+	swap24       = &byteTransform{inSize: 3, picks: []int{2, 1, 0}}
+	swap32       = &byteTransform{inSize: 4, picks: []int{3, 2, 1, 0}}
+	flipSign8    = &byteTransform{inSize: 1, picks: []int{0}, xor: 0x80}
+	s24_32leToLE = &byteTransform{inSize: 4, picks: []int{0, 1, 2}}
+	s24_32leToBE = &byteTransform{inSize: 4, picks: []int{2, 1, 0}}
+	s24_32beToLE = &byteTransform{inSize: 4, picks: []int{3, 2, 1}}
+	s24_32beToBE = &byteTransform{inSize: 4, picks: []int{1, 2, 3}}
+)
+
+// transform all whole samples in data, returning the result and any trailing
+// partial sample
+func (self *byteTransform) apply(data []byte) ([]byte, []byte) {
+	count := len(data) / self.inSize
+	out := make([]byte, count*len(self.picks))
+
+	for i := 0; i < count; i++ {
+		in := data[i*self.inSize:]
+
+		for j, pick := range self.picks {
+			out[i*len(self.picks)+j] = in[pick] ^ self.xor
+		}
+	}
+
+	rest := make([]byte, len(data)-count*self.inSize)
+	copy(rest, data[count*self.inSize:])
+
+	return out, rest
+}
+
+type transformReader struct {
+	source    io.Reader
+	transform *byteTransform
+	partial   []byte
+	output    []byte
+	err       error
+}
+
+func newTransformReader(source io.Reader, transform *byteTransform) io.Reader {
+	if transform == nil {
+		return source
+	}
+
+	return &transformReader{
+		source:    source,
+		transform: transform,
+	}
+}
+
+func (self *transformReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for len(self.output) == 0 {
+		if self.err != nil {
+			return 0, self.err
+		}
+
+		buf := make([]byte, len(p))
+		n, err := self.source.Read(buf)
+
+		self.partial = append(self.partial, buf[:n]...)
+		self.output, self.partial = self.transform.apply(self.partial)
+		self.err = err
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+// return a reader limited to length bytes, or the whole reader if the length
+// is unknown
+func dataReader(r io.Reader, length int64) io.Reader {
+	if length < 0 {
+		return r
+	}
+
+	return io.LimitReader(r, length)
+}
+
+func skipBytes(r io.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+
+	_, err := io.CopyN(ioutil.Discard, r, n)
+	return err
+}
+
+func frameSizeOf(spec SampleSpec) int {
+	return spec.Format.PCM().SampleSize() * spec.NumChannels
+}
@@ -0,0 +1,242 @@
+package pulse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/auroralaboratories/pulse/pcm"
+	"github.com/stretchr/testify/require"
+)
+
+var testContainerSamples = []float32{0, 0.5, -0.5, 0.25, -1, 0.75, 0.125, -0.125, 0.0625, -0.0625, 0.3, -0.3}
+
+func TestAudioFileRoundTrip(t *testing.T) {
+	assert := require.New(t)
+	dir, err := ioutil.TempDir(``, `pulse-audio-file`)
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	for _, ext := range []string{`.wav`, `.aiff`, `.au`} {
+		for _, format := range []SampleFormat{
+			FormatPcmU8,
+			FormatALaw8,
+			FormatMuLaw8,
+			FormatPcmS16LE,
+			FormatPcmS16BE,
+			FormatPcmS24PackedLE,
+			FormatPcmS24PackedBE,
+			FormatPcmS24Lsb32LE,
+			FormatPcmS24Lsb32BE,
+			FormatPcmS32BE,
+			FormatIEEEFloat32LE,
+			FormatIEEEFloat32BE,
+		} {
+			spec := SampleSpec{
+				Format:      format,
+				SampleRate:  22050,
+				NumChannels: 2,
+			}
+
+			input, err := pcm.Encode(format.PCM(), testContainerSamples)
+			assert.NoError(err)
+
+			path := filepath.Join(dir, `test`+ext)
+			writer, err := CreateAudioFile(path, spec)
+			assert.NoError(err)
+
+			// write in uneven pieces so partial samples must be carried over
+			_, err = writer.Write(input[:5])
+			assert.NoError(err)
+			_, err = writer.Write(input[5:])
+			assert.NoError(err)
+			assert.NoError(writer.Close())
+
+			decodedSpec, reader, err := OpenAudioFile(path)
+			assert.NoError(err, "%s %v", ext, format)
+			assert.Equal(spec.SampleRate, decodedSpec.SampleRate)
+			assert.Equal(spec.NumChannels, decodedSpec.NumChannels)
+
+			data, err := ioutil.ReadAll(reader)
+			assert.NoError(err)
+			reader.Close()
+
+			expected, _ := pcm.Decode(format.PCM(), input)
+			actual, err := pcm.Decode(decodedSpec.Format.PCM(), data)
+			assert.NoError(err, "%s %v -> %v", ext, format, decodedSpec.Format)
+			assert.Equal(expected, actual, "%s %v -> %v", ext, format, decodedSpec.Format)
+		}
+	}
+}
+
+func TestAudioWriterUnseekable(t *testing.T) {
+	assert := require.New(t)
+	spec := SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 1}
+	input := []byte{1, 2, 3, 4, 5, 6}
+
+	for _, create := range []func(w *bytes.Buffer) (*AudioWriter, error){
+		func(w *bytes.Buffer) (*AudioWriter, error) { return NewWAVWriter(w, spec) },
+		func(w *bytes.Buffer) (*AudioWriter, error) { return NewAIFFWriter(w, spec) },
+		func(w *bytes.Buffer) (*AudioWriter, error) { return NewAUWriter(w, spec) },
+	} {
+		var buf bytes.Buffer
+
+		writer, err := create(&buf)
+		assert.NoError(err)
+		_, err = writer.Write(input)
+		assert.NoError(err)
+		assert.NoError(writer.Close())
+
+		decodedSpec, reader, err := DecodeAudio(&buf)
+		assert.NoError(err)
+		assert.Equal(uint32(8000), decodedSpec.SampleRate)
+
+		data, err := ioutil.ReadAll(reader)
+		assert.NoError(err)
+		assert.Len(data, len(input))
+	}
+}
+
+func TestDecodeWAVExtensible(t *testing.T) {
+	assert := require.New(t)
+	var buf bytes.Buffer
+
+	le := func(v interface{}) {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	data := []byte{0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x40}
+
+	buf.WriteString(`RIFF`)
+	le(uint32(4 + 8 + 40 + 8 + len(data)))
+	buf.WriteString(`WAVE`)
+	buf.WriteString(`fmt `)
+	le(uint32(40))
+	le(uint16(wavFormatExtensible))
+	le(uint16(2))
+	le(uint32(48000))
+	le(uint32(48000 * 8))
+	le(uint16(8))
+	le(uint16(32))
+	le(uint16(22))
+	le(uint16(24))
+	le(uint32(3))
+	le(uint16(wavFormatPCM))
+	buf.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71})
+	buf.WriteString(`LIST`)
+	le(uint32(3))
+	buf.Write([]byte{1, 2, 3, 0})
+	buf.WriteString(`data`)
+	le(uint32(len(data)))
+	buf.Write(data)
+	buf.Write([]byte{0xFF, 0xFF})
+
+	spec, reader, err := DecodeAudio(&buf)
+	assert.NoError(err)
+	assert.Equal(SampleSpec{Format: FormatPcmS32LE, SampleRate: 48000, NumChannels: 2}, spec)
+
+	out, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal(data, out)
+}
+
+func TestDecodeWAVEmptyData(t *testing.T) {
+	assert := require.New(t)
+	var buf bytes.Buffer
+
+	_, err := NewWAVWriter(&buf, SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 1})
+	assert.NoError(err)
+
+	header := buf.Bytes()
+
+	// a finished, empty file: a zero-length data chunk followed by metadata
+	binary.LittleEndian.PutUint32(header[len(header)-4:], 0)
+	buf.WriteString(`LIST`)
+	binary.Write(&buf, binary.LittleEndian, uint32(4))
+	buf.WriteString(`INFO`)
+
+	_, reader, err := DecodeAudio(&buf)
+	assert.NoError(err)
+
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Empty(data)
+}
+
+func TestWAVHeaderLayout(t *testing.T) {
+	assert := require.New(t)
+
+	chunks := func(header []byte) map[string][]byte {
+		rv := make(map[string][]byte)
+
+		for offset := 12; offset+8 <= len(header); {
+			size := int(binary.LittleEndian.Uint32(header[offset+4 : offset+8]))
+			end := offset + 8 + size
+
+			if end > len(header) {
+				end = len(header)
+			}
+
+			rv[string(header[offset:offset+4])] = header[offset+8 : end]
+			offset += 8 + size
+		}
+
+		return rv
+	}
+
+	// plain PCM: a 16-byte fmt chunk and no fact chunk
+	header, err := wavHeader(SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 2}, 8)
+	assert.NoError(err)
+	parsed := chunks(header)
+	assert.Len(parsed[`fmt `], 16)
+	assert.NotContains(parsed, `fact`)
+
+	// more than two channels or 16 bits need WAVE_FORMAT_EXTENSIBLE
+	for _, spec := range []SampleSpec{
+		{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 6},
+		{Format: FormatPcmS24PackedLE, SampleRate: 8000, NumChannels: 2},
+	} {
+		header, err := wavHeader(spec, unknownDataLength)
+		assert.NoError(err)
+
+		format := chunks(header)[`fmt `]
+		assert.Len(format, 40)
+		assert.Equal(uint16(wavFormatExtensible), binary.LittleEndian.Uint16(format[0:2]))
+		assert.Equal(uint16(8*spec.Format.PCM().SampleSize()), binary.LittleEndian.Uint16(format[18:20]))
+		assert.Equal(uint16(wavFormatPCM), binary.LittleEndian.Uint16(format[24:26]))
+	}
+
+	// floating point carries a fact chunk with the length in frames
+	header, err = wavHeader(SampleSpec{Format: FormatIEEEFloat32LE, SampleRate: 8000, NumChannels: 2}, 80)
+	assert.NoError(err)
+	parsed = chunks(header)
+	assert.Equal(uint16(wavFormatIEEEFloat), binary.LittleEndian.Uint16(parsed[`fmt `][24:26]))
+	assert.Equal(uint32(10), binary.LittleEndian.Uint32(parsed[`fact`]))
+	assert.Equal(uint32(len(header)-8+80), binary.LittleEndian.Uint32(header[4:8]))
+
+	// sizes that don't fit are an error, not truncated
+	_, err = wavHeader(SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 2}, 1<<32)
+	assert.Error(err)
+
+	_, err = aiffHeader(SampleSpec{Format: FormatPcmS16BE, SampleRate: 8000, NumChannels: 2}, 1<<31)
+	assert.Error(err)
+}
+
+func TestDecodeAudioUnknown(t *testing.T) {
+	_, _, err := DecodeAudio(bytes.NewReader([]byte(`OggS and some other stuff`)))
+	require.Error(t, err)
+}
+
+func TestFloat80(t *testing.T) {
+	assert := require.New(t)
+
+	for _, rate := range []uint32{1, 8000, 22050, 44100, 48000, 96000, 192000} {
+		assert.Equal(rate, float80ToUint32(uint32ToFloat80(rate)))
+	}
+
+	// 44100Hz as written by most AIFF encoders
+	assert.Equal(uint32(44100), float80ToUint32([]byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}))
+}
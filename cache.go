@@ -0,0 +1,452 @@
+package pulse
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+const DEFAULT_CACHE_CHANGES_BUFFER = 64
+
+// A Cache mirrors the objects on a PulseAudio server.  Everything is loaded once
+// when the Cache is created; after that, subscription events cause only the
+// affected object to be re-fetched.  Reads never contact the server, are safe
+// to call from any goroutine, and return deep copies that the caller may modify
+// without affecting the cache.
+type Cache struct {
+	server     Server
+	sub        *Subscription
+	info       ServerInfo
+	sinks      map[int]*Sink
+	sources    map[int]*Source
+	sinkInputs map[int]*SinkInput
+	clients    map[int]*Client
+	modules    map[int]*Module
+	changes    chan Event
+	done       chan bool
+	lock       sync.RWMutex
+	closeOnce  sync.Once
+}
+
+// Create a Cache of every sink, source, sink input, client, and module on the
+// server, and keep it up to date until Close is called.
+func NewCache(server Server) (*Cache, error) {
+	cache := newCache(server)
+
+	// subscribe before loading so that nothing changing during the initial load is
+	// missed; events for objects already loaded just cause a harmless re-fetch
+	if sub, err := server.SubscribeEvents(SinkEvent, SourceEvent, SinkInputEvent, ClientEvent, ModuleEvent, ServerEvent); err == nil {
+		cache.sub = sub
+	} else {
+		return nil, err
+	}
+
+	if err := cache.load(); err != nil {
+		cache.sub.Close()
+		return nil, err
+	}
+
+	go cache.run()
+
+	return cache, nil
+}
+
+func newCache(server Server) *Cache {
+	return &Cache{
+		server:     server,
+		sinks:      make(map[int]*Sink),
+		sources:    make(map[int]*Source),
+		sinkInputs: make(map[int]*SinkInput),
+		clients:    make(map[int]*Client),
+		modules:    make(map[int]*Module),
+		changes:    make(chan Event, DEFAULT_CACHE_CHANGES_BUFFER),
+		done:       make(chan bool),
+	}
+}
+
+// Return a channel that receives an Event each time the Cache is updated.  Events
+// are dropped if the channel is not being read; the Cache itself is always
+// current regardless.
+func (self *Cache) Changes() <-chan Event {
+	return self.changes
+}
+
+// Stop updating the Cache and close the Changes channel.  The last known state
+// remains readable.
+func (self *Cache) Close() error {
+	var err error
+
+	self.closeOnce.Do(func() {
+		close(self.done)
+
+		if self.sub != nil {
+			err = self.sub.Close()
+		} else {
+			close(self.changes)
+		}
+	})
+
+	return err
+}
+
+// Return information about the server.
+func (self *Cache) ServerInfo() ServerInfo {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	return self.info
+}
+
+// Return all sinks, ordered by index.
+func (self *Cache) Sinks() []*Sink {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	out := make([]*Sink, 0, len(self.sinks))
+
+	for _, index := range sortedIndices(self.sinks) {
+		out = append(out, copySink(self.sinks[index]))
+	}
+
+	return out
+}
+
+// Return the sink with the given index.
+func (self *Cache) Sink(index int) (*Sink, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if sink, ok := self.sinks[index]; ok {
+		return copySink(sink), true
+	}
+
+	return nil, false
+}
+
+// Return all sources, ordered by index.
+func (self *Cache) Sources() []*Source {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	out := make([]*Source, 0, len(self.sources))
+
+	for _, index := range sortedIndices(self.sources) {
+		out = append(out, copySource(self.sources[index]))
+	}
+
+	return out
+}
+
+// Return the source with the given index.
+func (self *Cache) Source(index int) (*Source, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if source, ok := self.sources[index]; ok {
+		return copySource(source), true
+	}
+
+	return nil, false
+}
+
+// Return all sink inputs, ordered by index.
+func (self *Cache) SinkInputs() []SinkInput {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	out := make([]SinkInput, 0, len(self.sinkInputs))
+
+	for _, index := range sortedIndices(self.sinkInputs) {
+		out = append(out, *copySinkInput(self.sinkInputs[index]))
+	}
+
+	return out
+}
+
+// Return the sink input with the given index.
+func (self *Cache) SinkInput(index int) (SinkInput, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if sinkInput, ok := self.sinkInputs[index]; ok {
+		return *copySinkInput(sinkInput), true
+	}
+
+	return SinkInput{}, false
+}
+
+// Return all clients, ordered by index.
+func (self *Cache) Clients() []*Client {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	out := make([]*Client, 0, len(self.clients))
+
+	for _, index := range sortedIndices(self.clients) {
+		out = append(out, copyClient(self.clients[index]))
+	}
+
+	return out
+}
+
+// Return the client with the given index.
+func (self *Cache) Client(index int) (*Client, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if client, ok := self.clients[index]; ok {
+		return copyClient(client), true
+	}
+
+	return nil, false
+}
+
+// Return all modules, ordered by index.
+func (self *Cache) Modules() []*Module {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	out := make([]*Module, 0, len(self.modules))
+
+	for _, index := range sortedIndices(self.modules) {
+		out = append(out, copyModule(self.modules[index]))
+	}
+
+	return out
+}
+
+// Return the module with the given index.
+func (self *Cache) Module(index int) (*Module, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if module, ok := self.modules[index]; ok {
+		return copyModule(module), true
+	}
+
+	return nil, false
+}
+
+// Return the cached objects as a State, e.g.: for use with State.Diff.
+func (self *Cache) State() *State {
+	return &State{
+		Server:     self.ServerInfo(),
+		Sinks:      self.Sinks(),
+		Sources:    self.Sources(),
+		SinkInputs: self.SinkInputs(),
+		Clients:    self.Clients(),
+		Modules:    self.Modules(),
+	}
+}
+
+func (self *Cache) load() error {
+	if state, err := snapshot(self.server); err == nil {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+
+		self.info = state.Server
+
+		for _, sink := range state.Sinks {
+			self.sinks[sink.Index] = sink
+		}
+
+		for _, source := range state.Sources {
+			self.sources[source.Index] = source
+		}
+
+		for i := range state.SinkInputs {
+			self.sinkInputs[state.SinkInputs[i].Index] = &state.SinkInputs[i]
+		}
+
+		for _, client := range state.Clients {
+			self.clients[client.Index] = client
+		}
+
+		for _, module := range state.Modules {
+			self.modules[int(module.Index)] = module
+		}
+
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (self *Cache) run() {
+	defer close(self.changes)
+
+	for event := range self.sub.Events() {
+		if event.Kind == EventRemove {
+			self.apply(event, nil)
+		} else if object, err := self.fetch(event); err == nil {
+			self.apply(event, object)
+		} else {
+			// the object may have been removed before we could retrieve it; a
+			// remove event will follow, but don't keep serving stale data until then
+			self.apply(Event{
+				Facility: event.Facility,
+				Kind:     EventRemove,
+				Index:    event.Index,
+			}, nil)
+		}
+	}
+}
+
+// retrieve the object an event refers to
+func (self *Cache) fetch(event Event) (interface{}, error) {
+	switch event.Facility {
+	case SinkEvent:
+		return getSink(self.server, event.Index)
+	case SourceEvent:
+		return getSource(self.server, event.Index)
+	case SinkInputEvent:
+		return getSinkInput(self.server, event.Index)
+	case ClientEvent:
+		return getClient(self.server, event.Index)
+	case ModuleEvent:
+		return getModule(self.server, event.Index)
+	case ServerEvent:
+		return self.server.GetServerInfo()
+	default:
+		return nil, fmt.Errorf("Unsupported event facility %v", event.Facility)
+	}
+}
+
+// update the cache with the result of an event; a nil object removes the entry
+func (self *Cache) apply(event Event, object interface{}) {
+	self.lock.Lock()
+
+	switch event.Facility {
+	case SinkEvent:
+		if sink, ok := object.(*Sink); ok {
+			self.sinks[event.Index] = sink
+		} else {
+			delete(self.sinks, event.Index)
+		}
+	case SourceEvent:
+		if source, ok := object.(*Source); ok {
+			self.sources[event.Index] = source
+		} else {
+			delete(self.sources, event.Index)
+		}
+	case SinkInputEvent:
+		if sinkInput, ok := object.(*SinkInput); ok {
+			self.sinkInputs[event.Index] = sinkInput
+		} else {
+			delete(self.sinkInputs, event.Index)
+		}
+	case ClientEvent:
+		if client, ok := object.(*Client); ok {
+			self.clients[event.Index] = client
+		} else {
+			delete(self.clients, event.Index)
+		}
+	case ModuleEvent:
+		if module, ok := object.(*Module); ok {
+			self.modules[event.Index] = module
+		} else {
+			delete(self.modules, event.Index)
+		}
+	case ServerEvent:
+		if server, ok := object.(ServerInfo); ok {
+			self.info = server
+		}
+	default:
+		self.lock.Unlock()
+		return
+	}
+
+	self.lock.Unlock()
+
+	select {
+	case <-self.done:
+	case self.changes <- event:
+	default:
+	}
+}
+
+func copySink(sink *Sink) *Sink {
+	out := *sink
+	out.Properties = copyProperties(sink.Properties)
+	out.PropList = sink.PropList.Copy()
+	return &out
+}
+
+func copySource(source *Source) *Source {
+	out := *source
+	out.Properties = copyProperties(source.Properties)
+	out.PropList = source.PropList.Copy()
+	return &out
+}
+
+func copySinkInput(sinkInput *SinkInput) *SinkInput {
+	out := *sinkInput
+	out.Properties = copyProperties(sinkInput.Properties)
+	out.PropList = sinkInput.PropList.Copy()
+
+	if sinkInput.Channels != nil {
+		out.Channels = append([]Volume(nil), sinkInput.Channels...)
+	}
+
+	return &out
+}
+
+func copyClient(client *Client) *Client {
+	out := *client
+	out.Properties = copyProperties(client.Properties)
+	out.PropList = client.PropList.Copy()
+	return &out
+}
+
+func copyModule(module *Module) *Module {
+	out := *module
+	out.Properties = copyProperties(module.Properties)
+	out.PropList = module.PropList.Copy()
+	return &out
+}
+
+// copy a (possibly nested) properties map, as produced by maputil.DiffuseMap
+func copyProperties(properties map[string]interface{}) map[string]interface{} {
+	if properties == nil {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(properties))
+
+	for key, value := range properties {
+		out[key] = copyPropertyValue(value)
+	}
+
+	return out
+}
+
+func copyPropertyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return copyProperties(typed)
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+
+		for i, item := range typed {
+			out[i] = copyPropertyValue(item)
+		}
+
+		return out
+	default:
+		return value
+	}
+}
+
+// return the keys of a map of objects by index, in ascending order
+func sortedIndices(objects interface{}) []int {
+	keys := reflect.ValueOf(objects).MapKeys()
+	indices := make([]int, len(keys))
+
+	for i, key := range keys {
+		indices[i] = int(key.Int())
+	}
+
+	sort.Ints(indices)
+	return indices
+}
@@ -0,0 +1,93 @@
+package pulse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheApply(t *testing.T) {
+	assert := require.New(t)
+	cache := newCache(nil)
+
+	cache.apply(Event{Facility: SinkEvent, Kind: EventNew, Index: 4}, &Sink{Index: 4, Name: `headset`})
+	cache.apply(Event{Facility: SinkEvent, Kind: EventNew, Index: 1}, &Sink{Index: 1, Name: `speakers`})
+	cache.apply(Event{Facility: SinkInputEvent, Kind: EventNew, Index: 9}, &SinkInput{Index: 9, Name: `Playback`})
+	cache.apply(Event{Facility: ServerEvent, Kind: EventChange}, ServerInfo{DefaultSinkName: `headset`})
+
+	sinks := cache.Sinks()
+	assert.Len(sinks, 2)
+	assert.Equal(`speakers`, sinks[0].Name)
+	assert.Equal(`headset`, sinks[1].Name)
+	assert.Equal(`headset`, cache.ServerInfo().DefaultSinkName)
+
+	// returned objects are copies
+	sinks[0].Name = `changed`
+	sink, ok := cache.Sink(1)
+	assert.True(ok)
+	assert.Equal(`speakers`, sink.Name)
+
+	cache.apply(Event{Facility: SinkEvent, Kind: EventChange, Index: 1}, &Sink{Index: 1, Name: `renamed`})
+	sink, _ = cache.Sink(1)
+	assert.Equal(`renamed`, sink.Name)
+
+	cache.apply(Event{Facility: SinkEvent, Kind: EventRemove, Index: 4}, nil)
+	_, ok = cache.Sink(4)
+	assert.False(ok)
+	assert.Len(cache.Sinks(), 1)
+
+	sinkInputs := cache.SinkInputs()
+	assert.Len(sinkInputs, 1)
+	assert.Equal(`Playback`, sinkInputs[0].Name)
+
+	state := cache.State()
+	assert.Len(state.Sinks, 1)
+	assert.Len(state.SinkInputs, 1)
+
+	changes := make([]Event, 0)
+
+	for i := 0; i < 6; i++ {
+		changes = append(changes, <-cache.Changes())
+	}
+
+	assert.Equal(EventRemove, changes[5].Kind)
+	assert.Equal(4, changes[5].Index)
+
+	assert.NoError(cache.Close())
+	assert.NoError(cache.Close())
+}
+
+func TestCacheReturnsDeepCopies(t *testing.T) {
+	assert := require.New(t)
+	cache := newCache(nil)
+
+	cache.apply(Event{Facility: SinkEvent, Kind: EventNew, Index: 1}, &Sink{
+		Index:      1,
+		Properties: map[string]interface{}{`device`: map[string]interface{}{`class`: `sound`}},
+		PropList:   PropList{`device.class`: `sound`},
+	})
+
+	cache.apply(Event{Facility: SinkInputEvent, Kind: EventNew, Index: 2}, &SinkInput{
+		Index:    2,
+		Channels: []Volume{{Name: `front-left`, Value: 1}},
+		PropList: PropList{`media.role`: `music`},
+	})
+
+	sink, ok := cache.Sink(1)
+	assert.True(ok)
+	sink.Properties[`device`].(map[string]interface{})[`class`] = `modem`
+	sink.PropList[`device.class`] = `modem`
+
+	sinkInput, ok := cache.SinkInput(2)
+	assert.True(ok)
+	sinkInput.Channels[0].Value = 0
+	sinkInput.PropList[`media.role`] = `phone`
+
+	sink, _ = cache.Sink(1)
+	assert.Equal(`sound`, sink.Properties[`device`].(map[string]interface{})[`class`])
+	assert.Equal(`sound`, sink.PropList.Get(`device.class`))
+
+	sinkInput, _ = cache.SinkInput(2)
+	assert.Equal(1.0, sinkInput.Channels[0].Value)
+	assert.Equal(`music`, sinkInput.PropList.Get(`media.role`))
+}
@@ -0,0 +1,80 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+//export go_connStartupDone
+func go_connStartupDone(connID *C.char, message *C.char) {
+	if conn, ok := cgoget(C.GoString(connID)).(*Conn); ok {
+		conn.SignalAll(false)
+	}
+}
+
+//export go_operationSetProperty
+func go_operationSetProperty(operationId *C.char, k *C.char, v *C.char, convertTo *C.char) {
+	if operation, ok := cgoget(C.GoString(operationId)).(*Operation); ok {
+		var ctype string
+
+		if convertTo != nil {
+			ctype = C.GoString(convertTo)
+		}
+
+		operation.setProperty(C.GoString(k), C.GoString(v), ctype)
+	}
+}
+
+//export go_operationCreatePayload
+func go_operationCreatePayload(operationId *C.char) {
+	if operation, ok := cgoget(C.GoString(operationId)).(*Operation); ok {
+		operation.createPayload()
+	}
+}
+
+//export go_operationComplete
+func go_operationComplete(operationId *C.char) {
+	if operation, ok := cgoget(C.GoString(operationId)).(*Operation); ok {
+		operation.complete()
+
+		// unref pa_operation
+		if operation.paOper != nil {
+			C.pa_operation_unref(operation.paOper)
+		}
+
+		operation.Done()
+	}
+}
+
+//export go_operationFailed
+func go_operationFailed(operationId *C.char, message *C.char) {
+	if operation, ok := cgoget(C.GoString(operationId)).(*Operation); ok {
+		// unref pa_operation
+		if operation.paOper != nil {
+			C.pa_operation_unref(operation.paOper)
+		}
+
+		if msg := C.GoString(message); msg == `` {
+			operation.SetError(fmt.Errorf("Unknown error"))
+		} else {
+			operation.SetError(errors.New(msg))
+		}
+
+		operation.Done()
+	}
+}
+
+//export go_clientEventCallback
+func go_clientEventCallback(types C.pa_subscription_event_type_t, index C.uint32_t, connID *C.char) {
+	if conn, ok := cgoget(C.GoString(connID)).(*Conn); ok {
+		conn.events.Dispatch(ParseEvent(int(types), int(index)))
+	}
+}
@@ -0,0 +1,61 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"unsafe"
+)
+
+func channelPositionName(pos ChannelPosition) string {
+	return C.GoString(C.pa_channel_position_to_string(C.pa_channel_position_t(pos)))
+}
+
+func parseChannelPosition(name string) ChannelPosition {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	return ChannelPosition(C.pa_channel_position_from_string(cName))
+}
+
+func defaultChannelMap(channels int) ChannelMap {
+	var native C.pa_channel_map
+
+	if C.pa_channel_map_init_auto(&native, C.uint(channels), C.PA_CHANNEL_MAP_DEFAULT) == nil {
+		return nil
+	}
+
+	return channelMapFromNative(&native)
+}
+
+func (self ChannelMap) toNative() *C.pa_channel_map {
+	var native C.pa_channel_map
+
+	C.pa_channel_map_init(&native)
+
+	for i, pos := range self {
+		if i >= int(C.PA_CHANNELS_MAX) {
+			break
+		}
+
+		native.channels = C.uint8_t(i + 1)
+		native._map[i] = C.pa_channel_position_t(pos)
+	}
+
+	return &native
+}
+
+func channelMapFromNative(native *C.pa_channel_map) ChannelMap {
+	rv := make(ChannelMap, int(native.channels))
+
+	for i := range rv {
+		rv[i] = ChannelPosition(native._map[i])
+	}
+
+	return rv
+}
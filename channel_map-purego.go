@@ -0,0 +1,109 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+// position names as PulseAudio formats them, indexed by position
+var channelPositionNames = []string{
+	`mono`,
+	`front-left`,
+	`front-right`,
+	`front-center`,
+	`rear-center`,
+	`rear-left`,
+	`rear-right`,
+	`lfe`,
+	`front-left-of-center`,
+	`front-right-of-center`,
+	`side-left`,
+	`side-right`,
+	`aux0`,
+	`aux1`,
+	`aux2`,
+	`aux3`,
+	`aux4`,
+	`aux5`,
+	`aux6`,
+	`aux7`,
+	`aux8`,
+	`aux9`,
+	`aux10`,
+	`aux11`,
+	`aux12`,
+	`aux13`,
+	`aux14`,
+	`aux15`,
+	`aux16`,
+	`aux17`,
+	`aux18`,
+	`aux19`,
+	`aux20`,
+	`aux21`,
+	`aux22`,
+	`aux23`,
+	`aux24`,
+	`aux25`,
+	`aux26`,
+	`aux27`,
+	`aux28`,
+	`aux29`,
+	`aux30`,
+	`aux31`,
+	`top-center`,
+	`top-front-left`,
+	`top-front-right`,
+	`top-front-center`,
+	`top-rear-left`,
+	`top-rear-right`,
+	`top-rear-center`,
+}
+
+// alternative names accepted by pa_channel_position_from_string
+var channelPositionAliases = map[string]ChannelPosition{
+	`left`:      ChannelFrontLeft,
+	`right`:     ChannelFrontRight,
+	`center`:    ChannelFrontCenter,
+	`subwoofer`: ChannelLFE,
+}
+
+func channelPositionName(pos ChannelPosition) string {
+	if pos < 0 || int(pos) >= len(channelPositionNames) {
+		return ``
+	}
+
+	return channelPositionNames[pos]
+}
+
+func parseChannelPosition(name string) ChannelPosition {
+	for i, posName := range channelPositionNames {
+		if name == posName {
+			return ChannelPosition(i)
+		}
+	}
+
+	if pos, ok := channelPositionAliases[name]; ok {
+		return pos
+	}
+
+	return ChannelInvalid
+}
+
+// the AIFF channel map, which is PulseAudio's default
+func defaultChannelMap(channels int) ChannelMap {
+	switch channels {
+	case 1:
+		return ChannelMap{ChannelMono}
+	case 2:
+		return ChannelMap{ChannelFrontLeft, ChannelFrontRight}
+	case 3:
+		return ChannelMap{ChannelFrontLeft, ChannelFrontRight, ChannelFrontCenter}
+	case 4:
+		return ChannelMap{ChannelFrontLeft, ChannelFrontCenter, ChannelFrontRight, ChannelRearCenter}
+	case 5:
+		return ChannelMap{ChannelFrontLeft, ChannelFrontRight, ChannelFrontCenter, ChannelRearLeft, ChannelRearRight}
+	case 6:
+		return ChannelMap{ChannelFrontLeft, ChannelRearLeft, ChannelFrontCenter, ChannelFrontRight, ChannelRearRight, ChannelLFE}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package pulse
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ChannelPosition int
+
+const (
+	ChannelInvalid            ChannelPosition = -1
+	ChannelMono               ChannelPosition = 0
+	ChannelFrontLeft          ChannelPosition = 1
+	ChannelFrontRight         ChannelPosition = 2
+	ChannelFrontCenter        ChannelPosition = 3
+	ChannelRearCenter         ChannelPosition = 4
+	ChannelRearLeft           ChannelPosition = 5
+	ChannelRearRight          ChannelPosition = 6
+	ChannelLFE                ChannelPosition = 7
+	ChannelFrontLeftOfCenter  ChannelPosition = 8
+	ChannelFrontRightOfCenter ChannelPosition = 9
+	ChannelSideLeft           ChannelPosition = 10
+	ChannelSideRight          ChannelPosition = 11
+	ChannelAux0               ChannelPosition = 12
+	ChannelAux1               ChannelPosition = 13
+	ChannelAux2               ChannelPosition = 14
+	ChannelAux3               ChannelPosition = 15
+	ChannelAux4               ChannelPosition = 16
+	ChannelAux5               ChannelPosition = 17
+	ChannelAux6               ChannelPosition = 18
+	ChannelAux7               ChannelPosition = 19
+	ChannelAux8               ChannelPosition = 20
+	ChannelAux9               ChannelPosition = 21
+	ChannelAux10              ChannelPosition = 22
+	ChannelAux11              ChannelPosition = 23
+	ChannelAux12              ChannelPosition = 24
+	ChannelAux13              ChannelPosition = 25
+	ChannelAux14              ChannelPosition = 26
+	ChannelAux15              ChannelPosition = 27
+	ChannelAux16              ChannelPosition = 28
+	ChannelAux17              ChannelPosition = 29
+	ChannelAux18              ChannelPosition = 30
+	ChannelAux19              ChannelPosition = 31
+	ChannelAux20              ChannelPosition = 32
+	ChannelAux21              ChannelPosition = 33
+	ChannelAux22              ChannelPosition = 34
+	ChannelAux23              ChannelPosition = 35
+	ChannelAux24              ChannelPosition = 36
+	ChannelAux25              ChannelPosition = 37
+	ChannelAux26              ChannelPosition = 38
+	ChannelAux27              ChannelPosition = 39
+	ChannelAux28              ChannelPosition = 40
+	ChannelAux29              ChannelPosition = 41
+	ChannelAux30              ChannelPosition = 42
+	ChannelAux31              ChannelPosition = 43
+	ChannelTopCenter          ChannelPosition = 44
+	ChannelTopFrontLeft       ChannelPosition = 45
+	ChannelTopFrontRight      ChannelPosition = 46
+	ChannelTopFrontCenter     ChannelPosition = 47
+	ChannelTopRearLeft        ChannelPosition = 48
+	ChannelTopRearRight       ChannelPosition = 49
+	ChannelTopRearCenter      ChannelPosition = 50
+)
+
+// Return the position name as PulseAudio formats it (e.g.: "front-left").
+func (self ChannelPosition) String() string {
+	if self == ChannelInvalid {
+		return `invalid`
+	}
+
+	return channelPositionName(self)
+}
+
+// Parse a PulseAudio channel position name (e.g.: "front-left", "lfe", "aux3").
+func ParseChannelPosition(name string) (ChannelPosition, error) {
+	if pos := parseChannelPosition(name); pos != ChannelInvalid {
+		return pos, nil
+	}
+
+	return ChannelInvalid, fmt.Errorf("Invalid channel position %q", name)
+}
+
+// A ChannelMap describes the position of each channel in a stream of audio.
+type ChannelMap []ChannelPosition
+
+// Return the default channel map PulseAudio would use for the given number of
+// channels.
+func DefaultChannelMap(channels int) ChannelMap {
+	return defaultChannelMap(channels)
+}
+
+// Parse a comma-separated list of channel position names.
+func ParseChannelMap(spec string) (ChannelMap, error) {
+	rv := make(ChannelMap, 0)
+
+	for _, name := range strings.Split(spec, `,`) {
+		if pos, err := ParseChannelPosition(strings.TrimSpace(name)); err == nil {
+			rv = append(rv, pos)
+		} else {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+// Return the channel map as a comma-separated list of position names.
+func (self ChannelMap) String() string {
+	names := make([]string, len(self))
+
+	for i, pos := range self {
+		names[i] = pos.String()
+	}
+
+	return strings.Join(names, `,`)
+}
@@ -0,0 +1,184 @@
+package pulse
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/auroralaboratories/pulse/pcm"
+)
+
+// the ITU-R BS.775 downmix coefficient (-3dB) for center and surround channels
+const ituDownmixGain = 0.70710677
+
+// A ChannelMatrix describes how each channel of one channel map contributes to
+// each channel of another.  Gains[o][i] is the gain applied to input channel
+// From[i] when summing output channel To[o].
+type ChannelMatrix struct {
+	From  ChannelMap
+	To    ChannelMap
+	Gains [][]float32
+}
+
+// Return a matrix that maps between two channel maps.  Positions present in
+// both are copied unchanged; mono is copied to every output or averaged from
+// every input; and other unmatched channels are downmixed using the ITU-R
+// BS.775 coefficients, e.g.: 5.1 to stereo as L = FL + 0.707*C + 0.707*RL.
+// LFE and auxiliary channels with no matching output are dropped.
+func NewChannelMatrix(from ChannelMap, to ChannelMap) *ChannelMatrix {
+	rv := &ChannelMatrix{
+		From:  from,
+		To:    to,
+		Gains: make([][]float32, len(to)),
+	}
+
+	for o := range rv.Gains {
+		rv.Gains[o] = make([]float32, len(from))
+	}
+
+	monoOut := indexOfPosition(to, ChannelMono)
+	contributing := 0
+
+	for _, in := range from {
+		if in != ChannelLFE && !isAuxChannel(in) {
+			contributing++
+		}
+	}
+
+	for i, in := range from {
+		if o := indexOfPosition(to, in); o >= 0 {
+			rv.Gains[o][i] = 1
+			continue
+		}
+
+		switch {
+		case in == ChannelLFE || isAuxChannel(in):
+			continue
+
+		case in == ChannelMono:
+			for o, out := range to {
+				if out != ChannelLFE && !isAuxChannel(out) {
+					rv.Gains[o][i] = 1
+				}
+			}
+
+		case monoOut >= 0:
+			rv.Gains[monoOut][i] = 1 / float32(contributing)
+
+		default:
+			for _, o := range downmixTargets(to, channelSide(in)) {
+				rv.Gains[o][i] = ituDownmixGain
+			}
+		}
+	}
+
+	return rv
+}
+
+// Set the gain applied to the input position when summing the output position.
+func (self *ChannelMatrix) Set(from ChannelPosition, to ChannelPosition, gain float32) error {
+	if i := indexOfPosition(self.From, from); i < 0 {
+		return fmt.Errorf("Channel %v is not in the input channel map %v", from, self.From)
+	} else if o := indexOfPosition(self.To, to); o < 0 {
+		return fmt.Errorf("Channel %v is not in the output channel map %v", to, self.To)
+	} else {
+		self.Gains[o][i] = gain
+		return nil
+	}
+}
+
+// Return the gain applied to the input position when summing the output
+// position, or zero if either position is not present.
+func (self *ChannelMatrix) Get(from ChannelPosition, to ChannelPosition) float32 {
+	if i := indexOfPosition(self.From, from); i >= 0 {
+		if o := indexOfPosition(self.To, to); o >= 0 {
+			return self.Gains[o][i]
+		}
+	}
+
+	return 0
+}
+
+// Wrap the given reader so that interleaved audio in the matrix's input channel
+// map is remixed into its output channel map as it is read.  The sample format
+// is unchanged.
+func NewChannelMixer(source io.Reader, format SampleFormat, matrix *ChannelMatrix) (io.Reader, error) {
+	if !format.PCM().Valid() {
+		return nil, fmt.Errorf("Cannot remix unsupported sample format %v", format)
+	} else if err := pcm.ValidateMatrix(matrix.Gains); err != nil {
+		return nil, err
+	} else if len(matrix.Gains) != len(matrix.To) || len(matrix.Gains[0]) != len(matrix.From) {
+		return nil, fmt.Errorf("Channel matrix is %dx%d, but maps %v to %v", len(matrix.Gains[0]), len(matrix.Gains), matrix.From, matrix.To)
+	}
+
+	return pcm.NewRemixingReader(source, format.PCM(), matrix.Gains), nil
+}
+
+const (
+	sideCenter = iota
+	sideLeft
+	sideRight
+)
+
+func channelSide(pos ChannelPosition) int {
+	switch pos {
+	case ChannelFrontLeft, ChannelRearLeft, ChannelFrontLeftOfCenter, ChannelSideLeft, ChannelTopFrontLeft, ChannelTopRearLeft:
+		return sideLeft
+	case ChannelFrontRight, ChannelRearRight, ChannelFrontRightOfCenter, ChannelSideRight, ChannelTopFrontRight, ChannelTopRearRight:
+		return sideRight
+	default:
+		return sideCenter
+	}
+}
+
+func isAuxChannel(pos ChannelPosition) bool {
+	return pos >= ChannelAux0 && pos <= ChannelAux31
+}
+
+// return the output channels an unmatched input on the given side is folded
+// into: the front channel(s) on that side, otherwise any channel on that side,
+// otherwise the front center
+func downmixTargets(to ChannelMap, side int) []int {
+	targets := make([]int, 0)
+
+	for _, s := range []int{sideLeft, sideRight} {
+		if side != sideCenter && side != s {
+			continue
+		}
+
+		front := ChannelFrontLeft
+
+		if s == sideRight {
+			front = ChannelFrontRight
+		}
+
+		if o := indexOfPosition(to, front); o >= 0 {
+			targets = append(targets, o)
+			continue
+		}
+
+		for o, out := range to {
+			if channelSide(out) == s {
+				targets = append(targets, o)
+				break
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		if o := indexOfPosition(to, ChannelFrontCenter); o >= 0 {
+			targets = append(targets, o)
+		}
+	}
+
+	return targets
+}
+
+func indexOfPosition(channelMap ChannelMap, pos ChannelPosition) int {
+	for i, p := range channelMap {
+		if p == pos {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,64 @@
+package pulse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/auroralaboratories/pulse/pcm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMatrix(t *testing.T) {
+	assert := require.New(t)
+
+	mono := ChannelMap{ChannelMono}
+	stereo := ChannelMap{ChannelFrontLeft, ChannelFrontRight}
+	surround := ChannelMap{ChannelFrontLeft, ChannelFrontRight, ChannelFrontCenter, ChannelLFE, ChannelRearLeft, ChannelRearRight}
+
+	assert.Equal([][]float32{{1}, {1}}, NewChannelMatrix(mono, stereo).Gains)
+	assert.Equal([][]float32{{0.5, 0.5}}, NewChannelMatrix(stereo, mono).Gains)
+	assert.Equal([][]float32{{1, 0}, {0, 1}}, NewChannelMatrix(stereo, stereo).Gains)
+
+	assert.Equal([][]float32{
+		{1, 0, ituDownmixGain, 0, ituDownmixGain, 0},
+		{0, 1, ituDownmixGain, 0, 0, ituDownmixGain},
+	}, NewChannelMatrix(surround, stereo).Gains)
+
+	// upmixing only fills the matching positions
+	upmix := NewChannelMatrix(stereo, surround)
+	assert.Equal(float32(1), upmix.Get(ChannelFrontLeft, ChannelFrontLeft))
+	assert.Equal(float32(0), upmix.Get(ChannelFrontLeft, ChannelRearLeft))
+
+	custom := NewChannelMatrix(stereo, stereo)
+	assert.NoError(custom.Set(ChannelFrontLeft, ChannelFrontRight, 0.25))
+	assert.Equal(float32(0.25), custom.Get(ChannelFrontLeft, ChannelFrontRight))
+	assert.Error(custom.Set(ChannelLFE, ChannelFrontRight, 1))
+}
+
+func TestChannelMixer(t *testing.T) {
+	assert := require.New(t)
+
+	data, err := pcm.Encode(pcm.Float32LE, []float32{0.5, 0.25, -1, 1})
+	assert.NoError(err)
+
+	reader, err := NewChannelMixer(bytes.NewReader(data), FormatIEEEFloat32LE, NewChannelMatrix(
+		ChannelMap{ChannelFrontLeft, ChannelFrontRight},
+		ChannelMap{ChannelMono},
+	))
+	assert.NoError(err)
+
+	out, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+
+	samples, err := pcm.Decode(pcm.Float32LE, out)
+	assert.NoError(err)
+	assert.Equal([]float32{0.375, 0}, samples)
+
+	_, err = NewChannelMixer(nil, FormatIEEEFloat32LE, &ChannelMatrix{
+		From:  ChannelMap{ChannelMono},
+		To:    ChannelMap{ChannelMono},
+		Gains: [][]float32{{1, 1}},
+	})
+	assert.Error(err)
+}
@@ -1,10 +1,5 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"fmt"
 
@@ -19,6 +14,7 @@ type Client struct {
 	OwnerModuleIndex int
 	Driver           string
 	Properties       map[string]interface{}
+	PropList         PropList
 	conn             *Conn
 }
 
@@ -38,12 +34,7 @@ func (self *Client) Refresh() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_get_client_info(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_client_info_cb_t)(C.pulse_get_client_info_callback),
-		operation.Userdata(),
-	)
+	self.conn.getClientInfo(operation, self.Index)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -53,6 +44,8 @@ func (self *Client) Refresh() error {
 			if err := self.Initialize(payload.Properties); err != nil {
 				return err
 			}
+
+			self.PropList = payload.PropList
 		} else {
 			return fmt.Errorf("Invalid client response: expected 1 payload, got %d", l)
 		}
@@ -67,12 +60,7 @@ func (self *Client) Kill() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_kill_client(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-		operation.Userdata(),
-	)
+	self.conn.killClient(operation, self.Index)
 
 	// wait for the result, refresh, return any errors
 	if err := operation.Wait(); err == nil {
@@ -41,6 +41,14 @@ func main() {
 			Name:  `offset, o`,
 			Usage: `The numeric offset to seek to before returning results, used for implemented pagination.`,
 		},
+		cli.StringSliceFlag{
+			Name:  `sort, s`,
+			Usage: `Sort results by the given field (e.g.: "VolumeFactor desc", "@application.name"); may be repeated.`,
+		},
+		cli.StringSliceFlag{
+			Name:  `fields, F`,
+			Usage: `Only output the given fields of each result (e.g.: "Name", "@application.name"); may be repeated.`,
+		},
 	}
 
 	app.Before = func(c *cli.Context) error {
@@ -120,11 +128,27 @@ func main() {
 func print(c *cli.Context, data interface{}, txtfn func()) {
 	if data != nil {
 		if typeutil.IsArray(data) {
-			var limit = c.GlobalInt(`limit`)
-			var offset = c.GlobalInt(`offset`)
+			query := &pulse.Query{
+				Sort:   c.GlobalStringSlice(`sort`),
+				Fields: c.GlobalStringSlice(`fields`),
+				Limit:  c.GlobalInt(`limit`),
+			}
+
+			// the offset has only ever applied along with a limit
+			if query.Limit > 0 {
+				query.Offset = c.GlobalInt(`offset`)
+			}
+
+			var err error
+
+			if len(query.Fields) > 0 {
+				data, err = query.Project(data)
+			} else {
+				data, err = query.Select(data)
+			}
 
-			if limit > 0 {
-				data = sliceutil.Slice(data, offset, offset+limit)
+			if err != nil {
+				log.Fatalf("Invalid query: %v", err)
 			}
 		}
 
@@ -0,0 +1,231 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/ghetzel/go-stockutil/stringutil"
+)
+
+// A PulseAudio Conn represents a connection to a PulseAudio daemon (either locally or
+// on a remote host). A Conn is the primary entry point for working with PulseAudio
+// objects and data.
+type Conn struct {
+	ID               string
+	Name             string
+	Server           string
+	OperationTimeout time.Duration
+	state            chan error
+	mainloop         *C.pa_threaded_mainloop
+	context          *C.pa_context
+	api              *C.pa_mainloop_api
+	isLocked         bool
+	events           EventHub
+	subscriptionMask int
+	subscriptionLock sync.Mutex
+}
+
+// Connect to the PulseAudio server at the given address (e.g.:
+// "unix:/run/user/1000/pulse/native" or "tcp:host:4713").  An empty address
+// connects to the default server.
+func NewWithServer(name string, server string) (*Conn, error) {
+	rv := &Conn{
+		ID:               stringutil.UUID().String(),
+		Name:             name,
+		Server:           server,
+		OperationTimeout: (time.Duration(DEFAULT_OPERATION_TIMEOUT_MSEC) * time.Millisecond),
+		state:            make(chan error),
+	}
+
+	cgoregister(rv.ID, rv)
+
+	rv.mainloop = C.pa_threaded_mainloop_new()
+	if rv.mainloop == nil {
+		return nil, fmt.Errorf("Failed to create PulseAudio mainloop")
+	}
+
+	rv.api = C.pa_threaded_mainloop_get_api(rv.mainloop)
+	rv.context = C.pa_context_new(rv.api, C.CString(name))
+
+	C.pa_context_set_state_callback(
+		rv.context,
+		(C.pa_context_notify_cb_t)(C.pulse_context_state_callback),
+		rv.Userdata(),
+	)
+
+	// lock the mainloop until the context is ready
+	rv.Lock()
+
+	// start the mainloop
+	rv.Start()
+
+	var address *C.char
+
+	if server != `` {
+		address = C.CString(server)
+		defer C.free(unsafe.Pointer(address))
+	}
+
+	// initiate context connect
+	if int(C.pa_context_connect(rv.context, address, (C.pa_context_flags_t)(0), nil)) != 0 {
+		defer rv.Stop()
+		defer rv.Destroy()
+
+		return nil, rv.GetLastError()
+	}
+
+	// wait for context to be ready
+	for {
+		state := ContextState(int(C.pa_context_get_state(rv.context)))
+		breakOut := false
+
+		switch state {
+		case StateUnconnected, StateConnecting, StateAuthorizing, StateSettingName:
+			if err := rv.Wait(); err != nil {
+				return nil, err
+			}
+		case StateFailed:
+			return nil, rv.GetLastError()
+		case StateTerminated:
+			return nil, fmt.Errorf("PulseAudio connection was terminated during setup")
+		case StateReady:
+			breakOut = true
+		default:
+			return nil, fmt.Errorf("Encountered unknown connection state %d during setup", state)
+		}
+
+		if breakOut {
+			break
+		}
+	}
+
+	rv.Unlock()
+
+	return rv, nil
+}
+
+// Retrieve the last error message from the current context
+//
+func (self *Conn) GetLastError() error {
+	if self.context != nil {
+		msg := C.GoString(C.pa_strerror(C.pa_context_errno(self.context)))
+
+		if msg != `` {
+			return errors.New(msg)
+		}
+	}
+
+	return nil
+}
+
+// Acquire an exclusive lock on the mainloop
+//
+func (self *Conn) Lock() {
+	if self.mainloop != nil && !self.isLocked {
+		self.isLocked = true
+		C.pa_threaded_mainloop_lock(self.mainloop)
+	}
+}
+
+// Release an exclusive lock on the mainloop
+//
+func (self *Conn) Unlock() {
+	if self.mainloop != nil && self.isLocked {
+		C.pa_threaded_mainloop_unlock(self.mainloop)
+		self.isLocked = false
+	}
+}
+
+// Start the mainloop
+//
+func (self *Conn) Start() error {
+	if self.mainloop != nil {
+		if status := C.pa_threaded_mainloop_start(self.mainloop); status < 0 {
+			return fmt.Errorf("PulseAudio mainloop start failed with code %d", status)
+		}
+	} else {
+		return fmt.Errorf("Cannot operate on undefined PulseAudio mainloop")
+	}
+
+	return nil
+}
+
+// Wait for a signalling event on the mainloop
+//
+func (self *Conn) Wait() error {
+	if self.mainloop != nil {
+		C.pa_threaded_mainloop_wait(self.mainloop)
+	} else {
+		return fmt.Errorf("Cannot operate on undefined PulseAudio mainloop")
+	}
+
+	return nil
+}
+
+// Send a signalling event to all waiting threads
+//
+func (self *Conn) SignalAll(waitForAccept bool) error {
+	if self.mainloop != nil {
+		if waitForAccept {
+			C.pa_threaded_mainloop_signal(self.mainloop, C.int(1))
+		} else {
+			C.pa_threaded_mainloop_signal(self.mainloop, C.int(0))
+		}
+	} else {
+		return fmt.Errorf("Cannot operate on undefined PulseAudio mainloop")
+	}
+
+	return nil
+}
+
+// Stop the mainloop
+//
+func (self *Conn) Stop() error {
+	if self.mainloop != nil {
+		self.Unlock()
+		C.pa_threaded_mainloop_stop(self.mainloop)
+	} else {
+		return fmt.Errorf("Cannot operate on undefined PulseAudio mainloop")
+	}
+
+	return nil
+}
+
+// Disconnect from the server, stop the mainloop, and release the connection.
+//
+func (self *Conn) Close() error {
+	if self.context != nil {
+		self.LockFunc(func() error {
+			C.pa_context_disconnect(self.context)
+			return nil
+		})
+	}
+
+	err := self.Stop()
+	self.Destroy()
+
+	return err
+}
+
+// Unregister this client instance from the global CGO tracking pool
+//
+func (self *Conn) Destroy() {
+	cgounregister(self.ID)
+}
+
+// Wrap this client's ID in a format suitable for passing into C functions as a void-pointer
+//
+func (self *Conn) Userdata() unsafe.Pointer {
+	return unsafe.Pointer(C.CString(self.ID))
+}
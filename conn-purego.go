@@ -0,0 +1,257 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/auroralaboratories/pulse/proto"
+	"github.com/ghetzel/go-stockutil/stringutil"
+)
+
+// A PulseAudio Conn represents a connection to a PulseAudio daemon (either locally or
+// on a remote host). A Conn is the primary entry point for working with PulseAudio
+// objects and data.
+//
+// This implementation speaks the native protocol itself, and is used when
+// building without cgo (or with the purego build tag).
+type Conn struct {
+	ID               string
+	Name             string
+	Server           string
+	OperationTimeout time.Duration
+	client           *proto.Client
+	address          string
+	version          uint32
+	lock             sync.Mutex
+	cond             *sync.Cond
+	isLocked         bool
+	lastError        error
+	nextSyncID       uint32
+	playbackStreams  map[uint32]*Stream
+	recordStreams    map[uint32]*Stream
+	events           EventHub
+	subscriptionMask int
+	subscriptionLock sync.Mutex
+}
+
+// Connect to the PulseAudio server at the given address (e.g.:
+// "unix:/run/user/1000/pulse/native" or "tcp:host:4713").  An empty address
+// connects to the default server.
+func NewWithServer(name string, server string) (*Conn, error) {
+	rv := &Conn{
+		ID:               stringutil.UUID().String(),
+		Name:             name,
+		Server:           server,
+		OperationTimeout: (time.Duration(DEFAULT_OPERATION_TIMEOUT_MSEC) * time.Millisecond),
+		playbackStreams:  make(map[uint32]*Stream),
+		recordStreams:    make(map[uint32]*Stream),
+	}
+
+	rv.cond = sync.NewCond(&rv.lock)
+
+	var addrs []string
+
+	if server != `` {
+		addrs = []string{server}
+	}
+
+	conn, err := proto.Dial(addrs...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if remote := conn.RemoteAddr(); remote.Network() == `unix` {
+		rv.address = remote.String()
+	} else {
+		rv.address = remote.Network() + `:` + remote.String()
+	}
+
+	rv.client = proto.NewClient(conn)
+	rv.client.OnCommand = rv.handleCommand
+	rv.client.OnData = rv.handleData
+	rv.client.OnClose = rv.handleClose
+
+	go rv.client.Run()
+
+	props := proto.PropList{
+		`application.name`:           name,
+		`application.language`:       `C`,
+		`application.process.id`:     strconv.Itoa(os.Getpid()),
+		`application.process.binary`: filepath.Base(os.Args[0]),
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		props[`application.process.host`] = hostname
+	}
+
+	if err := rv.client.Handshake(proto.LoadCookie(), props); err != nil {
+		rv.client.Close()
+		return nil, err
+	}
+
+	rv.version = rv.client.Version
+
+	return rv, nil
+}
+
+// Retrieve the last error to occur on this connection
+//
+func (self *Conn) GetLastError() error {
+	return self.lastError
+}
+
+// Acquire an exclusive lock on the connection
+//
+func (self *Conn) Lock() {
+	self.lock.Lock()
+	self.isLocked = true
+}
+
+// Release an exclusive lock on the connection
+//
+func (self *Conn) Unlock() {
+	if self.isLocked {
+		self.isLocked = false
+		self.lock.Unlock()
+	}
+}
+
+// Start the connection.  Replies are read as soon as the connection is made,
+// so there is nothing to do.
+//
+func (self *Conn) Start() error {
+	return nil
+}
+
+// Wait for a signalling event on the connection; the connection must be locked.
+//
+func (self *Conn) Wait() error {
+	self.cond.Wait()
+	return nil
+}
+
+// Send a signalling event to all waiting threads
+//
+func (self *Conn) SignalAll(waitForAccept bool) error {
+	self.cond.Broadcast()
+	return nil
+}
+
+// Stop reading from the server and close the connection.  The lock is left
+// alone: anyone waiting on the connection is woken once the client has closed.
+//
+func (self *Conn) Stop() error {
+	self.client.Close()
+
+	return nil
+}
+
+// Disconnect from the server and release the connection.
+//
+func (self *Conn) Close() error {
+	err := self.Stop()
+	self.Destroy()
+
+	return err
+}
+
+// Release any resources held by the connection.  There are none beyond the
+// socket, which Stop has already closed.
+//
+func (self *Conn) Destroy() {}
+
+// Wrap this client's ID in the same form as the cgo implementation does.  Nothing
+// is passed to C here, so it is only useful as an opaque value.
+//
+func (self *Conn) Userdata() unsafe.Pointer {
+	return userdata(self.ID)
+}
+
+// Handle the commands the server sends of its own accord.  Called from the
+// client's read loop.
+func (self *Conn) handleCommand(command proto.Command, args *proto.TagStruct) {
+	// events don't touch the connection's state, so they are dispatched without
+	// holding its lock
+	if command == proto.CommandSubscribeEvent {
+		if event, err := args.GetU32(); err == nil {
+			if index, err := args.GetU32(); err == nil {
+				self.events.Dispatch(ParseEvent(int(event), int(index)))
+			}
+		}
+
+		return
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	defer self.cond.Broadcast()
+
+	switch command {
+	case proto.CommandRequest:
+		if channel, err := args.GetU32(); err == nil {
+			if length, err := args.GetU32(); err == nil {
+				if stream, ok := self.playbackStreams[channel]; ok {
+					stream.requested += int(length)
+					stream.fill()
+				}
+			}
+		}
+
+	case proto.CommandPlaybackStreamKilled:
+		if channel, err := args.GetU32(); err == nil {
+			if stream, ok := self.playbackStreams[channel]; ok {
+				stream.kill(proto.ErrKilled)
+			}
+		}
+
+	case proto.CommandRecordStreamKilled:
+		if channel, err := args.GetU32(); err == nil {
+			if stream, ok := self.recordStreams[channel]; ok {
+				stream.kill(proto.ErrKilled)
+			}
+		}
+	}
+}
+
+// Pass recorded data along to the stream it belongs to.  Called from the
+// client's read loop.
+func (self *Conn) handleData(packet *proto.Packet) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if stream, ok := self.recordStreams[packet.Channel]; ok {
+		stream.writeToDestination(packet.Data)
+	}
+}
+
+// Record why the connection went away, and fail any streams still open.
+// Outstanding operations have already been failed by the client.
+func (self *Conn) handleClose(err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	defer self.cond.Broadcast()
+
+	self.lastError = err
+
+	for _, stream := range self.playbackStreams {
+		stream.kill(err)
+	}
+
+	for _, stream := range self.recordStreams {
+		stream.kill(err)
+	}
+}
+
+// return an ID as a NUL-terminated string, like C.CString does
+func userdata(id string) unsafe.Pointer {
+	data := append([]byte(id), 0)
+	return unsafe.Pointer(&data[0])
+}
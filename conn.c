@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include "conn.h"
 
 #ifndef FALSE
@@ -105,6 +107,7 @@ void pulse_get_server_info_callback(pa_context *ctx, const pa_server_info *info,
 
 void pulse_get_sink_info_by_index_callback(pa_context *ctx, const pa_sink_info *info, int eol, void *op) {
     char buf[1024];
+    char key[1024];
 
     if (eol < 0) {
         OPERR(op, pa_strerror(pa_context_errno(ctx)));
@@ -113,6 +116,10 @@ void pulse_get_sink_info_by_index_callback(pa_context *ctx, const pa_sink_info *
             OPROP(op, "Name",                    info->name, "str");
             OPROP(op, "Description",             info->description, "str");
             OPROP(op, "MonitorSourceName",       info->monitor_source_name, "str");
+
+            if (info->active_port) {
+                OPROP(op, "ActivePort",          info->active_port->name, "str");
+            }
             OPROP(op, "DriverName",              info->driver, "str");
             OPROP(op, "Muted",                   (info->mute ? "true" : "false"), "bool");
 
@@ -161,6 +168,16 @@ void pulse_get_sink_info_by_index_callback(pa_context *ctx, const pa_sink_info *
 
                 sprintf(buf, "%."SINK_VOLUME_FACTOR_PRECISION"f", ((double)aggregateVolume / (double)info->n_volume_steps));
                 OPROP(op, "VolumeFactor",        buf, "float");
+
+                for (uint8_t i = 0; i < info->volume.channels; i++) {
+                    sprintf(key, "ChannelVolumes.%d.Name", i);
+                    sprintf(buf, "%s", pa_channel_position_to_string(info->channel_map.map[i]));
+                    OPROP(op, key, buf, "str");
+
+                    sprintf(key, "ChannelVolumes.%d.Value", i);
+                    sprintf(buf, "%d", info->volume.values[i]);
+                    OPROP(op, key, buf, "int");
+                }
             }
 
             // get all the other properties in the mix
@@ -196,6 +213,10 @@ void pulse_get_source_info_by_index_callback(pa_context *ctx, const pa_source_in
             OPROP(op, "Description",             info->description, "str");
             OPROP(op, "DriverName",              info->driver, "str");
             OPROP(op, "MonitorOfSinkName",       info->monitor_of_sink_name, "str");
+
+            if (info->active_port) {
+                OPROP(op, "ActivePort",          info->active_port->name, "str");
+            }
             OPROP(op, "Muted",                   (info->mute ? "true" : "false"), "bool");
 
             sprintf(buf, "%d", info->index);
@@ -323,6 +344,64 @@ void pulse_get_sink_input_info_by_index_callback(pa_context *ctx, const pa_sink_
     }
 }
 
+void pulse_get_source_output_info_list_callback(pa_context *ctx, const pa_source_output_info *info, int eol, void *op) {
+    if (eol < 0) {
+        OPERR(op, pa_strerror(pa_context_errno(ctx)));
+    }else{
+        pulse_get_source_output_info_by_index_callback(ctx, info, eol, op);
+    }
+}
+
+void pulse_get_source_output_info_by_index_callback(pa_context *ctx, const pa_source_output_info *info, int eol, void *op) {
+    char buf[1024];
+    char key[1024];
+
+    if (eol < 0) {
+        OPERR(op, pa_strerror(pa_context_errno(ctx)));
+    }else{
+        if (eol == 0) {
+            OPROP(op, "Name",                    info->name, "str");
+            OPROP(op, "Muted",                   (info->mute ? "true" : "false"), "bool");
+            OPROP(op, "Corked",                  (info->corked ? "true" : "false"), "bool");
+
+            sprintf(buf, "%d", info->index);
+            OPROP(op, "Index",                   buf, "int");
+
+            sprintf(buf, "%d", info->owner_module);
+            OPROP(op, "ModuleIndex",             buf, "int");
+
+            sprintf(buf, "%d", info->client);
+            OPROP(op, "ClientIndex",             buf, "int");
+
+            sprintf(buf, "%d", info->source);
+            OPROP(op, "SourceIndex",             buf, "int");
+
+            OPROP(op, "Volume.Name", "mean", "int");
+            sprintf(buf, "%d",  pa_cvolume_avg(&info->volume));
+            OPROP(op, "Volume.Value", buf, "int");
+
+            for (uint8_t i = 0; i < info->volume.channels; i++) {
+                sprintf(key, "Channels.%d.Name", i);
+                sprintf(buf, "%s", pa_channel_position_to_string(info->channel_map.map[i]));
+                OPROP(op, key, buf, "str");
+
+                sprintf(key, "Channels.%d.Value", i);
+                sprintf(buf, "%d", info->volume.values[i]);
+                OPROP(op, key, buf, "int");
+            }
+
+            // get all the other properties in the mix
+            pulse_populate_from_proplist(info->proplist, op);
+
+        // allocate the next potential response payload
+            OPINCR(op);
+        }else{
+        // complete the operation; which will resume blocking execution of the Operation.Wait() call
+            OPDONE(op);
+        }
+    }
+}
+
 void pulse_get_module_info_callback(pa_context *ctx, const pa_module_info *info, int eol, void *op) {
     char buf[1024];
 
@@ -439,6 +518,13 @@ void pulse_stream_write_callback(pa_stream *stream, size_t len, void *op) {
     go_streamPerformWrite(op, len);
 }
 
+// this callback will inform the proper stream that `len' bytes of data are
+// available to be read from the server
+//
+void pulse_stream_read_callback(pa_stream *stream, size_t len, void *op) {
+    go_streamPerformRead(op, len);
+}
+
 
 void pulse_stream_success_callback(pa_stream *stream, int success, void *op) {
     if(success > 0){
@@ -480,6 +566,19 @@ pa_buffer_attr pulse_stream_get_playback_attr(int32_t ml, int32_t tlen, int32_t
     buffer_attr.tlength   = (uint32_t)(tlen);
     buffer_attr.prebuf    = (uint32_t)(pb);
     buffer_attr.minreq    = (uint32_t)(mreq);
+    buffer_attr.fragsize  = (uint32_t)(-1);
+
+    return buffer_attr;
+}
+
+pa_buffer_attr pulse_stream_get_record_attr(int32_t ml, int32_t frag) {
+    pa_buffer_attr buffer_attr;
+
+    buffer_attr.maxlength = (uint32_t)(ml);
+    buffer_attr.tlength   = (uint32_t)(-1);
+    buffer_attr.prebuf    = (uint32_t)(-1);
+    buffer_attr.minreq    = (uint32_t)(-1);
+    buffer_attr.fragsize  = (uint32_t)(frag);
 
     return buffer_attr;
 }
@@ -499,6 +598,6 @@ void pulse_populate_from_proplist(pa_proplist *proplist, void *op) {
         }
 
         const char *value = pa_proplist_gets(proplist, key);
-        OPROP(op, key, value, NULL);
+        OPROP(op, key, value, "prop");
     }
 }
\ No newline at end of file
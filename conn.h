@@ -2,6 +2,7 @@
 #define GO_CLIENT_H
 
 #include <stdio.h>
+#include <stdlib.h>
 #include <pulse/context.h>
 #include <pulse/def.h>
 #include <pulse/error.h>
@@ -23,6 +24,8 @@ void            pulse_get_source_info_list_callback(pa_context*, const pa_source
 void            pulse_get_source_info_by_index_callback(pa_context*, const pa_source_info*, int, void*);
 void            pulse_get_sink_input_info_list_callback(pa_context*, const pa_sink_input_info*, int, void*);
 void            pulse_get_sink_input_info_by_index_callback(pa_context*, const pa_sink_input_info*, int, void*);
+void            pulse_get_source_output_info_list_callback(pa_context*, const pa_source_output_info*, int, void*);
+void            pulse_get_source_output_info_by_index_callback(pa_context*, const pa_source_output_info*, int, void*);
 void            pulse_get_module_info_list_callback(pa_context*, const pa_module_info*, int, void*);
 void            pulse_get_module_info_callback(pa_context*, const pa_module_info*, int, void*);
 void            pulse_get_client_info_callback(pa_context*, const pa_client_info*, int, void*);
@@ -31,9 +34,11 @@ pa_sample_spec  pulse_new_sample_spec(pa_sample_format_t, uint32_t, uint8_t);
 void            pulse_stream_success_callback(pa_stream*, int, void*);
 void            pulse_stream_state_callback(pa_stream*, void*);
 void            pulse_stream_write_callback(pa_stream*, size_t, void*);
+void            pulse_stream_read_callback(pa_stream*, size_t, void*);
 int             pulse_stream_write(pa_stream*, void*, size_t, void*);
 void            pulse_stream_write_done(void*);
 pa_buffer_attr  pulse_stream_get_playback_attr(int32_t, int32_t, int32_t, int32_t);
+pa_buffer_attr  pulse_stream_get_record_attr(int32_t, int32_t);
 void            pulse_subscription_event_callback(pa_context*, pa_subscription_event_type_t, uint32_t, void*);
 void            pulse_populate_from_proplist(pa_proplist*, void *);
 
@@ -0,0 +1,215 @@
+package pulse_test
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/pulsetest"
+)
+
+func TestGetServerInfo(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if info, err := conn.GetServerInfo(); err != nil {
+		t.Errorf("GetServerInfo() failed: %+v", err)
+	} else {
+		t.Logf("SERVER INFO: %+v", info)
+	}
+}
+
+func TestGetSinks(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if sinks, err := conn.GetSinks(); err != nil {
+		t.Errorf("GetSinks() failed: %+v", err)
+	} else {
+		for _, sink := range sinks {
+			t.Logf("GetSinks(): %+v", sink)
+		}
+	}
+}
+
+func TestGetSink0(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if sinks, err := conn.GetSinks(); err == nil {
+		if len(sinks) > 0 {
+			sink := sinks[0]
+
+			if err := sink.Refresh(); err == nil {
+				t.Logf("Sink %d", sink.Index)
+				t.Logf("  Volume: (%f%%) %d / %d", float64(sink.VolumeFactor*100.0), sink.CurrentVolumeStep, sink.NumVolumeSteps)
+			} else {
+				t.Errorf("Failed to refresh sink: %v", err)
+			}
+		} else {
+			t.Errorf("No sinks returned")
+		}
+	} else {
+		t.Errorf("GetSinks() failed: %+v", err)
+	}
+}
+
+func TestGetSink0SetVolume(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if sinks, err := conn.GetSinks(); err == nil {
+		if len(sinks) > 0 {
+			sink := sinks[0]
+
+			if err := sink.SetVolume(0.75); err == nil {
+				t.Logf("Sink %d", sink.Index)
+				t.Logf("Volume:    (%f%%) %d / %d", float64(sink.VolumeFactor*100.0), sink.CurrentVolumeStep, sink.NumVolumeSteps)
+			} else {
+				t.Errorf("Failed to set volume: %v", err)
+			}
+
+			if err := sink.IncreaseVolume(0.1); err == nil && sink.VolumeFactor == 0.85 {
+				t.Logf("Increased: (%f%%) %d / %d", float64(sink.VolumeFactor*100.0), sink.CurrentVolumeStep, sink.NumVolumeSteps)
+			} else {
+				t.Errorf("Failed to increase volume: %v", err)
+			}
+
+			if err := sink.DecreaseVolume(0.1); err == nil && sink.VolumeFactor == 0.75 {
+				t.Logf("Decreased: (%f%%) %d / %d", float64(sink.VolumeFactor*100.0), sink.CurrentVolumeStep, sink.NumVolumeSteps)
+			} else {
+				t.Errorf("Failed to decrease volume: %v", err)
+			}
+		} else {
+			t.Errorf("No sinks returned")
+		}
+	} else {
+		t.Errorf("GetSinks() failed: %+v", err)
+	}
+}
+
+func TestGetSink0SetMute(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if sinks, err := conn.GetSinks(); err == nil {
+		if len(sinks) > 0 {
+			sink := sinks[0]
+
+			if err := sink.Mute(); err != nil {
+				t.Errorf("Failed to mute sink: %v", err)
+			} else if !sink.Muted {
+				t.Errorf("Failed to mute sink: still not muted")
+			}
+
+			if err := sink.Unmute(); err != nil {
+				t.Errorf("Failed to unmute sink: %v", err)
+			} else if sink.Muted {
+				t.Errorf("Failed to unmute sink: still muted")
+			}
+
+			if err := sink.ToggleMute(); err != nil {
+				t.Errorf("Failed to toggle mute on: %v", err)
+			} else if !sink.Muted {
+				t.Errorf("Failed to toggle mute on: still not muted")
+			}
+
+			if err := sink.ToggleMute(); err != nil {
+				t.Errorf("Failed to toggle mute off: %v", err)
+			} else if sink.Muted {
+				t.Errorf("Failed to toggle mute off: still muted")
+			}
+		} else {
+			t.Errorf("No sinks returned")
+		}
+	} else {
+		t.Errorf("GetSinks() failed: %+v", err)
+	}
+}
+
+func TestGetSources(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if sources, err := conn.GetSources(); err != nil {
+		t.Errorf("GetSources() failed: %+v", err)
+	} else {
+		for _, source := range sources {
+			t.Logf("GetSources(): %+v", source)
+		}
+	}
+}
+
+func TestGetModules(t *testing.T) {
+	daemon := pulsetest.Start(t)
+	conn := daemon.Connect(`test-client-get-modules`)
+
+	daemon.LoadModule(`module-null-sink`, `sink_name=extra`)
+
+	if modules, err := conn.GetModules(`Argument/sink_name=extra`); err != nil {
+		t.Errorf("GetModules() failed: %+v", err)
+	} else if len(modules) != 1 {
+		t.Errorf("GetModules(): expected 1 module, got %d", len(modules))
+	} else if err := modules[0].Unload(); err != nil {
+		t.Errorf("Failed to unload module: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	daemon := pulsetest.Start(t)
+
+	// New connects to the default server, which $PULSE_SERVER overrides
+	previous, wasSet := os.LookupEnv(`PULSE_SERVER`)
+	os.Setenv(`PULSE_SERVER`, daemon.Server)
+
+	defer func() {
+		if wasSet {
+			os.Setenv(`PULSE_SERVER`, previous)
+		} else {
+			os.Unsetenv(`PULSE_SERVER`)
+		}
+	}()
+
+	if conn, err := pulse.New(`test-client-create`); err == nil {
+		conn.Close()
+	} else {
+		t.Errorf("%+v", err)
+	}
+}
+
+func TestCreateStream(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+
+	if stream, err := pulse.NewPlaybackStream(conn, `test-stream-create`, nil); err == nil {
+		stream.Destroy()
+	} else {
+		t.Errorf("Failed to initialize stream: %v", err)
+	}
+}
+
+func TestCreatePlaybackStream(t *testing.T) {
+	conn := pulsetest.NewConn(t)
+	spec := pulse.DefaultSampleSpec()
+
+	if stream, err := pulse.NewPlaybackStream(
+		conn,
+		`test-pb-stream-writeable`,
+		&spec,
+		pulse.StartCorked,
+		pulse.InterpolateTiming,
+		pulse.NotMonotonic,
+		pulse.AutoTimingUpdate,
+		pulse.AdjustLatency,
+	); err == nil {
+		defer stream.Destroy()
+
+		io.Copy(stream, pulse.NewSineGenerator(spec, 440, pulse.DEFAULT_GENERATOR_AMPLITUDE, time.Second))
+
+		if err := stream.Uncork(); err != nil {
+			t.Errorf("Failed to uncork stream: %v", err)
+			return
+		}
+
+		if err := stream.Drain(); err != nil {
+			t.Errorf("Failed to drain stream: %v", err)
+		}
+	} else {
+		t.Errorf("Failed to initialize stream: %v", err)
+	}
+}
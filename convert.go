@@ -0,0 +1,26 @@
+package pulse
+
+import (
+	"io"
+
+	"github.com/auroralaboratories/pulse/pcm"
+)
+
+// Return the equivalent pcm.Format for this sample format.
+func (self SampleFormat) PCM() pcm.Format {
+	if format := pcm.Format(self); format.Valid() {
+		return format
+	}
+
+	return pcm.Invalid
+}
+
+// Wrap the given reader so that audio data in one sample format is converted to
+// another as it is read.
+func NewFormatConverter(source io.Reader, from SampleFormat, to SampleFormat) io.Reader {
+	if from == to {
+		return source
+	}
+
+	return pcm.NewReader(source, from.PCM(), to.PCM())
+}
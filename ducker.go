@@ -0,0 +1,375 @@
+package pulse
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_DUCK_TRIGGER       = `@media.role/phone`
+	DEFAULT_DUCK_TARGETS       = `@media.role/in:music,video`
+	DEFAULT_DUCK_ATTENUATION   = 12.0
+	DEFAULT_DUCK_FADE          = 500 * time.Millisecond
+	DEFAULT_DUCK_FADE_INTERVAL = 20 * time.Millisecond
+)
+
+// A Ducker lowers the volume of some streams (e.g.: music) while other streams
+// (e.g.: a phone call) are playing, and restores it afterwards.  The original
+// volume of each stream is remembered; streams that start while ducking is in
+// effect are lowered as well.
+type Ducker struct {
+	// A filter matching the streams that cause ducking.  A trigger stream that is
+	// corked (paused) does not count.
+	Trigger string
+
+	// A filter matching the streams that are ducked.
+	Targets string
+
+	// How far to lower the targets, in decibels.
+	Attenuation float64
+
+	// How long to take lowering and restoring volumes.  Zero changes volumes
+	// immediately.
+	Fade time.Duration
+
+	server    Server
+	sub       *Subscription
+	trigger   *Matcher
+	targets   *Matcher
+	active    map[int]bool
+	streams   map[int]*duckStream
+	setVolume func(index int, factor float64) error
+	lock      sync.Mutex
+}
+
+// the state of a single target stream
+type duckStream struct {
+	original float64
+	level    float64
+	ducked   bool
+	cancel   chan bool
+	sent     []float64
+}
+
+type duckFade struct {
+	index  int
+	stream *duckStream
+	cancel chan bool
+	from   float64
+	to     float64
+}
+
+// Create a Ducker that lowers music and video streams by the default
+// attenuation while a phone stream is playing.  The Trigger, Targets,
+// Attenuation, and Fade fields may be changed before calling Start.
+func NewDucker(server Server) *Ducker {
+	ducker := &Ducker{
+		Trigger:     DEFAULT_DUCK_TRIGGER,
+		Targets:     DEFAULT_DUCK_TARGETS,
+		Attenuation: DEFAULT_DUCK_ATTENUATION,
+		Fade:        DEFAULT_DUCK_FADE,
+		server:      server,
+		active:      make(map[int]bool),
+		streams:     make(map[int]*duckStream),
+	}
+
+	ducker.setVolume = func(index int, factor float64) error {
+		return server.SetSinkInputVolume(index, factor)
+	}
+
+	return ducker
+}
+
+// Begin watching sink inputs.  If a trigger stream is already playing, ducking
+// starts immediately.
+func (self *Ducker) Start() error {
+	if err := self.compile(); err != nil {
+		return err
+	}
+
+	if sub, err := self.server.SubscribeEvents(SinkInputEvent); err == nil {
+		self.sub = sub
+	} else {
+		return err
+	}
+
+	if sinkInputs, err := self.server.GetSinkInputs(); err == nil {
+		for i := range sinkInputs {
+			self.update(&sinkInputs[i])
+		}
+	} else {
+		self.sub.Close()
+		return err
+	}
+
+	go self.run()
+
+	return nil
+}
+
+// Stop watching sink inputs and restore the volume of any ducked streams.
+func (self *Ducker) Stop() error {
+	var err error
+
+	if self.sub != nil {
+		err = self.sub.Close()
+	}
+
+	self.lock.Lock()
+	fades := make([]duckFade, 0)
+
+	for index := range self.active {
+		delete(self.active, index)
+	}
+
+	for index, stream := range self.streams {
+		if stream.ducked {
+			fades = append(fades, self.restore(index, stream))
+		}
+	}
+
+	self.lock.Unlock()
+
+	// restore immediately rather than fading, since we're going away
+	for _, fade := range fades {
+		self.setVolume(fade.index, fade.to)
+	}
+
+	return err
+}
+
+// Return whether ducking is currently in effect.
+func (self *Ducker) IsDucking() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return len(self.active) > 0
+}
+
+// Return the factor that a stream's volume is multiplied by while ducked.
+// PulseAudio volumes are on a cubic scale, so an attenuation of N dB is the cube
+// root of the linear amplitude change.
+func (self *Ducker) Gain() float64 {
+	return math.Cbrt(math.Pow(10, -math.Abs(self.Attenuation)/20))
+}
+
+func (self *Ducker) compile() error {
+	if matcher, err := CompileFilter(self.Trigger); err == nil {
+		self.trigger = matcher
+	} else {
+		return err
+	}
+
+	if matcher, err := CompileFilter(self.Targets); err == nil {
+		self.targets = matcher
+	} else {
+		return err
+	}
+
+	return nil
+}
+
+func (self *Ducker) run() {
+	for event := range self.sub.Events() {
+		if event.Kind == EventRemove {
+			self.remove(event.Index)
+		} else {
+			if sinkInput, err := getSinkInput(self.server, event.Index); err == nil {
+				self.update(sinkInput)
+			} else {
+				self.remove(event.Index)
+			}
+		}
+	}
+}
+
+// handle a sink input appearing or changing
+func (self *Ducker) update(sinkInput *SinkInput) {
+	self.lock.Lock()
+
+	wasDucking := len(self.active) > 0
+	fades := make([]duckFade, 0)
+
+	if self.trigger.Match(sinkInput) {
+		if sinkInput.Corked {
+			delete(self.active, sinkInput.Index)
+		} else {
+			self.active[sinkInput.Index] = true
+		}
+	} else if self.targets.Match(sinkInput) {
+		stream, ok := self.streams[sinkInput.Index]
+
+		if !ok {
+			stream = &duckStream{}
+			self.streams[sinkInput.Index] = stream
+		}
+
+		// while not ducked and not fading, the server's volume is the one to restore
+		// to later; this picks up any changes the user makes between calls.  Change
+		// events for volumes we set ourselves can arrive late (and out of order), so
+		// those are ignored.
+		if !stream.ducked && stream.cancel == nil && !stream.wasSent(sinkInput.VolumeFactor()) {
+			stream.original = sinkInput.VolumeFactor()
+			stream.level = stream.original
+			stream.sent = nil
+		}
+
+		if wasDucking && !stream.ducked {
+			fades = append(fades, self.duck(sinkInput.Index, stream))
+		}
+	} else if stream, ok := self.streams[sinkInput.Index]; ok {
+		// no longer a target (e.g.: its role changed); put it back as it was
+		if stream.ducked {
+			fades = append(fades, self.restore(sinkInput.Index, stream))
+		}
+
+		delete(self.streams, sinkInput.Index)
+	}
+
+	fades = append(fades, self.transition(wasDucking)...)
+	self.lock.Unlock()
+
+	self.fade(fades)
+}
+
+// handle a sink input going away
+func (self *Ducker) remove(index int) {
+	self.lock.Lock()
+
+	wasDucking := len(self.active) > 0
+	delete(self.active, index)
+
+	if stream, ok := self.streams[index]; ok {
+		if stream.cancel != nil {
+			close(stream.cancel)
+			stream.cancel = nil
+		}
+
+		delete(self.streams, index)
+	}
+
+	fades := self.transition(wasDucking)
+	self.lock.Unlock()
+
+	self.fade(fades)
+}
+
+// duck or restore every target if the trigger state has changed
+func (self *Ducker) transition(wasDucking bool) []duckFade {
+	fades := make([]duckFade, 0)
+	isDucking := len(self.active) > 0
+
+	for index, stream := range self.streams {
+		switch {
+		case isDucking && !wasDucking && !stream.ducked:
+			fades = append(fades, self.duck(index, stream))
+		case !isDucking && wasDucking && stream.ducked:
+			fades = append(fades, self.restore(index, stream))
+		}
+	}
+
+	return fades
+}
+
+func (self *Ducker) duck(index int, stream *duckStream) duckFade {
+	stream.ducked = true
+	return self.newFade(index, stream, stream.original*self.Gain())
+}
+
+func (self *Ducker) restore(index int, stream *duckStream) duckFade {
+	stream.ducked = false
+	return self.newFade(index, stream, stream.original)
+}
+
+// replace any fade in progress on the stream with one toward the given level
+func (self *Ducker) newFade(index int, stream *duckStream, to float64) duckFade {
+	if stream.cancel != nil {
+		close(stream.cancel)
+	}
+
+	stream.cancel = make(chan bool)
+	stream.sent = nil
+
+	return duckFade{
+		index:  index,
+		stream: stream,
+		cancel: stream.cancel,
+		from:   stream.level,
+		to:     to,
+	}
+}
+
+func (self *Ducker) fade(fades []duckFade) {
+	for _, fade := range fades {
+		if self.Fade <= 0 {
+			self.step(fade, fade.to, true)
+		} else {
+			go self.runFade(fade)
+		}
+	}
+}
+
+func (self *Ducker) runFade(fade duckFade) {
+	steps := int(self.Fade / DEFAULT_DUCK_FADE_INTERVAL)
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	ticker := time.NewTicker(self.Fade / time.Duration(steps))
+	defer ticker.Stop()
+
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-fade.cancel:
+			return
+		case <-ticker.C:
+			if !self.step(fade, fade.from+(fade.to-fade.from)*float64(i)/float64(steps), i == steps) {
+				return
+			}
+		}
+	}
+}
+
+// set one step of a fade, returning false if the fade has been superseded
+func (self *Ducker) step(fade duckFade, level float64, last bool) bool {
+	self.lock.Lock()
+
+	if fade.stream.cancel != fade.cancel {
+		self.lock.Unlock()
+		return false
+	}
+
+	fade.stream.level = level
+	fade.stream.sent = append(fade.stream.sent, level)
+	self.lock.Unlock()
+
+	self.setVolume(fade.index, level)
+
+	// the fade only ends once its final volume has been applied, so that change
+	// events for the steps before it aren't taken for the user's own changes
+	if last {
+		self.lock.Lock()
+
+		if fade.stream.cancel == fade.cancel {
+			fade.stream.cancel = nil
+		}
+
+		self.lock.Unlock()
+	}
+
+	return true
+}
+
+// return whether the given volume is one we set on the stream during its last
+// fade, to within the precision of a PulseAudio volume
+func (self *duckStream) wasSent(factor float64) bool {
+	for _, level := range self.sent {
+		if math.Abs(level-factor) <= 1.0/DefaultVolumeStep {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+package pulse
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type duckerTestVolumes struct {
+	levels map[int][]float64
+	lock   sync.Mutex
+}
+
+func (self *duckerTestVolumes) set(index int, factor float64) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.levels[index] = append(self.levels[index], factor)
+	return nil
+}
+
+func (self *duckerTestVolumes) get(index int) []float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return append([]float64(nil), self.levels[index]...)
+}
+
+func testDucker(fade time.Duration) (*Ducker, *duckerTestVolumes) {
+	volumes := &duckerTestVolumes{
+		levels: make(map[int][]float64),
+	}
+
+	ducker := NewDucker(nil)
+	ducker.Fade = fade
+	ducker.setVolume = volumes.set
+
+	if err := ducker.compile(); err != nil {
+		panic(err)
+	}
+
+	return ducker, volumes
+}
+
+func testDuckerStream(index int, role string, volume float64) *SinkInput {
+	return &SinkInput{
+		Index:      index,
+		Volume:     Volume{Value: volume * DefaultVolumeStep},
+		Properties: map[string]interface{}{`media`: map[string]interface{}{`role`: role}},
+	}
+}
+
+func TestDuckerDuckAndRestore(t *testing.T) {
+	assert := require.New(t)
+	ducker, volumes := testDucker(0)
+	gain := ducker.Gain()
+
+	assert.InDelta(0.631, gain, 0.001)
+
+	ducker.update(testDuckerStream(1, `music`, 1))
+	ducker.update(testDuckerStream(2, `video`, 0.5))
+	ducker.update(testDuckerStream(3, `event`, 1))
+	assert.False(ducker.IsDucking())
+	assert.Empty(volumes.get(1))
+
+	// a call starts: music and video are lowered, other streams are left alone
+	ducker.update(testDuckerStream(10, `phone`, 1))
+	assert.True(ducker.IsDucking())
+	assert.Equal([]float64{gain}, volumes.get(1))
+	assert.Equal([]float64{0.5 * gain}, volumes.get(2))
+	assert.Empty(volumes.get(3))
+	assert.Empty(volumes.get(10))
+
+	// our own volume changes don't replace the remembered volume
+	ducker.update(testDuckerStream(1, `music`, gain))
+
+	// a stream starting during the call is lowered too
+	ducker.update(testDuckerStream(4, `music`, 0.8))
+	assert.Equal([]float64{0.8 * gain}, volumes.get(4))
+
+	// a second call doesn't lower anything further
+	ducker.update(testDuckerStream(11, `phone`, 1))
+	assert.Len(volumes.get(1), 1)
+
+	// the ducking ends only when the last call does
+	ducker.remove(10)
+	assert.True(ducker.IsDucking())
+
+	ducker.remove(11)
+	assert.False(ducker.IsDucking())
+	assert.Equal([]float64{gain, 1}, volumes.get(1))
+	assert.Equal([]float64{0.5 * gain, 0.5}, volumes.get(2))
+	assert.Equal([]float64{0.8 * gain, 0.8}, volumes.get(4))
+}
+
+func TestDuckerCorkedTrigger(t *testing.T) {
+	assert := require.New(t)
+	ducker, volumes := testDucker(0)
+
+	ducker.update(testDuckerStream(1, `music`, 1))
+
+	call := testDuckerStream(10, `phone`, 1)
+	call.Corked = true
+	ducker.update(call)
+	assert.False(ducker.IsDucking())
+
+	call.Corked = false
+	ducker.update(call)
+	assert.True(ducker.IsDucking())
+
+	call.Corked = true
+	ducker.update(call)
+	assert.False(ducker.IsDucking())
+	assert.Len(volumes.get(1), 2)
+	assert.Equal(float64(1), volumes.get(1)[1])
+}
+
+func TestDuckerFade(t *testing.T) {
+	assert := require.New(t)
+	ducker, volumes := testDucker(100 * time.Millisecond)
+	gain := ducker.Gain()
+
+	ducker.update(testDuckerStream(1, `music`, 1))
+	ducker.update(testDuckerStream(10, `phone`, 1))
+
+	assert.Eventually(func() bool {
+		levels := volumes.get(1)
+		return len(levels) > 0 && levels[len(levels)-1] == gain
+	}, time.Second, 5*time.Millisecond)
+
+	levels := volumes.get(1)
+	assert.Len(levels, int(ducker.Fade/DEFAULT_DUCK_FADE_INTERVAL))
+
+	for i := 1; i < len(levels); i++ {
+		assert.True(levels[i] < levels[i-1])
+	}
+
+	assert.NoError(ducker.Stop())
+	levels = volumes.get(1)
+	assert.Equal(float64(1), levels[len(levels)-1])
+}
+
+func TestDuckerLateFadeEvents(t *testing.T) {
+	assert := require.New(t)
+	ducker, volumes := testDucker(100 * time.Millisecond)
+	gain := ducker.Gain()
+
+	fading := func() bool {
+		ducker.lock.Lock()
+		defer ducker.lock.Unlock()
+
+		return ducker.streams[1].cancel != nil
+	}
+
+	ducker.update(testDuckerStream(1, `music`, 0.8))
+	ducker.update(testDuckerStream(10, `phone`, 1))
+	assert.Eventually(func() bool { return !fading() }, time.Second, 5*time.Millisecond)
+
+	ducked := len(volumes.get(1))
+	ducker.remove(10)
+	assert.Eventually(func() bool { return !fading() }, time.Second, 5*time.Millisecond)
+
+	// change events for the restoring fade's steps arrive after it has finished,
+	// last first
+	levels := volumes.get(1)[ducked:]
+	assert.Equal(0.8, levels[len(levels)-1])
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		ducker.update(testDuckerStream(1, `music`, levels[i]))
+	}
+
+	ducker.lock.Lock()
+	assert.Equal(0.8, ducker.streams[1].original)
+	ducker.lock.Unlock()
+
+	// a volume the user sets afterwards is still picked up
+	ducker.update(testDuckerStream(1, `music`, 0.3))
+
+	ducker.lock.Lock()
+	assert.Equal(0.3, ducker.streams[1].original)
+	ducker.lock.Unlock()
+
+	ducker.Fade = 0
+	ducker.update(testDuckerStream(11, `phone`, 1))
+	levels = volumes.get(1)
+	assert.Equal(0.3*gain, levels[len(levels)-1])
+}
@@ -0,0 +1,159 @@
+package pulse
+
+import (
+	"sync"
+)
+
+// An EventHub fans events out to any number of Subscriptions.  Each Conn has
+// one, fed by the server's subscription callback; it is exported so that other
+// implementations of Server can deliver events the same way.
+type EventHub struct {
+	subscriptions []*Subscription
+	lock          sync.Mutex
+}
+
+// Create a Subscription that receives the given types of event (or all events,
+// if none are given) from this hub.
+func (self *EventHub) Subscribe(types ...EventType) *Subscription {
+	sub := &Subscription{
+		Types:  types,
+		hub:    self,
+		mask:   eventMask(types),
+		events: make(chan Event),
+		signal: make(chan bool, 1),
+		done:   make(chan bool),
+	}
+
+	self.lock.Lock()
+	self.subscriptions = append(self.subscriptions, sub)
+	self.lock.Unlock()
+
+	go sub.run()
+
+	return sub
+}
+
+// Deliver an event to every interested Subscription.  Dispatch never blocks.
+func (self *EventHub) Dispatch(event Event) {
+	// push takes each subscription's lock, which Close holds while removing it
+	// from the hub, so the hub's lock must not be held at the same time
+	self.lock.Lock()
+	subscriptions := make([]*Subscription, len(self.subscriptions))
+	copy(subscriptions, self.subscriptions)
+	self.lock.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.push(event)
+	}
+}
+
+func (self *EventHub) remove(sub *Subscription) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, existing := range self.subscriptions {
+		if existing == sub {
+			self.subscriptions = append(self.subscriptions[:i], self.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// A Subscription delivers events of the requested types.  Events are queued as
+// they arrive, so a slow reader never blocks the PulseAudio mainloop and no
+// events are lost.
+type Subscription struct {
+	Types   []EventType
+	hub     *EventHub
+	mask    int
+	events  chan Event
+	pending []Event
+	signal  chan bool
+	done    chan bool
+	lock    sync.Mutex
+	closed  bool
+}
+
+// Return the channel on which events are delivered.  The channel is closed when
+// the Subscription is closed.
+func (self *Subscription) Events() <-chan Event {
+	return self.events
+}
+
+// Stop receiving events.
+func (self *Subscription) Close() error {
+	self.lock.Lock()
+
+	if self.closed {
+		self.lock.Unlock()
+		return nil
+	}
+
+	self.closed = true
+	close(self.done)
+	self.lock.Unlock()
+
+	self.hub.remove(self)
+	return nil
+}
+
+func (self *Subscription) push(event Event) {
+	if self.mask&int(event.Facility) == 0 {
+		return
+	}
+
+	self.lock.Lock()
+
+	if self.closed {
+		self.lock.Unlock()
+		return
+	}
+
+	self.pending = append(self.pending, event)
+	self.lock.Unlock()
+
+	select {
+	case self.signal <- true:
+	default:
+	}
+}
+
+func (self *Subscription) run() {
+	defer close(self.events)
+
+	for {
+		select {
+		case <-self.signal:
+			self.lock.Lock()
+			batch := self.pending
+			self.pending = nil
+			self.lock.Unlock()
+
+			for _, event := range batch {
+				select {
+				case self.events <- event:
+				case <-self.done:
+					return
+				}
+			}
+		case <-self.done:
+			return
+		}
+	}
+}
+
+// return the subscription mask covering the given event types; no types means
+// all events
+func eventMask(types []EventType) int {
+	if len(types) == 0 {
+		return int(AllEvent)
+	}
+
+	mask := 0
+
+	for _, tm := range types {
+		mask |= int(tm)
+	}
+
+	return mask
+}
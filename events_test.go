@@ -0,0 +1,117 @@
+package pulse
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHub(t *testing.T) {
+	assert := require.New(t)
+	hub := &EventHub{}
+
+	sinks := hub.Subscribe(SinkEvent)
+	all := hub.Subscribe()
+
+	hub.Dispatch(Event{Facility: ClientEvent, Kind: EventNew, Index: 1})
+	hub.Dispatch(Event{Facility: SinkEvent, Kind: EventChange, Index: 2})
+
+	for _, expected := range []string{`new client 1`, `change sink 2`} {
+		select {
+		case event := <-all.Events():
+			assert.Equal(expected, event.String())
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	select {
+	case event := <-sinks.Events():
+		assert.Equal(`change sink 2`, event.String())
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	assert.NoError(sinks.Close())
+	assert.Len(hub.subscriptions, 1)
+
+	_, open := <-sinks.Events()
+	assert.False(open)
+
+	assert.NoError(all.Close())
+	assert.Empty(hub.subscriptions)
+}
+
+func TestEventHubCloseWhileDispatching(t *testing.T) {
+	hub := &EventHub{}
+	stop := make(chan bool)
+	finished := make(chan bool)
+
+	go func() {
+		defer close(finished)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				hub.Dispatch(Event{Facility: SinkEvent, Kind: EventChange})
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				hub.Subscribe(SinkEvent).Close()
+			}
+		}()
+	}
+
+	closed := make(chan bool)
+
+	go func() {
+		wg.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlocked closing subscriptions during dispatch")
+	}
+
+	close(stop)
+	<-finished
+
+	require.Empty(t, hub.subscriptions)
+}
+
+func TestParseEvent(t *testing.T) {
+	assert := require.New(t)
+
+	// PA_SUBSCRIPTION_EVENT_SINK_INPUT | PA_SUBSCRIPTION_EVENT_CHANGE
+	event := ParseEvent(0x12, 42)
+	assert.Equal(EventType(SinkInputEvent), event.Facility)
+	assert.Equal(EventChange, event.Kind)
+	assert.Equal(42, event.Index)
+	assert.Equal(`change sink-input 42`, event.String())
+
+	// PA_SUBSCRIPTION_EVENT_SERVER | PA_SUBSCRIPTION_EVENT_NEW
+	event = ParseEvent(0x07, 0)
+	assert.Equal(EventType(ServerEvent), event.Facility)
+	assert.Equal(EventNew, event.Kind)
+
+	// PA_SUBSCRIPTION_EVENT_MODULE | PA_SUBSCRIPTION_EVENT_REMOVE
+	event = ParseEvent(0x24, 3)
+	assert.Equal(EventType(ModuleEvent), event.Facility)
+	assert.Equal(EventRemove, event.Kind)
+}
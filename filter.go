@@ -1,6 +1,10 @@
 package pulse
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/ghetzel/go-stockutil/maputil"
@@ -9,9 +13,56 @@ import (
 	"github.com/ghetzel/go-stockutil/typeutil"
 )
 
+// Filters are made up of alternatives separated by FilterSeparator, any of
+// which may match.  Each alternative is one or more clauses separated by
+// FilterGroupSeparator, all of which must match.  A clause is a field name,
+// FieldValueSeparator, and a value to compare against, optionally prefixed
+// with an operator and a colon:
+//
+//	Name/alsa_output.usb                  field equals value
+//	VolumeFactor/gt:0.5                   numeric comparison (gt, gte, lt, lte)
+//	Name/contains:usb                     substring match
+//	Name/not:dummy                        field does not equal value
+//	Name/re:^alsa_(input|output)\.        regular expression match
+//	State/in:running,idle                 field equals any value in a list
+//	@device.bus/exists                    field is present (or "missing")
+//	@media.role/phone&Muted/false         both clauses must match
+//
+// A separator only counts as one when it is immediately followed by another
+// field name and FieldValueSeparator; anywhere else it is part of the value,
+// so "@application.name/Tom & Jerry" compares against the whole name.
+//
+// A leading @ on the field name refers to a PulseAudio property, e.g.:
+// "@application.name" is shorthand for "Properties.application.name".
 const FilterSeparator = `;`
+const FilterGroupSeparator = `&`
+const FilterListSeparator = `,`
 const FieldValueSeparator = `/`
 
+// A FilterSyntaxErr describes a filter clause that could not be parsed.
+type FilterSyntaxErr struct {
+	Clause   string
+	Operator string
+	Reason   string
+}
+
+func (self FilterSyntaxErr) Error() string {
+	if self.Operator != `` {
+		return fmt.Sprintf("Invalid filter clause %q (operator %q): %s", self.Clause, self.Operator, self.Reason)
+	}
+
+	return fmt.Sprintf("Invalid filter clause %q: %s", self.Clause, self.Reason)
+}
+
+func IsFilterSyntaxErr(err error) bool {
+	switch err.(type) {
+	case FilterSyntaxErr, *FilterSyntaxErr:
+		return true
+	default:
+		return false
+	}
+}
+
 func F(filters interface{}) (f Filter) {
 	for _, flt := range sliceutil.Stringify(filters) {
 		f = append(f, flt)
@@ -20,87 +71,383 @@ func F(filters interface{}) (f Filter) {
 	return
 }
 
+// Parse a filter string into a Filter, returning an error if any clause is
+// invalid.
+func ParseFilter(spec string) (Filter, error) {
+	if spec == `` {
+		return Filter{}, nil
+	}
+
+	filter := Filter(splitFilter(spec, FilterSeparator))
+
+	if _, err := filter.Groups(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
 type Filter []string
 
 func (self Filter) String() string {
 	return strings.Join(self, FilterSeparator)
 }
 
+// Parse every alternative in the filter into groups of clauses, returning the
+// first syntax error encountered.
+func (self Filter) Groups() ([]FilterGroup, error) {
+	groups := make([]FilterGroup, 0)
+
+	for _, alternative := range self.alternatives() {
+		if group, err := ParseFilterGroup(alternative); err == nil {
+			groups = append(groups, group)
+		} else {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// Return an error describing the first invalid clause in the filter, if any.
+func (self Filter) Validate() error {
+	_, err := self.Groups()
+	return err
+}
+
+func (self Filter) alternatives() []string {
+	alternatives := make([]string, 0)
+
+	for _, flt := range self {
+		for _, alternative := range splitFilter(flt, FilterSeparator) {
+			alternatives = append(alternatives, alternative)
+		}
+	}
+
+	return alternatives
+}
+
+// Return whether any alternative in the filter matches the given object.
+// Alternatives that fail to parse never match.
 func (self Filter) IsMatch(in interface{}) bool {
 	if len(self) == 0 {
 		return true
 	}
 
-	data := maputil.M(in).MapNative()
-	data, _ = maputil.CoalesceMap(data, `.`)
+	data, _ := maputil.CoalesceMap(nativeMap(in), `.`)
 
-	for k, v := range data {
-		if self.IsFieldMatch(k, v) {
-			return true
+	for _, alternative := range self.alternatives() {
+		if group, err := ParseFilterGroup(alternative); err == nil {
+			if group.IsMatch(data) {
+				return true
+			}
 		}
 	}
 
 	return false
 }
 
+// Return whether any clause in the filter that refers to the given field
+// matches the given value.  An "exists" clause naming a parent of the field
+// (e.g.: "Properties/exists" for "Properties.application.name") also matches.
 func (self Filter) IsFieldMatch(k string, v interface{}) bool {
 	if len(self) == 0 {
 		return true
 	}
 
-	var included bool
-	value := typeutil.V(v)
+	for _, alternative := range self.alternatives() {
+		if group, err := ParseFilterGroup(alternative); err == nil {
+			for _, clause := range group {
+				if clause.Field == k && clause.IsMatch(v, true) {
+					return true
+				} else if clause.Operator == `exists` && strings.HasPrefix(k, clause.Field+`.`) {
+					return true
+				}
+			}
+		}
+	}
 
-	for _, flt := range self {
-		field, vpair := stringutil.SplitPair(flt, FieldValueSeparator)
+	return false
+}
+
+func (self Filter) Apply(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	data, _ := maputil.CoalesceMap(nativeMap(in), `.`)
 
-		if strings.HasPrefix(field, `@`) {
-			field = `Properties.` + field[1:]
+	for k, v := range data {
+		if self.IsFieldMatch(k, v) {
+			maputil.DeepSet(out, strings.Split(k, `.`), v)
 		}
+	}
 
-		if field == k {
-			op, cmp := stringutil.SplitPairTrailing(vpair, `:`)
-			cmpI := typeutil.Int(cmp)
+	return out
+}
 
-			if field == `` || cmp == `` {
-				continue
-			}
+// matches the start of a clause: a field name (or @property) followed by
+// FieldValueSeparator
+var filterClauseStart = regexp.MustCompile(`^@?[\w][\w.\-]*` + regexp.QuoteMeta(FieldValueSeparator))
 
-			switch op {
-			case `contains`:
-				included = strings.Contains(value.String(), cmp)
-			case `gt`:
-				included = (value.Int() > cmpI)
-			case `lt`:
-				included = (value.Int() < cmpI)
-			case `gte`:
-				included = (value.Int() >= cmpI)
-			case `lte`:
-				included = (value.Int() <= cmpI)
-			case `not`:
-				included = (value.String() != cmp)
-			default:
-				included = (value.String() == cmp)
-			}
+// split a filter on the given separator wherever the text that follows it
+// starts a new clause
+func splitFilter(spec string, separator string) []string {
+	parts := make([]string, 0)
+	start := 0
+	offset := 0
+
+	for {
+		i := strings.Index(spec[offset:], separator)
 
-			if included {
+		if i < 0 {
+			break
+		}
+
+		offset += i + len(separator)
+
+		if filterClauseStart.MatchString(spec[offset:]) {
+			parts = append(parts, spec[start:offset-len(separator)])
+			start = offset
+		}
+	}
+
+	return append(parts, spec[start:])
+}
+
+// A FilterGroup is a set of clauses that must all match.
+type FilterGroup []*FilterClause
+
+// Parse a set of clauses separated by FilterGroupSeparator.
+func ParseFilterGroup(spec string) (FilterGroup, error) {
+	group := make(FilterGroup, 0)
+
+	for _, part := range splitFilter(spec, FilterGroupSeparator) {
+		if clause, err := ParseFilterClause(part); err == nil {
+			group = append(group, clause)
+		} else {
+			return nil, err
+		}
+	}
+
+	return group, nil
+}
+
+// Return whether every clause in the group matches the given flattened object.
+func (self FilterGroup) IsMatch(data map[string]interface{}) bool {
+	for _, clause := range self {
+		value, present := data[clause.Field]
+
+		if !clause.IsMatch(value, present) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (self FilterGroup) String() string {
+	parts := make([]string, len(self))
+
+	for i, clause := range self {
+		parts[i] = clause.String()
+	}
+
+	return strings.Join(parts, FilterGroupSeparator)
+}
+
+// A FilterClause compares a single field against a value.
+type FilterClause struct {
+	Field    string
+	Operator string
+	Value    string
+	source   string
+	path     []string
+	number   float64
+	pattern  *regexp.Regexp
+	list     []string
+}
+
+// Parse a single filter clause.
+func ParseFilterClause(spec string) (*FilterClause, error) {
+	field, vpair := stringutil.SplitPair(spec, FieldValueSeparator)
+	field = strings.TrimSpace(field)
+
+	if !strings.Contains(spec, FieldValueSeparator) {
+		return nil, FilterSyntaxErr{Clause: spec, Reason: `expected field` + FieldValueSeparator + `value`}
+	} else if field == `` || field == `@` {
+		return nil, FilterSyntaxErr{Clause: spec, Reason: `missing field name`}
+	}
+
+	if strings.HasPrefix(field, `@`) {
+		field = `Properties.` + field[1:]
+	}
+
+	clause := &FilterClause{
+		Field:  field,
+		source: spec,
+		path:   strings.Split(field, `.`),
+	}
+
+	if vpair == `exists` || vpair == `missing` {
+		clause.Operator = vpair
+		return clause, nil
+	}
+
+	op, cmp := stringutil.SplitPair(vpair, `:`)
+	explicit := strings.Contains(vpair, `:`)
+
+	switch op {
+	case `is`, `contains`, `not`, `gt`, `lt`, `gte`, `lte`, `re`, `in`:
+		if explicit {
+			clause.Operator = op
+			clause.Value = cmp
+			break
+		}
+
+		fallthrough
+	default:
+		// anything not starting with a known operator is a literal value,
+		// colons included
+		explicit = false
+		clause.Operator = `is`
+		clause.Value = vpair
+	}
+
+	// an empty value is only allowed when explicitly compared with is: or not:
+	if clause.Value == `` && !(explicit && (op == `is` || op == `not`)) {
+		return nil, FilterSyntaxErr{Clause: spec, Operator: clause.Operator, Reason: `missing value`}
+	}
+
+	switch clause.Operator {
+	case `gt`, `lt`, `gte`, `lte`:
+		if n, err := strconv.ParseFloat(clause.Value, 64); err == nil {
+			clause.number = n
+		} else {
+			return nil, FilterSyntaxErr{Clause: spec, Operator: clause.Operator, Reason: fmt.Sprintf("%q is not a number", clause.Value)}
+		}
+
+	case `re`:
+		if rx, err := regexp.Compile(clause.Value); err == nil {
+			clause.pattern = rx
+		} else {
+			return nil, FilterSyntaxErr{Clause: spec, Operator: clause.Operator, Reason: err.Error()}
+		}
+
+	case `in`:
+		clause.list = strings.Split(clause.Value, FilterListSeparator)
+	}
+
+	return clause, nil
+}
+
+// Return whether the clause matches the given value.  The present argument
+// indicates whether the field exists at all.
+func (self *FilterClause) IsMatch(v interface{}, present bool) bool {
+	switch self.Operator {
+	case `exists`:
+		return present
+	case `missing`:
+		return !present
+	}
+
+	if !present {
+		return false
+	}
+
+	value := typeutil.V(v).String()
+
+	switch self.Operator {
+	case `contains`:
+		return strings.Contains(value, self.Value)
+	case `not`:
+		return !valuesEqual(value, self.Value)
+	case `re`:
+		return self.pattern.MatchString(value)
+	case `in`:
+		for _, item := range self.list {
+			if valuesEqual(value, item) {
 				return true
 			}
 		}
+
+		return false
+	case `gt`, `lt`, `gte`, `lte`:
+		n, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			return false
+		}
+
+		switch self.Operator {
+		case `gt`:
+			return n > self.number
+		case `lt`:
+			return n < self.number
+		case `gte`:
+			return n >= self.number
+		default:
+			return n <= self.number
+		}
+	default:
+		return valuesEqual(value, self.Value)
+	}
+}
+
+func (self *FilterClause) String() string {
+	return self.source
+}
+
+// compare two values as strings, falling back to a numeric comparison so that
+// e.g.: "0.50" equals "0.5"
+func valuesEqual(a string, b string) bool {
+	if a == b {
+		return true
+	}
+
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			return x == y
+		}
 	}
 
 	return false
 }
 
-func (self Filter) Apply(in map[string]interface{}) map[string]interface{} {
-	out := make(map[string]interface{})
-	data, _ := maputil.CoalesceMap(in, `.`)
+// convert an object into a map of native types.  Nested maps of named types
+// (e.g.: PropList) are converted as well, since CoalesceMap only descends into
+// plain maps.
+func nativeMap(in interface{}) map[string]interface{} {
+	return nativeValue(maputil.M(in).MapNative()).(map[string]interface{})
+}
 
-	for k, v := range data {
-		if self.IsFieldMatch(k, v) {
-			maputil.DeepSet(out, strings.Split(k, `.`), v)
+func nativeValue(in interface{}) interface{} {
+	value := reflect.ValueOf(in)
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return in
+		}
+
+		out := make(map[string]interface{}, value.Len())
+
+		for _, key := range value.MapKeys() {
+			out[key.String()] = nativeValue(value.MapIndex(key).Interface())
 		}
+
+		return out
+
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return in
+		}
+
+		out := make([]interface{}, value.Len())
+
+		for i := range out {
+			out[i] = nativeValue(value.Index(i).Interface())
+		}
+
+		return out
 	}
 
-	return out
+	return in
 }
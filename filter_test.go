@@ -56,3 +56,114 @@ func TestFilterApply(t *testing.T) {
 	flt = Filter{`x.y.z/gt:123`}
 	assert.Empty(flt.Apply(original))
 }
+
+type filterTestObject struct {
+	Name         string
+	VolumeFactor float64
+	Muted        bool
+	State        string
+	Properties   map[string]interface{}
+}
+
+func TestFilterGrammar(t *testing.T) {
+	assert := require.New(t)
+
+	obj := filterTestObject{
+		Name:         `alsa_output.pci-0000:00:1f.3.analog-stereo`,
+		VolumeFactor: 0.75,
+		State:        `running`,
+		Properties: map[string]interface{}{
+			`media.role`: `phone`,
+		},
+	}
+
+	for flt, expected := range map[string]bool{
+		`Name/alsa_output.pci-0000:00:1f.3.analog-stereo`: true,
+		`Name/contains:analog`:                            true,
+		`Name/not:analog`:                                 true,
+		`Name/re:^alsa_(input|output)\.`:                  true,
+		`Name/re:^alsa_input`:                             false,
+		`VolumeFactor/gt:0.5`:                             true,
+		`VolumeFactor/lte:0.5`:                            false,
+		`VolumeFactor/0.750`:                              true,
+		`Name/gt:0`:                                       false,
+		`State/in:idle,running`:                           true,
+		`State/in:idle,suspended`:                         false,
+		`@media.role/exists`:                              true,
+		`@device.bus/missing`:                             true,
+		`@device.bus/exists`:                              false,
+		`@media.role/phone&Muted/false`:                   true,
+		`@media.role/phone&Muted/true`:                    false,
+		`State/idle;@media.role/phone`:                    true,
+		`State/idle;@media.role/music`:                    false,
+	} {
+		f, err := ParseFilter(flt)
+		assert.NoError(err, flt)
+		assert.Equal(expected, f.IsMatch(obj), flt)
+	}
+
+	// alternatives may also be given as separate elements
+	assert.True(Filter{`State/idle`, `State/running`}.IsMatch(obj))
+
+	for _, flt := range []string{
+		`Name`,
+		`/value`,
+		`Name/`,
+		`VolumeFactor/gt:loud`,
+		`Name/re:(unclosed`,
+		`Name/in:`,
+		`State/running&Muted/`,
+	} {
+		_, err := ParseFilter(flt)
+		assert.Error(err, flt)
+		assert.True(IsFilterSyntaxErr(err), flt)
+		assert.False(Filter{flt}.IsMatch(obj), flt)
+	}
+}
+
+// Filters written before the grammar gained groups and alternatives treated
+// each string as a single clause, so separators inside values must still be
+// taken literally.
+func TestFilterBaselineCompatibility(t *testing.T) {
+	assert := require.New(t)
+
+	obj := filterTestObject{
+		Name:  `Tom&Jerry`,
+		State: `running;idle`,
+		Properties: map[string]interface{}{
+			`application.name`: `Tom & Jerry`,
+			`media.name`:       `Rock & Roll; Live`,
+			`x`:                map[string]interface{}{`y`: 123},
+		},
+	}
+
+	for flt, expected := range map[string]bool{
+		`@application.name/Tom & Jerry`:      true,
+		`@application.name/Tom & Jerry & Co`: false,
+		`@media.name/Rock & Roll; Live`:      true,
+		`@media.name/contains:Roll; Live`:    true,
+		`Name/Tom&Jerry`:                     true,
+		`Name/not:Tom&Jerry`:                 false,
+		`State/running;idle`:                 true,
+		`State/running;`:                     false,
+		`State/running&Muted`:                false,
+		`@x.y/123`:                           true,
+		`@x.y/gte:123`:                       true,
+		`@x.y/gt:123`:                        false,
+	} {
+		matcher, err := CompileFilter(flt)
+		assert.NoError(err, flt)
+		assert.Equal(expected, matcher.Match(obj), flt)
+	}
+
+	// a separator followed by another clause still starts one
+	matcher, err := CompileFilter(`@application.name/Tom & Jerry&Name/Tom&Jerry`)
+	assert.NoError(err)
+	assert.Len(matcher.Filter.alternatives(), 1)
+	assert.True(matcher.Match(obj))
+
+	matcher, err = CompileFilter(`State/idle;@media.name/Rock & Roll; Live`)
+	assert.NoError(err)
+	assert.Len(matcher.Filter.alternatives(), 2)
+	assert.True(matcher.Match(obj))
+}
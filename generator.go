@@ -0,0 +1,253 @@
+package pulse
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/auroralaboratories/pulse/pcm"
+)
+
+const (
+	DEFAULT_GENERATOR_AMPLITUDE = 0.5
+	DEFAULT_NOISE_SEED          = 1
+)
+
+// standard DTMF row and column frequencies for each key
+var dtmfFrequencies = map[rune][2]float64{
+	'1': {697, 1209}, '2': {697, 1336}, '3': {697, 1477}, 'A': {697, 1633},
+	'4': {770, 1209}, '5': {770, 1336}, '6': {770, 1477}, 'B': {770, 1633},
+	'7': {852, 1209}, '8': {852, 1336}, '9': {852, 1477}, 'C': {852, 1633},
+	'*': {941, 1209}, '0': {941, 1336}, '#': {941, 1477}, 'D': {941, 1633},
+}
+
+// A Generator is an io.Reader that synthesizes a test signal in a given
+// SampleSpec, suitable for use as a PlaybackStream's Source.  Every channel
+// receives the same signal.  Generators with a zero Duration never end;
+// otherwise reads return io.EOF once Duration worth of audio has been produced.
+type Generator struct {
+	Spec      SampleSpec
+	Amplitude float64
+	Duration  time.Duration
+	signal    func(frame int64) float64
+	frame     int64
+	output    []byte
+}
+
+func newGenerator(spec SampleSpec, amplitude float64, duration time.Duration, signal func(frame int64) float64) *Generator {
+	return &Generator{
+		Spec:      spec,
+		Amplitude: amplitude,
+		Duration:  duration,
+		signal:    signal,
+	}
+}
+
+// return a signal function producing a periodic waveform, given a function that
+// maps a phase in cycles (0 <= phase < 1) to a value
+func periodicSignal(spec SampleSpec, frequency func(frame int64) float64, wave func(phase float64) float64) func(int64) float64 {
+	var phase float64
+
+	return func(frame int64) float64 {
+		v := wave(phase)
+		phase += frequency(frame) / float64(spec.SampleRate)
+		phase -= math.Floor(phase)
+		return v
+	}
+}
+
+func constantFrequency(frequency float64) func(int64) float64 {
+	return func(int64) float64 {
+		return frequency
+	}
+}
+
+func sineWave(phase float64) float64 {
+	return math.Sin(2 * math.Pi * phase)
+}
+
+func squareWave(phase float64) float64 {
+	if phase < 0.5 {
+		return 1
+	}
+
+	return -1
+}
+
+func sawtoothWave(phase float64) float64 {
+	return 2*phase - 1
+}
+
+// Generate a sine wave at the given frequency (in Hz).
+func NewSineGenerator(spec SampleSpec, frequency float64, amplitude float64, duration time.Duration) *Generator {
+	return newGenerator(spec, amplitude, duration, periodicSignal(spec, constantFrequency(frequency), sineWave))
+}
+
+// Generate a square wave at the given frequency (in Hz).
+func NewSquareGenerator(spec SampleSpec, frequency float64, amplitude float64, duration time.Duration) *Generator {
+	return newGenerator(spec, amplitude, duration, periodicSignal(spec, constantFrequency(frequency), squareWave))
+}
+
+// Generate a rising sawtooth wave at the given frequency (in Hz).
+func NewSawtoothGenerator(spec SampleSpec, frequency float64, amplitude float64, duration time.Duration) *Generator {
+	return newGenerator(spec, amplitude, duration, periodicSignal(spec, constantFrequency(frequency), sawtoothWave))
+}
+
+// Generate a sine wave whose frequency sweeps exponentially from one frequency
+// to another over the given duration, spending equal time in each octave.  A
+// zero duration holds the starting frequency indefinitely.
+func NewSweepGenerator(spec SampleSpec, from float64, to float64, amplitude float64, duration time.Duration) *Generator {
+	frames := float64(durationToFrames(spec, duration))
+
+	frequency := func(frame int64) float64 {
+		if frames <= 0 || from <= 0 || to <= 0 {
+			return from
+		}
+
+		return from * math.Pow(to/from, float64(frame)/frames)
+	}
+
+	return newGenerator(spec, amplitude, duration, periodicSignal(spec, frequency, sineWave))
+}
+
+// Generate uniformly distributed white noise.  The noise is produced from a
+// fixed seed, so the same parameters always produce the same audio.
+func NewWhiteNoiseGenerator(spec SampleSpec, amplitude float64, duration time.Duration) *Generator {
+	random := rand.New(rand.NewSource(DEFAULT_NOISE_SEED))
+
+	return newGenerator(spec, amplitude, duration, func(int64) float64 {
+		return random.Float64()*2 - 1
+	})
+}
+
+// Generate pink (1/f) noise, which has equal energy per octave.  Like white
+// noise, it is produced from a fixed seed.
+func NewPinkNoiseGenerator(spec SampleSpec, amplitude float64, duration time.Duration) *Generator {
+	random := rand.New(rand.NewSource(DEFAULT_NOISE_SEED))
+	var b [7]float64
+
+	// Paul Kellet's refined pink noise filter
+	return newGenerator(spec, amplitude, duration, func(int64) float64 {
+		white := random.Float64()*2 - 1
+
+		b[0] = 0.99886*b[0] + white*0.0555179
+		b[1] = 0.99332*b[1] + white*0.0750759
+		b[2] = 0.96900*b[2] + white*0.1538520
+		b[3] = 0.86650*b[3] + white*0.3104856
+		b[4] = 0.55000*b[4] + white*0.5329522
+		b[5] = -0.7616*b[5] - white*0.0168980
+		pink := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+		b[6] = white * 0.115926
+
+		// scale the filter's gain of roughly 9 back to full scale
+		return math.Max(-1, math.Min(1, pink*0.11))
+	})
+}
+
+// Generate silence.
+func NewSilenceGenerator(spec SampleSpec, duration time.Duration) *Generator {
+	return newGenerator(spec, 0, duration, func(int64) float64 {
+		return 0
+	})
+}
+
+// Generate the DTMF (touch-tone) signal for a sequence of keys (0-9, A-D, *, and
+// #), each played for toneDuration and followed by gapDuration of silence.  The
+// amplitude is split evenly between each key's two tones.
+func NewDTMFGenerator(spec SampleSpec, keys string, amplitude float64, toneDuration time.Duration, gapDuration time.Duration) (*Generator, error) {
+	tones := make([][2]float64, 0)
+
+	for _, key := range strings.ToUpper(keys) {
+		if frequencies, ok := dtmfFrequencies[key]; ok {
+			tones = append(tones, frequencies)
+		} else {
+			return nil, fmt.Errorf("Invalid DTMF key %q", key)
+		}
+	}
+
+	toneFrames := durationToFrames(spec, toneDuration)
+	keyFrames := toneFrames + durationToFrames(spec, gapDuration)
+	duration := time.Duration(len(tones)) * (toneDuration + gapDuration)
+
+	if keyFrames <= 0 {
+		return nil, fmt.Errorf("DTMF tone duration must be positive")
+	}
+
+	return newGenerator(spec, amplitude, duration, func(frame int64) float64 {
+		key := frame / keyFrames
+		offset := frame % keyFrames
+
+		if key >= int64(len(tones)) || offset >= toneFrames {
+			return 0
+		}
+
+		t := float64(offset) / float64(spec.SampleRate)
+
+		return 0.5*math.Sin(2*math.Pi*tones[key][0]*t) + 0.5*math.Sin(2*math.Pi*tones[key][1]*t)
+	}), nil
+}
+
+// Return the total number of frames this generator produces, or -1 if it never
+// ends.
+func (self *Generator) Frames() int64 {
+	if self.Duration <= 0 {
+		return -1
+	}
+
+	return durationToFrames(self.Spec, self.Duration)
+}
+
+// Read the next block of generated audio.
+func (self *Generator) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(self.output) == 0 {
+		format := self.Spec.Format.PCM()
+
+		if !format.Valid() || self.Spec.NumChannels <= 0 || self.Spec.SampleRate == 0 {
+			return 0, fmt.Errorf("Cannot generate audio in sample spec %v", self.Spec)
+		}
+
+		frames := int64(len(p) / frameSizeOf(self.Spec))
+
+		if frames == 0 {
+			frames = 1
+		}
+
+		if total := self.Frames(); total >= 0 {
+			if remaining := total - self.frame; remaining <= 0 {
+				return 0, io.EOF
+			} else if frames > remaining {
+				frames = remaining
+			}
+		}
+
+		samples := make([]float32, int(frames)*self.Spec.NumChannels)
+
+		for f := 0; f < int(frames); f++ {
+			v := float32(self.Amplitude * self.signal(self.frame))
+			self.frame++
+
+			for c := 0; c < self.Spec.NumChannels; c++ {
+				samples[f*self.Spec.NumChannels+c] = v
+			}
+		}
+
+		self.output = make([]byte, len(samples)*format.SampleSize())
+		pcm.EncodeInto(self.output, format, samples)
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+func durationToFrames(spec SampleSpec, duration time.Duration) int64 {
+	return int64(math.Round(duration.Seconds() * float64(spec.SampleRate)))
+}
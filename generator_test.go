@@ -0,0 +1,110 @@
+package pulse
+
+import (
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse/pcm"
+	"github.com/stretchr/testify/require"
+)
+
+var generatorSpec = SampleSpec{Format: FormatIEEEFloat32LE, SampleRate: 8000, NumChannels: 2}
+
+func generate(t *testing.T, generator *Generator) []float32 {
+	data, err := ioutil.ReadAll(generator)
+	require.NoError(t, err)
+
+	samples, err := pcm.Decode(pcm.Float32LE, data)
+	require.NoError(t, err)
+
+	return samples
+}
+
+func TestGenerators(t *testing.T) {
+	assert := require.New(t)
+
+	sine := generate(t, NewSineGenerator(generatorSpec, 1000, 0.5, 10*time.Millisecond))
+	assert.Len(sine, 80*2)
+	assert.Equal(sine[0], sine[1])
+	assert.InDelta(0, sine[0], 1e-6)
+	assert.InDelta(0.5*math.Sin(2*math.Pi/8), sine[2], 1e-6)
+	assert.InDelta(0.5, sine[4], 1e-6)
+
+	square := generate(t, NewSquareGenerator(generatorSpec, 1000, 0.25, time.Millisecond))
+	assert.Equal([]float32{0.25, 0.25, 0.25, 0.25, 0.25, 0.25, 0.25, 0.25, -0.25, -0.25}, square[:10])
+
+	saw := generate(t, NewSawtoothGenerator(generatorSpec, 2000, 1, time.Millisecond))
+	assert.Equal([]float32{-1, -1, -0.5, -0.5, 0, 0, 0.5, 0.5, -1, -1}, saw[:10])
+
+	silence, err := ioutil.ReadAll(NewSilenceGenerator(SampleSpec{Format: FormatPcmU8, SampleRate: 8000, NumChannels: 1}, time.Millisecond))
+	assert.NoError(err)
+	assert.Equal([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80}, silence)
+
+	for _, noise := range []*Generator{
+		NewWhiteNoiseGenerator(generatorSpec, 0.5, 100*time.Millisecond),
+		NewPinkNoiseGenerator(generatorSpec, 0.5, 100*time.Millisecond),
+	} {
+		samples := generate(t, noise)
+
+		for _, v := range samples {
+			assert.True(v >= -0.5 && v <= 0.5)
+		}
+
+		assert.NotEqual(samples[0], samples[2])
+	}
+
+	// noise is reproducible
+	assert.Equal(
+		generate(t, NewPinkNoiseGenerator(generatorSpec, 1, 10*time.Millisecond)),
+		generate(t, NewPinkNoiseGenerator(generatorSpec, 1, 10*time.Millisecond)),
+	)
+
+	sweep := generate(t, NewSweepGenerator(generatorSpec, 100, 1000, 1, time.Second))
+	assert.Len(sweep, 8000*2)
+}
+
+func TestDTMFGenerator(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := NewDTMFGenerator(generatorSpec, `12x`, 1, 10*time.Millisecond, 0)
+	assert.Error(err)
+
+	dtmf, err := NewDTMFGenerator(generatorSpec, `1#`, 1, 10*time.Millisecond, 5*time.Millisecond)
+	assert.NoError(err)
+	assert.Equal(int64(240), dtmf.Frames())
+
+	samples := generate(t, dtmf)
+	assert.Len(samples, 240*2)
+
+	// gaps are silent
+	for _, v := range samples[80*2 : 120*2] {
+		assert.Equal(float32(0), v)
+	}
+
+	assert.NotEqual(float32(0), samples[121*2])
+}
+
+func TestGeneratorPartialReads(t *testing.T) {
+	assert := require.New(t)
+
+	whole := generate(t, NewSineGenerator(generatorSpec, 440, 1, 10*time.Millisecond))
+	generator := NewSineGenerator(generatorSpec, 440, 1, 10*time.Millisecond)
+	data := make([]byte, 0)
+	buf := make([]byte, 3)
+
+	for {
+		n, err := generator.Read(buf)
+		data = append(data, buf[:n]...)
+
+		if err != nil {
+			break
+		}
+	}
+
+	samples, err := pcm.Decode(pcm.Float32LE, data)
+	assert.NoError(err)
+	assert.Equal(whole, samples)
+	assert.Equal(int64(-1), NewSilenceGenerator(generatorSpec, 0).Frames())
+}
@@ -1,9 +1,10 @@
 module github.com/auroralaboratories/pulse
 
-go 1.12
+go 1.14
 
 require (
 	github.com/ghetzel/cli v1.17.0
 	github.com/ghetzel/go-stockutil v1.8.81
 	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
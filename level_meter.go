@@ -0,0 +1,154 @@
+package pulse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	DEFAULT_LEVEL_METER_RATE   = 25
+	DEFAULT_LEVEL_METER_BUFFER = 16
+)
+
+// A LevelMeter reports the peak audio level of a source, the monitor of a sink,
+// or a single sink input.  Peak detection is performed by the PulseAudio server,
+// which delivers only Rate values per second to the client.
+type LevelMeter struct {
+	Rate    int
+	stream  *RecordStream
+	levels  chan float64
+	partial []byte
+	closed  bool
+	lock    sync.Mutex
+}
+
+// Create a level meter that reports peak levels from the named source at the
+// given rate (in values per second).
+func NewLevelMeter(conn *Conn, source string, rate int) (*LevelMeter, error) {
+	return newLevelMeter(conn, source, -1, rate)
+}
+
+// Create a level meter that reports peak levels of everything playing on the
+// given sink, as seen on that sink's monitor source.
+func NewSinkLevelMeter(sink *Sink, rate int) (*LevelMeter, error) {
+	if sink.MonitorSourceName == `` {
+		return nil, fmt.Errorf("Sink %d has no monitor source", sink.Index)
+	}
+
+	return newLevelMeter(sink.conn, sink.MonitorSourceName, -1, rate)
+}
+
+// Create a level meter that reports peak levels of a single sink input.
+func NewSinkInputLevelMeter(sinkInput *SinkInput, rate int) (*LevelMeter, error) {
+	sink := &Sink{
+		Index: sinkInput.SinkIndex,
+		conn:  sinkInput.conn,
+	}
+
+	if err := sink.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return newLevelMeter(sinkInput.conn, sink.MonitorSourceName, sinkInput.Index, rate)
+}
+
+func newLevelMeter(conn *Conn, device string, sinkInputIndex int, rate int) (*LevelMeter, error) {
+	if rate <= 0 {
+		rate = DEFAULT_LEVEL_METER_RATE
+	}
+
+	meter := &LevelMeter{
+		Rate:   rate,
+		levels: make(chan float64, DEFAULT_LEVEL_METER_BUFFER),
+	}
+
+	flags := []StreamFlags{PeakDetect, AdjustLatency}
+
+	if sinkInputIndex >= 0 {
+		flags = append(flags, DontMove)
+	} else {
+		flags = append(flags, DontInhibitAutoSuspend)
+	}
+
+	stream := newRecordStream(conn, `level-meter`, device, &SampleSpec{
+		Format:      FormatIEEEFloat32LE,
+		SampleRate:  uint32(rate),
+		NumChannels: 1,
+	}, flags...)
+
+	// one peak value per fragment keeps latency (and wakeups) to a minimum
+	stream.FragmentSize = 4
+	stream.MonitorStream = sinkInputIndex
+	stream.Destination = meter
+
+	if err := stream.initialize(); err != nil {
+		stream.Destroy()
+		return nil, err
+	}
+
+	meter.stream = stream
+
+	return meter, nil
+}
+
+// Return a channel that receives peak levels (0.0 <= v <= 1.0) as they are
+// reported by the server.  Values are dropped if the channel is not drained
+// quickly enough.  The channel is closed when the meter is closed.
+func (self *LevelMeter) Levels() <-chan float64 {
+	return self.levels
+}
+
+// Receives raw peak data from the underlying record stream.
+func (self *LevelMeter) Write(data []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.closed {
+		return len(data), nil
+	}
+
+	self.partial = append(self.partial, data...)
+
+	for len(self.partial) >= 4 {
+		level := float64(math.Float32frombits(binary.LittleEndian.Uint32(self.partial[0:4])))
+		self.partial = self.partial[4:]
+
+		if level < 0 {
+			level = 0
+		} else if level > 1 {
+			level = 1
+		}
+
+		select {
+		case self.levels <- level:
+		default:
+		}
+	}
+
+	return len(data), nil
+}
+
+// Stop metering and close the Levels channel.
+func (self *LevelMeter) Close() error {
+	self.lock.Lock()
+
+	if self.closed {
+		self.lock.Unlock()
+		return nil
+	}
+
+	self.closed = true
+	self.lock.Unlock()
+
+	// Write is called with the mainloop locked and takes our lock, so the
+	// stream must be destroyed without holding it
+	self.stream.Destroy()
+
+	self.lock.Lock()
+	close(self.levels)
+	self.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package pulse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/pulsetest"
+	"github.com/stretchr/testify/require"
+)
+
+// levels reported by the server are converted from the (16-bit) samples played,
+// so they come close to the generated amplitude but rarely match it exactly
+const levelTolerance = 0.05
+
+// play a sine tone at the given amplitude on the daemon's default sink, from a
+// connection of its own
+func playTone(t *testing.T, daemon *pulsetest.Daemon, name string, amplitude float64) {
+	conn := daemon.Connect(name)
+	spec := pulse.DefaultSampleSpec()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		pulse.Play(conn, name, &spec, pulse.NewSineGenerator(spec, 440, amplitude, 5*time.Second))
+	}()
+
+	// cleanups run last-in first-out, so playback finishes before the
+	// connection is closed
+	t.Cleanup(func() {
+		<-done
+	})
+}
+
+// wait for the meter to report a level close to the given amplitude, failing if
+// it reports anything clearly louder first
+func requireLevel(t *testing.T, meter *pulse.LevelMeter, amplitude float64) {
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case level, ok := <-meter.Levels():
+			if !ok {
+				t.Fatal("level meter closed")
+			} else if level > amplitude+levelTolerance {
+				t.Fatalf("reported level %f is louder than the %f tone", level, amplitude)
+			} else if level > amplitude-levelTolerance {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a level close to %f", amplitude)
+		}
+	}
+}
+
+func TestSinkLevelMeter(t *testing.T) {
+	assert := require.New(t)
+	daemon := pulsetest.Start(t)
+	conn := daemon.Connect(`test-sink-level-meter`)
+
+	sinks, err := conn.GetSinks()
+	assert.NoError(err)
+	assert.NotEmpty(sinks)
+
+	meter, err := pulse.NewSinkLevelMeter(sinks[0], 0)
+	assert.NoError(err)
+	assert.Equal(pulse.DEFAULT_LEVEL_METER_RATE, meter.Rate)
+	defer meter.Close()
+
+	playTone(t, daemon, `test-sink-level-meter-tone`, 0.5)
+	requireLevel(t, meter, 0.5)
+
+	assert.NoError(meter.Close())
+
+	_, open := <-meter.Levels()
+	assert.False(open)
+}
+
+func TestSinkInputLevelMeter(t *testing.T) {
+	assert := require.New(t)
+	daemon := pulsetest.Start(t)
+	conn := daemon.Connect(`test-sink-input-level-meter`)
+
+	// the louder tone plays on the same sink, so it would show up in the
+	// level of the quiet one if the sink's whole monitor were being metered
+	playTone(t, daemon, `test-level-meter-loud`, 0.8)
+	playTone(t, daemon, `test-level-meter-quiet`, 0.3)
+
+	var quiet []pulse.SinkInput
+
+	assert.Eventually(func() bool {
+		quiet, _ = conn.GetSinkInputs(`Name/test-level-meter-quiet`)
+		return len(quiet) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	meter, err := pulse.NewSinkInputLevelMeter(&quiet[0], 0)
+	assert.NoError(err)
+	defer meter.Close()
+
+	requireLevel(t, meter, 0.3)
+}
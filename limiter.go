@@ -0,0 +1,400 @@
+package pulse
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const DEFAULT_LIMITER_MAX_VOLUME = 1.0
+
+// volumes read back from the server are rounded, so differences smaller than
+// this are not treated as changes
+const limiterTolerance = 0.005
+
+// The reasons a Limiter overrides a volume.
+const (
+	LimitMaximum = `maximum`
+	LimitRate    = `rate`
+)
+
+// A LimitRule restricts the volume of sinks or sink inputs matching a filter.
+type LimitRule struct {
+	// A name for the rule, reported in overrides.
+	Name string
+
+	// A filter that objects must match, as accepted by CompileFilter.  An empty
+	// filter matches everything.
+	Filter string
+
+	// The kind of object the rule applies to (ObjectSink or ObjectSinkInput).
+	// If empty, the rule applies to both.
+	Kind string
+
+	// The highest volume allowed, as a factor of the normal volume (1.0 is 100%).
+	// Zero means no maximum.
+	MaxVolume float64
+
+	// The largest increase in volume allowed within each Period.  Zero means
+	// volume may be increased at any rate.
+	MaxIncrease float64
+	Period      time.Duration
+
+	matcher *Matcher
+}
+
+func (self *LimitRule) String() string {
+	if self.Name != `` {
+		return self.Name
+	}
+
+	return self.Filter
+}
+
+// An Override describes a volume change that a Limiter reverted or reduced.
+// Requested and Applied are the volumes of the object's loudest channel; the
+// other channels are scaled by the same proportion.  Error is the message of
+// the error returned when correcting the volume, if any.
+type Override struct {
+	Time      time.Time
+	Rule      string
+	Kind      string
+	Index     int
+	Name      string
+	Reason    string
+	Requested float64
+	Applied   float64
+	Error     string `json:",omitempty"`
+}
+
+func (self Override) String() string {
+	return fmt.Sprintf("%s %d (%s): limited %g to %g (%s) [rule %s]", self.Kind, self.Index, self.Name, self.Requested, self.Applied, self.Reason, self.Rule)
+}
+
+// A Limiter enforces a volume policy on sinks and sink inputs: volumes above a
+// configured maximum are clamped, and volume increases can be capped to a
+// given amount per unit of time.  Each object is governed by the first rule it
+// matches, and is judged by its loudest channel so that unbalanced channels
+// can't hide a loud one behind a quiet one.
+type Limiter struct {
+	Rules []*LimitRule
+
+	// Called (from the Limiter's own goroutine) each time a volume is overridden.
+	OnOverride func(Override)
+
+	server    Server
+	sub       *Subscription
+	levels    map[string]*limiterLevel
+	setVolume func(kind string, index int, factors []float64) error
+	lock      sync.Mutex
+}
+
+// the last volume of an object that the limiter accepted, and how much it may
+// still be increased (refilled over time at the rule's rate)
+type limiterLevel struct {
+	level   float64
+	budget  float64
+	updated time.Time
+}
+
+// Create a Limiter with the given rules.  If no rules are given, every sink and
+// sink input is limited to DEFAULT_LIMITER_MAX_VOLUME.
+func NewLimiter(server Server, rules ...LimitRule) (*Limiter, error) {
+	limiter := &Limiter{
+		Rules:  make([]*LimitRule, 0),
+		server: server,
+		levels: make(map[string]*limiterLevel),
+	}
+
+	limiter.setVolume = func(kind string, index int, factors []float64) error {
+		// channels at the same level are set together, as a balanced volume
+		if balanced(factors) {
+			switch kind {
+			case ObjectSink:
+				return server.SetSinkVolume(index, factors[0])
+			default:
+				return server.SetSinkInputVolume(index, factors[0])
+			}
+		}
+
+		switch kind {
+		case ObjectSink:
+			return server.SetSinkChannelVolumes(index, factors)
+		default:
+			return server.SetSinkInputChannelVolumes(index, factors)
+		}
+	}
+
+	if len(rules) == 0 {
+		rules = []LimitRule{{
+			Name:      `default`,
+			MaxVolume: DEFAULT_LIMITER_MAX_VOLUME,
+		}}
+	}
+
+	for i := range rules {
+		rule := rules[i]
+
+		switch rule.Kind {
+		case ``, ObjectSink, ObjectSinkInput:
+		default:
+			return nil, fmt.Errorf("Limit rule %q cannot apply to %q objects", rule.String(), rule.Kind)
+		}
+
+		if rule.MaxVolume < 0 || rule.MaxIncrease < 0 {
+			return nil, fmt.Errorf("Limit rule %q cannot have a negative limit", rule.String())
+		} else if rule.MaxIncrease > 0 && rule.Period <= 0 {
+			return nil, fmt.Errorf("Limit rule %q must specify a period for its maximum increase", rule.String())
+		}
+
+		if matcher, err := CompileFilter(rule.Filter); err == nil {
+			rule.matcher = matcher
+		} else {
+			return nil, err
+		}
+
+		limiter.Rules = append(limiter.Rules, &rule)
+	}
+
+	return limiter, nil
+}
+
+// Enforce the policy on all current sinks and sink inputs, then keep enforcing
+// it as volumes change until Stop is called.
+func (self *Limiter) Start() error {
+	if sub, err := self.server.SubscribeEvents(SinkEvent, SinkInputEvent); err == nil {
+		self.sub = sub
+	} else {
+		return err
+	}
+
+	if sinks, err := self.server.GetSinks(); err == nil {
+		for _, sink := range sinks {
+			self.enforce(ObjectSink, sink.Index, sink.Name, sink, sinkChannels(sink), time.Now())
+		}
+	} else {
+		self.sub.Close()
+		return err
+	}
+
+	if sinkInputs, err := self.server.GetSinkInputs(); err == nil {
+		for i := range sinkInputs {
+			sinkInput := &sinkInputs[i]
+			self.enforce(ObjectSinkInput, sinkInput.Index, sinkInput.Name, sinkInput, sinkInputChannels(sinkInput), time.Now())
+		}
+	} else {
+		self.sub.Close()
+		return err
+	}
+
+	go self.run()
+
+	return nil
+}
+
+// Stop enforcing the policy.
+func (self *Limiter) Stop() error {
+	if self.sub != nil {
+		return self.sub.Close()
+	}
+
+	return nil
+}
+
+func (self *Limiter) run() {
+	for event := range self.sub.Events() {
+		switch event.Facility {
+		case SinkEvent:
+			if event.Kind == EventRemove {
+				self.forget(ObjectSink, event.Index)
+				continue
+			}
+
+			if sink, err := getSink(self.server, event.Index); err == nil {
+				self.enforce(ObjectSink, sink.Index, sink.Name, sink, sinkChannels(sink), time.Now())
+			}
+
+		case SinkInputEvent:
+			if event.Kind == EventRemove {
+				self.forget(ObjectSinkInput, event.Index)
+				continue
+			}
+
+			if sinkInput, err := getSinkInput(self.server, event.Index); err == nil {
+				self.enforce(ObjectSinkInput, sinkInput.Index, sinkInput.Name, sinkInput, sinkInputChannels(sinkInput), time.Now())
+			}
+		}
+	}
+}
+
+// return the first rule matching the given object
+func (self *Limiter) ruleFor(kind string, object interface{}) *LimitRule {
+	for _, rule := range self.Rules {
+		if (rule.Kind == `` || rule.Kind == kind) && rule.matcher.Match(object) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// check the loudest of an object's channel volumes against the policy,
+// correcting them and reporting the override if necessary
+func (self *Limiter) enforce(kind string, index int, name string, object interface{}, channels []float64, now time.Time) *Override {
+	rule := self.ruleFor(kind, object)
+
+	if rule == nil || len(channels) == 0 {
+		return nil
+	}
+
+	loudest := channels[0]
+
+	for _, channel := range channels[1:] {
+		if channel > loudest {
+			loudest = channel
+		}
+	}
+
+	override := self.limit(rule, kind, index, loudest, now)
+
+	if override == nil {
+		return nil
+	}
+
+	override.Name = name
+
+	// scale every channel down by the same proportion so that the balance
+	// between them is kept
+	corrected := make([]float64, len(channels))
+
+	for i, channel := range channels {
+		if channel == loudest {
+			corrected[i] = override.Applied
+		} else {
+			corrected[i] = channel * override.Applied / loudest
+		}
+	}
+
+	if err := self.setVolume(kind, index, corrected); err != nil {
+		override.Error = err.Error()
+	}
+
+	if self.OnOverride != nil {
+		self.OnOverride(*override)
+	}
+
+	return override
+}
+
+// apply a rule to a volume, returning an Override if the volume is not allowed
+func (self *Limiter) limit(rule *LimitRule, kind string, index int, volume float64, now time.Time) *Override {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	key := fmt.Sprintf("%s:%d", kind, index)
+	state, seen := self.levels[key]
+
+	if !seen {
+		state = &limiterLevel{
+			level:   volume,
+			budget:  rule.MaxIncrease,
+			updated: now,
+		}
+
+		self.levels[key] = state
+	}
+
+	allowed := volume
+	reason := ``
+
+	// refill the increase budget for the time elapsed since the last change, but
+	// never beyond a single period's worth
+	if seen && rule.MaxIncrease > 0 {
+		state.budget += rule.MaxIncrease * float64(now.Sub(state.updated)) / float64(rule.Period)
+
+		if state.budget > rule.MaxIncrease {
+			state.budget = rule.MaxIncrease
+		}
+
+		if increase := volume - state.level; increase > limiterTolerance {
+			if increase > state.budget {
+				allowed = state.level + state.budget
+				reason = LimitRate
+			}
+		}
+	}
+
+	if rule.MaxVolume > 0 && allowed > rule.MaxVolume {
+		allowed = rule.MaxVolume
+		reason = LimitMaximum
+	}
+
+	if seen && allowed > state.level {
+		state.budget -= (allowed - state.level)
+	}
+
+	state.level = allowed
+	state.updated = now
+
+	if volume-allowed <= limiterTolerance {
+		return nil
+	}
+
+	return &Override{
+		Time:      now,
+		Rule:      rule.String(),
+		Kind:      kind,
+		Index:     index,
+		Reason:    reason,
+		Requested: volume,
+		Applied:   allowed,
+	}
+}
+
+func (self *Limiter) forget(kind string, index int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	delete(self.levels, fmt.Sprintf("%s:%d", kind, index))
+}
+
+// the volume of each of a sink's channels, as factors of the normal volume
+func sinkChannels(sink *Sink) []float64 {
+	if len(sink.ChannelVolumes) == 0 || sink.NumVolumeSteps <= 0 {
+		return []float64{sink.VolumeFactor}
+	}
+
+	factors := make([]float64, len(sink.ChannelVolumes))
+
+	for i, channel := range sink.ChannelVolumes {
+		factors[i] = channel.Value / float64(sink.NumVolumeSteps)
+	}
+
+	return factors
+}
+
+// the volume of each of a sink input's channels, as factors of the normal volume
+func sinkInputChannels(sinkInput *SinkInput) []float64 {
+	if len(sinkInput.Channels) == 0 {
+		return []float64{sinkInput.VolumeFactor()}
+	}
+
+	factors := make([]float64, len(sinkInput.Channels))
+
+	for i, channel := range sinkInput.Channels {
+		factors[i] = channel.Value / DefaultVolumeStep
+	}
+
+	return factors
+}
+
+// whether all the given volumes are the same
+func balanced(factors []float64) bool {
+	for _, factor := range factors[1:] {
+		if factor != factors[0] {
+			return false
+		}
+	}
+
+	return true
+}
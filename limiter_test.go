@@ -0,0 +1,151 @@
+package pulse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterRules(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := NewLimiter(nil, LimitRule{Kind: `card`, MaxVolume: 1})
+	assert.Error(err)
+
+	_, err = NewLimiter(nil, LimitRule{MaxIncrease: 0.1})
+	assert.Error(err)
+
+	_, err = NewLimiter(nil, LimitRule{Filter: `Name/re:(`})
+	assert.True(IsFilterSyntaxErr(err))
+
+	limiter, err := NewLimiter(nil)
+	assert.NoError(err)
+	assert.Len(limiter.Rules, 1)
+	assert.Equal(DEFAULT_LIMITER_MAX_VOLUME, limiter.Rules[0].MaxVolume)
+}
+
+func TestLimiterMaximum(t *testing.T) {
+	assert := require.New(t)
+
+	limiter, err := NewLimiter(nil,
+		LimitRule{Name: `headphones`, Filter: `Name/headset`, Kind: ObjectSink, MaxVolume: 0.7},
+		LimitRule{Name: `streams`, Kind: ObjectSinkInput, MaxVolume: 1},
+	)
+	assert.NoError(err)
+
+	applied := make(map[int]float64)
+	overrides := make([]Override, 0)
+
+	limiter.setVolume = func(kind string, index int, factors []float64) error {
+		applied[index] = factors[0]
+		return nil
+	}
+
+	limiter.OnOverride = func(override Override) {
+		overrides = append(overrides, override)
+	}
+
+	now := time.Now()
+	headset := &Sink{Index: 1, Name: `headset`}
+	speakers := &Sink{Index: 2, Name: `speakers`}
+
+	assert.Nil(limiter.enforce(ObjectSink, 1, `headset`, headset, []float64{0.5}, now))
+	assert.Nil(limiter.enforce(ObjectSink, 2, `speakers`, speakers, []float64{1.5}, now))
+
+	override := limiter.enforce(ObjectSink, 1, `headset`, headset, []float64{1.5}, now)
+	assert.NotNil(override)
+	assert.Equal(LimitMaximum, override.Reason)
+	assert.Equal(`headphones`, override.Rule)
+	assert.Equal(1.5, override.Requested)
+	assert.Equal(0.7, applied[1])
+
+	// the corrected volume coming back from the server is accepted
+	assert.Nil(limiter.enforce(ObjectSink, 1, `headset`, headset, []float64{0.7}, now))
+
+	override = limiter.enforce(ObjectSinkInput, 9, `Playback`, &SinkInput{Index: 9}, []float64{1.5}, now)
+	assert.NotNil(override)
+	assert.Equal(1.0, applied[9])
+
+	assert.Len(overrides, 2)
+	assert.Equal(`sink 1 (headset): limited 1.5 to 0.7 (maximum) [rule headphones]`, overrides[0].String())
+}
+
+func TestLimiterUnbalanced(t *testing.T) {
+	assert := require.New(t)
+
+	limiter, err := NewLimiter(nil)
+	assert.NoError(err)
+
+	var applied []float64
+
+	limiter.setVolume = func(kind string, index int, factors []float64) error {
+		applied = factors
+		return nil
+	}
+
+	sink := &Sink{Index: 1}
+	now := time.Now()
+
+	// the channels average out to 1.0, but the left one is still too loud
+	override := limiter.enforce(ObjectSink, 1, ``, sink, []float64{1.5, 0.5}, now)
+	assert.NotNil(override)
+	assert.Equal(LimitMaximum, override.Reason)
+	assert.Equal(1.5, override.Requested)
+	assert.Equal(1.0, override.Applied)
+
+	// both channels are scaled down, keeping their balance
+	assert.Len(applied, 2)
+	assert.Equal(1.0, applied[0])
+	assert.InDelta(1.0/3, applied[1], 0.0001)
+
+	// the corrected channels coming back from the server are accepted
+	assert.Nil(limiter.enforce(ObjectSink, 1, ``, sink, []float64{1.0, 1.0 / 3}, now))
+}
+
+func TestLimiterRate(t *testing.T) {
+	assert := require.New(t)
+
+	limiter, err := NewLimiter(nil, LimitRule{MaxVolume: 1.2, MaxIncrease: 0.2, Period: time.Second})
+	assert.NoError(err)
+
+	limiter.setVolume = func(string, int, []float64) error { return nil }
+	sink := &Sink{Index: 1}
+	now := time.Now()
+
+	assert.Nil(limiter.enforce(ObjectSink, 1, ``, sink, []float64{0.3}, now))
+
+	// small increases are fine until the budget runs out
+	assert.Nil(limiter.enforce(ObjectSink, 1, ``, sink, []float64{0.4}, now))
+
+	override := limiter.enforce(ObjectSink, 1, ``, sink, []float64{0.9}, now)
+	assert.NotNil(override)
+	assert.Equal(LimitRate, override.Reason)
+	assert.InDelta(0.5, override.Applied, 0.0001)
+
+	// decreases are always allowed
+	assert.Nil(limiter.enforce(ObjectSink, 1, ``, sink, []float64{0.2}, now))
+
+	// half a period later, half the budget is available again
+	override = limiter.enforce(ObjectSink, 1, ``, sink, []float64{0.9}, now.Add(500*time.Millisecond))
+	assert.NotNil(override)
+	assert.InDelta(0.3, override.Applied, 0.0001)
+
+	// the budget never exceeds a single period's worth
+	override = limiter.enforce(ObjectSink, 1, ``, sink, []float64{1}, now.Add(time.Hour))
+	assert.NotNil(override)
+	assert.InDelta(0.5, override.Applied, 0.0001)
+
+	// the maximum still applies once the rate allows more
+	for i, expected := range []float64{0.7, 0.9, 1.1, 1.2} {
+		override = limiter.enforce(ObjectSink, 1, ``, sink, []float64{1.5}, now.Add(time.Duration(i+2)*time.Hour))
+		assert.InDelta(expected, override.Applied, 0.0001)
+	}
+
+	assert.Equal(LimitMaximum, override.Reason)
+
+	// once the object goes away, its history is forgotten
+	limiter.forget(ObjectSink, 1)
+	override = limiter.enforce(ObjectSink, 1, ``, sink, []float64{1.5}, now)
+	assert.Equal(1.2, override.Applied)
+}
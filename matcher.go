@@ -0,0 +1,152 @@
+package pulse
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Matcher is a filter that has been parsed and validated once, and can then be
+// matched quickly against any number of objects.  Fields are resolved directly
+// on structs, maps, and slices rather than by flattening each object into a map.
+type Matcher struct {
+	Filter Filter
+	groups []FilterGroup
+}
+
+// Parse and validate the given filters, any of which may match.  An error
+// identifying the offending clause is returned if any filter is invalid.  Empty
+// filter strings are ignored; a Matcher with no filters matches everything.
+func CompileFilter(filters ...string) (*Matcher, error) {
+	rv := &Matcher{
+		Filter: make(Filter, 0),
+		groups: make([]FilterGroup, 0),
+	}
+
+	for _, flt := range filters {
+		if strings.TrimSpace(flt) == `` {
+			continue
+		}
+
+		rv.Filter = append(rv.Filter, flt)
+	}
+
+	if groups, err := rv.Filter.Groups(); err == nil {
+		rv.groups = groups
+	} else {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// Return whether the given object (typically a *Sink, *Source, SinkInput,
+// *Module, or *Client) satisfies the filter.
+func (self *Matcher) Match(obj interface{}) bool {
+	if self == nil || len(self.groups) == 0 {
+		return true
+	}
+
+	value := reflect.ValueOf(obj)
+
+	for _, group := range self.groups {
+		if group.isValueMatch(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (self *Matcher) String() string {
+	return self.Filter.String()
+}
+
+func (self FilterGroup) isValueMatch(value reflect.Value) bool {
+	for _, clause := range self {
+		v, present := lookupFieldPath(value, clause.path)
+
+		if !clause.IsMatch(v, present) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Resolve a dot-separated field path against a value, following struct fields,
+// string-keyed map entries, and slice indices.  Only leaf values are considered
+// present, mirroring how objects are flattened by Filter.IsMatch.
+func lookupFieldPath(value reflect.Value, path []string) (interface{}, bool) {
+	for {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, len(path) == 0
+			}
+
+			value = value.Elem()
+		}
+
+		if len(path) == 0 {
+			break
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			if field, ok := value.Type().FieldByName(path[0]); ok && field.PkgPath == `` {
+				value = value.FieldByIndex(field.Index)
+				path = path[1:]
+			} else {
+				return nil, false
+			}
+
+		case reflect.Map:
+			keyType := value.Type().Key()
+
+			if keyType.Kind() != reflect.String {
+				return nil, false
+			}
+
+			found := false
+
+			// prefer the longest matching key so that maps containing dotted keys
+			// (e.g.: "application.name") resolve as well as nested maps do
+			for n := len(path); n > 0; n-- {
+				key := reflect.ValueOf(strings.Join(path[:n], `.`)).Convert(keyType)
+
+				if v := value.MapIndex(key); v.IsValid() {
+					value = v
+					path = path[n:]
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, false
+			}
+
+		case reflect.Slice, reflect.Array:
+			if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < value.Len() {
+				value = value.Index(i)
+				path = path[1:]
+			} else {
+				return nil, false
+			}
+
+		default:
+			return nil, false
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return nil, false
+	}
+
+	if !value.IsValid() {
+		return nil, false
+	}
+
+	return value.Interface(), true
+}
@@ -0,0 +1,93 @@
+package pulse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilter(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := CompileFilter(`Name/foo`, `VolumeFactor/gt:loud`)
+	assert.Error(err)
+	assert.True(IsFilterSyntaxErr(err))
+
+	syntaxErr := err.(FilterSyntaxErr)
+	assert.Equal(`VolumeFactor/gt:loud`, syntaxErr.Clause)
+	assert.Equal(`gt`, syntaxErr.Operator)
+
+	_, err = CompileFilter(`/foo`)
+	assert.Equal(FilterSyntaxErr{Clause: `/foo`, Reason: `missing field name`}, err)
+
+	matcher, err := CompileFilter()
+	assert.NoError(err)
+	assert.True(matcher.Match(&Sink{}))
+
+	matcher, err = CompileFilter(``, ` `)
+	assert.NoError(err)
+	assert.True(matcher.Match(&Sink{}))
+}
+
+func TestMatcherAgreesWithFilter(t *testing.T) {
+	assert := require.New(t)
+
+	sink := &Sink{
+		Index:        3,
+		Name:         `alsa_output.usb-headset`,
+		VolumeFactor: 0.8,
+		State:        SinkStateRunning,
+		Properties: map[string]interface{}{
+			`device`: map[string]interface{}{
+				`bus`: `usb`,
+			},
+			`application.name`: `Firefox`,
+		},
+	}
+
+	sinkInput := SinkInput{
+		Index: 12,
+		Name:  `Playback`,
+		Volume: Volume{
+			Name:  `mean`,
+			Value: 100,
+		},
+		Channels: []Volume{
+			{Name: `front-left`, Value: 100},
+			{Name: `front-right`, Value: 50},
+		},
+	}
+
+	for _, flt := range []string{
+		`Name/alsa_output.usb-headset`,
+		`Index/gte:3&VolumeFactor/lt:0.9`,
+		`@device.bus/usb`,
+		`@device.bus/exists`,
+		`@device/exists`,
+		`@device.class/missing`,
+		`@application.name/re:^Fire`,
+		`Index/in:1,2,12`,
+		`conn/missing`,
+		`Name/nope;Index/3`,
+	} {
+		matcher, err := CompileFilter(flt)
+		assert.NoError(err, flt)
+
+		for _, obj := range []interface{}{sink, sinkInput} {
+			assert.Equal(Filter{flt}.IsMatch(obj), matcher.Match(obj), flt)
+		}
+	}
+
+	// nested structs and slices are resolved too, which flattening does not do
+	for flt, expected := range map[string]bool{
+		`Volume.Value/100`:            true,
+		`Channels.1.Name/front-right`: true,
+		`Channels.1.Value/gt:60`:      false,
+		`Channels.2.Name/missing`:     true,
+		`Channels/exists`:             false,
+	} {
+		matcher, err := CompileFilter(flt)
+		assert.NoError(err, flt)
+		assert.Equal(expected, matcher.Match(sinkInput), flt)
+	}
+}
@@ -0,0 +1,259 @@
+package pulse
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/auroralaboratories/pulse/pcm"
+)
+
+const (
+	DEFAULT_MIXER_FINISHED_BUFFER = 64
+	mixerSoftClipThreshold        = 0.8
+)
+
+// A MixerInput is a single reader being mixed by a Mixer.
+type MixerInput struct {
+	ID       int
+	reader   io.Reader
+	gain     float32
+	finished bool
+	err      error
+	lock     sync.Mutex
+}
+
+// Return the gain currently applied to this input.
+func (self *MixerInput) Gain() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return float64(self.gain)
+}
+
+// Change the gain applied to this input, taking effect from the next read.
+func (self *MixerInput) SetGain(gain float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.gain = float32(gain)
+}
+
+// Return whether this input has reached the end of its data (or failed).
+func (self *MixerInput) Finished() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.finished
+}
+
+// Return the error that ended this input, if it was anything other than EOF.
+func (self *MixerInput) Err() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.err
+}
+
+// A Mixer combines any number of readers, all containing audio in the same
+// SampleSpec, into a single stream of audio suitable for use as a
+// PlaybackStream's Source.  Inputs may be added and removed at any time.  The
+// mixed signal is soft-clipped so that overlapping loud inputs saturate
+// smoothly instead of wrapping or hard clipping.
+//
+// While the Mixer has no inputs, reads return silence; reads return io.EOF only
+// once the Mixer has been closed.
+type Mixer struct {
+	Spec     SampleSpec
+	inputs   []*MixerInput
+	nextID   int
+	finished chan *MixerInput
+	output   []byte
+	closed   bool
+	lock     sync.Mutex
+}
+
+func NewMixer(spec SampleSpec) (*Mixer, error) {
+	if !spec.Format.PCM().Valid() {
+		return nil, fmt.Errorf("Cannot mix unsupported sample format %v", spec.Format)
+	} else if spec.NumChannels <= 0 {
+		return nil, fmt.Errorf("Invalid sample spec for mixing: %v", spec)
+	}
+
+	return &Mixer{
+		Spec:     spec,
+		finished: make(chan *MixerInput, DEFAULT_MIXER_FINISHED_BUFFER),
+	}, nil
+}
+
+// Start mixing the given reader, which must contain audio in the Mixer's Spec,
+// at the given gain (1.0 being unity).
+func (self *Mixer) AddInput(reader io.Reader, gain float64) *MixerInput {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.nextID++
+
+	input := &MixerInput{
+		ID:     self.nextID,
+		reader: reader,
+		gain:   float32(gain),
+	}
+
+	self.inputs = append(self.inputs, input)
+
+	return input
+}
+
+// Stop mixing the given input.  Returns false if the input was not part of the
+// mix (e.g.: it was already removed or has finished).
+func (self *Mixer) Remove(input *MixerInput) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, existing := range self.inputs {
+		if existing == input {
+			self.inputs = append(self.inputs[:i], self.inputs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the inputs currently being mixed.
+func (self *Mixer) Inputs() []*MixerInput {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	inputs := make([]*MixerInput, len(self.inputs))
+	copy(inputs, self.inputs)
+
+	return inputs
+}
+
+// Return a channel that receives each input as it reaches the end of its data
+// and is removed from the mix.  Notifications are dropped if the channel is not
+// drained quickly enough; MixerInput.Finished can always be checked directly.
+func (self *Mixer) Finished() <-chan *MixerInput {
+	return self.finished
+}
+
+// Stop mixing.  Subsequent reads return io.EOF, and the Finished channel is
+// closed.
+func (self *Mixer) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if !self.closed {
+		self.closed = true
+		close(self.finished)
+	}
+
+	return nil
+}
+
+// Read the next block of mixed audio.
+func (self *Mixer) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(self.output) == 0 {
+		self.lock.Lock()
+		closed := self.closed
+		inputs := make([]*MixerInput, len(self.inputs))
+		copy(inputs, self.inputs)
+		self.lock.Unlock()
+
+		if closed {
+			return 0, io.EOF
+		}
+
+		frameSize := frameSizeOf(self.Spec)
+		frames := len(p) / frameSize
+
+		if frames == 0 {
+			frames = 1
+		}
+
+		self.output = self.mix(inputs, frames)
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+func (self *Mixer) mix(inputs []*MixerInput, frames int) []byte {
+	format := self.Spec.Format.PCM()
+	sum := make([]float32, frames*self.Spec.NumChannels)
+	buf := make([]byte, len(sum)*format.SampleSize())
+	samples := make([]float32, len(sum))
+
+	for _, input := range inputs {
+		n, err := io.ReadFull(input.reader, buf)
+		whole := n / format.SampleSize()
+
+		pcm.DecodeInto(samples[:whole], format, buf[:whole*format.SampleSize()])
+
+		gain := float32(input.Gain())
+
+		for i, v := range samples[:whole] {
+			sum[i] += v * gain
+		}
+
+		if err != nil {
+			self.finish(input, err)
+		}
+	}
+
+	for i, v := range sum {
+		sum[i] = softClip(v)
+	}
+
+	out := make([]byte, len(buf))
+	pcm.EncodeInto(out, format, sum)
+
+	return out
+}
+
+func (self *Mixer) finish(input *MixerInput, err error) {
+	input.lock.Lock()
+	input.finished = true
+
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		input.err = err
+	}
+
+	input.lock.Unlock()
+
+	if self.Remove(input) {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+
+		if !self.closed {
+			select {
+			case self.finished <- input:
+			default:
+			}
+		}
+	}
+}
+
+// pass values below the threshold through unchanged, and smoothly compress
+// anything above it so that the output never exceeds full scale
+func softClip(v float32) float32 {
+	x := math.Abs(float64(v))
+
+	if x <= mixerSoftClipThreshold {
+		return v
+	}
+
+	headroom := 1 - mixerSoftClipThreshold
+	x = mixerSoftClipThreshold + headroom*math.Tanh((x-mixerSoftClipThreshold)/headroom)
+
+	return float32(math.Copysign(x, float64(v)))
+}
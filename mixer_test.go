@@ -0,0 +1,64 @@
+package pulse
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/auroralaboratories/pulse/pcm"
+	"github.com/stretchr/testify/require"
+)
+
+func readMixed(t *testing.T, mixer *Mixer, frames int) []float32 {
+	buf := make([]byte, frames*4)
+	_, err := io.ReadFull(mixer, buf)
+	require.NoError(t, err)
+
+	samples, err := pcm.Decode(pcm.Float32LE, buf)
+	require.NoError(t, err)
+
+	return samples
+}
+
+func TestMixer(t *testing.T) {
+	assert := require.New(t)
+
+	mixer, err := NewMixer(SampleSpec{Format: FormatIEEEFloat32LE, SampleRate: 8000, NumChannels: 1})
+	assert.NoError(err)
+
+	// silence with no inputs
+	assert.Equal([]float32{0, 0}, readMixed(t, mixer, 2))
+
+	short, _ := pcm.Encode(pcm.Float32LE, []float32{0.25, 0.25})
+	long, _ := pcm.Encode(pcm.Float32LE, []float32{0.1, 0.1, 0.1, 0.1})
+
+	a := mixer.AddInput(bytes.NewReader(short), 1)
+	b := mixer.AddInput(bytes.NewReader(long), 2)
+	assert.Len(mixer.Inputs(), 2)
+
+	assert.InDeltaSlice([]float32{0.45, 0.45, 0.2}, readMixed(t, mixer, 3), 1e-6)
+	assert.True(a.Finished())
+	assert.NoError(a.Err())
+	assert.False(b.Finished())
+	assert.Equal(a, <-mixer.Finished())
+	assert.Len(mixer.Inputs(), 1)
+
+	b.SetGain(0.5)
+	assert.InDeltaSlice([]float32{0.05}, readMixed(t, mixer, 1), 1e-6)
+
+	assert.True(mixer.Remove(b))
+	assert.False(mixer.Remove(b))
+
+	// loud inputs are compressed rather than clipped
+	loud, _ := pcm.Encode(pcm.Float32LE, []float32{0.9, 0.5})
+	mixer.AddInput(bytes.NewReader(loud), 1)
+	mixer.AddInput(bytes.NewReader(loud), 1)
+
+	mixed := readMixed(t, mixer, 2)
+	assert.True(mixed[0] < 1 && mixed[0] > 0.9)
+	assert.True(mixed[1] < mixed[0] && mixed[1] > 0.8)
+
+	assert.NoError(mixer.Close())
+	_, err = mixer.Read(make([]byte, 4))
+	assert.Equal(io.EOF, err)
+}
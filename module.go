@@ -1,18 +1,15 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"fmt"
-	"unsafe"
 
 	"github.com/ghetzel/go-stockutil/maputil"
 	"github.com/ghetzel/go-stockutil/typeutil"
 )
 
+// The index of a module that isn't loaded (PA_INVALID_INDEX).
+const invalidModuleIndex = uint(0xFFFFFFFF)
+
 var NoSuchModuleErr = fmt.Errorf("no such module")
 
 func IsNoSuchModuleErr(err error) bool {
@@ -30,13 +27,14 @@ type Module struct {
 	Index      uint
 	Name       string
 	Properties map[string]interface{}
+	PropList   PropList
 	conn       *Conn
 }
 
 // Populate this module's fields with data in a string-interface{} map.
 func (self *Module) Initialize(properties map[string]interface{}) error {
 	self.Properties, _ = maputil.DiffuseMap(properties, `.`)
-	self.Index = uint(C.PA_INVALID_INDEX)
+	self.Index = invalidModuleIndex
 
 	return populateStruct(self.Properties, self)
 }
@@ -50,12 +48,7 @@ func (self *Module) Refresh() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_get_module_info(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_module_info_cb_t)(unsafe.Pointer(C.pulse_get_module_info_callback)),
-		operation.Userdata(),
-	)
+	self.conn.getModuleInfo(operation, self.Index)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -65,6 +58,8 @@ func (self *Module) Refresh() error {
 			if err := self.Initialize(payload.Properties); err != nil {
 				return err
 			}
+
+			self.PropList = payload.PropList
 		} else {
 			return fmt.Errorf("Invalid source response: expected 1 payload, got %d", l)
 		}
@@ -76,19 +71,13 @@ func (self *Module) Refresh() error {
 
 // Return whether the module is currently loaded or not
 func (self *Module) IsLoaded() bool {
-	return (self.Index != uint(C.PA_INVALID_INDEX))
+	return (self.Index != invalidModuleIndex)
 }
 
 // Load the module if it is not currently loaded
 func (self *Module) Load() error {
 	operation := NewOperation(self.conn)
-	operation.paOper = C.pa_context_load_module(
-		self.conn.context,
-		C.CString(self.Name),
-		C.CString(self.Argument),
-		(C.pa_context_index_cb_t)(unsafe.Pointer(C.pulse_generic_index_callback)),
-		operation.Userdata(),
-	)
+	self.conn.loadModule(operation, self.Name, self.Argument)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -104,16 +93,11 @@ func (self *Module) Load() error {
 func (self *Module) Unload() error {
 	if self.IsLoaded() {
 		operation := NewOperation(self.conn)
-		operation.paOper = C.pa_context_unload_module(
-			self.conn.context,
-			C.uint32_t(self.Index),
-			(C.pa_context_success_cb_t)(unsafe.Pointer(C.pulse_generic_success_callback)),
-			operation.Userdata(),
-		)
+		self.conn.unloadModule(operation, self.Index)
 
 		// wait for the operation to finish and handle success and error cases
 		return operation.WaitSuccess(func(op *Operation) error {
-			self.Index = uint(C.PA_INVALID_INDEX)
+			self.Index = invalidModuleIndex
 			return nil
 		})
 	} else {
@@ -0,0 +1,73 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/ghetzel/go-stockutil/stringutil"
+)
+
+// An Operation represents a request to a PulseAudio daemon to perform a specific
+// task or retrieve data. Operations will either complete successfully (nil will
+// be retruned on the Done channel), encounter an error or timeout (non-nil error
+// on the Done channel).  Any returned data will be in the form of one or more
+// Payload instances in the Payloads slice.
+//
+type Operation struct {
+	ID        string
+	Index     int
+	Timeout   time.Duration
+	Payloads  []*Payload
+	lastError error
+	paOper    *C.pa_operation
+	conn      *Conn
+}
+
+func NewOperation(c *Conn) *Operation {
+	rv := &Operation{
+		ID:       stringutil.UUID().String(),
+		conn:     c,
+		Index:    -1,
+		Timeout:  c.OperationTimeout,
+		Payloads: make([]*Payload, 0),
+	}
+
+	cgoregister(rv.ID, rv)
+
+	// lock the client for the duration of this operation
+	c.Lock()
+
+	return rv
+}
+
+// Returns a pointer to the ID used for registering this object
+func (self *Operation) Userdata() unsafe.Pointer {
+	return unsafe.Pointer(C.CString(self.ID))
+}
+
+// Performs the operation in a threadsafe manner and returns the last error to occur.
+func (self *Operation) Run() error {
+	self.conn.Wait()
+	err := self.GetLastError()
+	self.conn.Unlock()
+
+	return err
+}
+
+// Signal the client mainloop that the operation is complete
+//
+func (self *Operation) Done() {
+	self.conn.SignalAll(false)
+}
+
+func (self *Operation) Destroy() {
+	cgounregister(self.ID)
+}
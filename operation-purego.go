@@ -0,0 +1,94 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/auroralaboratories/pulse/proto"
+	"github.com/ghetzel/go-stockutil/stringutil"
+)
+
+// An Operation represents a request to a PulseAudio daemon to perform a specific
+// task or retrieve data. Operations will either complete successfully (nil will
+// be retruned on the Done channel), encounter an error or timeout (non-nil error
+// on the Done channel).  Any returned data will be in the form of one or more
+// Payload instances in the Payloads slice.
+//
+type Operation struct {
+	ID        string
+	Index     int
+	Timeout   time.Duration
+	Payloads  []*Payload
+	lastError error
+	conn      *Conn
+	tag       uint32
+	done      chan struct{}
+	finished  bool
+}
+
+func NewOperation(c *Conn) *Operation {
+	rv := &Operation{
+		ID:       stringutil.UUID().String(),
+		conn:     c,
+		Index:    -1,
+		Timeout:  c.OperationTimeout,
+		Payloads: make([]*Payload, 0),
+		tag:      proto.NoTag,
+		done:     make(chan struct{}),
+	}
+
+	// lock the client until the operation's request has been sent
+	c.Lock()
+
+	return rv
+}
+
+// Performs the operation in a threadsafe manner and returns the last error to occur.
+func (self *Operation) Run() error {
+	self.conn.Unlock()
+
+	var timeout <-chan time.Time
+
+	if self.Timeout > 0 && self.Timeout < MaxDuration() {
+		timer := time.NewTimer(self.Timeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case <-self.done:
+	case <-timeout:
+		self.conn.Lock()
+
+		// the reply may have arrived while we were waiting for the lock
+		if !self.finished {
+			self.conn.client.Cancel(self.tag)
+			self.SetError(proto.ErrTimeout)
+			self.Done()
+		}
+
+		self.conn.Unlock()
+	}
+
+	return self.GetLastError()
+}
+
+// Mark the operation as complete, waking up Run.  The connection must be locked.
+//
+func (self *Operation) Done() {
+	if !self.finished {
+		self.finished = true
+		close(self.done)
+	}
+}
+
+func (self *Operation) Destroy() {}
+
+// Returns a pointer to the ID used for identifying this object
+func (self *Operation) Userdata() unsafe.Pointer {
+	return userdata(self.ID)
+}
@@ -1,16 +1,10 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"sync"
-	"time"
-	"unsafe"
 
 	"github.com/ghetzel/go-stockutil/stringutil"
+	"github.com/ghetzel/go-stockutil/typeutil"
 )
 
 const (
@@ -34,6 +28,7 @@ var outstandingOperations sync.Map
 type Payload struct {
 	Operation  *Operation
 	Properties map[string]interface{}
+	PropList   PropList
 	Data       []byte
 }
 
@@ -45,44 +40,6 @@ func NewPayload(operation *Operation) *Payload {
 	}
 }
 
-// An Operation represents a request to a PulseAudio daemon to perform a specific
-// task or retrieve data. Operations will either complete successfully (nil will
-// be retruned on the Done channel), encounter an error or timeout (non-nil error
-// on the Done channel).  Any returned data will be in the form of one or more
-// Payload instances in the Payloads slice.
-//
-type Operation struct {
-	ID        string
-	Index     int
-	Timeout   time.Duration
-	Payloads  []*Payload
-	lastError error
-	paOper    *C.pa_operation
-	conn      *Conn
-}
-
-func NewOperation(c *Conn) *Operation {
-	rv := &Operation{
-		ID:       stringutil.UUID().String(),
-		conn:     c,
-		Index:    -1,
-		Timeout:  c.OperationTimeout,
-		Payloads: make([]*Payload, 0),
-	}
-
-	cgoregister(rv.ID, rv)
-
-	// lock the client for the duration of this operation
-	c.Lock()
-
-	return rv
-}
-
-// Returns a pointer to the ID used for registering this object
-func (self *Operation) Userdata() unsafe.Pointer {
-	return unsafe.Pointer(C.CString(self.ID))
-}
-
 // Set an error message on this operation
 //
 func (self *Operation) SetError(err error) {
@@ -95,21 +52,6 @@ func (self *Operation) GetLastError() error {
 	return self.lastError
 }
 
-// Performs the operation in a threadsafe manner and returns the last error to occur.
-func (self *Operation) Run() error {
-	self.conn.Wait()
-	err := self.GetLastError()
-	self.conn.Unlock()
-
-	return err
-}
-
-// Signal the client mainloop that the operation is complete
-//
-func (self *Operation) Done() {
-	self.conn.SignalAll(false)
-}
-
 // Create a new payload object and add it to the Payloads stack
 //
 func (self *Operation) AddPayload() *Payload {
@@ -158,6 +100,79 @@ func (self *Operation) Wait() error {
 	})
 }
 
-func (self *Operation) Destroy() {
-	cgounregister(self.ID)
+// Set a property on the operation's current payload (creating it if need be),
+// converting the value to the given type: one of "bool", "float", "int",
+// "volume" (a factor of DefaultVolumeStep), "time", or "str".  The type "prop"
+// marks an entry of the object's property list, which is also kept verbatim in
+// the payload's PropList.  Empty values are not set.
+func (self *Operation) setProperty(key string, value string, convertTo string) {
+	var payload *Payload
+
+	if self.Index < 0 {
+		payload = self.AddPayload()
+		self.Index = 0
+	} else {
+		payload = self.Payloads[self.Index]
+	}
+
+	if key != `` {
+		// property list entries are kept verbatim in the PropList, as well as
+		// being converted into Properties like everything else
+		if convertTo == `prop` {
+			payload.PropList.Set(key, value)
+			convertTo = ``
+		}
+
+		if value != `` {
+			if convertTo != `` {
+				var ctype stringutil.ConvertType
+
+				switch convertTo {
+				case `bool`:
+					ctype = stringutil.Boolean
+				case `float`:
+					ctype = stringutil.Float
+				case `int`:
+					ctype = stringutil.Integer
+				case `volume`:
+					ctype = stringutil.Integer
+				case `time`:
+					ctype = stringutil.Time
+				default:
+					ctype = stringutil.String
+				}
+
+				if cV, err := stringutil.ConvertTo(ctype, value); err == nil {
+					switch convertTo {
+					case `volume`:
+						payload.Properties[key] = typeutil.Float(cV) / DefaultVolumeStep
+					default:
+						payload.Properties[key] = cV
+					}
+
+				} else {
+					payload.Properties[key] = value
+				}
+			} else {
+				payload.Properties[key] = typeutil.Auto(value)
+			}
+		}
+	}
+}
+
+// Start a new payload; subsequent properties are set on it.
+func (self *Operation) createPayload() {
+	self.AddPayload()
+	self.Index = (len(self.Payloads) - 1)
+}
+
+// Drop any payloads that never received data, leaving only the objects that
+// were actually returned.
+func (self *Operation) complete() {
+	// truncate empty payloads
+	for i, payload := range self.Payloads {
+		if len(payload.Properties) == 0 && len(payload.PropList) == 0 && len(payload.Data) == 0 {
+			self.Payloads = append(self.Payloads[:i], self.Payloads[i+1:]...)
+		}
+	}
 }
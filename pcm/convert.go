@@ -0,0 +1,214 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	scale8  = 1 << 7
+	scale16 = 1 << 15
+	scale24 = 1 << 23
+	scale32 = 1 << 31
+)
+
+// Decode raw samples in the given format into normalized float32 samples in
+// the range -1.0 to 1.0.  The length of data must be a multiple of the
+// format's sample size.
+func Decode(format Format, data []byte) ([]float32, error) {
+	if !format.Valid() {
+		return nil, invalidFormatErr(format)
+	}
+
+	size := format.SampleSize()
+
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("pcm: %d bytes is not a whole number of %v samples", len(data), format)
+	}
+
+	out := make([]float32, len(data)/size)
+	DecodeInto(out, format, data)
+
+	return out, nil
+}
+
+// Decode as many whole samples from data as will fit into out, returning the
+// number of samples decoded.
+func DecodeInto(out []float32, format Format, data []byte) int {
+	size := format.SampleSize()
+
+	if size == 0 {
+		return 0
+	}
+
+	n := len(data) / size
+
+	if n > len(out) {
+		n = len(out)
+	}
+
+	for i := 0; i < n; i++ {
+		out[i] = decodeSample(format, data[i*size:(i+1)*size])
+	}
+
+	return n
+}
+
+// Encode normalized float32 samples into raw samples in the given format.
+// Values outside of -1.0 to 1.0 are clipped.
+func Encode(format Format, samples []float32) ([]byte, error) {
+	if !format.Valid() {
+		return nil, invalidFormatErr(format)
+	}
+
+	out := make([]byte, len(samples)*format.SampleSize())
+	EncodeInto(out, format, samples)
+
+	return out, nil
+}
+
+// Encode as many samples as will fit into out, returning the number of bytes
+// written.
+func EncodeInto(out []byte, format Format, samples []float32) int {
+	size := format.SampleSize()
+
+	if size == 0 {
+		return 0
+	}
+
+	n := len(out) / size
+
+	if n > len(samples) {
+		n = len(samples)
+	}
+
+	for i := 0; i < n; i++ {
+		encodeSample(format, out[i*size:(i+1)*size], samples[i])
+	}
+
+	return n * size
+}
+
+// Convert raw samples directly from one format to another.
+func Convert(from Format, to Format, data []byte) ([]byte, error) {
+	if from == to && from.Valid() {
+		out := make([]byte, len(data))
+		copy(out, data)
+		return out, nil
+	}
+
+	if samples, err := Decode(from, data); err == nil {
+		return Encode(to, samples)
+	} else {
+		return nil, err
+	}
+}
+
+// Convert signed 16-bit integer samples to normalized float32 samples.
+func FromInt16(samples []int16) []float32 {
+	out := make([]float32, len(samples))
+
+	for i, s := range samples {
+		out[i] = float32(s) / scale16
+	}
+
+	return out
+}
+
+// Convert normalized float32 samples to signed 16-bit integer samples.
+func ToInt16(samples []float32) []int16 {
+	out := make([]int16, len(samples))
+
+	for i, s := range samples {
+		out[i] = int16(quantize(s, scale16-1))
+	}
+
+	return out
+}
+
+func clip(sample float32) float64 {
+	if sample > 1 {
+		return 1
+	} else if sample < -1 {
+		return -1
+	} else if sample != sample {
+		return 0
+	}
+
+	return float64(sample)
+}
+
+func quantize(sample float32, max float64) int64 {
+	return int64(math.Round(clip(sample) * max))
+}
+
+func decodeSample(format Format, data []byte) float32 {
+	switch format {
+	case U8:
+		return float32(int(data[0])-scale8) / scale8
+	case ALaw:
+		return float32(aLawToLinear(data[0])) / scale16
+	case MuLaw:
+		return float32(muLawToLinear(data[0])) / scale16
+	case S16LE:
+		return float32(int16(binary.LittleEndian.Uint16(data))) / scale16
+	case S16BE:
+		return float32(int16(binary.BigEndian.Uint16(data))) / scale16
+	case Float32LE:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data))
+	case Float32BE:
+		return math.Float32frombits(binary.BigEndian.Uint32(data))
+	case S32LE:
+		return float32(float64(int32(binary.LittleEndian.Uint32(data))) / scale32)
+	case S32BE:
+		return float32(float64(int32(binary.BigEndian.Uint32(data))) / scale32)
+	case S24LE:
+		return float32(signExtend24(uint32(data[0])|uint32(data[1])<<8|uint32(data[2])<<16)) / scale24
+	case S24BE:
+		return float32(signExtend24(uint32(data[2])|uint32(data[1])<<8|uint32(data[0])<<16)) / scale24
+	case S24_32LE:
+		return float32(signExtend24(binary.LittleEndian.Uint32(data))) / scale24
+	case S24_32BE:
+		return float32(signExtend24(binary.BigEndian.Uint32(data))) / scale24
+	default:
+		return 0
+	}
+}
+
+func encodeSample(format Format, out []byte, sample float32) {
+	switch format {
+	case U8:
+		out[0] = byte(quantize(sample, scale8-1) + scale8)
+	case ALaw:
+		out[0] = linearToALaw(int16(quantize(sample, scale16-1)))
+	case MuLaw:
+		out[0] = linearToMuLaw(int16(quantize(sample, scale16-1)))
+	case S16LE:
+		binary.LittleEndian.PutUint16(out, uint16(quantize(sample, scale16-1)))
+	case S16BE:
+		binary.BigEndian.PutUint16(out, uint16(quantize(sample, scale16-1)))
+	case Float32LE:
+		binary.LittleEndian.PutUint32(out, math.Float32bits(float32(clip(sample))))
+	case Float32BE:
+		binary.BigEndian.PutUint32(out, math.Float32bits(float32(clip(sample))))
+	case S32LE:
+		binary.LittleEndian.PutUint32(out, uint32(quantize(sample, scale32-1)))
+	case S32BE:
+		binary.BigEndian.PutUint32(out, uint32(quantize(sample, scale32-1)))
+	case S24LE:
+		v := uint32(quantize(sample, scale24-1))
+		out[0], out[1], out[2] = byte(v), byte(v>>8), byte(v>>16)
+	case S24BE:
+		v := uint32(quantize(sample, scale24-1))
+		out[0], out[1], out[2] = byte(v>>16), byte(v>>8), byte(v)
+	case S24_32LE:
+		binary.LittleEndian.PutUint32(out, uint32(quantize(sample, scale24-1))&0xFFFFFF)
+	case S24_32BE:
+		binary.BigEndian.PutUint32(out, uint32(quantize(sample, scale24-1))&0xFFFFFF)
+	}
+}
+
+func signExtend24(v uint32) int32 {
+	return int32(v<<8) >> 8
+}
@@ -0,0 +1,104 @@
+// Package pcm converts raw PCM audio between the sample formats supported by
+// PulseAudio and normalized float32 samples, without any dependency on libpulse.
+package pcm
+
+import (
+	"fmt"
+)
+
+// A Format identifies a raw sample encoding.  Values are identical to those of
+// PulseAudio's pa_sample_format_t, so a pulse.SampleFormat can be converted
+// directly to a Format (and back).
+type Format int
+
+const (
+	Invalid   Format = -1
+	U8        Format = 0  // Unsigned 8 Bit PCM.
+	ALaw      Format = 1  // 8 Bit a-Law
+	MuLaw     Format = 2  // 8 Bit mu-Law
+	S16LE     Format = 3  // Signed 16 Bit PCM, little endian
+	S16BE     Format = 4  // Signed 16 Bit PCM, big endian
+	Float32LE Format = 5  // 32 Bit IEEE floating point, little endian, range -1.0 to 1.0
+	Float32BE Format = 6  // 32 Bit IEEE floating point, big endian, range -1.0 to 1.0
+	S32LE     Format = 7  // Signed 32 Bit PCM, little endian
+	S32BE     Format = 8  // Signed 32 Bit PCM, big endian
+	S24LE     Format = 9  // Signed 24 Bit PCM packed, little endian
+	S24BE     Format = 10 // Signed 24 Bit PCM packed, big endian
+	S24_32LE  Format = 11 // Signed 24 Bit PCM in LSB of 32 Bit words, little endian
+	S24_32BE  Format = 12 // Signed 24 Bit PCM in LSB of 32 Bit words, big endian
+)
+
+// All valid formats, in PulseAudio's order.
+var Formats = []Format{
+	U8,
+	ALaw,
+	MuLaw,
+	S16LE,
+	S16BE,
+	Float32LE,
+	Float32BE,
+	S32LE,
+	S32BE,
+	S24LE,
+	S24BE,
+	S24_32LE,
+	S24_32BE,
+}
+
+// Return whether the format is one that can be converted.
+func (self Format) Valid() bool {
+	return self >= U8 && self <= S24_32BE
+}
+
+// Return the size (in bytes) of a single sample in this format.
+func (self Format) SampleSize() int {
+	switch self {
+	case U8, ALaw, MuLaw:
+		return 1
+	case S16LE, S16BE:
+		return 2
+	case S24LE, S24BE:
+		return 3
+	case Float32LE, Float32BE, S32LE, S32BE, S24_32LE, S24_32BE:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func (self Format) String() string {
+	switch self {
+	case U8:
+		return `u8`
+	case ALaw:
+		return `aLaw`
+	case MuLaw:
+		return `uLaw`
+	case S16LE:
+		return `s16le`
+	case S16BE:
+		return `s16be`
+	case Float32LE:
+		return `float32le`
+	case Float32BE:
+		return `float32be`
+	case S32LE:
+		return `s32le`
+	case S32BE:
+		return `s32be`
+	case S24LE:
+		return `s24le`
+	case S24BE:
+		return `s24be`
+	case S24_32LE:
+		return `s24-32le`
+	case S24_32BE:
+		return `s24-32be`
+	default:
+		return `invalid`
+	}
+}
+
+func invalidFormatErr(format Format) error {
+	return fmt.Errorf("pcm: invalid sample format %d", int(format))
+}
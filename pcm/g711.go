@@ -0,0 +1,109 @@
+package pcm
+
+// G.711 a-law and mu-law companding, operating on 16-bit linear samples.  This
+// follows the reference implementation published by Sun Microsystems, which is
+// also what PulseAudio uses internally.
+
+const (
+	g711SignBit   = 0x80
+	g711QuantMask = 0x0F
+	g711SegShift  = 4
+	g711SegMask   = 0x70
+	muLawBias     = 0x84
+	muLawClip     = 8159
+)
+
+var aLawSegmentEnds = [8]int{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+var muLawSegmentEnds = [8]int{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+
+func g711Segment(value int, ends [8]int) int {
+	for i, end := range ends {
+		if value <= end {
+			return i
+		}
+	}
+
+	return len(ends)
+}
+
+func linearToALaw(pcm int16) byte {
+	value := int(pcm) >> 3
+	mask := 0xD5
+
+	if value < 0 {
+		mask = 0x55
+		value = -value - 1
+	}
+
+	segment := g711Segment(value, aLawSegmentEnds)
+
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+
+	code := segment << g711SegShift
+
+	if segment < 2 {
+		code |= (value >> 1) & g711QuantMask
+	} else {
+		code |= (value >> uint(segment)) & g711QuantMask
+	}
+
+	return byte(code ^ mask)
+}
+
+func aLawToLinear(code byte) int16 {
+	value := int(code ^ 0x55)
+	linear := (value & g711QuantMask) << 4
+
+	switch segment := uint(value&g711SegMask) >> g711SegShift; segment {
+	case 0:
+		linear += 8
+	case 1:
+		linear += 0x108
+	default:
+		linear += 0x108
+		linear <<= segment - 1
+	}
+
+	if value&g711SignBit != 0 {
+		return int16(linear)
+	}
+
+	return int16(-linear)
+}
+
+func linearToMuLaw(pcm int16) byte {
+	value := int(pcm) >> 2
+	mask := 0xFF
+
+	if value < 0 {
+		value = -value
+		mask = 0x7F
+	}
+
+	if value > muLawClip {
+		value = muLawClip
+	}
+
+	value += muLawBias >> 2
+	segment := g711Segment(value, muLawSegmentEnds)
+
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+
+	return byte(((segment << g711SegShift) | ((value >> uint(segment+1)) & g711QuantMask)) ^ mask)
+}
+
+func muLawToLinear(code byte) int16 {
+	value := int(^code)
+	linear := ((value & g711QuantMask) << 3) + muLawBias
+	linear <<= uint(value&g711SegMask) >> g711SegShift
+
+	if value&g711SignBit != 0 {
+		return int16(muLawBias - linear)
+	}
+
+	return int16(linear - muLawBias)
+}
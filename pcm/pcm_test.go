@@ -0,0 +1,144 @@
+package pcm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testSamples = []float32{0, 0.5, -0.5, 0.25, -1, 0.999, 0.1, -0.1}
+
+func tolerance(format Format) float64 {
+	switch format {
+	case U8:
+		return 2.0 / 127
+	case ALaw, MuLaw:
+		return 0.04
+	case S16LE, S16BE:
+		return 2.0 / 32767
+	default:
+		return 2.0 / 8388607
+	}
+}
+
+func TestRoundTripAllFormats(t *testing.T) {
+	assert := require.New(t)
+
+	for _, format := range Formats {
+		data, err := Encode(format, testSamples)
+		assert.NoError(err)
+		assert.Len(data, len(testSamples)*format.SampleSize(), format.String())
+
+		out, err := Decode(format, data)
+		assert.NoError(err)
+		assert.Len(out, len(testSamples))
+
+		for i, sample := range testSamples {
+			assert.InDelta(sample, out[i], tolerance(format), "%v sample %d", format, i)
+		}
+	}
+}
+
+func TestConvertBetweenAllFormats(t *testing.T) {
+	assert := require.New(t)
+
+	for _, from := range Formats {
+		for _, to := range Formats {
+			input, err := Encode(from, testSamples)
+			assert.NoError(err)
+
+			converted, err := Convert(from, to, input)
+			assert.NoError(err)
+
+			out, err := Decode(to, converted)
+			assert.NoError(err)
+
+			for i, sample := range testSamples {
+				assert.InDelta(sample, out[i], tolerance(from)+tolerance(to), "%v -> %v sample %d", from, to, i)
+			}
+		}
+	}
+}
+
+func TestKnownEncodings(t *testing.T) {
+	assert := require.New(t)
+
+	data, _ := Encode(S16LE, []float32{1, -1, 0})
+	assert.Equal([]byte{0xFF, 0x7F, 0x01, 0x80, 0x00, 0x00}, data)
+
+	data, _ = Encode(S24BE, []float32{-1})
+	assert.Equal([]byte{0x80, 0x00, 0x01}, data)
+
+	data, _ = Encode(U8, []float32{0, 2})
+	assert.Equal([]byte{0x80, 0xFF}, data)
+
+	// clipping and silence in the companded formats
+	data, _ = Encode(MuLaw, []float32{0})
+	assert.Equal([]byte{0xFF}, data)
+
+	data, _ = Encode(ALaw, []float32{0})
+	assert.Equal([]byte{0xD5}, data)
+
+	out, _ := Decode(S24_32LE, []byte{0xFF, 0xFF, 0xFF, 0x00})
+	assert.InDelta(-1.0/8388608, out[0], 1e-9)
+}
+
+func TestG711CodesRoundTrip(t *testing.T) {
+	assert := require.New(t)
+
+	for i := 0; i < 256; i++ {
+		code := byte(i)
+
+		assert.Equal(code, linearToALaw(aLawToLinear(code)), "a-law code %#x", code)
+
+		if linear := muLawToLinear(code); linear != 0 {
+			assert.Equal(code, linearToMuLaw(linear), "mu-law code %#x", code)
+		}
+	}
+}
+
+func TestDecodeRejectsPartialSamples(t *testing.T) {
+	_, err := Decode(S16LE, []byte{0x00, 0x01, 0x02})
+	require.Error(t, err)
+
+	_, err = Encode(Invalid, testSamples)
+	require.Error(t, err)
+}
+
+func TestReader(t *testing.T) {
+	assert := require.New(t)
+	input, _ := Encode(Float32LE, testSamples)
+
+	// one byte at a time forces partial samples to be carried between reads
+	reader := NewReader(iotest.OneByteReader(bytes.NewReader(input)), Float32LE, S16BE)
+	output, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+
+	expected, _ := Encode(S16BE, testSamples)
+	assert.Equal(expected, output)
+
+	// a trailing partial sample is dropped
+	reader = NewReader(bytes.NewReader(append(input, 0x01)), Float32LE, S32LE)
+	output, err = ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Len(output, len(testSamples)*4)
+
+	// identical formats pass through untouched
+	reader = NewReader(bytes.NewReader(input), Float32LE, Float32LE)
+	output, err = ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal(input, output)
+}
+
+func TestInt16Helpers(t *testing.T) {
+	assert := require.New(t)
+
+	floats := FromInt16([]int16{0, 16384, -32768})
+	assert.Equal([]float32{0, 0.5, -1}, floats)
+	assert.Equal([]int16{0, 16384, -32767}, ToInt16(floats))
+	assert.Equal([]int16{0}, ToInt16([]float32{float32(math.NaN())}))
+}
@@ -0,0 +1,88 @@
+package pcm
+
+import (
+	"io"
+)
+
+const DefaultReadSize = 4096
+
+// A Reader converts raw audio read from an underlying reader from one sample
+// format to another as it is read.  Partial samples are held until the rest of
+// the sample arrives; a trailing partial sample at the end of the stream is
+// discarded.
+type Reader struct {
+	source  io.Reader
+	from    Format
+	to      Format
+	input   []byte
+	output  []byte
+	samples []float32
+	err     error
+}
+
+func NewReader(source io.Reader, from Format, to Format) *Reader {
+	return &Reader{
+		source: source,
+		from:   from,
+		to:     to,
+	}
+}
+
+func (self *Reader) Read(p []byte) (int, error) {
+	if !self.from.Valid() {
+		return 0, invalidFormatErr(self.from)
+	} else if !self.to.Valid() {
+		return 0, invalidFormatErr(self.to)
+	}
+
+	for len(self.output) == 0 {
+		if self.err != nil {
+			return 0, self.err
+		}
+
+		self.fill(len(p))
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+func (self *Reader) fill(want int) {
+	fromSize := self.from.SampleSize()
+	toSize := self.to.SampleSize()
+	count := want / toSize
+
+	if count <= 0 {
+		count = 1
+	} else if count*fromSize > DefaultReadSize && count > 1 {
+		count = DefaultReadSize / fromSize
+	}
+
+	buf := make([]byte, count*fromSize)
+	n, err := self.source.Read(buf)
+	self.input = append(self.input, buf[:n]...)
+
+	if self.from == self.to {
+		self.output = self.input
+		self.input = nil
+	} else if whole := len(self.input) / fromSize; whole > 0 {
+		if cap(self.samples) < whole {
+			self.samples = make([]float32, whole)
+		}
+
+		samples := self.samples[:whole]
+		DecodeInto(samples, self.from, self.input)
+
+		out := make([]byte, whole*toSize)
+		EncodeInto(out, self.to, samples)
+
+		self.output = out
+		self.input = self.input[whole*fromSize:]
+	}
+
+	if err != nil {
+		self.err = err
+	}
+}
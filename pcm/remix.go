@@ -0,0 +1,111 @@
+package pcm
+
+import (
+	"fmt"
+	"io"
+)
+
+// Remix interleaved input frames through a gain matrix, where matrix[o][i] is
+// the gain applied to input channel i when summing output channel o.  The
+// number of input channels is the length of each matrix row.
+func Remix(matrix [][]float32, input []float32) []float32 {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return nil
+	}
+
+	inChannels := len(matrix[0])
+	frames := len(input) / inChannels
+	out := make([]float32, frames*len(matrix))
+
+	for f := 0; f < frames; f++ {
+		in := input[f*inChannels : (f+1)*inChannels]
+
+		for o, row := range matrix {
+			var sum float32
+
+			for i, gain := range row {
+				sum += gain * in[i]
+			}
+
+			out[f*len(matrix)+o] = sum
+		}
+	}
+
+	return out
+}
+
+// Return an error if the matrix is empty or its rows differ in length.
+func ValidateMatrix(matrix [][]float32) error {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return fmt.Errorf("Remix matrix must have at least one input and one output channel")
+	}
+
+	for o, row := range matrix {
+		if len(row) != len(matrix[0]) {
+			return fmt.Errorf("Remix matrix row %d has %d inputs, expected %d", o, len(row), len(matrix[0]))
+		}
+	}
+
+	return nil
+}
+
+// A RemixingReader reads raw audio from an underlying reader and mixes its
+// channels through a gain matrix.  The sample format is unchanged.
+type RemixingReader struct {
+	source  io.Reader
+	format  Format
+	matrix  [][]float32
+	partial []byte
+	output  []byte
+	err     error
+}
+
+func NewRemixingReader(source io.Reader, format Format, matrix [][]float32) *RemixingReader {
+	return &RemixingReader{
+		source: source,
+		format: format,
+		matrix: matrix,
+	}
+}
+
+func (self *RemixingReader) Read(p []byte) (int, error) {
+	if !self.format.Valid() {
+		return 0, invalidFormatErr(self.format)
+	} else if err := ValidateMatrix(self.matrix); err != nil {
+		return 0, err
+	}
+
+	for len(self.output) == 0 {
+		if self.err != nil {
+			return 0, self.err
+		}
+
+		self.fill()
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+func (self *RemixingReader) fill() {
+	frameSize := self.format.SampleSize() * len(self.matrix[0])
+	buf := make([]byte, (DefaultReadSize/frameSize)*frameSize)
+	n, err := self.source.Read(buf)
+
+	self.partial = append(self.partial, buf[:n]...)
+	whole := (len(self.partial) / frameSize) * frameSize
+
+	samples := make([]float32, whole/self.format.SampleSize())
+	DecodeInto(samples, self.format, self.partial[:whole])
+	self.partial = self.partial[whole:]
+
+	out := Remix(self.matrix, samples)
+	self.output = make([]byte, len(out)*self.format.SampleSize())
+	EncodeInto(self.output, self.format, out)
+
+	if err != nil {
+		self.err = err
+	}
+}
@@ -0,0 +1,42 @@
+package pcm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemix(t *testing.T) {
+	assert := require.New(t)
+
+	stereoToMono := [][]float32{{0.5, 0.5}}
+	monoToStereo := [][]float32{{1}, {1}}
+
+	assert.Equal([]float32{0.5, 0}, Remix(stereoToMono, []float32{1, 0, 0.5, -0.5}))
+	assert.Equal([]float32{0.25, 0.25, -1, -1}, Remix(monoToStereo, []float32{0.25, -1}))
+	assert.Nil(Remix(nil, []float32{1}))
+
+	assert.NoError(ValidateMatrix(stereoToMono))
+	assert.Error(ValidateMatrix(nil))
+	assert.Error(ValidateMatrix([][]float32{{1, 0}, {1}}))
+}
+
+func TestRemixingReader(t *testing.T) {
+	assert := require.New(t)
+
+	data, err := Encode(S16LE, []float32{0.5, 0.25, -0.5, -0.25, 0, 1})
+	assert.NoError(err)
+
+	out, err := ioutil.ReadAll(NewRemixingReader(iotest.OneByteReader(bytes.NewReader(data)), S16LE, [][]float32{{1, 0}}))
+	assert.NoError(err)
+
+	samples, err := Decode(S16LE, out)
+	assert.NoError(err)
+	assert.InDeltaSlice([]float32{0.5, -0.5, 0}, samples, 1e-4)
+
+	_, err = ioutil.ReadAll(NewRemixingReader(bytes.NewReader(data), S16LE, nil))
+	assert.Error(err)
+}
@@ -0,0 +1,235 @@
+package pcm
+
+import (
+	"math"
+)
+
+// A Quality selects the interpolation method used by a Resampler.
+type Quality int
+
+const (
+	Linear   Quality = iota // Linear interpolation between adjacent frames; cheapest, with audible aliasing.
+	SincFast                // Blackman-windowed sinc with 8 zero crossings.
+	Sinc                    // Blackman-windowed sinc with 32 zero crossings.
+)
+
+const sincTableResolution = 512
+
+func (self Quality) String() string {
+	switch self {
+	case Linear:
+		return `linear`
+	case SincFast:
+		return `sinc-fast`
+	case Sinc:
+		return `sinc`
+	default:
+		return `invalid`
+	}
+}
+
+func (self Quality) zeroCrossings() int {
+	switch self {
+	case SincFast:
+		return 8
+	case Sinc:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// A Resampler converts interleaved float32 audio from one sample rate to
+// another.  Output depends only on the input samples, not on how they are
+// split across calls to Process, so results are fully deterministic.
+type Resampler struct {
+	Channels   int
+	InputRate  int
+	OutputRate int
+	Quality    Quality
+	buffer     []float32
+	base       int64
+	position   int64
+	phase      int64
+	received   int64
+	halfWidth  int64
+	cutoff     float64
+	table      []float64
+}
+
+func NewResampler(channels int, inputRate int, outputRate int, quality Quality) *Resampler {
+	rv := &Resampler{
+		Channels:   channels,
+		InputRate:  inputRate,
+		OutputRate: outputRate,
+		Quality:    quality,
+		halfWidth:  1,
+		cutoff:     1,
+	}
+
+	if crossings := quality.zeroCrossings(); crossings > 0 {
+		// when downsampling, lower the cutoff to the output Nyquist frequency
+		if outputRate < inputRate {
+			rv.cutoff = float64(outputRate) / float64(inputRate)
+		}
+
+		rv.halfWidth = int64(math.Ceil(float64(crossings) / rv.cutoff))
+		rv.table = windowedSincTable(crossings)
+	}
+
+	return rv
+}
+
+// compute one side of a Blackman-windowed sinc kernel spanning the given number
+// of zero crossings
+func windowedSincTable(crossings int) []float64 {
+	table := make([]float64, crossings*sincTableResolution+2)
+
+	for i := range table {
+		x := float64(i) / sincTableResolution
+
+		if x >= float64(crossings) {
+			continue
+		}
+
+		sinc := 1.0
+
+		if x > 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+
+		w := x / float64(crossings)
+		window := 0.42 + 0.5*math.Cos(math.Pi*w) + 0.08*math.Cos(2*math.Pi*w)
+		table[i] = sinc * window
+	}
+
+	return table
+}
+
+func (self *Resampler) kernel(x float64) float64 {
+	x = math.Abs(x) * sincTableResolution
+	i := int(x)
+
+	if i+1 >= len(self.table) {
+		return 0
+	}
+
+	f := x - float64(i)
+	return self.table[i]*(1-f) + self.table[i+1]*f
+}
+
+// Add interleaved input samples (which must be whole frames) and return all
+// output frames that can be computed so far.
+func (self *Resampler) Process(input []float32) []float32 {
+	if self.InputRate == self.OutputRate {
+		out := make([]float32, len(input))
+		copy(out, input)
+		return out
+	}
+
+	self.buffer = append(self.buffer, input...)
+	self.received += int64(len(input) / self.Channels)
+
+	return self.generate(false)
+}
+
+// Return the remaining output frames once all input has been processed.
+func (self *Resampler) Flush() []float32 {
+	if self.InputRate == self.OutputRate {
+		return nil
+	}
+
+	return self.generate(true)
+}
+
+func (self *Resampler) sample(frame int64, channel int) float32 {
+	if frame < 0 || frame >= self.received {
+		return 0
+	}
+
+	return self.buffer[int(frame-self.base)*self.Channels+channel]
+}
+
+func (self *Resampler) generate(final bool) []float32 {
+	out := make([]float32, 0)
+
+	for {
+		if final {
+			if self.position >= self.received {
+				break
+			}
+		} else if self.position+self.halfWidth >= self.received {
+			break
+		}
+
+		fraction := float64(self.phase) / float64(self.OutputRate)
+
+		if self.table == nil {
+			for c := 0; c < self.Channels; c++ {
+				a := float64(self.sample(self.position, c))
+				b := float64(self.sample(self.position+1, c))
+				out = append(out, float32(a+(b-a)*fraction))
+			}
+		} else {
+			out = self.convolve(out, fraction)
+		}
+
+		// advance by InputRate/OutputRate input frames, tracked exactly as a
+		// whole number of frames plus a remainder
+		self.phase += int64(self.InputRate)
+		self.position += self.phase / int64(self.OutputRate)
+		self.phase %= int64(self.OutputRate)
+	}
+
+	// discard input that is no longer within reach of the kernel
+	if keep := self.position - self.halfWidth + 1; keep > self.base {
+		if drop := int(keep-self.base) * self.Channels; drop < len(self.buffer) {
+			self.buffer = append(self.buffer[:0], self.buffer[drop:]...)
+		} else {
+			self.buffer = self.buffer[:0]
+		}
+
+		self.base = keep
+	}
+
+	return out
+}
+
+func (self *Resampler) convolve(out []float32, fraction float64) []float32 {
+	var sums [64]float64
+	var weights float64
+
+	acc := sums[:0]
+
+	if self.Channels <= len(sums) {
+		acc = sums[:self.Channels]
+	} else {
+		acc = make([]float64, self.Channels)
+	}
+
+	for j := self.position - self.halfWidth + 1; j <= self.position+self.halfWidth; j++ {
+		distance := (float64(self.position-j) + fraction) * self.cutoff
+		weight := self.kernel(distance)
+
+		if weight == 0 {
+			continue
+		}
+
+		weights += weight
+
+		for c := range acc {
+			acc[c] += weight * float64(self.sample(j, c))
+		}
+	}
+
+	for c := range acc {
+		// normalizing by the kernel sum keeps unity gain at every phase
+		if weights != 0 {
+			acc[c] /= weights
+		}
+
+		out = append(out, float32(acc[c]))
+	}
+
+	return out
+}
@@ -0,0 +1,72 @@
+package pcm
+
+import (
+	"io"
+)
+
+// A ResamplingReader reads raw audio in one format and sample rate from an
+// underlying reader and yields it in another.
+type ResamplingReader struct {
+	source    io.Reader
+	from      Format
+	to        Format
+	channels  int
+	resampler *Resampler
+	partial   []byte
+	output    []byte
+	err       error
+}
+
+func NewResamplingReader(source io.Reader, channels int, from Format, inputRate int, to Format, outputRate int, quality Quality) *ResamplingReader {
+	return &ResamplingReader{
+		source:    source,
+		from:      from,
+		to:        to,
+		channels:  channels,
+		resampler: NewResampler(channels, inputRate, outputRate, quality),
+	}
+}
+
+func (self *ResamplingReader) Read(p []byte) (int, error) {
+	if !self.from.Valid() {
+		return 0, invalidFormatErr(self.from)
+	} else if !self.to.Valid() {
+		return 0, invalidFormatErr(self.to)
+	}
+
+	for len(self.output) == 0 {
+		if self.err != nil {
+			return 0, self.err
+		}
+
+		self.fill()
+	}
+
+	n := copy(p, self.output)
+	self.output = self.output[n:]
+
+	return n, nil
+}
+
+func (self *ResamplingReader) fill() {
+	frameSize := self.from.SampleSize() * self.channels
+	buf := make([]byte, (DefaultReadSize/frameSize)*frameSize)
+	n, err := self.source.Read(buf)
+
+	self.partial = append(self.partial, buf[:n]...)
+	whole := (len(self.partial) / frameSize) * frameSize
+
+	samples := make([]float32, whole/self.from.SampleSize())
+	DecodeInto(samples, self.from, self.partial[:whole])
+	self.partial = self.partial[whole:]
+
+	out := self.resampler.Process(samples)
+
+	if err != nil {
+		out = append(out, self.resampler.Flush()...)
+		self.err = err
+	}
+
+	self.output = make([]byte, len(out)*self.to.SampleSize())
+	EncodeInto(self.output, self.to, out)
+}
@@ -0,0 +1,133 @@
+package pcm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sineFrames(rate int, frequency float64, frames int, channels int) []float32 {
+	out := make([]float32, frames*channels)
+
+	for i := 0; i < frames; i++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*frequency*float64(i)/float64(rate)))
+
+		for c := 0; c < channels; c++ {
+			out[i*channels+c] = v
+		}
+	}
+
+	return out
+}
+
+func resampleAll(r *Resampler, input []float32, chunk int) []float32 {
+	out := make([]float32, 0)
+
+	for len(input) > 0 {
+		n := chunk
+
+		if n > len(input) {
+			n = len(input)
+		}
+
+		out = append(out, r.Process(input[:n])...)
+		input = input[n:]
+	}
+
+	return append(out, r.Flush()...)
+}
+
+func TestResamplerLength(t *testing.T) {
+	assert := require.New(t)
+
+	for _, quality := range []Quality{Linear, SincFast, Sinc} {
+		for _, rates := range [][2]int{{44100, 48000}, {48000, 44100}, {8000, 48000}, {48000, 8000}, {22050, 22050}} {
+			input := sineFrames(rates[0], 440, 1000, 2)
+			out := resampleAll(NewResampler(2, rates[0], rates[1], quality), input, 2*128)
+			expected := int(math.Ceil(1000 * float64(rates[1]) / float64(rates[0])))
+
+			assert.Len(out, expected*2, "%v %v", quality, rates)
+		}
+	}
+}
+
+func TestResamplerChunkingIsDeterministic(t *testing.T) {
+	assert := require.New(t)
+
+	for _, quality := range []Quality{Linear, SincFast, Sinc} {
+		input := sineFrames(44100, 1000, 4410, 2)
+		whole := resampleAll(NewResampler(2, 44100, 48000, quality), input, len(input))
+
+		for _, chunk := range []int{2, 14, 1024} {
+			assert.Equal(whole, resampleAll(NewResampler(2, 44100, 48000, quality), input, chunk), "%v chunk %d", quality, chunk)
+		}
+	}
+}
+
+func TestResamplerPreservesSignal(t *testing.T) {
+	assert := require.New(t)
+
+	for _, quality := range []Quality{Linear, SincFast, Sinc} {
+		for _, rates := range [][2]int{{44100, 48000}, {48000, 32000}} {
+			out := resampleAll(NewResampler(1, rates[0], rates[1], quality), sineFrames(rates[0], 440, rates[0]/10, 1), 512)
+			expected := sineFrames(rates[1], 440, len(out), 1)
+
+			// compare away from the edges, where the kernel runs past the input
+			var errorSum float64
+			margin := 64
+
+			for i := margin; i < len(out)-margin; i++ {
+				errorSum += math.Abs(float64(out[i] - expected[i]))
+			}
+
+			mean := errorSum / float64(len(out)-2*margin)
+
+			if quality == Linear {
+				assert.True(mean < 5e-3, "%v %v: mean error %v", quality, rates, mean)
+			} else {
+				assert.True(mean < 5e-4, "%v %v: mean error %v", quality, rates, mean)
+			}
+		}
+	}
+}
+
+func TestResamplerDownsamplingFiltersAliases(t *testing.T) {
+	assert := require.New(t)
+
+	// a 20kHz tone is above the Nyquist frequency of a 16kHz output
+	out := resampleAll(NewResampler(1, 48000, 16000, Sinc), sineFrames(48000, 20000, 4800, 1), 4800)
+
+	var peak float64
+
+	for _, v := range out[64 : len(out)-64] {
+		peak = math.Max(peak, math.Abs(float64(v)))
+	}
+
+	assert.True(peak < 0.01, "peak %v", peak)
+}
+
+func TestResamplingReader(t *testing.T) {
+	assert := require.New(t)
+
+	input := sineFrames(44100, 440, 4410, 2)
+	data, err := Encode(S16LE, input)
+	assert.NoError(err)
+
+	expected, err := Encode(Float32LE, resampleAll(NewResampler(2, 44100, 48000, SincFast), mustDecode(t, S16LE, data), 1<<20))
+	assert.NoError(err)
+
+	out, err := ioutil.ReadAll(NewResamplingReader(iotest.OneByteReader(bytes.NewReader(data)), 2, S16LE, 44100, Float32LE, 48000, SincFast))
+	assert.NoError(err)
+	assert.Equal(expected, out)
+}
+
+func mustDecode(t *testing.T, format Format, data []byte) []float32 {
+	samples, err := Decode(format, data)
+	require.NoError(t, err)
+
+	return samples
+}
@@ -0,0 +1,357 @@
+package pulse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Profile is a saved audio layout (e.g.: "meeting mode", "music mode") that
+// can be captured from the server and applied to it again later.  Devices are
+// matched by name and applications by their application.name property, so a
+// profile remains valid across restarts even though object indices change.
+//
+// Every setting is optional: a profile that only names a default sink changes
+// nothing else.
+type Profile struct {
+	Name          string               `json:"name,omitempty"           yaml:"name,omitempty"`
+	DefaultSink   string               `json:"default_sink,omitempty"   yaml:"default_sink,omitempty"`
+	DefaultSource string               `json:"default_source,omitempty" yaml:"default_source,omitempty"`
+	Sinks         []DeviceProfile      `json:"sinks,omitempty"          yaml:"sinks,omitempty"`
+	Sources       []DeviceProfile      `json:"sources,omitempty"        yaml:"sources,omitempty"`
+	Applications  []ApplicationProfile `json:"applications,omitempty"   yaml:"applications,omitempty"`
+}
+
+// The settings for a single sink or source, identified by name.  Volume is a
+// factor of the normal volume (1.0 is 100%).
+type DeviceProfile struct {
+	Name   string   `json:"name"             yaml:"name"`
+	Volume *float64 `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Muted  *bool    `json:"muted,omitempty"  yaml:"muted,omitempty"`
+	Port   string   `json:"port,omitempty"   yaml:"port,omitempty"`
+}
+
+// The settings for every stream belonging to an application, identified by its
+// application.name property.
+type ApplicationProfile struct {
+	Name   string   `json:"name"             yaml:"name"`
+	Volume *float64 `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Muted  *bool    `json:"muted,omitempty"  yaml:"muted,omitempty"`
+}
+
+// A ProfileFailure describes a single profile entry that could not be applied.
+type ProfileFailure struct {
+	Entry  string
+	Reason string
+}
+
+func (self ProfileFailure) String() string {
+	return fmt.Sprintf("%s: %s", self.Entry, self.Reason)
+}
+
+// A ProfileErr is returned by ApplyProfile when one or more entries could not be
+// applied.  All other entries were still applied.
+type ProfileErr struct {
+	Profile  string
+	Failures []ProfileFailure
+}
+
+func (self ProfileErr) Error() string {
+	failures := make([]string, len(self.Failures))
+
+	for i, failure := range self.Failures {
+		failures[i] = failure.String()
+	}
+
+	if self.Profile != `` {
+		return fmt.Sprintf("Failed to apply %d entries of profile %q: %s", len(failures), self.Profile, strings.Join(failures, `; `))
+	}
+
+	return fmt.Sprintf("Failed to apply %d profile entries: %s", len(failures), strings.Join(failures, `; `))
+}
+
+func IsProfileErr(err error) bool {
+	switch err.(type) {
+	case ProfileErr, *ProfileErr:
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse a profile from JSON or YAML.
+func ParseProfile(data []byte) (*Profile, error) {
+	profile := &Profile{}
+
+	// YAML is a superset of JSON, so both formats are handled by the same decoder
+	if err := yaml.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("Invalid profile: %v", err)
+	}
+
+	return profile, nil
+}
+
+// Load a profile from a JSON or YAML file.
+func LoadProfile(filename string) (*Profile, error) {
+	if data, err := ioutil.ReadFile(filename); err == nil {
+		return ParseProfile(data)
+	} else {
+		return nil, err
+	}
+}
+
+// Save the profile to a file.  Files ending in ".json" are written as JSON, all
+// others as YAML.
+func (self *Profile) Save(filename string) error {
+	var data []byte
+	var err error
+
+	if strings.ToLower(filepath.Ext(filename)) == `.json` {
+		data, err = json.MarshalIndent(self, ``, `  `)
+	} else {
+		data, err = yaml.Marshal(self)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// Capture the current defaults, device settings, and application volumes as a
+// Profile.  Monitor sources are not included.  If several streams belong to the
+// same application, the first one's settings are used.
+func (self *Conn) CaptureProfile() (*Profile, error) {
+	if state, err := self.Snapshot(); err == nil {
+		return newProfileFromState(state), nil
+	} else {
+		return nil, err
+	}
+}
+
+func newProfileFromState(state *State) *Profile {
+	profile := &Profile{
+		DefaultSink:   state.Server.DefaultSinkName,
+		DefaultSource: state.Server.DefaultSourceName,
+	}
+
+	for _, sink := range state.Sinks {
+		profile.Sinks = append(profile.Sinks, DeviceProfile{
+			Name:   sink.Name,
+			Volume: profileFloat(sink.VolumeFactor),
+			Muted:  profileBool(sink.Muted),
+			Port:   sink.ActivePort,
+		})
+	}
+
+	for _, source := range state.Sources {
+		if source.MonitorOfSinkName != `` {
+			continue
+		}
+
+		profile.Sources = append(profile.Sources, DeviceProfile{
+			Name:   source.Name,
+			Volume: profileFloat(source.VolumeFactor),
+			Muted:  profileBool(source.Muted),
+			Port:   source.ActivePort,
+		})
+	}
+
+	seen := make(map[string]bool)
+
+	for _, sinkInput := range state.SinkInputs {
+		if name := sinkInput.PropList.ApplicationName(); name != `` && !seen[name] {
+			seen[name] = true
+
+			profile.Applications = append(profile.Applications, ApplicationProfile{
+				Name:   name,
+				Volume: profileFloat(sinkInput.VolumeFactor()),
+				Muted:  profileBool(sinkInput.Muted),
+			})
+		}
+	}
+
+	return profile
+}
+
+// Apply a profile to the server.  Every entry is attempted; if any could not be
+// applied (e.g.: a device is not present, or an application is not running) a
+// ProfileErr listing them is returned.
+func (self *Conn) ApplyProfile(profile *Profile) error {
+	if state, err := self.Snapshot(); err == nil {
+		actions, failures := profile.plan(self, state)
+
+		for _, action := range actions {
+			if err := action.apply(); err != nil {
+				failures = append(failures, ProfileFailure{
+					Entry:  action.Entry,
+					Reason: err.Error(),
+				})
+			}
+		}
+
+		if len(failures) > 0 {
+			return ProfileErr{
+				Profile:  profile.Name,
+				Failures: failures,
+			}
+		}
+
+		return nil
+	} else {
+		return err
+	}
+}
+
+type profileAction struct {
+	Entry       string
+	Description string
+	apply       func() error
+}
+
+// work out what needs to be done to apply this profile to the given state,
+// returning the actions to take and any entries that cannot be applied at all
+func (self *Profile) plan(server Server, state *State) ([]profileAction, []ProfileFailure) {
+	actions := make([]profileAction, 0)
+	failures := make([]ProfileFailure, 0)
+
+	sinks := make(map[string]*Sink)
+	sources := make(map[string]*Source)
+
+	for _, sink := range state.Sinks {
+		sinks[sink.Name] = sink
+	}
+
+	for _, source := range state.Sources {
+		sources[source.Name] = source
+	}
+
+	if name := self.DefaultSink; name != `` {
+		entry := fmt.Sprintf("default sink %q", name)
+
+		if _, ok := sinks[name]; ok {
+			actions = append(actions, profileAction{entry, `set default`, func() error {
+				return server.SetDefaultSink(name)
+			}})
+		} else {
+			failures = append(failures, ProfileFailure{entry, `no such sink`})
+		}
+	}
+
+	if name := self.DefaultSource; name != `` {
+		entry := fmt.Sprintf("default source %q", name)
+
+		if _, ok := sources[name]; ok {
+			actions = append(actions, profileAction{entry, `set default`, func() error {
+				return server.SetDefaultSource(name)
+			}})
+		} else {
+			failures = append(failures, ProfileFailure{entry, `no such source`})
+		}
+	}
+
+	for _, device := range self.Sinks {
+		device := device
+		entry := fmt.Sprintf("sink %q", device.Name)
+
+		if sink, ok := sinks[device.Name]; ok {
+			if device.Port != `` {
+				actions = append(actions, profileAction{entry, `set port ` + device.Port, func() error {
+					return server.SetSinkPort(sink.Index, device.Port)
+				}})
+			}
+
+			if device.Volume != nil {
+				actions = append(actions, profileAction{entry, fmt.Sprintf("set volume %g", *device.Volume), func() error {
+					return server.SetSinkVolume(sink.Index, *device.Volume)
+				}})
+			}
+
+			if device.Muted != nil {
+				actions = append(actions, profileAction{entry, fmt.Sprintf("set muted %v", *device.Muted), func() error {
+					return server.SetSinkMute(sink.Index, *device.Muted)
+				}})
+			}
+		} else {
+			failures = append(failures, ProfileFailure{entry, `no such sink`})
+		}
+	}
+
+	for _, device := range self.Sources {
+		device := device
+		entry := fmt.Sprintf("source %q", device.Name)
+
+		if source, ok := sources[device.Name]; ok {
+			if device.Port != `` {
+				actions = append(actions, profileAction{entry, `set port ` + device.Port, func() error {
+					return server.SetSourcePort(source.Index, device.Port)
+				}})
+			}
+
+			if device.Volume != nil {
+				actions = append(actions, profileAction{entry, fmt.Sprintf("set volume %g", *device.Volume), func() error {
+					return server.SetSourceVolume(source.Index, *device.Volume)
+				}})
+			}
+
+			if device.Muted != nil {
+				actions = append(actions, profileAction{entry, fmt.Sprintf("set muted %v", *device.Muted), func() error {
+					return server.SetSourceMute(source.Index, *device.Muted)
+				}})
+			}
+		} else {
+			failures = append(failures, ProfileFailure{entry, `no such source`})
+		}
+	}
+
+	for _, application := range self.Applications {
+		application := application
+		entry := fmt.Sprintf("application %q", application.Name)
+		found := false
+
+		for i := range state.SinkInputs {
+			sinkInput := &state.SinkInputs[i]
+
+			if sinkInput.PropList.ApplicationName() != application.Name {
+				continue
+			}
+
+			found = true
+			streamEntry := fmt.Sprintf("%s (sink input %d)", entry, sinkInput.Index)
+
+			if application.Volume != nil {
+				actions = append(actions, profileAction{streamEntry, fmt.Sprintf("set volume %g", *application.Volume), func() error {
+					return server.SetSinkInputVolume(sinkInput.Index, *application.Volume)
+				}})
+			}
+
+			if application.Muted != nil {
+				actions = append(actions, profileAction{streamEntry, fmt.Sprintf("set muted %v", *application.Muted), func() error {
+					return server.SetSinkInputMute(sinkInput.Index, *application.Muted)
+				}})
+			}
+		}
+
+		if !found {
+			failures = append(failures, ProfileFailure{entry, `no streams from this application`})
+		}
+	}
+
+	return actions, failures
+}
+
+func profileFloat(value float64) *float64 {
+	// captured volumes are rounded so saved profiles stay readable
+	value = math.Round(value*1000) / 1000
+	return &value
+}
+
+func profileBool(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,137 @@
+package pulse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileParse(t *testing.T) {
+	assert := require.New(t)
+
+	profile, err := ParseProfile([]byte(`
+name: meeting
+default_sink: headset
+sinks:
+  - name: headset
+    volume: 0.8
+    port: analog-output-headphones
+  - name: speakers
+    muted: true
+applications:
+  - name: Firefox
+    volume: 0.25
+`))
+
+	assert.NoError(err)
+	assert.Equal(`meeting`, profile.Name)
+	assert.Equal(`headset`, profile.DefaultSink)
+	assert.Len(profile.Sinks, 2)
+	assert.Equal(0.8, *profile.Sinks[0].Volume)
+	assert.Nil(profile.Sinks[0].Muted)
+	assert.Equal(`analog-output-headphones`, profile.Sinks[0].Port)
+	assert.True(*profile.Sinks[1].Muted)
+	assert.Nil(profile.Sinks[1].Volume)
+	assert.Equal(0.25, *profile.Applications[0].Volume)
+
+	profile, err = ParseProfile([]byte(`{"name": "music", "default_source": "mic", "sources": [{"name": "mic", "muted": false}]}`))
+	assert.NoError(err)
+	assert.Equal(`music`, profile.Name)
+	assert.Equal(`mic`, profile.DefaultSource)
+	assert.False(*profile.Sources[0].Muted)
+
+	_, err = ParseProfile([]byte(`sinks: nope`))
+	assert.Error(err)
+}
+
+func TestProfileSaveLoad(t *testing.T) {
+	assert := require.New(t)
+
+	dir, err := ioutil.TempDir(``, `pulse-profile`)
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	profile := newProfileFromState(testState())
+
+	for _, name := range []string{`profile.json`, `profile.yaml`} {
+		filename := filepath.Join(dir, name)
+		assert.NoError(profile.Save(filename))
+
+		loaded, err := LoadProfile(filename)
+		assert.NoError(err, name)
+		assert.Equal(profile, loaded, name)
+	}
+}
+
+func TestProfileCapture(t *testing.T) {
+	assert := require.New(t)
+
+	state := testState()
+	state.Sources = []*Source{
+		{Index: 0, Name: `speakers.monitor`, MonitorOfSinkName: `speakers`},
+		{Index: 1, Name: `mic`, VolumeFactor: 0.75, ActivePort: `analog-input-mic`},
+	}
+	state.SinkInputs[0].PropList = PropList{PropApplicationName: `Firefox`}
+	state.SinkInputs[0].Volume = Volume{Name: `mean`, Value: DefaultVolumeStep / 2}
+
+	profile := newProfileFromState(state)
+	assert.Equal(`speakers`, profile.DefaultSink)
+	assert.Len(profile.Sinks, 2)
+	assert.Equal(0.5, *profile.Sinks[0].Volume)
+	assert.False(*profile.Sinks[0].Muted)
+	assert.Len(profile.Sources, 1)
+	assert.Equal(`mic`, profile.Sources[0].Name)
+	assert.Equal(`analog-input-mic`, profile.Sources[0].Port)
+	assert.Len(profile.Applications, 1)
+	assert.Equal(`Firefox`, profile.Applications[0].Name)
+	assert.Equal(0.5, *profile.Applications[0].Volume)
+}
+
+func TestProfilePlan(t *testing.T) {
+	assert := require.New(t)
+
+	state := testState()
+	state.SinkInputs[0].PropList = PropList{PropApplicationName: `Firefox`}
+
+	profile := &Profile{
+		DefaultSink:   `headset`,
+		DefaultSource: `mic`,
+		Sinks: []DeviceProfile{
+			{Name: `headset`, Volume: profileFloat(0.8), Port: `analog-output-headphones`},
+			{Name: `hdmi`, Muted: profileBool(true)},
+		},
+		Applications: []ApplicationProfile{
+			{Name: `Firefox`, Muted: profileBool(false)},
+			{Name: `Zoom`, Volume: profileFloat(1)},
+		},
+	}
+
+	actions, failures := profile.plan(nil, state)
+
+	planned := make([]string, len(actions))
+
+	for i, action := range actions {
+		planned[i] = action.Entry + `: ` + action.Description
+	}
+
+	assert.Equal([]string{
+		`default sink "headset": set default`,
+		`sink "headset": set port analog-output-headphones`,
+		`sink "headset": set volume 0.8`,
+		`application "Firefox" (sink input 7): set muted false`,
+	}, planned)
+
+	assert.Equal([]ProfileFailure{
+		{`default source "mic"`, `no such source`},
+		{`sink "hdmi"`, `no such sink`},
+		{`application "Zoom"`, `no streams from this application`},
+	}, failures)
+
+	err := ProfileErr{Profile: `meeting`, Failures: failures}
+	assert.True(IsProfileErr(err))
+	assert.True(IsProfileErr(&err))
+	assert.Contains(err.Error(), `sink "hdmi": no such sink`)
+}
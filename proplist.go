@@ -0,0 +1,272 @@
+package pulse
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Well-known property keys, as defined by PulseAudio's PA_PROP_* constants.
+const (
+	PropMediaName                   = `media.name`
+	PropMediaTitle                  = `media.title`
+	PropMediaArtist                 = `media.artist`
+	PropMediaCopyright              = `media.copyright`
+	PropMediaSoftware               = `media.software`
+	PropMediaLanguage               = `media.language`
+	PropMediaFilename               = `media.filename`
+	PropMediaIconName               = `media.icon_name`
+	PropMediaRole                   = `media.role`
+	PropFilterWant                  = `filter.want`
+	PropFilterApply                 = `filter.apply`
+	PropFilterSuppress              = `filter.suppress`
+	PropEventID                     = `event.id`
+	PropEventDescription            = `event.description`
+	PropWindowName                  = `window.name`
+	PropWindowID                    = `window.id`
+	PropWindowIconName              = `window.icon_name`
+	PropWindowDesktop               = `window.desktop`
+	PropWindowX11Display            = `window.x11.display`
+	PropWindowX11Screen             = `window.x11.screen`
+	PropWindowX11Monitor            = `window.x11.monitor`
+	PropWindowX11XID                = `window.x11.xid`
+	PropApplicationName             = `application.name`
+	PropApplicationID               = `application.id`
+	PropApplicationVersion          = `application.version`
+	PropApplicationIconName         = `application.icon_name`
+	PropApplicationLanguage         = `application.language`
+	PropApplicationProcessID        = `application.process.id`
+	PropApplicationProcessBinary    = `application.process.binary`
+	PropApplicationProcessUser      = `application.process.user`
+	PropApplicationProcessHost      = `application.process.host`
+	PropApplicationProcessMachineID = `application.process.machine_id`
+	PropApplicationProcessSessionID = `application.process.session_id`
+	PropDeviceString                = `device.string`
+	PropDeviceAPI                   = `device.api`
+	PropDeviceDescription           = `device.description`
+	PropDeviceBusPath               = `device.bus_path`
+	PropDeviceSerial                = `device.serial`
+	PropDeviceVendorID              = `device.vendor.id`
+	PropDeviceVendorName            = `device.vendor.name`
+	PropDeviceProductID             = `device.product.id`
+	PropDeviceProductName           = `device.product.name`
+	PropDeviceClass                 = `device.class`
+	PropDeviceFormFactor            = `device.form_factor`
+	PropDeviceBus                   = `device.bus`
+	PropDeviceIconName              = `device.icon_name`
+	PropDeviceAccessMode            = `device.access_mode`
+	PropDeviceMasterDevice          = `device.master_device`
+	PropDeviceBufferingBufferSize   = `device.buffering.buffer_size`
+	PropDeviceBufferingFragmentSize = `device.buffering.fragment_size`
+	PropDeviceProfileName           = `device.profile.name`
+	PropDeviceProfileDescription    = `device.profile.description`
+	PropDeviceIntendedRoles         = `device.intended_roles`
+	PropModuleAuthor                = `module.author`
+	PropModuleDescription           = `module.description`
+	PropModuleUsage                 = `module.usage`
+	PropModuleVersion               = `module.version`
+	PropFormatSampleFormat          = `format.sample_format`
+	PropFormatRate                  = `format.rate`
+	PropFormatChannels              = `format.channels`
+	PropFormatChannelMap            = `format.channel_map`
+)
+
+// A PropList holds an object's PulseAudio property list exactly as the server
+// reported it.  Unlike the Properties map, values are never converted, so
+// version strings, IDs with leading zeros, and the like are preserved.
+type PropList map[string]string
+
+// Return the value of the given property, or an empty string if it is not set.
+func (self PropList) Get(key string) string {
+	return self[key]
+}
+
+// Return the value of the given property and whether it is set.
+func (self PropList) Lookup(key string) (string, bool) {
+	value, ok := self[key]
+	return value, ok
+}
+
+// Return whether the given property is set.
+func (self PropList) Has(key string) bool {
+	_, ok := self[key]
+	return ok
+}
+
+// Set the value of a property, allocating the PropList if necessary.
+func (self *PropList) Set(key string, value string) {
+	if *self == nil {
+		*self = make(PropList)
+	}
+
+	(*self)[key] = value
+}
+
+// Return the value of the given property parsed as an integer.
+func (self PropList) Int(key string) (int64, bool) {
+	if value, ok := self[key]; ok {
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			return n, true
+		}
+	}
+
+	return 0, false
+}
+
+// Return the names of all properties, sorted.
+func (self PropList) Keys() []string {
+	keys := make([]string, 0, len(self))
+
+	for key := range self {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Return a copy of the property list.
+func (self PropList) Copy() PropList {
+	if self == nil {
+		return nil
+	}
+
+	out := make(PropList, len(self))
+
+	for key, value := range self {
+		out[key] = value
+	}
+
+	return out
+}
+
+func (self PropList) ApplicationName() string {
+	return self[PropApplicationName]
+}
+
+func (self PropList) ApplicationID() string {
+	return self[PropApplicationID]
+}
+
+func (self PropList) ApplicationVersion() string {
+	return self[PropApplicationVersion]
+}
+
+func (self PropList) ApplicationIconName() string {
+	return self[PropApplicationIconName]
+}
+
+// Return the process ID of the client application, if known.
+func (self PropList) ProcessID() (int, bool) {
+	n, ok := self.Int(PropApplicationProcessID)
+	return int(n), ok
+}
+
+func (self PropList) ProcessBinary() string {
+	return self[PropApplicationProcessBinary]
+}
+
+func (self PropList) ProcessUser() string {
+	return self[PropApplicationProcessUser]
+}
+
+func (self PropList) ProcessHost() string {
+	return self[PropApplicationProcessHost]
+}
+
+func (self PropList) MediaName() string {
+	return self[PropMediaName]
+}
+
+func (self PropList) MediaTitle() string {
+	return self[PropMediaTitle]
+}
+
+func (self PropList) MediaArtist() string {
+	return self[PropMediaArtist]
+}
+
+// Return the role of a stream (e.g.: "music", "phone", "event").
+func (self PropList) MediaRole() string {
+	return self[PropMediaRole]
+}
+
+func (self PropList) WindowName() string {
+	return self[PropWindowName]
+}
+
+func (self PropList) EventID() string {
+	return self[PropEventID]
+}
+
+func (self PropList) DeviceString() string {
+	return self[PropDeviceString]
+}
+
+func (self PropList) DeviceAPI() string {
+	return self[PropDeviceAPI]
+}
+
+func (self PropList) DeviceDescription() string {
+	return self[PropDeviceDescription]
+}
+
+// Return the bus a device is attached to (e.g.: "usb", "pci", "bluetooth").
+func (self PropList) DeviceBus() string {
+	return self[PropDeviceBus]
+}
+
+func (self PropList) DeviceBusPath() string {
+	return self[PropDeviceBusPath]
+}
+
+func (self PropList) DeviceSerial() string {
+	return self[PropDeviceSerial]
+}
+
+func (self PropList) DeviceVendorName() string {
+	return self[PropDeviceVendorName]
+}
+
+func (self PropList) DeviceProductName() string {
+	return self[PropDeviceProductName]
+}
+
+// Return the device class (e.g.: "sound", "modem", "monitor", "filter").
+func (self PropList) DeviceClass() string {
+	return self[PropDeviceClass]
+}
+
+// Return the form factor of a device (e.g.: "headset", "speaker", "internal").
+func (self PropList) DeviceFormFactor() string {
+	return self[PropDeviceFormFactor]
+}
+
+func (self PropList) DeviceIconName() string {
+	return self[PropDeviceIconName]
+}
+
+func (self PropList) DeviceProfileName() string {
+	return self[PropDeviceProfileName]
+}
+
+// Return the roles a device is intended for (e.g.: "phone").
+func (self PropList) DeviceIntendedRoles() []string {
+	return strings.Fields(self[PropDeviceIntendedRoles])
+}
+
+func (self PropList) ModuleAuthor() string {
+	return self[PropModuleAuthor]
+}
+
+func (self PropList) ModuleDescription() string {
+	return self[PropModuleDescription]
+}
+
+func (self PropList) ModuleUsage() string {
+	return self[PropModuleUsage]
+}
+
+func (self PropList) ModuleVersion() string {
+	return self[PropModuleVersion]
+}
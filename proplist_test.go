@@ -0,0 +1,76 @@
+package pulse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPropList(t *testing.T) {
+	assert := require.New(t)
+
+	var props PropList
+
+	assert.Equal(``, props.ApplicationName())
+	assert.False(props.Has(PropMediaRole))
+
+	props.Set(PropApplicationName, `Firefox`)
+	props.Set(PropApplicationVersion, `1.10`)
+	props.Set(PropApplicationProcessID, `4242`)
+	props.Set(PropDeviceSerial, `007`)
+	props.Set(PropMediaRole, `phone`)
+	props.Set(PropDeviceIntendedRoles, `phone music`)
+	props.Set(`custom.key`, `0x10`)
+
+	assert.Equal(`Firefox`, props.ApplicationName())
+	assert.Equal(`1.10`, props.ApplicationVersion())
+	assert.Equal(`007`, props.DeviceSerial())
+	assert.Equal(`phone`, props.MediaRole())
+	assert.Equal([]string{`phone`, `music`}, props.DeviceIntendedRoles())
+
+	pid, ok := props.ProcessID()
+	assert.True(ok)
+	assert.Equal(4242, pid)
+
+	_, ok = props.Int(`custom.key`)
+	assert.False(ok)
+
+	value, ok := props.Lookup(`custom.key`)
+	assert.True(ok)
+	assert.Equal(`0x10`, value)
+
+	assert.Equal([]string{
+		PropApplicationName,
+		PropApplicationProcessID,
+		PropApplicationVersion,
+		`custom.key`,
+		PropDeviceIntendedRoles,
+		PropDeviceSerial,
+		PropMediaRole,
+	}, props.Keys())
+
+	copied := props.Copy()
+	copied.Set(PropMediaRole, `music`)
+	assert.Equal(`phone`, props.MediaRole())
+}
+
+func TestPropListFilter(t *testing.T) {
+	assert := require.New(t)
+
+	sink := &Sink{
+		Name:     `headset`,
+		PropList: PropList{PropDeviceBus: `usb`},
+	}
+
+	assert.True(Filter{`PropList.device.bus/usb`}.IsMatch(sink))
+	assert.False(Filter{`PropList.device.bus/pci`}.IsMatch(sink))
+
+	out := Filter{`PropList/exists`}.Apply(nativeMap(sink))
+	assert.Equal(map[string]interface{}{
+		`PropList`: map[string]interface{}{
+			`device`: map[string]interface{}{
+				`bus`: `usb`,
+			},
+		},
+	}, out)
+}
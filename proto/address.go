@@ -0,0 +1,139 @@
+package proto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// The TCP port of the native protocol.
+	DEFAULT_PORT = 4713
+
+	// The size of the authentication cookie.
+	CookieSize = 256
+
+	// How long to wait for the socket to connect.
+	DEFAULT_DIAL_TIMEOUT = 5 * time.Second
+)
+
+// Return the addresses to try, in order, when no server was given: those in
+// $PULSE_SERVER, or else the per-user and system-wide sockets.
+func DefaultAddresses() []string {
+	if servers := os.Getenv(`PULSE_SERVER`); servers != `` {
+		var addrs []string
+
+		for _, addr := range strings.Fields(servers) {
+			// strip the "{machine-id}" prefix that restricts an entry to one host
+			if strings.HasPrefix(addr, `{`) {
+				if i := strings.Index(addr, `}`); i >= 0 {
+					addr = addr[i+1:]
+				}
+			}
+
+			if addr != `` {
+				addrs = append(addrs, addr)
+			}
+		}
+
+		return addrs
+	}
+
+	var addrs []string
+
+	if dir := os.Getenv(`PULSE_RUNTIME_PATH`); dir != `` {
+		addrs = append(addrs, `unix:`+filepath.Join(dir, `native`))
+	} else if dir := os.Getenv(`XDG_RUNTIME_DIR`); dir != `` {
+		addrs = append(addrs, `unix:`+filepath.Join(dir, `pulse`, `native`))
+	} else {
+		addrs = append(addrs, fmt.Sprintf("unix:/run/user/%d/pulse/native", os.Getuid()))
+	}
+
+	return append(addrs, `unix:/var/run/pulse/native`)
+}
+
+// Parse a server address (e.g.: "unix:/run/pulse/native", "tcp:host:4713", or
+// a bare socket path) into the network and address to dial.
+func ParseAddress(addr string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(addr, `unix:`):
+		return `unix`, strings.TrimPrefix(addr, `unix:`), nil
+
+	case strings.HasPrefix(addr, `tcp:`), strings.HasPrefix(addr, `tcp4:`), strings.HasPrefix(addr, `tcp6:`):
+		parts := strings.SplitN(addr, `:`, 2)
+		host := parts[1]
+
+		if host == `` {
+			host = `localhost`
+		}
+
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, `[]`), fmt.Sprintf("%d", DEFAULT_PORT))
+		}
+
+		return parts[0], host, nil
+
+	case strings.HasPrefix(addr, `/`):
+		return `unix`, addr, nil
+
+	default:
+		return ``, ``, fmt.Errorf("Unsupported server address %q", addr)
+	}
+}
+
+// Connect to the first reachable of the given addresses, or of
+// DefaultAddresses() if none are given.
+func Dial(addrs ...string) (net.Conn, error) {
+	if len(addrs) == 0 {
+		addrs = DefaultAddresses()
+	}
+
+	var lastErr error = ErrConnectionRefused
+
+	for _, addr := range addrs {
+		if network, address, err := ParseAddress(addr); err == nil {
+			if conn, err := net.DialTimeout(network, address, DEFAULT_DIAL_TIMEOUT); err == nil {
+				return conn, nil
+			} else {
+				lastErr = err
+			}
+		} else {
+			lastErr = err
+		}
+	}
+
+	return nil, lastErr
+}
+
+// Load the authentication cookie from $PULSE_COOKIE or the user's PulseAudio
+// configuration.  Servers that don't check cookies (e.g.: those using
+// module-native-protocol-unix's default credential checks) accept anything,
+// so a cookie of zeros is returned if none is found.
+func LoadCookie() []byte {
+	var paths []string
+
+	if path := os.Getenv(`PULSE_COOKIE`); path != `` {
+		paths = append(paths, path)
+	}
+
+	if dir := os.Getenv(`XDG_CONFIG_HOME`); dir != `` {
+		paths = append(paths, filepath.Join(dir, `pulse`, `cookie`))
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, `.config`, `pulse`, `cookie`))
+		paths = append(paths, filepath.Join(home, `.pulse-cookie`))
+	}
+
+	for _, path := range paths {
+		if data, err := ioutil.ReadFile(path); err == nil && len(data) >= CookieSize {
+			return data[:CookieSize]
+		}
+	}
+
+	return make([]byte, CookieSize)
+}
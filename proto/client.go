@@ -0,0 +1,277 @@
+package proto
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// How long the handshake may take before the connection is abandoned.
+	DEFAULT_HANDSHAKE_TIMEOUT = 5 * time.Second
+
+	// The tag of commands sent by the server on its own accord.
+	NoTag uint32 = 0xFFFFFFFF
+)
+
+// A ReplyFunc receives the reply to a request: the TagStruct positioned after
+// the reply's tag, or the error the server (or connection) reported.
+type ReplyFunc func(reply *TagStruct, err error)
+
+// A Client is one end of a native protocol connection.  Requests are matched
+// to their replies by tag; everything else the server sends is passed to the
+// OnCommand and OnData hooks.  All callbacks are called from the goroutine
+// running Run, one at a time.
+type Client struct {
+	// The protocol version agreed with the server during the handshake.
+	Version uint32
+
+	// The protocol version the server announced.
+	ServerVersion uint32
+
+	// The index the server assigned to this client.
+	ClientIndex uint32
+
+	// Called with commands the server sends that are not replies (e.g.:
+	// CommandRequest, CommandSubscribeEvent).
+	OnCommand func(command Command, args *TagStruct)
+
+	// Called with every packet of stream data.
+	OnData func(packet *Packet)
+
+	// Called once the connection is closed, with the reason.
+	OnClose func(err error)
+
+	conn      net.Conn
+	pending   map[uint32]ReplyFunc
+	nextTag   uint32
+	closeErr  error
+	lock      sync.Mutex
+	writeLock sync.Mutex
+}
+
+// Wrap an established connection.  Set any hooks, start Run in its own
+// goroutine, and then call Handshake.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:    conn,
+		pending: make(map[uint32]ReplyFunc),
+	}
+}
+
+// Read and dispatch packets until the connection fails or is closed.  Any
+// requests still waiting for a reply fail with ErrConnectionTerminated.
+func (self *Client) Run() {
+	var err error
+
+	for {
+		var packet *Packet
+
+		if packet, err = ReadPacket(self.conn); err != nil {
+			break
+		}
+
+		if !packet.IsControl() {
+			if self.OnData != nil {
+				self.OnData(packet)
+			}
+
+			continue
+		}
+
+		if err = self.dispatch(packet); err != nil {
+			break
+		}
+	}
+
+	self.shutdown(err)
+}
+
+func (self *Client) dispatch(packet *Packet) error {
+	command, tag, args, err := packet.Command()
+
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case CommandReply, CommandError:
+		self.lock.Lock()
+		reply, ok := self.pending[tag]
+		delete(self.pending, tag)
+		self.lock.Unlock()
+
+		if !ok {
+			return nil
+		}
+
+		if command == CommandError {
+			if code, err := args.GetU32(); err == nil {
+				reply(nil, ErrorCode(code))
+			} else {
+				reply(nil, ErrProtocol)
+			}
+		} else {
+			reply(args, nil)
+		}
+
+	default:
+		if self.OnCommand != nil {
+			self.OnCommand(command, args)
+		}
+	}
+
+	return nil
+}
+
+func (self *Client) shutdown(err error) {
+	self.lock.Lock()
+
+	if self.closeErr != nil {
+		self.lock.Unlock()
+		return
+	}
+
+	if err == nil || err == io.EOF {
+		err = ErrConnectionTerminated
+	}
+
+	self.closeErr = err
+	pending := self.pending
+	self.pending = make(map[uint32]ReplyFunc)
+	self.lock.Unlock()
+
+	self.conn.Close()
+
+	for _, reply := range pending {
+		reply(nil, ErrConnectionTerminated)
+	}
+
+	if self.OnClose != nil {
+		self.OnClose(err)
+	}
+}
+
+// Send a command, calling the given function (if any) with the server's reply.
+// The request's tag is returned so that it can be cancelled.  If the request
+// could not be sent, an error is returned and the function is never called.
+func (self *Client) Request(command Command, args *TagStruct, reply ReplyFunc) (uint32, error) {
+	self.lock.Lock()
+
+	if self.closeErr != nil {
+		self.lock.Unlock()
+		return NoTag, ErrConnectionTerminated
+	}
+
+	tag := self.nextTag
+	self.nextTag++
+
+	if reply != nil {
+		self.pending[tag] = reply
+	}
+
+	self.lock.Unlock()
+
+	if err := self.Send(NewCommandPacket(command, tag, args)); err != nil {
+		// unless the connection's shutdown already claimed the request (and
+		// will report its failure), it is the caller's to handle
+		if reply == nil || self.Cancel(tag) {
+			return NoTag, ErrConnectionTerminated
+		}
+	}
+
+	return tag, nil
+}
+
+// Forget a pending request; its reply (if one arrives) is ignored.  Returns
+// whether the request was still pending.
+func (self *Client) Cancel(tag uint32) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	_, ok := self.pending[tag]
+	delete(self.pending, tag)
+
+	return ok
+}
+
+// Send a request and wait up to the given timeout for its reply.  This must
+// not be called from a callback.
+func (self *Client) Call(command Command, args *TagStruct, timeout time.Duration) (*TagStruct, error) {
+	type result struct {
+		reply *TagStruct
+		err   error
+	}
+
+	done := make(chan result, 1)
+
+	tag, err := self.Request(command, args, func(reply *TagStruct, err error) {
+		done <- result{reply, err}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-done:
+		return res.reply, res.err
+	case <-time.After(timeout):
+		self.Cancel(tag)
+		return nil, ErrTimeout
+	}
+}
+
+// Send a packet (typically stream data) as-is.
+func (self *Client) Send(packet *Packet) error {
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+
+	return WritePacket(self.conn, packet)
+}
+
+// Authenticate with the given cookie and announce the client's properties
+// (e.g.: "application.name").  Run must already be running.
+func (self *Client) Handshake(cookie []byte, props PropList) error {
+	auth := NewTagStruct()
+	auth.PutU32(ProtocolVersion)
+	auth.PutArbitrary(cookie)
+
+	if reply, err := self.Call(CommandAuth, auth, DEFAULT_HANDSHAKE_TIMEOUT); err != nil {
+		return err
+	} else if version, err := reply.GetU32(); err != nil {
+		return err
+	} else {
+		self.ServerVersion = version & VersionMask
+	}
+
+	if self.ServerVersion < MinProtocolVersion {
+		return ErrVersion
+	}
+
+	self.Version = ProtocolVersion
+
+	if self.ServerVersion < self.Version {
+		self.Version = self.ServerVersion
+	}
+
+	name := NewTagStruct()
+	name.PutPropList(props)
+
+	if reply, err := self.Call(CommandSetClientName, name, DEFAULT_HANDSHAKE_TIMEOUT); err != nil {
+		return err
+	} else if index, err := reply.GetU32(); err != nil {
+		return err
+	} else {
+		self.ClientIndex = index
+	}
+
+	return nil
+}
+
+// Close the connection.  Pending requests fail and OnClose is called once Run
+// notices.
+func (self *Client) Close() error {
+	return self.conn.Close()
+}
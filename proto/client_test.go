@@ -0,0 +1,151 @@
+package proto
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Serve one connection, answering the handshake and then handing every other
+// request to the given function.
+func serve(conn net.Conn, version uint32, handle func(command Command, tag uint32, args *TagStruct)) {
+	reply := func(tag uint32, args *TagStruct) {
+		WritePacket(conn, NewCommandPacket(CommandReply, tag, args))
+	}
+
+	for {
+		packet, err := ReadPacket(conn)
+
+		if err != nil {
+			return
+		}
+
+		command, tag, args, _ := packet.Command()
+
+		switch command {
+		case CommandAuth:
+			t := NewTagStruct()
+			t.PutU32(version)
+			reply(tag, t)
+
+		case CommandSetClientName:
+			t := NewTagStruct()
+			t.PutU32(17)
+			reply(tag, t)
+
+		default:
+			handle(command, tag, args)
+		}
+	}
+}
+
+func TestClientHandshake(t *testing.T) {
+	assert := require.New(t)
+	local, remote := net.Pipe()
+
+	go serve(remote, 0x80000000|28, func(command Command, tag uint32, args *TagStruct) {
+		if command == CommandGetServerInfo {
+			t := NewTagStruct()
+
+			info := ServerInfo{
+				PackageName:     `pulseaudio`,
+				DefaultSinkName: `speakers`,
+			}
+
+			info.Put(t, 28)
+			WritePacket(remote, NewCommandPacket(CommandReply, tag, t))
+		} else {
+			t := NewTagStruct()
+			t.PutU32(uint32(ErrNoEntity))
+			WritePacket(remote, NewCommandPacket(CommandError, tag, t))
+		}
+	})
+
+	client := NewClient(local)
+	go client.Run()
+	defer client.Close()
+
+	assert.NoError(client.Handshake(make([]byte, CookieSize), PropList{`application.name`: `test`}))
+	assert.Equal(uint32(28), client.ServerVersion)
+	assert.Equal(uint32(28), client.Version)
+	assert.Equal(uint32(17), client.ClientIndex)
+
+	reply, err := client.Call(CommandGetServerInfo, nil, time.Second)
+	assert.NoError(err)
+
+	var info ServerInfo
+	assert.NoError(info.Get(reply, client.Version))
+	assert.Equal(`speakers`, info.DefaultSinkName)
+
+	_, err = client.Call(CommandGetSinkInfo, nil, time.Second)
+	assert.Equal(ErrNoEntity, err)
+	assert.EqualError(err, `No such entity`)
+}
+
+func TestClientOldServer(t *testing.T) {
+	assert := require.New(t)
+	local, remote := net.Pipe()
+
+	go serve(remote, 12, nil)
+
+	client := NewClient(local)
+	go client.Run()
+	defer client.Close()
+
+	assert.Equal(ErrVersion, client.Handshake(nil, nil))
+}
+
+func TestClientConnectionDropped(t *testing.T) {
+	assert := require.New(t)
+	local, remote := net.Pipe()
+	closed := make(chan error, 1)
+
+	go serve(remote, ProtocolVersion, func(command Command, tag uint32, args *TagStruct) {
+		remote.Close()
+	})
+
+	client := NewClient(local)
+	client.OnClose = func(err error) {
+		closed <- err
+	}
+
+	go client.Run()
+
+	assert.NoError(client.Handshake(nil, nil))
+
+	_, err := client.Call(CommandGetServerInfo, nil, time.Second)
+	assert.Equal(ErrConnectionTerminated, err)
+
+	select {
+	case err := <-closed:
+		assert.Equal(ErrConnectionTerminated, err)
+	case <-time.After(time.Second):
+		t.Fatal("OnClose was not called")
+	}
+
+	_, err = client.Call(CommandGetServerInfo, nil, time.Second)
+	assert.Equal(ErrConnectionTerminated, err)
+}
+
+func TestParseAddress(t *testing.T) {
+	assert := require.New(t)
+
+	for addr, want := range map[string][2]string{
+		`unix:/run/pulse/native`: {`unix`, `/run/pulse/native`},
+		`/tmp/pulse.sock`:        {`unix`, `/tmp/pulse.sock`},
+		`tcp:example.com`:        {`tcp`, `example.com:4713`},
+		`tcp:example.com:1234`:   {`tcp`, `example.com:1234`},
+		`tcp:`:                   {`tcp`, `localhost:4713`},
+		`tcp6:[::1]`:             {`tcp6`, `[::1]:4713`},
+	} {
+		network, address, err := ParseAddress(addr)
+		assert.NoError(err, addr)
+		assert.Equal(want[0], network, addr)
+		assert.Equal(want[1], address, addr)
+	}
+
+	_, _, err := ParseAddress(`bogus`)
+	assert.Error(err)
+}
@@ -0,0 +1,71 @@
+package proto
+
+// A Command identifies the request, reply, or notification carried by a control
+// packet.  Values are those of PulseAudio's native protocol.
+type Command uint32
+
+const (
+	CommandError                    Command = 0
+	CommandReply                    Command = 2
+	CommandCreatePlaybackStream     Command = 3
+	CommandDeletePlaybackStream     Command = 4
+	CommandCreateRecordStream       Command = 5
+	CommandDeleteRecordStream       Command = 6
+	CommandAuth                     Command = 8
+	CommandSetClientName            Command = 9
+	CommandDrainPlaybackStream      Command = 12
+	CommandGetServerInfo            Command = 20
+	CommandGetSinkInfo              Command = 21
+	CommandGetSinkInfoList          Command = 22
+	CommandGetSourceInfo            Command = 23
+	CommandGetSourceInfoList        Command = 24
+	CommandGetModuleInfo            Command = 25
+	CommandGetModuleInfoList        Command = 26
+	CommandGetClientInfo            Command = 27
+	CommandGetClientInfoList        Command = 28
+	CommandGetSinkInputInfo         Command = 29
+	CommandGetSinkInputInfoList     Command = 30
+	CommandGetSourceOutputInfo      Command = 31
+	CommandGetSourceOutputInfoList  Command = 32
+	CommandSubscribe                Command = 35
+	CommandSetSinkVolume            Command = 36
+	CommandSetSinkInputVolume       Command = 37
+	CommandSetSourceVolume          Command = 38
+	CommandSetSinkMute              Command = 39
+	CommandSetSourceMute            Command = 40
+	CommandCorkPlaybackStream       Command = 41
+	CommandFlushPlaybackStream      Command = 42
+	CommandTriggerPlaybackStream    Command = 43
+	CommandSetDefaultSink           Command = 44
+	CommandSetDefaultSource         Command = 45
+	CommandKillClient               Command = 48
+	CommandKillSinkInput            Command = 49
+	CommandKillSourceOutput         Command = 50
+	CommandLoadModule               Command = 51
+	CommandUnloadModule             Command = 52
+	CommandCorkRecordStream         Command = 58
+	CommandFlushRecordStream        Command = 59
+	CommandPrebufPlaybackStream     Command = 60
+	CommandRequest                  Command = 61
+	CommandOverflow                 Command = 62
+	CommandUnderflow                Command = 63
+	CommandPlaybackStreamKilled     Command = 64
+	CommandRecordStreamKilled       Command = 65
+	CommandSubscribeEvent           Command = 66
+	CommandMoveSinkInput            Command = 67
+	CommandMoveSourceOutput         Command = 68
+	CommandSetSinkInputMute         Command = 69
+	CommandUpdatePlaybackStreamRate Command = 74
+	CommandUpdateRecordStreamRate   Command = 75
+	CommandPlaybackStreamSuspended  Command = 76
+	CommandRecordStreamSuspended    Command = 77
+	CommandPlaybackStreamMoved      Command = 78
+	CommandRecordStreamMoved        Command = 79
+	CommandStarted                  Command = 86
+	CommandSetSinkPort              Command = 96
+	CommandSetSourcePort            Command = 97
+	CommandSetSourceOutputVolume    Command = 98
+	CommandSetSourceOutputMute      Command = 99
+	CommandEnableSRBChannel         Command = 101
+	CommandRegisterMemfdShmID       Command = 103
+)
@@ -0,0 +1,78 @@
+package proto
+
+import (
+	"fmt"
+)
+
+// An ErrorCode is an error reported by the server in reply to a request, or
+// one raised locally for the same reasons (e.g.: a malformed reply is a
+// protocol error).  Values and messages are those of libpulse.
+type ErrorCode uint32
+
+const (
+	ErrOK                   ErrorCode = 0
+	ErrAccess               ErrorCode = 1
+	ErrCommand              ErrorCode = 2
+	ErrInvalid              ErrorCode = 3
+	ErrExist                ErrorCode = 4
+	ErrNoEntity             ErrorCode = 5
+	ErrConnectionRefused    ErrorCode = 6
+	ErrProtocol             ErrorCode = 7
+	ErrTimeout              ErrorCode = 8
+	ErrAuthKey              ErrorCode = 9
+	ErrInternal             ErrorCode = 10
+	ErrConnectionTerminated ErrorCode = 11
+	ErrKilled               ErrorCode = 12
+	ErrInvalidServer        ErrorCode = 13
+	ErrModInitFailed        ErrorCode = 14
+	ErrBadState             ErrorCode = 15
+	ErrNoData               ErrorCode = 16
+	ErrVersion              ErrorCode = 17
+	ErrTooLarge             ErrorCode = 18
+	ErrNotSupported         ErrorCode = 19
+	ErrUnknown              ErrorCode = 20
+	ErrNoExtension          ErrorCode = 21
+	ErrObsolete             ErrorCode = 22
+	ErrNotImplemented       ErrorCode = 23
+	ErrForked               ErrorCode = 24
+	ErrIO                   ErrorCode = 25
+	ErrBusy                 ErrorCode = 26
+)
+
+var errorMessages = []string{
+	`OK`,
+	`Access denied`,
+	`Unknown command`,
+	`Invalid argument`,
+	`Entity exists`,
+	`No such entity`,
+	`Connection refused`,
+	`Protocol error`,
+	`Timeout`,
+	`No authentication key`,
+	`Internal error`,
+	`Connection terminated`,
+	`Entity killed`,
+	`Invalid server`,
+	`Module initialization failed`,
+	`Bad state`,
+	`No data`,
+	`Incompatible protocol version`,
+	`Too large`,
+	`Not supported`,
+	`Unknown error code`,
+	`No such extension`,
+	`Obsolete functionality`,
+	`Missing implementation`,
+	`Client forked`,
+	`Input/Output error`,
+	`Device or resource busy`,
+}
+
+func (self ErrorCode) Error() string {
+	if int(self) < len(errorMessages) {
+		return errorMessages[self]
+	}
+
+	return fmt.Sprintf("Unknown error code %d", uint32(self))
+}
@@ -0,0 +1,641 @@
+package proto
+
+// The layouts below follow the server's introspection replies.  Each has a Put
+// method that writes it as a server would for a client of the given protocol
+// version, and a Get method that reads it back.  Fields introduced after
+// MinProtocolVersion are only present on the wire for newer versions.
+
+// The reply to CommandGetServerInfo.
+type ServerInfo struct {
+	PackageName       string
+	PackageVersion    string
+	UserName          string
+	HostName          string
+	SampleSpec        SampleSpec
+	DefaultSinkName   string
+	DefaultSourceName string
+	Cookie            uint32
+	ChannelMap        ChannelMap
+}
+
+func (self *ServerInfo) Put(t *TagStruct, version uint32) {
+	t.PutNullableString(self.PackageName)
+	t.PutNullableString(self.PackageVersion)
+	t.PutNullableString(self.UserName)
+	t.PutNullableString(self.HostName)
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutNullableString(self.DefaultSinkName)
+	t.PutNullableString(self.DefaultSourceName)
+	t.PutU32(self.Cookie)
+
+	if version >= 15 {
+		t.PutChannelMap(self.ChannelMap)
+	}
+}
+
+func (self *ServerInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.string(&self.PackageName)
+	r.string(&self.PackageVersion)
+	r.string(&self.UserName)
+	r.string(&self.HostName)
+	r.sampleSpec(&self.SampleSpec)
+	r.string(&self.DefaultSinkName)
+	r.string(&self.DefaultSourceName)
+	r.u32(&self.Cookie)
+
+	if version >= 15 {
+		r.channelMap(&self.ChannelMap)
+	}
+
+	return r.err
+}
+
+// A device port, as listed in SinkInfo and SourceInfo.
+type PortInfo struct {
+	Name        string
+	Description string
+	Priority    uint32
+	Available   uint32
+}
+
+func putPorts(t *TagStruct, version uint32, ports []PortInfo, active string) {
+	t.PutU32(uint32(len(ports)))
+
+	for _, port := range ports {
+		t.PutNullableString(port.Name)
+		t.PutNullableString(port.Description)
+		t.PutU32(port.Priority)
+
+		if version >= 24 {
+			t.PutU32(port.Available)
+		}
+	}
+
+	t.PutNullableString(active)
+}
+
+func (self *reader) ports(version uint32, ports *[]PortInfo, active *string) {
+	var n uint32
+
+	if self.u32(&n); self.err != nil {
+		return
+	}
+
+	*ports = make([]PortInfo, 0)
+
+	for i := uint32(0); i < n && self.err == nil; i++ {
+		var port PortInfo
+
+		self.string(&port.Name)
+		self.string(&port.Description)
+		self.u32(&port.Priority)
+
+		if version >= 24 {
+			self.u32(&port.Available)
+		}
+
+		*ports = append(*ports, port)
+	}
+
+	self.string(active)
+}
+
+func putFormats(t *TagStruct, formats []FormatInfo) {
+	t.PutU8(uint8(len(formats)))
+
+	for _, format := range formats {
+		t.PutFormatInfo(format)
+	}
+}
+
+func (self *reader) formats(formats *[]FormatInfo) {
+	var n uint8
+
+	if self.u8(&n); self.err != nil {
+		return
+	}
+
+	*formats = make([]FormatInfo, 0)
+
+	for i := uint8(0); i < n && self.err == nil; i++ {
+		var format FormatInfo
+
+		self.formatInfo(&format)
+		*formats = append(*formats, format)
+	}
+}
+
+// An entry in the reply to CommandGetSinkInfo or CommandGetSinkInfoList.
+type SinkInfo struct {
+	Index             uint32
+	Name              string
+	Description       string
+	SampleSpec        SampleSpec
+	ChannelMap        ChannelMap
+	OwnerModule       uint32
+	Volume            CVolume
+	Mute              bool
+	MonitorSource     uint32
+	MonitorSourceName string
+	Latency           uint64
+	Driver            string
+	Flags             uint32
+	PropList          PropList
+	ConfiguredLatency uint64
+	BaseVolume        uint32
+	State             uint32
+	NumVolumeSteps    uint32
+	Card              uint32
+	Ports             []PortInfo
+	ActivePort        string
+	Formats           []FormatInfo
+}
+
+func (self *SinkInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutNullableString(self.Description)
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutU32(self.OwnerModule)
+	t.PutCVolume(self.Volume)
+	t.PutBool(self.Mute)
+	t.PutU32(self.MonitorSource)
+	t.PutNullableString(self.MonitorSourceName)
+	t.PutUsec(self.Latency)
+	t.PutNullableString(self.Driver)
+	t.PutU32(self.Flags)
+	t.PutPropList(self.PropList)
+	t.PutUsec(self.ConfiguredLatency)
+
+	if version >= 15 {
+		t.PutVolume(self.BaseVolume)
+		t.PutU32(self.State)
+		t.PutU32(self.NumVolumeSteps)
+		t.PutU32(self.Card)
+	}
+
+	if version >= 16 {
+		putPorts(t, version, self.Ports, self.ActivePort)
+	}
+
+	if version >= 21 {
+		putFormats(t, self.Formats)
+	}
+}
+
+func (self *SinkInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.string(&self.Description)
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.u32(&self.OwnerModule)
+	r.cvolume(&self.Volume)
+	r.bool(&self.Mute)
+	r.u32(&self.MonitorSource)
+	r.string(&self.MonitorSourceName)
+	r.usec(&self.Latency)
+	r.string(&self.Driver)
+	r.u32(&self.Flags)
+	r.propList(&self.PropList)
+	r.usec(&self.ConfiguredLatency)
+
+	if version >= 15 {
+		r.volume(&self.BaseVolume)
+		r.u32(&self.State)
+		r.u32(&self.NumVolumeSteps)
+		r.u32(&self.Card)
+	} else {
+		// what libpulse reports for servers that don't say
+		self.BaseVolume = VolumeNorm
+		self.State = InvalidIndex
+		self.NumVolumeSteps = VolumeNorm + 1
+		self.Card = InvalidIndex
+	}
+
+	if version >= 16 {
+		r.ports(version, &self.Ports, &self.ActivePort)
+	}
+
+	if version >= 21 {
+		r.formats(&self.Formats)
+	}
+
+	return r.err
+}
+
+// An entry in the reply to CommandGetSourceInfo or CommandGetSourceInfoList.
+type SourceInfo struct {
+	Index             uint32
+	Name              string
+	Description       string
+	SampleSpec        SampleSpec
+	ChannelMap        ChannelMap
+	OwnerModule       uint32
+	Volume            CVolume
+	Mute              bool
+	MonitorOfSink     uint32
+	MonitorOfSinkName string
+	Latency           uint64
+	Driver            string
+	Flags             uint32
+	PropList          PropList
+	ConfiguredLatency uint64
+	BaseVolume        uint32
+	State             uint32
+	NumVolumeSteps    uint32
+	Card              uint32
+	Ports             []PortInfo
+	ActivePort        string
+	Formats           []FormatInfo
+}
+
+func (self *SourceInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutNullableString(self.Description)
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutU32(self.OwnerModule)
+	t.PutCVolume(self.Volume)
+	t.PutBool(self.Mute)
+	t.PutU32(self.MonitorOfSink)
+	t.PutNullableString(self.MonitorOfSinkName)
+	t.PutUsec(self.Latency)
+	t.PutNullableString(self.Driver)
+	t.PutU32(self.Flags)
+	t.PutPropList(self.PropList)
+	t.PutUsec(self.ConfiguredLatency)
+
+	if version >= 15 {
+		t.PutVolume(self.BaseVolume)
+		t.PutU32(self.State)
+		t.PutU32(self.NumVolumeSteps)
+		t.PutU32(self.Card)
+	}
+
+	if version >= 16 {
+		putPorts(t, version, self.Ports, self.ActivePort)
+	}
+
+	if version >= 22 {
+		putFormats(t, self.Formats)
+	}
+}
+
+func (self *SourceInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.string(&self.Description)
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.u32(&self.OwnerModule)
+	r.cvolume(&self.Volume)
+	r.bool(&self.Mute)
+	r.u32(&self.MonitorOfSink)
+	r.string(&self.MonitorOfSinkName)
+	r.usec(&self.Latency)
+	r.string(&self.Driver)
+	r.u32(&self.Flags)
+	r.propList(&self.PropList)
+	r.usec(&self.ConfiguredLatency)
+
+	if version >= 15 {
+		r.volume(&self.BaseVolume)
+		r.u32(&self.State)
+		r.u32(&self.NumVolumeSteps)
+		r.u32(&self.Card)
+	} else {
+		// what libpulse reports for servers that don't say
+		self.BaseVolume = VolumeNorm
+		self.State = InvalidIndex
+		self.NumVolumeSteps = VolumeNorm + 1
+		self.Card = InvalidIndex
+	}
+
+	if version >= 16 {
+		r.ports(version, &self.Ports, &self.ActivePort)
+	}
+
+	if version >= 22 {
+		r.formats(&self.Formats)
+	}
+
+	return r.err
+}
+
+// An entry in the reply to CommandGetSinkInputInfo or
+// CommandGetSinkInputInfoList.
+type SinkInputInfo struct {
+	Index          uint32
+	Name           string
+	OwnerModule    uint32
+	Client         uint32
+	Sink           uint32
+	SampleSpec     SampleSpec
+	ChannelMap     ChannelMap
+	Volume         CVolume
+	BufferUsec     uint64
+	SinkUsec       uint64
+	ResampleMethod string
+	Driver         string
+	Mute           bool
+	PropList       PropList
+	Corked         bool
+	HasVolume      bool
+	VolumeWritable bool
+	Format         FormatInfo
+}
+
+func (self *SinkInputInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutU32(self.OwnerModule)
+	t.PutU32(self.Client)
+	t.PutU32(self.Sink)
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutCVolume(self.Volume)
+	t.PutUsec(self.BufferUsec)
+	t.PutUsec(self.SinkUsec)
+	t.PutNullableString(self.ResampleMethod)
+	t.PutNullableString(self.Driver)
+	t.PutBool(self.Mute)
+	t.PutPropList(self.PropList)
+
+	if version >= 19 {
+		t.PutBool(self.Corked)
+	}
+
+	if version >= 20 {
+		t.PutBool(self.HasVolume)
+		t.PutBool(self.VolumeWritable)
+	}
+
+	if version >= 21 {
+		t.PutFormatInfo(self.Format)
+	}
+}
+
+func (self *SinkInputInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.u32(&self.OwnerModule)
+	r.u32(&self.Client)
+	r.u32(&self.Sink)
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.cvolume(&self.Volume)
+	r.usec(&self.BufferUsec)
+	r.usec(&self.SinkUsec)
+	r.string(&self.ResampleMethod)
+	r.string(&self.Driver)
+	r.bool(&self.Mute)
+	r.propList(&self.PropList)
+
+	if version >= 19 {
+		r.bool(&self.Corked)
+	}
+
+	if version >= 20 {
+		r.bool(&self.HasVolume)
+		r.bool(&self.VolumeWritable)
+	}
+
+	if version >= 21 {
+		r.formatInfo(&self.Format)
+	}
+
+	return r.err
+}
+
+// An entry in the reply to CommandGetSourceOutputInfo or
+// CommandGetSourceOutputInfoList.
+type SourceOutputInfo struct {
+	Index          uint32
+	Name           string
+	OwnerModule    uint32
+	Client         uint32
+	Source         uint32
+	SampleSpec     SampleSpec
+	ChannelMap     ChannelMap
+	BufferUsec     uint64
+	SourceUsec     uint64
+	ResampleMethod string
+	Driver         string
+	PropList       PropList
+	Corked         bool
+	Volume         CVolume
+	Mute           bool
+	HasVolume      bool
+	VolumeWritable bool
+	Format         FormatInfo
+}
+
+func (self *SourceOutputInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutU32(self.OwnerModule)
+	t.PutU32(self.Client)
+	t.PutU32(self.Source)
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutUsec(self.BufferUsec)
+	t.PutUsec(self.SourceUsec)
+	t.PutNullableString(self.ResampleMethod)
+	t.PutNullableString(self.Driver)
+	t.PutPropList(self.PropList)
+
+	if version >= 19 {
+		t.PutBool(self.Corked)
+	}
+
+	if version >= 22 {
+		t.PutCVolume(self.Volume)
+		t.PutBool(self.Mute)
+		t.PutBool(self.HasVolume)
+		t.PutBool(self.VolumeWritable)
+		t.PutFormatInfo(self.Format)
+	}
+}
+
+func (self *SourceOutputInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.u32(&self.OwnerModule)
+	r.u32(&self.Client)
+	r.u32(&self.Source)
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.usec(&self.BufferUsec)
+	r.usec(&self.SourceUsec)
+	r.string(&self.ResampleMethod)
+	r.string(&self.Driver)
+	r.propList(&self.PropList)
+
+	if version >= 19 {
+		r.bool(&self.Corked)
+	}
+
+	if version >= 22 {
+		r.cvolume(&self.Volume)
+		r.bool(&self.Mute)
+		r.bool(&self.HasVolume)
+		r.bool(&self.VolumeWritable)
+		r.formatInfo(&self.Format)
+	}
+
+	return r.err
+}
+
+// An entry in the reply to CommandGetModuleInfo or CommandGetModuleInfoList.
+type ModuleInfo struct {
+	Index    uint32
+	Name     string
+	Argument string
+	NumUsed  uint32
+	PropList PropList
+}
+
+func (self *ModuleInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutNullableString(self.Argument)
+	t.PutU32(self.NumUsed)
+
+	if version < 15 {
+		t.PutBool(false)
+	} else {
+		t.PutPropList(self.PropList)
+	}
+}
+
+func (self *ModuleInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.string(&self.Argument)
+	r.u32(&self.NumUsed)
+
+	if version < 15 {
+		var autoUnload bool
+		r.bool(&autoUnload)
+	} else {
+		r.propList(&self.PropList)
+	}
+
+	return r.err
+}
+
+// An entry in the reply to CommandGetClientInfo or CommandGetClientInfoList.
+type ClientInfo struct {
+	Index       uint32
+	Name        string
+	OwnerModule uint32
+	Driver      string
+	PropList    PropList
+}
+
+func (self *ClientInfo) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Index)
+	t.PutNullableString(self.Name)
+	t.PutU32(self.OwnerModule)
+	t.PutNullableString(self.Driver)
+	t.PutPropList(self.PropList)
+}
+
+func (self *ClientInfo) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Index)
+	r.string(&self.Name)
+	r.u32(&self.OwnerModule)
+	r.string(&self.Driver)
+	r.propList(&self.PropList)
+
+	return r.err
+}
+
+// reads a sequence of values, stopping at (and keeping) the first error
+type reader struct {
+	t   *TagStruct
+	err error
+}
+
+func (self *reader) u8(v *uint8) {
+	if self.err == nil {
+		*v, self.err = self.t.GetU8()
+	}
+}
+
+func (self *reader) u32(v *uint32) {
+	if self.err == nil {
+		*v, self.err = self.t.GetU32()
+	}
+}
+
+func (self *reader) usec(v *uint64) {
+	if self.err == nil {
+		*v, self.err = self.t.GetUsec()
+	}
+}
+
+func (self *reader) volume(v *uint32) {
+	if self.err == nil {
+		*v, self.err = self.t.GetVolume()
+	}
+}
+
+func (self *reader) bool(v *bool) {
+	if self.err == nil {
+		*v, self.err = self.t.GetBool()
+	}
+}
+
+func (self *reader) string(v *string) {
+	if self.err == nil {
+		*v, self.err = self.t.GetString()
+	}
+}
+
+func (self *reader) sampleSpec(v *SampleSpec) {
+	if self.err == nil {
+		*v, self.err = self.t.GetSampleSpec()
+	}
+}
+
+func (self *reader) channelMap(v *ChannelMap) {
+	if self.err == nil {
+		*v, self.err = self.t.GetChannelMap()
+	}
+}
+
+func (self *reader) cvolume(v *CVolume) {
+	if self.err == nil {
+		*v, self.err = self.t.GetCVolume()
+	}
+}
+
+func (self *reader) propList(v *PropList) {
+	if self.err == nil {
+		*v, self.err = self.t.GetPropList()
+	}
+}
+
+func (self *reader) formatInfo(v *FormatInfo) {
+	if self.err == nil {
+		*v, self.err = self.t.GetFormatInfo()
+	}
+}
@@ -0,0 +1,119 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The fixtures below are written out byte by byte from the order in which
+// PulseAudio's protocol-native.c (command_get_server_info and
+// sink_fill_tagstruct) puts each field for a protocol version 32 client, so
+// that they check the layouts independently of the Put methods.  The sink is
+// the one module-null-sink creates when no other devices are present.
+
+var serverInfoFixture = []byte("" +
+	"tpulseaudio\x00" + // package name
+	"t15.0\x00" + // package version
+	"tuser\x00" + // user name
+	"thost\x00" + // host name
+	"a\x03\x02\x00\x00\xac\x44" + // sample spec: s16le, 2 channels, 44100 Hz
+	"tauto_null\x00" + // default sink
+	"tauto_null.monitor\x00" + // default source
+	"L\x12\x34\xab\xcd" + // cookie
+	"m\x02\x01\x02") // channel map: front-left, front-right (version >= 15)
+
+var sinkInfoFixture = []byte("" +
+	"L\x00\x00\x00\x00" + // index
+	"tauto_null\x00" + // name
+	"tDummy Output\x00" + // description
+	"a\x03\x02\x00\x00\xac\x44" + // sample spec
+	"m\x02\x01\x02" + // channel map
+	"L\x00\x00\x00\x0b" + // owner module
+	"v\x02\x00\x01\x00\x00\x00\x01\x00\x00" + // volume: 2 channels at 100%
+	"0" + // mute
+	"L\x00\x00\x00\x00" + // monitor source
+	"tauto_null.monitor\x00" + // monitor source name
+	"U\x00\x00\x00\x00\x00\x00\x00\x00" + // latency
+	"tmodule-null-sink.c\x00" + // driver
+	"L\x00\x00\x00\xa2" + // flags: latency, decibel volume, dynamic latency
+	"P" + // property list (version >= 13)
+	"tdevice.class\x00L\x00\x00\x00\x09x\x00\x00\x00\x09abstract\x00" +
+	"tdevice.description\x00L\x00\x00\x00\x0dx\x00\x00\x00\x0dDummy Output\x00" +
+	"N" +
+	"U\x00\x00\x00\x00\x00\x00\x75\x30" + // configured latency (version >= 13)
+	"V\x00\x01\x00\x00" + // base volume (version >= 15)
+	"L\x00\x00\x00\x02" + // state: suspended
+	"L\x00\x01\x00\x01" + // volume steps
+	"L\xff\xff\xff\xff" + // card: none
+	"L\x00\x00\x00\x00" + // ports (version >= 16): none
+	"N" + // active port
+	"B\x01" + // formats (version >= 21)
+	"fB\x01PN") // PCM, no properties
+
+func TestServerInfoFixture(t *testing.T) {
+	assert := require.New(t)
+
+	var info ServerInfo
+	reply := ParseTagStruct(serverInfoFixture)
+
+	assert.NoError(info.Get(reply, 32))
+	assert.True(reply.Eof())
+
+	assert.Equal(ServerInfo{
+		PackageName:       `pulseaudio`,
+		PackageVersion:    `15.0`,
+		UserName:          `user`,
+		HostName:          `host`,
+		SampleSpec:        SampleSpec{Format: 3, Channels: 2, Rate: 44100},
+		DefaultSinkName:   `auto_null`,
+		DefaultSourceName: `auto_null.monitor`,
+		Cookie:            0x1234ABCD,
+		ChannelMap:        ChannelMap{1, 2},
+	}, info)
+
+	out := NewTagStruct()
+	info.Put(out, 32)
+	assert.Equal(serverInfoFixture, out.Bytes())
+}
+
+func TestSinkInfoFixture(t *testing.T) {
+	assert := require.New(t)
+
+	var sink SinkInfo
+	reply := ParseTagStruct(sinkInfoFixture)
+
+	assert.NoError(sink.Get(reply, 32))
+	assert.True(reply.Eof())
+
+	assert.Equal(SinkInfo{
+		Index:             0,
+		Name:              `auto_null`,
+		Description:       `Dummy Output`,
+		SampleSpec:        SampleSpec{Format: 3, Channels: 2, Rate: 44100},
+		ChannelMap:        ChannelMap{1, 2},
+		OwnerModule:       11,
+		Volume:            CVolume{VolumeNorm, VolumeNorm},
+		MonitorSource:     0,
+		MonitorSourceName: `auto_null.monitor`,
+		Driver:            `module-null-sink.c`,
+		Flags:             0xA2,
+		PropList: PropList{
+			`device.class`:       `abstract`,
+			`device.description`: `Dummy Output`,
+		},
+		ConfiguredLatency: 30000,
+		BaseVolume:        VolumeNorm,
+		State:             2,
+		NumVolumeSteps:    VolumeNorm + 1,
+		Card:              InvalidIndex,
+		Ports:             []PortInfo{},
+		Formats: []FormatInfo{
+			{Encoding: EncodingPCM, PropList: PropList{}},
+		},
+	}, sink)
+
+	out := NewTagStruct()
+	sink.Put(out, 32)
+	assert.Equal(sinkInfoFixture, out.Bytes())
+}
@@ -0,0 +1,116 @@
+// Package proto implements the client side of PulseAudio's native protocol: the
+// framing of packets, the tagstruct serialization of commands, the layout of
+// the server's introspection replies, and a Client that authenticates with the
+// server and matches replies to requests.
+//
+// Audio is carried in packets on the socket itself; shared memory transports
+// are never negotiated.
+package proto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// The channel of packets carrying commands rather than stream data.
+	ControlChannel uint32 = 0xFFFFFFFF
+
+	// The size of the descriptor that precedes every packet.
+	DescriptorSize = 20
+
+	// The largest packet accepted from the server.
+	MaxPacketSize = 16 * 1024 * 1024
+
+	// The bits of a data packet's flags holding its seek mode.
+	SeekMask uint32 = 0x000000FF
+)
+
+// A Packet is a single frame on the socket.  Control packets hold a TagStruct;
+// data packets hold audio for the stream identified by Channel, to be written
+// at Offset according to the seek mode in Flags.
+type Packet struct {
+	Channel uint32
+	Offset  int64
+	Flags   uint32
+	Data    []byte
+}
+
+// Create a control packet holding the given command.
+func NewCommandPacket(command Command, tag uint32, args *TagStruct) *Packet {
+	t := NewTagStruct()
+	t.PutU32(uint32(command))
+	t.PutU32(tag)
+
+	if args != nil {
+		t.data = append(t.data, args.Bytes()...)
+	}
+
+	return &Packet{
+		Channel: ControlChannel,
+		Data:    t.Bytes(),
+	}
+}
+
+// Return whether this packet holds a command rather than stream data.
+func (self *Packet) IsControl() bool {
+	return self.Channel == ControlChannel
+}
+
+// Decode a control packet's command and tag, returning the TagStruct
+// positioned at the command's arguments.
+func (self *Packet) Command() (Command, uint32, *TagStruct, error) {
+	t := ParseTagStruct(self.Data)
+
+	if command, err := t.GetU32(); err != nil {
+		return 0, 0, nil, err
+	} else if tag, err := t.GetU32(); err != nil {
+		return 0, 0, nil, err
+	} else {
+		return Command(command), tag, t, nil
+	}
+}
+
+// Read the next packet from the given reader.
+func ReadPacket(r io.Reader) (*Packet, error) {
+	var descriptor [DescriptorSize]byte
+
+	if _, err := io.ReadFull(r, descriptor[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(descriptor[0:])
+
+	if length > MaxPacketSize {
+		return nil, fmt.Errorf("Packet of %d bytes exceeds the maximum size", length)
+	}
+
+	packet := &Packet{
+		Channel: binary.BigEndian.Uint32(descriptor[4:]),
+		Offset:  int64(uint64(binary.BigEndian.Uint32(descriptor[8:]))<<32 | uint64(binary.BigEndian.Uint32(descriptor[12:]))),
+		Flags:   binary.BigEndian.Uint32(descriptor[16:]),
+		Data:    make([]byte, int(length)),
+	}
+
+	if _, err := io.ReadFull(r, packet.Data); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+// Write a packet to the given writer in a single call.
+func WritePacket(w io.Writer, packet *Packet) error {
+	frame := make([]byte, DescriptorSize+len(packet.Data))
+
+	binary.BigEndian.PutUint32(frame[0:], uint32(len(packet.Data)))
+	binary.BigEndian.PutUint32(frame[4:], packet.Channel)
+	binary.BigEndian.PutUint32(frame[8:], uint32(uint64(packet.Offset)>>32))
+	binary.BigEndian.PutUint32(frame[12:], uint32(uint64(packet.Offset)))
+	binary.BigEndian.PutUint32(frame[16:], packet.Flags)
+	copy(frame[DescriptorSize:], packet.Data)
+
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,329 @@
+package proto
+
+// Stream flags, as passed to pa_stream_connect_playback() and
+// pa_stream_connect_record().  Only those that change what is sent to the
+// server are listed.
+const (
+	FlagStartCorked            uint32 = 0x0001
+	FlagNoRemapChannels        uint32 = 0x0010
+	FlagNoRemixChannels        uint32 = 0x0020
+	FlagFixFormat              uint32 = 0x0040
+	FlagFixRate                uint32 = 0x0080
+	FlagFixChannels            uint32 = 0x0100
+	FlagDontMove               uint32 = 0x0200
+	FlagVariableRate           uint32 = 0x0400
+	FlagPeakDetect             uint32 = 0x0800
+	FlagStartMuted             uint32 = 0x1000
+	FlagAdjustLatency          uint32 = 0x2000
+	FlagEarlyRequests          uint32 = 0x4000
+	FlagDontInhibitAutoSuspend uint32 = 0x8000
+	FlagStartUnmuted           uint32 = 0x10000
+	FlagFailOnSuspend          uint32 = 0x20000
+	FlagRelativeVolume         uint32 = 0x40000
+	FlagPassthrough            uint32 = 0x80000
+)
+
+// Buffer metrics of a stream.  Playback streams use MaxLength, TLength,
+// PreBuf, and MinReq; record streams use MaxLength and FragSize.
+type BufferAttr struct {
+	MaxLength uint32
+	TLength   uint32
+	PreBuf    uint32
+	MinReq    uint32
+	FragSize  uint32
+}
+
+// Return buffer metrics that leave every value to the server.
+func DefaultBufferAttr() BufferAttr {
+	return BufferAttr{
+		MaxLength: DefaultBufferValue,
+		TLength:   DefaultBufferValue,
+		PreBuf:    DefaultBufferValue,
+		MinReq:    DefaultBufferValue,
+		FragSize:  DefaultBufferValue,
+	}
+}
+
+// The arguments of CommandCreatePlaybackStream (or, if Record is set,
+// CommandCreateRecordStream).
+type CreateStream struct {
+	Record        bool
+	SampleSpec    SampleSpec
+	ChannelMap    ChannelMap
+	DeviceIndex   uint32
+	DeviceName    string
+	BufferAttr    BufferAttr
+	Flags         uint32
+	SyncID        uint32
+	Volume        CVolume
+	VolumeSet     bool
+	PropList      PropList
+	DirectOnInput uint32
+	Formats       []FormatInfo
+}
+
+func (self *CreateStream) flag(flag uint32) bool {
+	return self.Flags&flag != 0
+}
+
+func (self *CreateStream) Put(t *TagStruct, version uint32) {
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutU32(self.DeviceIndex)
+	t.PutNullableString(self.DeviceName)
+	t.PutU32(self.BufferAttr.MaxLength)
+	t.PutBool(self.flag(FlagStartCorked))
+
+	if self.Record {
+		t.PutU32(self.BufferAttr.FragSize)
+	} else {
+		t.PutU32(self.BufferAttr.TLength)
+		t.PutU32(self.BufferAttr.PreBuf)
+		t.PutU32(self.BufferAttr.MinReq)
+		t.PutU32(self.SyncID)
+		t.PutCVolume(self.Volume)
+	}
+
+	for _, flag := range []uint32{
+		FlagNoRemapChannels,
+		FlagNoRemixChannels,
+		FlagFixFormat,
+		FlagFixRate,
+		FlagFixChannels,
+		FlagDontMove,
+		FlagVariableRate,
+	} {
+		t.PutBool(self.flag(flag))
+	}
+
+	if self.Record {
+		t.PutBool(self.flag(FlagPeakDetect))
+	} else {
+		t.PutBool(self.flag(FlagStartMuted))
+	}
+
+	t.PutBool(self.flag(FlagAdjustLatency))
+	t.PutPropList(self.PropList)
+
+	if self.Record {
+		t.PutU32(self.DirectOnInput)
+	}
+
+	if version >= 14 {
+		if !self.Record {
+			t.PutBool(self.VolumeSet)
+		}
+
+		t.PutBool(self.flag(FlagEarlyRequests))
+	}
+
+	if version >= 15 {
+		if !self.Record {
+			t.PutBool(self.flag(FlagStartMuted | FlagStartUnmuted))
+		}
+
+		t.PutBool(self.flag(FlagDontInhibitAutoSuspend))
+		t.PutBool(self.flag(FlagFailOnSuspend))
+	}
+
+	if !self.Record && version >= 17 {
+		t.PutBool(self.flag(FlagRelativeVolume))
+	}
+
+	if !self.Record && version >= 18 {
+		t.PutBool(self.flag(FlagPassthrough))
+	}
+
+	if (!self.Record && version >= 21) || version >= 22 {
+		putFormats(t, self.Formats)
+	}
+
+	if self.Record && version >= 22 {
+		t.PutCVolume(self.Volume)
+		t.PutBool(self.flag(FlagStartMuted))
+		t.PutBool(self.VolumeSet)
+		t.PutBool(self.flag(FlagStartMuted | FlagStartUnmuted))
+		t.PutBool(self.flag(FlagRelativeVolume))
+		t.PutBool(self.flag(FlagPassthrough))
+	}
+}
+
+// Read the arguments of a create request; Record must already be set
+// according to the command.
+func (self *CreateStream) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	getFlag := func(flag uint32) {
+		var v bool
+
+		if r.bool(&v); v {
+			self.Flags |= flag
+		}
+	}
+
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.u32(&self.DeviceIndex)
+	r.string(&self.DeviceName)
+	r.u32(&self.BufferAttr.MaxLength)
+	getFlag(FlagStartCorked)
+
+	if self.Record {
+		r.u32(&self.BufferAttr.FragSize)
+	} else {
+		r.u32(&self.BufferAttr.TLength)
+		r.u32(&self.BufferAttr.PreBuf)
+		r.u32(&self.BufferAttr.MinReq)
+		r.u32(&self.SyncID)
+		r.cvolume(&self.Volume)
+	}
+
+	for _, flag := range []uint32{
+		FlagNoRemapChannels,
+		FlagNoRemixChannels,
+		FlagFixFormat,
+		FlagFixRate,
+		FlagFixChannels,
+		FlagDontMove,
+		FlagVariableRate,
+	} {
+		getFlag(flag)
+	}
+
+	if self.Record {
+		getFlag(FlagPeakDetect)
+	} else {
+		getFlag(FlagStartMuted)
+	}
+
+	getFlag(FlagAdjustLatency)
+	r.propList(&self.PropList)
+
+	if self.Record {
+		r.u32(&self.DirectOnInput)
+	}
+
+	if version >= 14 {
+		if !self.Record {
+			r.bool(&self.VolumeSet)
+		}
+
+		getFlag(FlagEarlyRequests)
+	}
+
+	if version >= 15 {
+		if !self.Record {
+			var mutedSet bool
+			r.bool(&mutedSet)
+		}
+
+		getFlag(FlagDontInhibitAutoSuspend)
+		getFlag(FlagFailOnSuspend)
+	}
+
+	if !self.Record && version >= 17 {
+		getFlag(FlagRelativeVolume)
+	}
+
+	if !self.Record && version >= 18 {
+		getFlag(FlagPassthrough)
+	}
+
+	if (!self.Record && version >= 21) || version >= 22 {
+		r.formats(&self.Formats)
+	}
+
+	if self.Record && version >= 22 {
+		var muted, mutedSet bool
+
+		r.cvolume(&self.Volume)
+		r.bool(&muted)
+		r.bool(&self.VolumeSet)
+		r.bool(&mutedSet)
+		getFlag(FlagRelativeVolume)
+		getFlag(FlagPassthrough)
+
+		if muted {
+			self.Flags |= FlagStartMuted
+		}
+	}
+
+	return r.err
+}
+
+// The reply to CommandCreatePlaybackStream (or, if Record is set,
+// CommandCreateRecordStream).
+type CreateStreamReply struct {
+	Record            bool
+	Channel           uint32
+	StreamIndex       uint32
+	RequestedBytes    uint32
+	BufferAttr        BufferAttr
+	SampleSpec        SampleSpec
+	ChannelMap        ChannelMap
+	DeviceIndex       uint32
+	DeviceName        string
+	Suspended         bool
+	ConfiguredLatency uint64
+	Format            FormatInfo
+}
+
+func (self *CreateStreamReply) Put(t *TagStruct, version uint32) {
+	t.PutU32(self.Channel)
+	t.PutU32(self.StreamIndex)
+
+	if self.Record {
+		t.PutU32(self.BufferAttr.MaxLength)
+		t.PutU32(self.BufferAttr.FragSize)
+	} else {
+		t.PutU32(self.RequestedBytes)
+		t.PutU32(self.BufferAttr.MaxLength)
+		t.PutU32(self.BufferAttr.TLength)
+		t.PutU32(self.BufferAttr.PreBuf)
+		t.PutU32(self.BufferAttr.MinReq)
+	}
+
+	t.PutSampleSpec(self.SampleSpec)
+	t.PutChannelMap(self.ChannelMap)
+	t.PutU32(self.DeviceIndex)
+	t.PutNullableString(self.DeviceName)
+	t.PutBool(self.Suspended)
+	t.PutUsec(self.ConfiguredLatency)
+
+	if (!self.Record && version >= 21) || version >= 22 {
+		t.PutFormatInfo(self.Format)
+	}
+}
+
+// Read the reply to a create request; Record must already be set according to
+// the request.
+func (self *CreateStreamReply) Get(t *TagStruct, version uint32) error {
+	r := reader{t: t}
+
+	r.u32(&self.Channel)
+	r.u32(&self.StreamIndex)
+
+	if self.Record {
+		r.u32(&self.BufferAttr.MaxLength)
+		r.u32(&self.BufferAttr.FragSize)
+	} else {
+		r.u32(&self.RequestedBytes)
+		r.u32(&self.BufferAttr.MaxLength)
+		r.u32(&self.BufferAttr.TLength)
+		r.u32(&self.BufferAttr.PreBuf)
+		r.u32(&self.BufferAttr.MinReq)
+	}
+
+	r.sampleSpec(&self.SampleSpec)
+	r.channelMap(&self.ChannelMap)
+	r.u32(&self.DeviceIndex)
+	r.string(&self.DeviceName)
+	r.bool(&self.Suspended)
+	r.usec(&self.ConfiguredLatency)
+
+	if (!self.Record && version >= 21) || version >= 22 {
+		r.formatInfo(&self.Format)
+	}
+
+	return r.err
+}
@@ -0,0 +1,435 @@
+package proto
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// A Tag precedes every value in a TagStruct, identifying its type.
+type Tag byte
+
+const (
+	TagString       Tag = 't'
+	TagStringNull   Tag = 'N'
+	TagU32          Tag = 'L'
+	TagU8           Tag = 'B'
+	TagU64          Tag = 'R'
+	TagS64          Tag = 'r'
+	TagSampleSpec   Tag = 'a'
+	TagArbitrary    Tag = 'x'
+	TagBooleanTrue  Tag = '1'
+	TagBooleanFalse Tag = '0'
+	TagTimeval      Tag = 'T'
+	TagUsec         Tag = 'U'
+	TagChannelMap   Tag = 'm'
+	TagCVolume      Tag = 'v'
+	TagPropList     Tag = 'P'
+	TagVolume       Tag = 'V'
+	TagFormatInfo   Tag = 'f'
+)
+
+// A TagStruct is the serialization format of control packets: a sequence of
+// tagged values, read back in the order they were written.  Get methods
+// return ErrProtocol if the next value is missing or of another type.
+type TagStruct struct {
+	data   []byte
+	rindex int
+}
+
+// Create an empty TagStruct for writing.
+func NewTagStruct() *TagStruct {
+	return &TagStruct{}
+}
+
+// Wrap the given data for reading.
+func ParseTagStruct(data []byte) *TagStruct {
+	return &TagStruct{
+		data: data,
+	}
+}
+
+// Return the serialized TagStruct.
+func (self *TagStruct) Bytes() []byte {
+	return self.data
+}
+
+// Return whether every value has been read.
+func (self *TagStruct) Eof() bool {
+	return self.rindex >= len(self.data)
+}
+
+func (self *TagStruct) put(tag Tag, data ...byte) {
+	self.data = append(self.data, byte(tag))
+	self.data = append(self.data, data...)
+}
+
+func (self *TagStruct) putRaw32(v uint32) {
+	var raw [4]byte
+
+	binary.BigEndian.PutUint32(raw[:], v)
+	self.data = append(self.data, raw[:]...)
+}
+
+func (self *TagStruct) putRaw64(v uint64) {
+	var raw [8]byte
+
+	binary.BigEndian.PutUint64(raw[:], v)
+	self.data = append(self.data, raw[:]...)
+}
+
+func (self *TagStruct) PutU8(v uint8) {
+	self.put(TagU8, v)
+}
+
+func (self *TagStruct) PutU32(v uint32) {
+	self.put(TagU32)
+	self.putRaw32(v)
+}
+
+func (self *TagStruct) PutU64(v uint64) {
+	self.put(TagU64)
+	self.putRaw64(v)
+}
+
+func (self *TagStruct) PutS64(v int64) {
+	self.put(TagS64)
+	self.putRaw64(uint64(v))
+}
+
+// Put a duration in microseconds.
+func (self *TagStruct) PutUsec(v uint64) {
+	self.put(TagUsec)
+	self.putRaw64(v)
+}
+
+func (self *TagStruct) PutVolume(v uint32) {
+	self.put(TagVolume)
+	self.putRaw32(v)
+}
+
+func (self *TagStruct) PutBool(v bool) {
+	if v {
+		self.put(TagBooleanTrue)
+	} else {
+		self.put(TagBooleanFalse)
+	}
+}
+
+func (self *TagStruct) PutString(v string) {
+	self.put(TagString, []byte(v)...)
+	self.data = append(self.data, 0)
+}
+
+// Put a string, or a null string if it is empty.  Requests that identify an
+// object by index or by name expect the unused name to be null.
+func (self *TagStruct) PutNullableString(v string) {
+	if v == `` {
+		self.put(TagStringNull)
+	} else {
+		self.PutString(v)
+	}
+}
+
+func (self *TagStruct) PutArbitrary(v []byte) {
+	self.put(TagArbitrary)
+	self.putRaw32(uint32(len(v)))
+	self.data = append(self.data, v...)
+}
+
+func (self *TagStruct) PutSampleSpec(v SampleSpec) {
+	self.put(TagSampleSpec, v.Format, v.Channels)
+	self.putRaw32(v.Rate)
+}
+
+func (self *TagStruct) PutChannelMap(v ChannelMap) {
+	self.put(TagChannelMap, uint8(len(v)))
+	self.data = append(self.data, v...)
+}
+
+func (self *TagStruct) PutCVolume(v CVolume) {
+	self.put(TagCVolume, uint8(len(v)))
+
+	for _, volume := range v {
+		self.putRaw32(volume)
+	}
+}
+
+// Put a property list.  Keys are written in sorted order, and values are
+// written NUL-terminated, as libpulse does for string properties.
+func (self *TagStruct) PutPropList(v PropList) {
+	keys := make([]string, 0, len(v))
+
+	for key := range v {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	self.put(TagPropList)
+
+	for _, key := range keys {
+		value := append([]byte(v[key]), 0)
+
+		self.PutString(key)
+		self.PutU32(uint32(len(value)))
+		self.PutArbitrary(value)
+	}
+
+	self.put(TagStringNull)
+}
+
+func (self *TagStruct) PutFormatInfo(v FormatInfo) {
+	self.put(TagFormatInfo)
+	self.PutU8(v.Encoding)
+	self.PutPropList(v.PropList)
+}
+
+func (self *TagStruct) take(n int) ([]byte, error) {
+	if n < 0 || self.rindex+n > len(self.data) {
+		return nil, ErrProtocol
+	}
+
+	data := self.data[self.rindex : self.rindex+n]
+	self.rindex += n
+
+	return data, nil
+}
+
+func (self *TagStruct) expect(tag Tag) error {
+	if data, err := self.take(1); err != nil {
+		return err
+	} else if Tag(data[0]) != tag {
+		self.rindex--
+		return ErrProtocol
+	}
+
+	return nil
+}
+
+func (self *TagStruct) peek() Tag {
+	if self.Eof() {
+		return 0
+	}
+
+	return Tag(self.data[self.rindex])
+}
+
+func (self *TagStruct) getRaw32() (uint32, error) {
+	if data, err := self.take(4); err == nil {
+		return binary.BigEndian.Uint32(data), nil
+	} else {
+		return 0, err
+	}
+}
+
+func (self *TagStruct) getRaw64(tag Tag) (uint64, error) {
+	if err := self.expect(tag); err != nil {
+		return 0, err
+	}
+
+	if data, err := self.take(8); err == nil {
+		return binary.BigEndian.Uint64(data), nil
+	} else {
+		return 0, err
+	}
+}
+
+func (self *TagStruct) GetU8() (uint8, error) {
+	if err := self.expect(TagU8); err != nil {
+		return 0, err
+	}
+
+	if data, err := self.take(1); err == nil {
+		return data[0], nil
+	} else {
+		return 0, err
+	}
+}
+
+func (self *TagStruct) GetU32() (uint32, error) {
+	if err := self.expect(TagU32); err != nil {
+		return 0, err
+	}
+
+	return self.getRaw32()
+}
+
+func (self *TagStruct) GetU64() (uint64, error) {
+	return self.getRaw64(TagU64)
+}
+
+func (self *TagStruct) GetS64() (int64, error) {
+	v, err := self.getRaw64(TagS64)
+	return int64(v), err
+}
+
+func (self *TagStruct) GetUsec() (uint64, error) {
+	return self.getRaw64(TagUsec)
+}
+
+func (self *TagStruct) GetVolume() (uint32, error) {
+	if err := self.expect(TagVolume); err != nil {
+		return 0, err
+	}
+
+	return self.getRaw32()
+}
+
+func (self *TagStruct) GetBool() (bool, error) {
+	switch self.peek() {
+	case TagBooleanTrue:
+		self.rindex++
+		return true, nil
+	case TagBooleanFalse:
+		self.rindex++
+		return false, nil
+	default:
+		return false, ErrProtocol
+	}
+}
+
+// Get a string; a null string is returned as an empty one.
+func (self *TagStruct) GetString() (string, error) {
+	switch self.peek() {
+	case TagStringNull:
+		self.rindex++
+		return ``, nil
+	case TagString:
+		self.rindex++
+
+		for i := self.rindex; i < len(self.data); i++ {
+			if self.data[i] == 0 {
+				value := string(self.data[self.rindex:i])
+				self.rindex = i + 1
+				return value, nil
+			}
+		}
+
+		self.rindex--
+		return ``, ErrProtocol
+	default:
+		return ``, ErrProtocol
+	}
+}
+
+func (self *TagStruct) GetArbitrary() ([]byte, error) {
+	if err := self.expect(TagArbitrary); err != nil {
+		return nil, err
+	}
+
+	if length, err := self.getRaw32(); err == nil {
+		return self.take(int(length))
+	} else {
+		return nil, err
+	}
+}
+
+func (self *TagStruct) GetSampleSpec() (SampleSpec, error) {
+	if err := self.expect(TagSampleSpec); err != nil {
+		return SampleSpec{}, err
+	}
+
+	if data, err := self.take(6); err == nil {
+		return SampleSpec{
+			Format:   data[0],
+			Channels: data[1],
+			Rate:     binary.BigEndian.Uint32(data[2:]),
+		}, nil
+	} else {
+		return SampleSpec{}, err
+	}
+}
+
+func (self *TagStruct) GetChannelMap() (ChannelMap, error) {
+	if err := self.expect(TagChannelMap); err != nil {
+		return nil, err
+	}
+
+	if n, err := self.take(1); err != nil {
+		return nil, err
+	} else if data, err := self.take(int(n[0])); err == nil {
+		return append(ChannelMap{}, data...), nil
+	} else {
+		return nil, err
+	}
+}
+
+func (self *TagStruct) GetCVolume() (CVolume, error) {
+	if err := self.expect(TagCVolume); err != nil {
+		return nil, err
+	}
+
+	n, err := self.take(1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make(CVolume, int(n[0]))
+
+	for i := range rv {
+		if rv[i], err = self.getRaw32(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+// Get a property list.  A single trailing NUL is stripped from each value.
+func (self *TagStruct) GetPropList() (PropList, error) {
+	if err := self.expect(TagPropList); err != nil {
+		return nil, err
+	}
+
+	rv := make(PropList)
+
+	for {
+		if self.peek() == TagStringNull {
+			self.rindex++
+			return rv, nil
+		}
+
+		key, err := self.GetString()
+
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := self.GetU32()
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := self.GetArbitrary()
+
+		if err != nil {
+			return nil, err
+		} else if len(value) != int(length) {
+			return nil, ErrProtocol
+		}
+
+		if n := len(value); n > 0 && value[n-1] == 0 {
+			value = value[:n-1]
+		}
+
+		rv[key] = string(value)
+	}
+}
+
+func (self *TagStruct) GetFormatInfo() (FormatInfo, error) {
+	var rv FormatInfo
+	var err error
+
+	if err = self.expect(TagFormatInfo); err != nil {
+		return rv, err
+	}
+
+	if rv.Encoding, err = self.GetU8(); err != nil {
+		return rv, err
+	}
+
+	rv.PropList, err = self.GetPropList()
+	return rv, err
+}
@@ -0,0 +1,182 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagStructRoundTrip(t *testing.T) {
+	assert := require.New(t)
+	ts := NewTagStruct()
+
+	ts.PutU8(7)
+	ts.PutU32(0xDEADBEEF)
+	ts.PutU64(1 << 40)
+	ts.PutS64(-42)
+	ts.PutUsec(123456)
+	ts.PutVolume(VolumeNorm)
+	ts.PutBool(true)
+	ts.PutBool(false)
+	ts.PutString(`hello`)
+	ts.PutNullableString(``)
+	ts.PutArbitrary([]byte{1, 2, 3})
+	ts.PutSampleSpec(SampleSpec{Format: 3, Channels: 2, Rate: 44100})
+	ts.PutChannelMap(ChannelMap{1, 2})
+	ts.PutCVolume(CVolume{VolumeNorm, VolumeNorm / 2})
+	ts.PutPropList(PropList{`media.name`: `test`, `application.name`: `app`})
+	ts.PutFormatInfo(FormatInfo{Encoding: EncodingPCM, PropList: PropList{}})
+
+	rt := ParseTagStruct(ts.Bytes())
+
+	u8, err := rt.GetU8()
+	assert.NoError(err)
+	assert.Equal(uint8(7), u8)
+
+	u32, err := rt.GetU32()
+	assert.NoError(err)
+	assert.Equal(uint32(0xDEADBEEF), u32)
+
+	u64, err := rt.GetU64()
+	assert.NoError(err)
+	assert.Equal(uint64(1<<40), u64)
+
+	s64, err := rt.GetS64()
+	assert.NoError(err)
+	assert.Equal(int64(-42), s64)
+
+	usec, err := rt.GetUsec()
+	assert.NoError(err)
+	assert.Equal(uint64(123456), usec)
+
+	volume, err := rt.GetVolume()
+	assert.NoError(err)
+	assert.Equal(VolumeNorm, volume)
+
+	b, err := rt.GetBool()
+	assert.NoError(err)
+	assert.True(b)
+
+	b, err = rt.GetBool()
+	assert.NoError(err)
+	assert.False(b)
+
+	str, err := rt.GetString()
+	assert.NoError(err)
+	assert.Equal(`hello`, str)
+
+	str, err = rt.GetString()
+	assert.NoError(err)
+	assert.Equal(``, str)
+
+	arb, err := rt.GetArbitrary()
+	assert.NoError(err)
+	assert.Equal([]byte{1, 2, 3}, arb)
+
+	spec, err := rt.GetSampleSpec()
+	assert.NoError(err)
+	assert.Equal(SampleSpec{Format: 3, Channels: 2, Rate: 44100}, spec)
+
+	cmap, err := rt.GetChannelMap()
+	assert.NoError(err)
+	assert.Equal(ChannelMap{1, 2}, cmap)
+
+	cvol, err := rt.GetCVolume()
+	assert.NoError(err)
+	assert.Equal(CVolume{VolumeNorm, VolumeNorm / 2}, cvol)
+
+	props, err := rt.GetPropList()
+	assert.NoError(err)
+	assert.Equal(PropList{`media.name`: `test`, `application.name`: `app`}, props)
+
+	format, err := rt.GetFormatInfo()
+	assert.NoError(err)
+	assert.Equal(EncodingPCM, format.Encoding)
+
+	assert.True(rt.Eof())
+
+	_, err = rt.GetU32()
+	assert.Equal(ErrProtocol, err)
+}
+
+func TestTagStructTypeMismatch(t *testing.T) {
+	assert := require.New(t)
+	ts := NewTagStruct()
+	ts.PutString(`nope`)
+
+	_, err := ParseTagStruct(ts.Bytes()).GetU32()
+	assert.Equal(ErrProtocol, err)
+}
+
+func TestSinkInfoVersions(t *testing.T) {
+	assert := require.New(t)
+
+	sink := SinkInfo{
+		Index:             1,
+		Name:              `speakers`,
+		Description:       `Speakers`,
+		SampleSpec:        SampleSpec{Format: 3, Channels: 2, Rate: 48000},
+		ChannelMap:        ChannelMap{1, 2},
+		OwnerModule:       InvalidIndex,
+		Volume:            CVolume{VolumeNorm, VolumeNorm},
+		MonitorSource:     2,
+		MonitorSourceName: `speakers.monitor`,
+		Driver:            `module-null-sink.c`,
+		PropList:          PropList{`device.class`: `sound`},
+		BaseVolume:        VolumeNorm,
+		NumVolumeSteps:    VolumeNorm + 1,
+		Card:              InvalidIndex,
+		Ports:             []PortInfo{{Name: `analog-output`, Description: `Analog Output`, Priority: 100}},
+		ActivePort:        `analog-output`,
+		Formats:           []FormatInfo{{Encoding: EncodingPCM, PropList: PropList{}}},
+	}
+
+	for _, version := range []uint32{MinProtocolVersion, ProtocolVersion} {
+		ts := NewTagStruct()
+		sink.Put(ts, version)
+
+		var got SinkInfo
+		assert.NoError(got.Get(ParseTagStruct(ts.Bytes()), version))
+		assert.Equal(sink.Name, got.Name)
+		assert.Equal(sink.Volume, got.Volume)
+		assert.Equal(sink.MonitorSourceName, got.MonitorSourceName)
+
+		if version >= 16 {
+			assert.Equal(sink.Ports, got.Ports)
+			assert.Equal(sink.ActivePort, got.ActivePort)
+		} else {
+			assert.Empty(got.Ports)
+		}
+	}
+}
+
+func TestPacketFraming(t *testing.T) {
+	assert := require.New(t)
+	var buf bytes.Buffer
+
+	args := NewTagStruct()
+	args.PutU32(5)
+
+	assert.NoError(WritePacket(&buf, NewCommandPacket(CommandGetSinkInfo, 9, args)))
+	assert.NoError(WritePacket(&buf, &Packet{Channel: 3, Offset: -4, Flags: 1, Data: []byte{1, 2, 3, 4}}))
+	assert.Equal(2*DescriptorSize+15+4, buf.Len())
+
+	packet, err := ReadPacket(&buf)
+	assert.NoError(err)
+	assert.True(packet.IsControl())
+
+	command, tag, rest, err := packet.Command()
+	assert.NoError(err)
+	assert.Equal(CommandGetSinkInfo, command)
+	assert.Equal(uint32(9), tag)
+
+	index, err := rest.GetU32()
+	assert.NoError(err)
+	assert.Equal(uint32(5), index)
+
+	packet, err = ReadPacket(&buf)
+	assert.NoError(err)
+	assert.False(packet.IsControl())
+	assert.Equal(&Packet{Channel: 3, Offset: -4, Flags: 1, Data: []byte{1, 2, 3, 4}}, packet)
+}
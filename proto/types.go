@@ -0,0 +1,101 @@
+package proto
+
+const (
+	// The protocol version announced by this package.  Replies are decoded
+	// according to the lower of this and the server's version.
+	ProtocolVersion uint32 = 32
+
+	// The oldest server protocol version (PulseAudio 0.9.11) this package can
+	// talk to.
+	MinProtocolVersion uint32 = 13
+
+	// Masks the version number out of the AUTH request and reply, whose upper
+	// bits carry shared memory capabilities.
+	VersionMask uint32 = 0x0000FFFF
+
+	// The index used to mean "no object", e.g.: a sink without a card.
+	InvalidIndex uint32 = 0xFFFFFFFF
+
+	// The volume of a channel at 100%, without amplification.
+	VolumeNorm uint32 = 0x10000
+
+	// The value of buffer metrics left to the server to choose.
+	DefaultBufferValue uint32 = 0xFFFFFFFF
+
+	// The FormatInfo encoding of plain PCM audio.
+	EncodingPCM uint8 = 1
+)
+
+// A SampleSpec is the wire form of a pa_sample_spec.
+type SampleSpec struct {
+	Format   uint8
+	Channels uint8
+	Rate     uint32
+}
+
+// A ChannelMap lists the pa_channel_position_t of each channel.
+type ChannelMap []uint8
+
+// A CVolume holds the volume of each channel.
+type CVolume []uint32
+
+// Return a volume with the given number of channels, all set to v.
+func NewCVolume(channels int, v uint32) CVolume {
+	rv := make(CVolume, channels)
+
+	for i := range rv {
+		rv[i] = v
+	}
+
+	return rv
+}
+
+// Return the average volume of all channels, as pa_cvolume_avg does.
+func (self CVolume) Avg() uint32 {
+	if len(self) == 0 {
+		return 0
+	}
+
+	var sum uint64
+
+	for _, v := range self {
+		sum += uint64(v)
+	}
+
+	return uint32(sum / uint64(len(self)))
+}
+
+// Return the lowest volume of all channels.
+func (self CVolume) Min() uint32 {
+	var rv uint32
+
+	for i, v := range self {
+		if i == 0 || v < rv {
+			rv = v
+		}
+	}
+
+	return rv
+}
+
+// Return the highest volume of all channels.
+func (self CVolume) Max() uint32 {
+	var rv uint32
+
+	for _, v := range self {
+		if v > rv {
+			rv = v
+		}
+	}
+
+	return rv
+}
+
+// A PropList holds the string properties of an object.
+type PropList map[string]string
+
+// A FormatInfo describes the encoding of a stream and its parameters.
+type FormatInfo struct {
+	Encoding uint8
+	PropList PropList
+}
@@ -0,0 +1,1148 @@
+package pulsefake
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/proto"
+)
+
+// A NativeHandler answers a request in place of a NativeServer, given the
+// request's arguments and the protocol version agreed with the client.  The
+// returned TagStruct (which may be nil) is sent as the reply; an error is sent
+// as the error code it wraps (see errorCode).
+type NativeHandler func(args *proto.TagStruct, version uint32) (*proto.TagStruct, error)
+
+// A NativeServer serves a fake Server's state over PulseAudio's native protocol
+// on a private unix socket, so that real clients (libpulse via pulse.Conn, or
+// a proto.Client) can be tested against it.
+//
+// Introspection, volume, mute, default device, module, and subscription
+// requests are answered through the Server's pulse.Server methods, so they are
+// recorded in its Calls and honour its Fail and FailNext scripting.  Requests
+// the NativeServer doesn't implement (e.g.: creating streams) fail with
+// proto.ErrNotImplemented.
+type NativeServer struct {
+	// The path of the server's socket.
+	Socket string
+
+	// The address to pass to pulse.NewWithServer.
+	Address string
+
+	// The protocol version announced to clients.  Replies are encoded for the
+	// lower of this and the client's version.
+	Version uint32
+
+	server   *Server
+	dir      string
+	listener net.Listener
+	handlers map[proto.Command]NativeHandler
+	failNext map[proto.Command][]proto.ErrorCode
+	delays   map[proto.Command]time.Duration
+	dropNext map[proto.Command]int
+	commands []proto.Command
+	conns    map[*nativeConn]bool
+	closed   chan struct{}
+	lock     sync.Mutex
+}
+
+// Start serving the given fake Server on a socket in a new temporary directory.
+// Close the NativeServer to remove it.
+func NewNativeServer(server *Server) (*NativeServer, error) {
+	dir, err := ioutil.TempDir(``, `pulsefake`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	socket := filepath.Join(dir, `native`)
+	listener, err := net.Listen(`unix`, socket)
+
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	rv := &NativeServer{
+		Socket:   socket,
+		Address:  `unix:` + socket,
+		Version:  proto.ProtocolVersion,
+		server:   server,
+		dir:      dir,
+		listener: listener,
+		handlers: make(map[proto.Command]NativeHandler),
+		failNext: make(map[proto.Command][]proto.ErrorCode),
+		delays:   make(map[proto.Command]time.Duration),
+		dropNext: make(map[proto.Command]int),
+		commands: make([]proto.Command, 0),
+		conns:    make(map[*nativeConn]bool),
+		closed:   make(chan struct{}),
+	}
+
+	go rv.accept()
+
+	return rv, nil
+}
+
+// Answer every subsequent request for the given command with the given
+// handler, instead of the server's own implementation.  A nil handler restores
+// the default.
+func (self *NativeServer) Handle(command proto.Command, handler NativeHandler) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if handler == nil {
+		delete(self.handlers, command)
+	} else {
+		self.handlers[command] = handler
+	}
+}
+
+// Make only the next request for the given command fail with the given error
+// code (e.g.: proto.ErrTimeout).  Repeated calls queue up further failures.
+func (self *NativeServer) FailNext(command proto.Command, code proto.ErrorCode) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failNext[command] = append(self.failNext[command], code)
+}
+
+// Wait for the given duration before answering each request for the given
+// command.  Requests are answered in order, so later requests on the same
+// connection are held up as well.  A zero duration removes the delay.
+func (self *NativeServer) Delay(command proto.Command, delay time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if delay <= 0 {
+		delete(self.delays, command)
+	} else {
+		self.delays[command] = delay
+	}
+}
+
+// Drop the connection instead of answering the next request for the given
+// command.  Repeated calls drop further requests.
+func (self *NativeServer) DropNext(command proto.Command) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.dropNext[command]++
+}
+
+// Drop every connected client.
+func (self *NativeServer) Disconnect() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for conn := range self.conns {
+		conn.conn.Close()
+	}
+}
+
+// Return the commands received so far (including the handshake), in order.
+func (self *NativeServer) Commands() []proto.Command {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return append([]proto.Command(nil), self.commands...)
+}
+
+// Stop accepting connections, drop all clients, and remove the socket.
+func (self *NativeServer) Close() error {
+	select {
+	case <-self.closed:
+		return nil
+	default:
+		close(self.closed)
+	}
+
+	err := self.listener.Close()
+	self.Disconnect()
+	os.RemoveAll(self.dir)
+
+	return err
+}
+
+func (self *NativeServer) accept() {
+	for {
+		conn, err := self.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		client := &nativeConn{
+			server: self,
+			conn:   conn,
+		}
+
+		self.lock.Lock()
+		self.conns[client] = true
+		self.lock.Unlock()
+
+		go client.serve()
+	}
+}
+
+// record a request and return how it has been scripted to be answered
+func (self *NativeServer) script(command proto.Command) (drop bool, delay time.Duration, fail proto.ErrorCode, handler NativeHandler) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.commands = append(self.commands, command)
+
+	if self.dropNext[command] > 0 {
+		self.dropNext[command]--
+		return true, 0, proto.ErrOK, nil
+	}
+
+	if queued := self.failNext[command]; len(queued) > 0 {
+		self.failNext[command] = queued[1:]
+		fail = queued[0]
+	}
+
+	return false, self.delays[command], fail, self.handlers[command]
+}
+
+// One client connection.
+type nativeConn struct {
+	server    *NativeServer
+	conn      net.Conn
+	version   uint32
+	client    *pulse.Client
+	sub       *pulse.Subscription
+	mask      uint32
+	writeLock sync.Mutex
+}
+
+func (self *nativeConn) serve() {
+	defer self.close()
+
+	for {
+		packet, err := proto.ReadPacket(self.conn)
+
+		if err != nil {
+			return
+		}
+
+		// stream data is not supported, and is discarded
+		if !packet.IsControl() {
+			continue
+		}
+
+		command, tag, args, err := packet.Command()
+
+		if err != nil {
+			return
+		}
+
+		drop, delay, fail, handler := self.server.script(command)
+
+		if drop {
+			return
+		}
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-self.server.closed:
+				return
+			}
+		}
+
+		var reply *proto.TagStruct
+
+		if fail != proto.ErrOK {
+			err = fail
+		} else if handler != nil {
+			reply, err = handler(args, self.version)
+		} else {
+			reply, err = self.handle(command, args)
+		}
+
+		if err == nil {
+			err = self.send(proto.NewCommandPacket(proto.CommandReply, tag, reply))
+		} else {
+			t := proto.NewTagStruct()
+			t.PutU32(uint32(errorCode(err)))
+			err = self.send(proto.NewCommandPacket(proto.CommandError, tag, t))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (self *nativeConn) send(packet *proto.Packet) error {
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+
+	return proto.WritePacket(self.conn, packet)
+}
+
+func (self *nativeConn) close() {
+	self.conn.Close()
+
+	self.server.lock.Lock()
+	delete(self.server.conns, self)
+	self.server.lock.Unlock()
+
+	if self.sub != nil {
+		self.sub.Close()
+	}
+
+	if self.client != nil {
+		self.server.server.Remove(pulse.ClientEvent, self.client.Index)
+	}
+}
+
+// Pass the events the client subscribed to along to it.
+func (self *nativeConn) forward(sub *pulse.Subscription) {
+	for event := range sub.Events() {
+		if atomic.LoadUint32(&self.mask)&uint32(event.Facility) != 0 {
+			t := proto.NewTagStruct()
+			t.PutU32(eventCode(event))
+			t.PutU32(uint32(event.Index))
+
+			self.send(proto.NewCommandPacket(proto.CommandSubscribeEvent, proto.NoTag, t))
+		}
+	}
+}
+
+// Answer a request from the fake Server's state.
+func (self *nativeConn) handle(command proto.Command, t *proto.TagStruct) (*proto.TagStruct, error) {
+	fake := self.server.server
+	args := &nativeArgs{t: t}
+	reply := proto.NewTagStruct()
+
+	switch command {
+	case proto.CommandAuth:
+		version := args.u32() & proto.VersionMask
+		args.arbitrary()
+
+		if args.err != nil {
+			return nil, args.err
+		} else if version < proto.MinProtocolVersion {
+			return nil, proto.ErrVersion
+		}
+
+		self.version = self.server.Version
+
+		if version < self.version {
+			self.version = version
+		}
+
+		reply.PutU32(self.server.Version)
+		return reply, nil
+
+	case proto.CommandSetClientName:
+		props := args.propList()
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		if self.client == nil {
+			self.client = fake.AddClient(pulse.Client{
+				Name:             props[`application.name`],
+				OwnerModuleIndex: -1,
+				Driver:           `protocol-native.c`,
+				PropList:         pulse.PropList(props),
+			})
+		} else {
+			fake.lock.Lock()
+			self.client.Name = props[`application.name`]
+			self.client.PropList = pulse.PropList(props)
+			fake.lock.Unlock()
+
+			fake.Emit(pulse.ClientEvent, pulse.EventChange, self.client.Index)
+		}
+
+		reply.PutU32(uint32(self.client.Index))
+		return reply, nil
+
+	case proto.CommandGetServerInfo:
+		if info, err := fake.GetServerInfo(); err == nil {
+			serverInfo(info).Put(reply, self.version)
+			return reply, nil
+		} else {
+			return nil, err
+		}
+
+	case proto.CommandGetSinkInfo, proto.CommandGetSinkInfoList:
+		sinks, err := fake.GetSinks()
+
+		if err != nil {
+			return nil, err
+		}
+
+		spec := self.sampleSpec()
+		index, name, single := args.device(command == proto.CommandGetSinkInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for _, sink := range sinks {
+			if !single || uint32(sink.Index) == index || (name != `` && sink.Name == name) {
+				info := sinkInfo(sink, spec)
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandGetSourceInfo, proto.CommandGetSourceInfoList:
+		sources, err := fake.GetSources()
+
+		if err != nil {
+			return nil, err
+		}
+
+		spec := self.sampleSpec()
+		index, name, single := args.device(command == proto.CommandGetSourceInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for _, source := range sources {
+			if !single || uint32(source.Index) == index || (name != `` && source.Name == name) {
+				info := sourceInfo(source, spec)
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandGetSinkInputInfo, proto.CommandGetSinkInputInfoList:
+		sinkInputs, err := fake.GetSinkInputs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		spec := self.sampleSpec()
+		index, single := args.index(command == proto.CommandGetSinkInputInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for i := range sinkInputs {
+			if !single || uint32(sinkInputs[i].Index) == index {
+				info := sinkInputInfo(&sinkInputs[i], spec)
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandGetSourceOutputInfo, proto.CommandGetSourceOutputInfoList:
+		sourceOutputs, err := fake.GetSourceOutputs()
+
+		if err != nil {
+			return nil, err
+		}
+
+		spec := self.sampleSpec()
+		index, single := args.index(command == proto.CommandGetSourceOutputInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for i := range sourceOutputs {
+			if !single || uint32(sourceOutputs[i].Index) == index {
+				info := sourceOutputInfo(&sourceOutputs[i], spec)
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandGetModuleInfo, proto.CommandGetModuleInfoList:
+		modules, err := fake.GetModules()
+
+		if err != nil {
+			return nil, err
+		}
+
+		index, single := args.index(command == proto.CommandGetModuleInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for _, module := range modules {
+			if !single || uint32(module.Index) == index {
+				info := proto.ModuleInfo{
+					Index:    uint32(module.Index),
+					Name:     module.Name,
+					Argument: module.Argument,
+					NumUsed:  proto.InvalidIndex,
+					PropList: proto.PropList(module.PropList),
+				}
+
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandGetClientInfo, proto.CommandGetClientInfoList:
+		clients, err := fake.GetClients()
+
+		if err != nil {
+			return nil, err
+		}
+
+		index, single := args.index(command == proto.CommandGetClientInfo)
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		for _, client := range clients {
+			if !single || uint32(client.Index) == index {
+				info := proto.ClientInfo{
+					Index:       uint32(client.Index),
+					Name:        client.Name,
+					OwnerModule: uint32(client.OwnerModuleIndex),
+					Driver:      client.Driver,
+					PropList:    proto.PropList(client.PropList),
+				}
+
+				info.Put(reply, self.version)
+
+				if single {
+					return reply, nil
+				}
+			}
+		}
+
+		return single.orNoEntity(reply)
+
+	case proto.CommandSetDefaultSink:
+		if name := args.str(); args.err == nil {
+			return nil, fake.SetDefaultSink(name)
+		}
+
+	case proto.CommandSetDefaultSource:
+		if name := args.str(); args.err == nil {
+			return nil, fake.SetDefaultSource(name)
+		}
+
+	case proto.CommandSetSinkVolume:
+		index, name, _ := args.device(true)
+		volume := args.cvolume()
+
+		if args.err == nil {
+			if sink, ok := self.server.findSink(index, name); ok {
+				if volume.Min() != volume.Max() {
+					return nil, fake.SetSinkChannelVolumes(sink.Index, volumeFactors(volume, sink.NumVolumeSteps))
+				}
+
+				return nil, fake.SetSinkVolume(sink.Index, volumeFactor(volume, sink.NumVolumeSteps))
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSinkMute:
+		index, name, _ := args.device(true)
+		mute := args.boolean()
+
+		if args.err == nil {
+			if sink, ok := self.server.findSink(index, name); ok {
+				return nil, fake.SetSinkMute(sink.Index, mute)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSinkPort:
+		index, name, _ := args.device(true)
+		port := args.str()
+
+		if args.err == nil {
+			if sink, ok := self.server.findSink(index, name); ok {
+				return nil, fake.SetSinkPort(sink.Index, port)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSourceVolume:
+		index, name, _ := args.device(true)
+		volume := args.cvolume()
+
+		if args.err == nil {
+			if source, ok := self.server.findSource(index, name); ok {
+				return nil, fake.SetSourceVolume(source.Index, volumeFactor(volume, source.NumVolumeSteps))
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSourceMute:
+		index, name, _ := args.device(true)
+		mute := args.boolean()
+
+		if args.err == nil {
+			if source, ok := self.server.findSource(index, name); ok {
+				return nil, fake.SetSourceMute(source.Index, mute)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSourcePort:
+		index, name, _ := args.device(true)
+		port := args.str()
+
+		if args.err == nil {
+			if source, ok := self.server.findSource(index, name); ok {
+				return nil, fake.SetSourcePort(source.Index, port)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandSetSinkInputVolume:
+		index := args.u32()
+		volume := args.cvolume()
+
+		if args.err == nil {
+			if volume.Min() != volume.Max() {
+				return nil, fake.SetSinkInputChannelVolumes(int(index), volumeFactors(volume, pulse.DefaultVolumeStep))
+			}
+
+			return nil, fake.SetSinkInputVolume(int(index), volumeFactor(volume, pulse.DefaultVolumeStep))
+		}
+
+	case proto.CommandSetSinkInputMute:
+		index := args.u32()
+		mute := args.boolean()
+
+		if args.err == nil {
+			return nil, fake.SetSinkInputMute(int(index), mute)
+		}
+
+	case proto.CommandSetSourceOutputVolume:
+		index := args.u32()
+		volume := args.cvolume()
+
+		if args.err == nil {
+			return nil, fake.SetSourceOutputVolume(int(index), volumeFactor(volume, pulse.DefaultVolumeStep))
+		}
+
+	case proto.CommandSetSourceOutputMute:
+		index := args.u32()
+		mute := args.boolean()
+
+		if args.err == nil {
+			return nil, fake.SetSourceOutputMute(int(index), mute)
+		}
+
+	case proto.CommandMoveSinkInput:
+		index := args.u32()
+		sinkIndex, sinkName, _ := args.device(true)
+
+		if args.err == nil {
+			if sink, ok := self.server.findSink(sinkIndex, sinkName); ok {
+				return nil, fake.MoveSinkInput(int(index), sink.Index)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandMoveSourceOutput:
+		index := args.u32()
+		sourceIndex, sourceName, _ := args.device(true)
+
+		if args.err == nil {
+			if source, ok := self.server.findSource(sourceIndex, sourceName); ok {
+				return nil, fake.MoveSourceOutput(int(index), source.Index)
+			} else {
+				return nil, proto.ErrNoEntity
+			}
+		}
+
+	case proto.CommandKillSinkInput:
+		if index := args.u32(); args.err == nil {
+			return nil, fake.KillSinkInput(int(index))
+		}
+
+	case proto.CommandKillSourceOutput:
+		if index := args.u32(); args.err == nil {
+			return nil, fake.KillSourceOutput(int(index))
+		}
+
+	case proto.CommandLoadModule:
+		name := args.str()
+		argument := args.str()
+
+		if args.err == nil {
+			// LoadModule doesn't return the module, but it will be given the
+			// next index
+			fake.lock.Lock()
+			index := fake.nextIndex[pulse.ModuleEvent]
+			fake.lock.Unlock()
+
+			if err := fake.LoadModule(name, argument); err != nil {
+				return nil, err
+			}
+
+			reply.PutU32(uint32(index))
+			return reply, nil
+		}
+
+	case proto.CommandUnloadModule:
+		if index := args.u32(); args.err == nil {
+			return nil, fake.UnloadModule(int(index))
+		}
+
+	case proto.CommandSubscribe:
+		mask := args.u32()
+
+		if args.err != nil {
+			return nil, args.err
+		}
+
+		if self.sub == nil {
+			if sub, err := fake.SubscribeEvents(); err == nil {
+				self.sub = sub
+				go self.forward(sub)
+			} else {
+				return nil, err
+			}
+		}
+
+		atomic.StoreUint32(&self.mask, mask)
+		return nil, nil
+
+	default:
+		return nil, proto.ErrNotImplemented
+	}
+
+	return nil, args.err
+}
+
+// the sample spec devices and streams are reported with
+func (self *nativeConn) sampleSpec() proto.SampleSpec {
+	self.server.server.lock.Lock()
+	defer self.server.server.lock.Unlock()
+
+	return sampleSpec(self.server.server.info)
+}
+
+// find a sink by index or, if the index is invalid, by name
+func (self *NativeServer) findSink(index uint32, name string) (pulse.Sink, bool) {
+	self.server.lock.Lock()
+	defer self.server.lock.Unlock()
+
+	for _, sink := range self.server.sinks {
+		if uint32(sink.Index) == index || (index == proto.InvalidIndex && sink.Name == name) {
+			return *sink, true
+		}
+	}
+
+	return pulse.Sink{}, false
+}
+
+// find a source by index or, if the index is invalid, by name
+func (self *NativeServer) findSource(index uint32, name string) (pulse.Source, bool) {
+	self.server.lock.Lock()
+	defer self.server.lock.Unlock()
+
+	for _, source := range self.server.sources {
+		if uint32(source.Index) == index || (index == proto.InvalidIndex && source.Name == name) {
+			return *source, true
+		}
+	}
+
+	return pulse.Source{}, false
+}
+
+// Reads a request's arguments, remembering the first error so that a request
+// can be decoded in full before checking.
+type nativeArgs struct {
+	t   *proto.TagStruct
+	err error
+}
+
+// a malformed request is a protocol error, as far as the client is concerned
+func (self *nativeArgs) check() {
+	if self.err != nil {
+		self.err = proto.ErrProtocol
+	}
+}
+
+func (self *nativeArgs) u32() uint32 {
+	var v uint32
+
+	if self.err == nil {
+		v, self.err = self.t.GetU32()
+		self.check()
+	}
+
+	return v
+}
+
+func (self *nativeArgs) str() string {
+	var v string
+
+	if self.err == nil {
+		v, self.err = self.t.GetString()
+		self.check()
+	}
+
+	return v
+}
+
+func (self *nativeArgs) boolean() bool {
+	var v bool
+
+	if self.err == nil {
+		v, self.err = self.t.GetBool()
+		self.check()
+	}
+
+	return v
+}
+
+func (self *nativeArgs) arbitrary() []byte {
+	var v []byte
+
+	if self.err == nil {
+		v, self.err = self.t.GetArbitrary()
+		self.check()
+	}
+
+	return v
+}
+
+func (self *nativeArgs) cvolume() proto.CVolume {
+	var v proto.CVolume
+
+	if self.err == nil {
+		v, self.err = self.t.GetCVolume()
+		self.check()
+	}
+
+	return v
+}
+
+func (self *nativeArgs) propList() proto.PropList {
+	var v proto.PropList
+
+	if self.err == nil {
+		v, self.err = self.t.GetPropList()
+		self.check()
+	}
+
+	return v
+}
+
+// Whether a request asks for a single object (rather than a list), which must
+// then exist.
+type lookup bool
+
+func (self lookup) orNoEntity(reply *proto.TagStruct) (*proto.TagStruct, error) {
+	if self {
+		return nil, proto.ErrNoEntity
+	}
+
+	return reply, nil
+}
+
+// read the index of the object a single-object request is about
+func (self *nativeArgs) index(single bool) (uint32, lookup) {
+	if single {
+		return self.u32(), true
+	}
+
+	return proto.InvalidIndex, false
+}
+
+// read the index and name of the device a request is about
+func (self *nativeArgs) device(single bool) (uint32, string, lookup) {
+	if single {
+		return self.u32(), self.str(), true
+	}
+
+	return proto.InvalidIndex, ``, false
+}
+
+// Convert an error from the fake Server (or a handler) into the code sent to
+// the client.  Errors that aren't a proto.ErrorCode are reported as
+// proto.ErrInternal, except for NoSuchEntityErr.
+func errorCode(err error) proto.ErrorCode {
+	if code, ok := err.(proto.ErrorCode); ok {
+		return code
+	} else if err == NoSuchEntityErr {
+		return proto.ErrNoEntity
+	}
+
+	return proto.ErrInternal
+}
+
+// encode an event the way the server does: the facility's number, plus the kind
+func eventCode(event pulse.Event) uint32 {
+	code := uint32(event.Kind)
+
+	for facility := uint32(0); facility < 16; facility++ {
+		if event.Facility == pulse.EventType(1<<facility) {
+			code |= facility
+		}
+	}
+
+	return code
+}
+
+func sampleSpec(info pulse.ServerInfo) proto.SampleSpec {
+	format, err := pulse.ParseSampleFormat(info.SampleFormat)
+
+	if err != nil {
+		format = pulse.FormatPcmS16LE
+	}
+
+	return proto.SampleSpec{
+		Format:   uint8(format),
+		Channels: uint8(info.Channels),
+		Rate:     uint32(info.SampleRate),
+	}
+}
+
+func serverInfo(info pulse.ServerInfo) *proto.ServerInfo {
+	spec := sampleSpec(info)
+
+	return &proto.ServerInfo{
+		PackageName:       info.Name,
+		PackageVersion:    info.Version,
+		UserName:          info.DaemonUser,
+		HostName:          info.DaemonHostname,
+		SampleSpec:        spec,
+		DefaultSinkName:   info.DefaultSinkName,
+		DefaultSourceName: info.DefaultSourceName,
+		Cookie:            uint32(info.Cookie),
+		ChannelMap:        channelMap(int(spec.Channels)),
+	}
+}
+
+// the standard channel map for the given number of channels, using auxiliary
+// channels if there is none
+func channelMap(channels int) proto.ChannelMap {
+	standard := pulse.DefaultChannelMap(channels)
+	rv := make(proto.ChannelMap, channels)
+
+	for i := range rv {
+		if i < len(standard) {
+			rv[i] = uint8(standard[i])
+		} else {
+			rv[i] = uint8(pulse.ChannelAux0) + uint8(i)
+		}
+	}
+
+	return rv
+}
+
+// the channel map of a stream with the given (named) channels
+func streamChannelMap(channels []pulse.Volume) proto.ChannelMap {
+	rv := channelMap(len(channels))
+
+	for i, channel := range channels {
+		if position, err := pulse.ParseChannelPosition(channel.Name); err == nil {
+			rv[i] = uint8(position)
+		}
+	}
+
+	return rv
+}
+
+func streamVolumes(channels []pulse.Volume) proto.CVolume {
+	rv := make(proto.CVolume, len(channels))
+
+	for i, channel := range channels {
+		rv[i] = uint32(channel.Value)
+	}
+
+	return rv
+}
+
+// the factor of the given number of volume steps a volume represents
+func volumeFactor(volume proto.CVolume, steps int) float64 {
+	if steps <= 0 {
+		steps = pulse.DefaultVolumeStep
+	}
+
+	return float64(volume.Avg()) / float64(steps)
+}
+
+// the factor of the given number of volume steps each channel of a volume
+// represents
+func volumeFactors(volume proto.CVolume, steps int) []float64 {
+	if steps <= 0 {
+		steps = pulse.DefaultVolumeStep
+	}
+
+	rv := make([]float64, len(volume))
+
+	for i, value := range volume {
+		rv[i] = float64(value) / float64(steps)
+	}
+
+	return rv
+}
+
+// the volume of a sink, per channel if it has them
+func sinkVolume(sink *pulse.Sink) proto.CVolume {
+	if len(sink.ChannelVolumes) == sink.Channels {
+		return streamVolumes(sink.ChannelVolumes)
+	}
+
+	return proto.NewCVolume(sink.Channels, uint32(sink.VolumeFactor*float64(sink.NumVolumeSteps)))
+}
+
+func pcmFormats() []proto.FormatInfo {
+	return []proto.FormatInfo{{
+		Encoding: proto.EncodingPCM,
+		PropList: proto.PropList{},
+	}}
+}
+
+func ports(active string) []proto.PortInfo {
+	if active == `` {
+		return nil
+	}
+
+	return []proto.PortInfo{{
+		Name:        active,
+		Description: active,
+	}}
+}
+
+func sinkInfo(sink *pulse.Sink, spec proto.SampleSpec) *proto.SinkInfo {
+	spec.Channels = uint8(sink.Channels)
+
+	return &proto.SinkInfo{
+		Index:             uint32(sink.Index),
+		Name:              sink.Name,
+		Description:       sink.Description,
+		SampleSpec:        spec,
+		ChannelMap:        channelMap(sink.Channels),
+		OwnerModule:       uint32(sink.ModuleIndex),
+		Volume:            sinkVolume(sink),
+		Mute:              sink.Muted,
+		MonitorSource:     uint32(sink.MonitorSourceIndex),
+		MonitorSourceName: sink.MonitorSourceName,
+		Driver:            sink.DriverName,
+		PropList:          proto.PropList(sink.PropList),
+		BaseVolume:        proto.VolumeNorm,
+		State:             uint32(sink.State),
+		NumVolumeSteps:    uint32(sink.NumVolumeSteps),
+		Card:              uint32(sink.CardIndex),
+		Ports:             ports(sink.ActivePort),
+		ActivePort:        sink.ActivePort,
+		Formats:           pcmFormats(),
+	}
+}
+
+func sourceInfo(source *pulse.Source, spec proto.SampleSpec) *proto.SourceInfo {
+	spec.Channels = uint8(source.Channels)
+
+	return &proto.SourceInfo{
+		Index:             uint32(source.Index),
+		Name:              source.Name,
+		Description:       source.Description,
+		SampleSpec:        spec,
+		ChannelMap:        channelMap(source.Channels),
+		OwnerModule:       uint32(source.ModuleIndex),
+		Volume:            proto.NewCVolume(source.Channels, uint32(source.VolumeFactor*float64(source.NumVolumeSteps))),
+		Mute:              source.Muted,
+		MonitorOfSink:     uint32(source.MonitorOfSinkIndex),
+		MonitorOfSinkName: source.MonitorOfSinkName,
+		Driver:            source.DriverName,
+		PropList:          proto.PropList(source.PropList),
+		BaseVolume:        proto.VolumeNorm,
+		State:             uint32(source.State),
+		NumVolumeSteps:    uint32(source.NumVolumeSteps),
+		Card:              uint32(source.CardIndex),
+		Ports:             ports(source.ActivePort),
+		ActivePort:        source.ActivePort,
+		Formats:           pcmFormats(),
+	}
+}
+
+func sinkInputInfo(sinkInput *pulse.SinkInput, spec proto.SampleSpec) *proto.SinkInputInfo {
+	spec.Channels = uint8(len(sinkInput.Channels))
+
+	return &proto.SinkInputInfo{
+		Index:          uint32(sinkInput.Index),
+		Name:           sinkInput.Name,
+		OwnerModule:    uint32(sinkInput.ModuleIndex),
+		Client:         uint32(sinkInput.ClientIndex),
+		Sink:           uint32(sinkInput.SinkIndex),
+		SampleSpec:     spec,
+		ChannelMap:     streamChannelMap(sinkInput.Channels),
+		Volume:         streamVolumes(sinkInput.Channels),
+		Driver:         `protocol-native.c`,
+		Mute:           sinkInput.Muted,
+		PropList:       proto.PropList(sinkInput.PropList),
+		Corked:         sinkInput.Corked,
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         pcmFormats()[0],
+	}
+}
+
+func sourceOutputInfo(sourceOutput *pulse.SourceOutput, spec proto.SampleSpec) *proto.SourceOutputInfo {
+	spec.Channels = uint8(len(sourceOutput.Channels))
+
+	return &proto.SourceOutputInfo{
+		Index:          uint32(sourceOutput.Index),
+		Name:           sourceOutput.Name,
+		OwnerModule:    uint32(sourceOutput.ModuleIndex),
+		Client:         uint32(sourceOutput.ClientIndex),
+		Source:         uint32(sourceOutput.SourceIndex),
+		SampleSpec:     spec,
+		ChannelMap:     streamChannelMap(sourceOutput.Channels),
+		Driver:         `protocol-native.c`,
+		PropList:       proto.PropList(sourceOutput.PropList),
+		Corked:         sourceOutput.Corked,
+		Volume:         streamVolumes(sourceOutput.Channels),
+		Mute:           sourceOutput.Muted,
+		HasVolume:      true,
+		VolumeWritable: true,
+		Format:         pcmFormats()[0],
+	}
+}
@@ -0,0 +1,58 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulsefake
+
+import (
+	"testing"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// libpulse is an independent client implementation, so connecting it to the
+// native server checks the server against something other than the proto
+// package that it shares with the pure-Go backend.
+func TestNativeServerLibpulse(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	server.AddSink(pulse.Sink{Name: `speakers`, Description: `Speakers`, VolumeFactor: 1})
+
+	native := newNativeServer(t, server)
+
+	conn, err := pulse.NewWithServer(`native-test`, native.Address)
+	assert.NoError(err)
+	defer conn.Close()
+
+	info, err := conn.GetServerInfo()
+	assert.NoError(err)
+	assert.Equal(`pulsefake`, info.Name)
+	assert.Equal(`speakers`, info.DefaultSinkName)
+	assert.Equal(44100, info.SampleRate)
+	assert.Equal(2, info.Channels)
+
+	sub, err := conn.SubscribeEvents(pulse.SinkEvent)
+	assert.NoError(err)
+	defer sub.Close()
+
+	sinks, err := conn.GetSinks()
+	assert.NoError(err)
+	assert.Len(sinks, 1)
+	assert.Equal(`speakers`, sinks[0].Name)
+	assert.Equal(`Speakers`, sinks[0].Description)
+	assert.Equal(1.0, sinks[0].VolumeFactor)
+	assert.NoError(sinks[0].SetVolume(0.5))
+
+	event := nextEvent(t, sub)
+	assert.Equal(pulse.EventChange, event.Kind)
+	assert.Equal(sinks[0].Index, event.Index)
+
+	assert.NoError(sinks[0].Refresh())
+	assert.Equal(0.5, sinks[0].VolumeFactor)
+
+	native.FailNext(proto.CommandGetSinkInfoList, proto.ErrTimeout)
+
+	_, err = conn.GetSinks()
+	assert.EqualError(err, `Timeout`)
+}
@@ -0,0 +1,49 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulsefake
+
+import (
+	"testing"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNativeServerConn(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1})
+
+	native := newNativeServer(t, server)
+
+	conn, err := pulse.NewWithServer(`native-test`, native.Address)
+	assert.NoError(err)
+	defer conn.Close()
+
+	info, err := conn.GetServerInfo()
+	assert.NoError(err)
+	assert.Equal(`speakers`, info.DefaultSinkName)
+
+	sub, err := conn.SubscribeEvents(pulse.SinkEvent)
+	assert.NoError(err)
+	defer sub.Close()
+
+	sinks, err := conn.GetSinks()
+	assert.NoError(err)
+	assert.Len(sinks, 1)
+	assert.NoError(sinks[0].SetVolume(0.5))
+
+	event := nextEvent(t, sub)
+	assert.Equal(pulse.EventChange, event.Kind)
+	assert.Equal(sinks[0].Index, event.Index)
+
+	assert.NoError(sinks[0].Refresh())
+	assert.Equal(0.5, sinks[0].VolumeFactor)
+
+	native.FailNext(proto.CommandGetSinkInfoList, proto.ErrTimeout)
+
+	_, err = conn.GetSinks()
+	assert.EqualError(err, `Timeout`)
+}
@@ -0,0 +1,276 @@
+package pulsefake
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// connect a protocol client to the given native server and complete the
+// handshake
+func dialNative(t *testing.T, native *NativeServer) *proto.Client {
+	conn, err := net.Dial(`unix`, native.Socket)
+	require.NoError(t, err)
+
+	client := proto.NewClient(conn)
+	go client.Run()
+
+	require.NoError(t, client.Handshake(nil, proto.PropList{
+		`application.name`: `native-test`,
+	}))
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+func newNativeServer(t *testing.T, server *Server) *NativeServer {
+	native, err := NewNativeServer(server)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		native.Close()
+	})
+
+	return native
+}
+
+func TestNativeServerIntrospection(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+
+	server.AddSink(pulse.Sink{
+		Name:           `speakers`,
+		Description:    `Speakers`,
+		VolumeFactor:   0.5,
+		NumVolumeSteps: pulse.DefaultVolumeStep,
+		ActivePort:     `analog-output`,
+	})
+
+	server.AddSink(pulse.Sink{Name: `headset`, VolumeFactor: 1})
+
+	native := newNativeServer(t, server)
+	client := dialNative(t, native)
+
+	assert.Equal(uint32(proto.ProtocolVersion), client.Version)
+
+	clients, err := server.GetClients()
+	assert.NoError(err)
+	assert.Len(clients, 1)
+	assert.Equal(`native-test`, clients[0].Name)
+	assert.Equal(uint32(clients[0].Index), client.ClientIndex)
+
+	reply, err := client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.NoError(err)
+
+	var info proto.ServerInfo
+	assert.NoError(info.Get(reply, client.Version))
+	assert.Equal(`pulsefake`, info.PackageName)
+	assert.Equal(`speakers`, info.DefaultSinkName)
+	assert.Equal(uint32(44100), info.SampleSpec.Rate)
+	assert.Len(info.ChannelMap, 2)
+
+	reply, err = client.Call(proto.CommandGetSinkInfoList, nil, time.Second)
+	assert.NoError(err)
+
+	sinks := make([]proto.SinkInfo, 0)
+
+	for !reply.Eof() {
+		var sink proto.SinkInfo
+		assert.NoError(sink.Get(reply, client.Version))
+		sinks = append(sinks, sink)
+	}
+
+	assert.Len(sinks, 2)
+	assert.Equal(`speakers`, sinks[0].Name)
+	assert.Equal(`Speakers`, sinks[0].Description)
+	assert.Equal(proto.NewCVolume(2, proto.VolumeNorm/2), sinks[0].Volume)
+	assert.Equal(`analog-output`, sinks[0].ActivePort)
+	assert.Equal(`headset`, sinks[1].Name)
+
+	// by name
+	args := proto.NewTagStruct()
+	args.PutU32(proto.InvalidIndex)
+	args.PutString(`headset`)
+
+	reply, err = client.Call(proto.CommandGetSinkInfo, args, time.Second)
+	assert.NoError(err)
+
+	var headset proto.SinkInfo
+	assert.NoError(headset.Get(reply, client.Version))
+	assert.Equal(uint32(1), headset.Index)
+
+	args = proto.NewTagStruct()
+	args.PutU32(42)
+	args.PutNullableString(``)
+
+	_, err = client.Call(proto.CommandGetSinkInfo, args, time.Second)
+	assert.Equal(proto.ErrNoEntity, err)
+
+	_, err = client.Call(proto.CommandCreatePlaybackStream, nil, time.Second)
+	assert.Equal(proto.ErrNotImplemented, err)
+
+	assert.Contains(native.Commands(), proto.CommandGetSinkInfoList)
+}
+
+func TestNativeServerVolumeAndMute(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	sink := server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1})
+
+	client := dialNative(t, newNativeServer(t, server))
+
+	args := proto.NewTagStruct()
+	args.PutU32(uint32(sink.Index))
+	args.PutNullableString(``)
+	args.PutCVolume(proto.NewCVolume(2, pulse.DefaultVolumeStep/4))
+
+	_, err := client.Call(proto.CommandSetSinkVolume, args, time.Second)
+	assert.NoError(err)
+
+	args = proto.NewTagStruct()
+	args.PutU32(proto.InvalidIndex)
+	args.PutString(`speakers`)
+	args.PutBool(true)
+
+	_, err = client.Call(proto.CommandSetSinkMute, args, time.Second)
+	assert.NoError(err)
+
+	sinks, err := server.GetSinks()
+	assert.NoError(err)
+	assert.Equal(0.25, sinks[0].VolumeFactor)
+	assert.True(sinks[0].Muted)
+
+	calls := make([]string, 0)
+
+	for _, call := range server.Calls() {
+		calls = append(calls, call.String())
+	}
+
+	assert.Contains(calls, `SetSinkVolume[0 0.25]`)
+	assert.Contains(calls, `SetSinkMute[0 true]`)
+
+	// unbalanced volumes are set per channel, and reported that way
+	args = proto.NewTagStruct()
+	args.PutU32(uint32(sink.Index))
+	args.PutNullableString(``)
+	args.PutCVolume(proto.CVolume{pulse.DefaultVolumeStep, pulse.DefaultVolumeStep / 2})
+
+	_, err = client.Call(proto.CommandSetSinkVolume, args, time.Second)
+	assert.NoError(err)
+
+	sinks, err = server.GetSinks()
+	assert.NoError(err)
+	assert.Equal(0.75, sinks[0].VolumeFactor)
+
+	calls = make([]string, 0)
+
+	for _, call := range server.Calls() {
+		calls = append(calls, call.String())
+	}
+
+	assert.Contains(calls, `SetSinkChannelVolumes[0 [1 0.5]]`)
+
+	reply, err := client.Call(proto.CommandGetSinkInfoList, nil, time.Second)
+	assert.NoError(err)
+
+	var info proto.SinkInfo
+	assert.NoError(info.Get(reply, client.Version))
+	assert.Equal(proto.CVolume{pulse.DefaultVolumeStep, pulse.DefaultVolumeStep / 2}, info.Volume)
+}
+
+func TestNativeServerSubscriptions(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	sink := server.AddSink(pulse.Sink{Name: `speakers`})
+
+	client := dialNative(t, newNativeServer(t, server))
+	events := make(chan pulse.Event, 8)
+
+	client.OnCommand = func(command proto.Command, args *proto.TagStruct) {
+		if command == proto.CommandSubscribeEvent {
+			code, _ := args.GetU32()
+			index, _ := args.GetU32()
+			events <- pulse.ParseEvent(int(code), int(index))
+		}
+	}
+
+	args := proto.NewTagStruct()
+	args.PutU32(uint32(pulse.SinkEvent))
+
+	_, err := client.Call(proto.CommandSubscribe, args, time.Second)
+	assert.NoError(err)
+
+	// not subscribed to
+	server.AddSinkInput(pulse.SinkInput{Name: `music`})
+
+	server.UpdateSink(sink.Index, func(sink *pulse.Sink) {
+		sink.Description = `Speakers`
+	})
+
+	select {
+	case event := <-events:
+		assert.Equal(pulse.EventType(pulse.SinkEvent), event.Facility)
+		assert.Equal(pulse.EventChange, event.Kind)
+		assert.Equal(sink.Index, event.Index)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestNativeServerFailures(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	native := newNativeServer(t, server)
+	client := dialNative(t, native)
+
+	native.FailNext(proto.CommandGetServerInfo, proto.ErrTimeout)
+
+	_, err := client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.Equal(proto.ErrTimeout, err)
+	assert.EqualError(err, `Timeout`)
+
+	_, err = client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.NoError(err)
+
+	// errors scripted on the fake server come through too
+	server.FailNext(`GetSinks`, NoSuchEntityErr)
+
+	_, err = client.Call(proto.CommandGetSinkInfoList, nil, time.Second)
+	assert.Equal(proto.ErrNoEntity, err)
+
+	native.Handle(proto.CommandGetServerInfo, func(args *proto.TagStruct, version uint32) (*proto.TagStruct, error) {
+		return nil, proto.ErrAccess
+	})
+
+	_, err = client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.Equal(proto.ErrAccess, err)
+
+	native.Handle(proto.CommandGetServerInfo, nil)
+
+	// slow replies
+	native.Delay(proto.CommandGetServerInfo, 200*time.Millisecond)
+
+	_, err = client.Call(proto.CommandGetServerInfo, nil, 20*time.Millisecond)
+	assert.Equal(proto.ErrTimeout, err)
+
+	native.Delay(proto.CommandGetServerInfo, 0)
+
+	// dropped connections
+	native.DropNext(proto.CommandGetServerInfo)
+
+	_, err = client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.Equal(proto.ErrConnectionTerminated, err)
+
+	client = dialNative(t, native)
+	native.Disconnect()
+
+	_, err = client.Call(proto.CommandGetServerInfo, nil, time.Second)
+	assert.Equal(proto.ErrConnectionTerminated, err)
+}
@@ -0,0 +1,953 @@
+// Package pulsefake provides an in-memory implementation of pulse.Server for
+// testing code that inspects or controls PulseAudio without a running daemon.
+//
+// A fake Server starts out empty.  Tests populate it with AddSink, AddSinkInput,
+// and friends, exercise the code under test, and then inspect the resulting
+// state (or the recorded Calls).  Changes made through either the scripting
+// methods or the pulse.Server methods emit the same subscription events that
+// a real daemon would.
+//
+// A NativeServer serves a fake Server over the native protocol on a private
+// socket, for testing a pulse.Conn (or any other client) end to end.
+package pulsefake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/ghetzel/go-stockutil/maputil"
+)
+
+var NoSuchEntityErr = fmt.Errorf("No such entity")
+
+// A Call records a single pulse.Server method invoked on the fake.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+func (self Call) String() string {
+	return fmt.Sprintf("%s%v", self.Method, self.Args)
+}
+
+// A Server is a fake PulseAudio server.  It is safe for concurrent use.
+type Server struct {
+	// Called by LoadModule after the module has been added, e.g.: to simulate
+	// module-null-sink creating a sink.  Returning an error fails the call.
+	OnLoadModule func(module *pulse.Module) error
+
+	info          pulse.ServerInfo
+	sinks         map[int]*pulse.Sink
+	sources       map[int]*pulse.Source
+	sinkInputs    map[int]*pulse.SinkInput
+	sourceOutputs map[int]*pulse.SourceOutput
+	modules       map[int]*pulse.Module
+	clients       map[int]*pulse.Client
+	nextIndex     map[pulse.EventType]int
+	errors        map[string]error
+	failNext      map[string][]error
+	calls         []Call
+	events        pulse.EventHub
+	lock          sync.Mutex
+}
+
+var _ pulse.Server = (*Server)(nil)
+
+// Create an empty fake server.
+func New() *Server {
+	return &Server{
+		info: pulse.ServerInfo{
+			Name:            `pulsefake`,
+			Version:         `0.0.0`,
+			SampleFormat:    `s16le`,
+			SampleRate:      44100,
+			Channels:        2,
+			ProtocolVersion: 32,
+		},
+		sinks:         make(map[int]*pulse.Sink),
+		sources:       make(map[int]*pulse.Source),
+		sinkInputs:    make(map[int]*pulse.SinkInput),
+		sourceOutputs: make(map[int]*pulse.SourceOutput),
+		modules:       make(map[int]*pulse.Module),
+		clients:       make(map[int]*pulse.Client),
+		nextIndex:     make(map[pulse.EventType]int),
+		errors:        make(map[string]error),
+		failNext:      make(map[string][]error),
+		calls:         make([]Call, 0),
+	}
+}
+
+// Make every subsequent call to the named pulse.Server method (e.g.:
+// "SetSinkVolume") fail with the given error.  A nil error clears it.
+func (self *Server) Fail(method string, err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if err == nil {
+		delete(self.errors, method)
+	} else {
+		self.errors[method] = err
+	}
+}
+
+// Make only the next call to the named pulse.Server method fail with the given
+// error.  Repeated calls queue up further failures.
+func (self *Server) FailNext(method string, err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.failNext[method] = append(self.failNext[method], err)
+}
+
+// Return the pulse.Server methods called so far, in order.
+func (self *Server) Calls() []Call {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return append([]Call(nil), self.calls...)
+}
+
+// Forget the calls recorded so far.
+func (self *Server) ClearCalls() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.calls = make([]Call, 0)
+}
+
+// Deliver an arbitrary event to subscribers.
+func (self *Server) Emit(facility pulse.EventType, kind pulse.EventKind, index int) {
+	self.events.Dispatch(pulse.Event{
+		Facility: facility,
+		Kind:     kind,
+		Index:    index,
+	})
+}
+
+// Replace the server information.
+func (self *Server) SetServerInfo(info pulse.ServerInfo) {
+	self.lock.Lock()
+	self.info = info
+	self.lock.Unlock()
+
+	self.Emit(pulse.ServerEvent, pulse.EventChange, 0)
+}
+
+// Add a sink, assigning it the next free index.  If the sink has no volume
+// steps or channels, typical values are filled in.  The first sink added
+// becomes the default.
+func (self *Server) AddSink(sink pulse.Sink) *pulse.Sink {
+	self.lock.Lock()
+
+	sink.Index = self.allocate(pulse.SinkEvent)
+	sink.Properties = properties(sink.Properties, sink.PropList)
+
+	if sink.NumVolumeSteps == 0 {
+		sink.NumVolumeSteps = pulse.DefaultVolumeStep
+	}
+
+	if sink.Channels == 0 {
+		sink.Channels = 2
+
+		if len(sink.ChannelVolumes) > 0 {
+			sink.Channels = len(sink.ChannelVolumes)
+		}
+	}
+
+	if sink.MonitorSourceName == `` {
+		sink.MonitorSourceName = sink.Name + `.monitor`
+	}
+
+	self.sinks[sink.Index] = &sink
+	isDefault := (self.info.DefaultSinkName == ``)
+
+	if isDefault {
+		self.info.DefaultSinkName = sink.Name
+	}
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.SinkEvent, pulse.EventNew, sink.Index)
+
+	if isDefault {
+		self.Emit(pulse.ServerEvent, pulse.EventChange, 0)
+	}
+
+	return &sink
+}
+
+// Add a source, assigning it the next free index.  The first non-monitor source
+// added becomes the default.
+func (self *Server) AddSource(source pulse.Source) *pulse.Source {
+	self.lock.Lock()
+
+	source.Index = self.allocate(pulse.SourceEvent)
+	source.Properties = properties(source.Properties, source.PropList)
+
+	if source.NumVolumeSteps == 0 {
+		source.NumVolumeSteps = pulse.DefaultVolumeStep
+	}
+
+	if source.Channels == 0 {
+		source.Channels = 2
+	}
+
+	self.sources[source.Index] = &source
+	isDefault := (self.info.DefaultSourceName == `` && source.MonitorOfSinkName == ``)
+
+	if isDefault {
+		self.info.DefaultSourceName = source.Name
+	}
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.SourceEvent, pulse.EventNew, source.Index)
+
+	if isDefault {
+		self.Emit(pulse.ServerEvent, pulse.EventChange, 0)
+	}
+
+	return &source
+}
+
+// Add a playback stream, assigning it the next free index.  If it has no
+// channels, it is given two at its current volume (or 100% if unset).
+func (self *Server) AddSinkInput(sinkInput pulse.SinkInput) *pulse.SinkInput {
+	self.lock.Lock()
+
+	sinkInput.Index = self.allocate(pulse.SinkInputEvent)
+	sinkInput.Properties = properties(sinkInput.Properties, sinkInput.PropList)
+	sinkInput.Volume, sinkInput.Channels = streamVolume(sinkInput.Volume, sinkInput.Channels)
+	self.sinkInputs[sinkInput.Index] = &sinkInput
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.SinkInputEvent, pulse.EventNew, sinkInput.Index)
+	return &sinkInput
+}
+
+// Add a recording stream, assigning it the next free index.
+func (self *Server) AddSourceOutput(sourceOutput pulse.SourceOutput) *pulse.SourceOutput {
+	self.lock.Lock()
+
+	sourceOutput.Index = self.allocate(pulse.SourceOutputEvent)
+	sourceOutput.Properties = properties(sourceOutput.Properties, sourceOutput.PropList)
+	sourceOutput.Volume, sourceOutput.Channels = streamVolume(sourceOutput.Volume, sourceOutput.Channels)
+	self.sourceOutputs[sourceOutput.Index] = &sourceOutput
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.SourceOutputEvent, pulse.EventNew, sourceOutput.Index)
+	return &sourceOutput
+}
+
+// Add a module, assigning it the next free index.
+func (self *Server) AddModule(module pulse.Module) *pulse.Module {
+	self.lock.Lock()
+
+	module.Index = uint(self.allocate(pulse.ModuleEvent))
+	module.Properties = properties(module.Properties, module.PropList)
+	self.modules[int(module.Index)] = &module
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.ModuleEvent, pulse.EventNew, int(module.Index))
+	return &module
+}
+
+// Add a client, assigning it the next free index.
+func (self *Server) AddClient(client pulse.Client) *pulse.Client {
+	self.lock.Lock()
+
+	client.Index = self.allocate(pulse.ClientEvent)
+	client.Properties = properties(client.Properties, client.PropList)
+	self.clients[client.Index] = &client
+
+	self.lock.Unlock()
+
+	self.Emit(pulse.ClientEvent, pulse.EventNew, client.Index)
+	return &client
+}
+
+// Change a sink in place, emitting a change event.
+func (self *Server) UpdateSink(index int, update func(sink *pulse.Sink)) error {
+	self.lock.Lock()
+
+	sink, ok := self.sinks[index]
+
+	if ok {
+		update(sink)
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(ok, pulse.SinkEvent, index)
+}
+
+// Change a source in place, emitting a change event.
+func (self *Server) UpdateSource(index int, update func(source *pulse.Source)) error {
+	self.lock.Lock()
+
+	source, ok := self.sources[index]
+
+	if ok {
+		update(source)
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(ok, pulse.SourceEvent, index)
+}
+
+// Change a sink input in place, emitting a change event.
+func (self *Server) UpdateSinkInput(index int, update func(sinkInput *pulse.SinkInput)) error {
+	self.lock.Lock()
+
+	sinkInput, ok := self.sinkInputs[index]
+
+	if ok {
+		update(sinkInput)
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(ok, pulse.SinkInputEvent, index)
+}
+
+// Change a source output in place, emitting a change event.
+func (self *Server) UpdateSourceOutput(index int, update func(sourceOutput *pulse.SourceOutput)) error {
+	self.lock.Lock()
+
+	sourceOutput, ok := self.sourceOutputs[index]
+
+	if ok {
+		update(sourceOutput)
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(ok, pulse.SourceOutputEvent, index)
+}
+
+// Remove an object of the given kind (e.g.: pulse.SinkEvent), emitting a remove
+// event.
+func (self *Server) Remove(facility pulse.EventType, index int) error {
+	self.lock.Lock()
+
+	var ok bool
+
+	switch facility {
+	case pulse.SinkEvent:
+		_, ok = self.sinks[index]
+		delete(self.sinks, index)
+	case pulse.SourceEvent:
+		_, ok = self.sources[index]
+		delete(self.sources, index)
+	case pulse.SinkInputEvent:
+		_, ok = self.sinkInputs[index]
+		delete(self.sinkInputs, index)
+	case pulse.SourceOutputEvent:
+		_, ok = self.sourceOutputs[index]
+		delete(self.sourceOutputs, index)
+	case pulse.ModuleEvent:
+		_, ok = self.modules[index]
+		delete(self.modules, index)
+	case pulse.ClientEvent:
+		_, ok = self.clients[index]
+		delete(self.clients, index)
+	}
+
+	self.lock.Unlock()
+
+	if !ok {
+		return NoSuchEntityErr
+	}
+
+	self.Emit(facility, pulse.EventRemove, index)
+	return nil
+}
+
+func (self *Server) GetServerInfo() (pulse.ServerInfo, error) {
+	if err := self.call(`GetServerInfo`); err != nil {
+		return pulse.ServerInfo{}, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.info, nil
+}
+
+func (self *Server) GetSinks(filters ...string) ([]*pulse.Sink, error) {
+	matcher, err := self.query(`GetSinks`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	sinks := make([]*pulse.Sink, 0)
+
+	for _, index := range sortedIndices(self.sinks) {
+		sink := *self.sinks[index]
+		sink.PropList = sink.PropList.Copy()
+		sink.ChannelVolumes = append([]pulse.Volume(nil), sink.ChannelVolumes...)
+
+		if matcher.Match(&sink) {
+			sinks = append(sinks, &sink)
+		}
+	}
+
+	return sinks, nil
+}
+
+func (self *Server) GetSources(filters ...string) ([]*pulse.Source, error) {
+	matcher, err := self.query(`GetSources`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	sources := make([]*pulse.Source, 0)
+
+	for _, index := range sortedIndices(self.sources) {
+		source := *self.sources[index]
+		source.PropList = source.PropList.Copy()
+
+		if matcher.Match(&source) {
+			sources = append(sources, &source)
+		}
+	}
+
+	return sources, nil
+}
+
+func (self *Server) GetSinkInputs(filters ...string) ([]pulse.SinkInput, error) {
+	matcher, err := self.query(`GetSinkInputs`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	sinkInputs := make([]pulse.SinkInput, 0)
+
+	for _, index := range sortedIndices(self.sinkInputs) {
+		sinkInput := *self.sinkInputs[index]
+		sinkInput.PropList = sinkInput.PropList.Copy()
+		sinkInput.Channels = append([]pulse.Volume(nil), sinkInput.Channels...)
+
+		if matcher.Match(sinkInput) {
+			sinkInputs = append(sinkInputs, sinkInput)
+		}
+	}
+
+	return sinkInputs, nil
+}
+
+func (self *Server) GetSourceOutputs(filters ...string) ([]pulse.SourceOutput, error) {
+	matcher, err := self.query(`GetSourceOutputs`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	sourceOutputs := make([]pulse.SourceOutput, 0)
+
+	for _, index := range sortedIndices(self.sourceOutputs) {
+		sourceOutput := *self.sourceOutputs[index]
+		sourceOutput.PropList = sourceOutput.PropList.Copy()
+		sourceOutput.Channels = append([]pulse.Volume(nil), sourceOutput.Channels...)
+
+		if matcher.Match(sourceOutput) {
+			sourceOutputs = append(sourceOutputs, sourceOutput)
+		}
+	}
+
+	return sourceOutputs, nil
+}
+
+func (self *Server) GetModules(filters ...string) ([]*pulse.Module, error) {
+	matcher, err := self.query(`GetModules`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	modules := make([]*pulse.Module, 0)
+
+	for _, index := range sortedIndices(self.modules) {
+		module := *self.modules[index]
+		module.PropList = module.PropList.Copy()
+
+		if matcher.Match(&module) {
+			modules = append(modules, &module)
+		}
+	}
+
+	return modules, nil
+}
+
+func (self *Server) GetClients(filters ...string) ([]*pulse.Client, error) {
+	matcher, err := self.query(`GetClients`, filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	clients := make([]*pulse.Client, 0)
+
+	for _, index := range sortedIndices(self.clients) {
+		client := *self.clients[index]
+		client.PropList = client.PropList.Copy()
+
+		if matcher.Match(&client) {
+			clients = append(clients, &client)
+		}
+	}
+
+	return clients, nil
+}
+
+func (self *Server) SetDefaultSink(name string) error {
+	if err := self.call(`SetDefaultSink`, name); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+
+	found := false
+
+	for _, sink := range self.sinks {
+		if sink.Name == name {
+			found = true
+			self.info.DefaultSinkName = name
+			break
+		}
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(found, pulse.ServerEvent, 0)
+}
+
+func (self *Server) SetDefaultSource(name string) error {
+	if err := self.call(`SetDefaultSource`, name); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+
+	found := false
+
+	for _, source := range self.sources {
+		if source.Name == name {
+			found = true
+			self.info.DefaultSourceName = name
+			break
+		}
+	}
+
+	self.lock.Unlock()
+
+	return self.changed(found, pulse.ServerEvent, 0)
+}
+
+func (self *Server) SetSinkVolume(index int, factor float64) error {
+	if err := self.call(`SetSinkVolume`, index, factor); err != nil {
+		return err
+	}
+
+	return self.UpdateSink(index, func(sink *pulse.Sink) {
+		sink.VolumeFactor = factor
+		sink.CurrentVolumeStep = int(factor * float64(sink.NumVolumeSteps))
+
+		if len(sink.ChannelVolumes) > 0 {
+			factors := make([]float64, len(sink.ChannelVolumes))
+
+			for i := range factors {
+				factors[i] = factor
+			}
+
+			_, sink.ChannelVolumes = setChannelVolumes(sink.ChannelVolumes, factors, sink.NumVolumeSteps)
+		}
+	})
+}
+
+// Set the volume of each channel of a sink.  The sink's VolumeFactor becomes
+// the mean of its channels, as it does when reading an unbalanced sink from a
+// real server.
+func (self *Server) SetSinkChannelVolumes(index int, factors []float64) error {
+	if err := self.call(`SetSinkChannelVolumes`, index, factors); err != nil {
+		return err
+	}
+
+	return self.UpdateSink(index, func(sink *pulse.Sink) {
+		var mean pulse.Volume
+
+		mean, sink.ChannelVolumes = setChannelVolumes(sink.ChannelVolumes, factors, sink.NumVolumeSteps)
+		sink.Channels = len(factors)
+		sink.CurrentVolumeStep = int(mean.Value)
+		sink.VolumeFactor = mean.Value / float64(sink.NumVolumeSteps)
+	})
+}
+
+func (self *Server) SetSinkMute(index int, mute bool) error {
+	if err := self.call(`SetSinkMute`, index, mute); err != nil {
+		return err
+	}
+
+	return self.UpdateSink(index, func(sink *pulse.Sink) {
+		sink.Muted = mute
+	})
+}
+
+func (self *Server) SetSinkPort(index int, port string) error {
+	if err := self.call(`SetSinkPort`, index, port); err != nil {
+		return err
+	}
+
+	return self.UpdateSink(index, func(sink *pulse.Sink) {
+		sink.ActivePort = port
+	})
+}
+
+func (self *Server) SetSourceVolume(index int, factor float64) error {
+	if err := self.call(`SetSourceVolume`, index, factor); err != nil {
+		return err
+	}
+
+	return self.UpdateSource(index, func(source *pulse.Source) {
+		source.VolumeFactor = factor
+		source.CurrentVolumeStep = int(factor * float64(source.NumVolumeSteps))
+	})
+}
+
+func (self *Server) SetSourceMute(index int, mute bool) error {
+	if err := self.call(`SetSourceMute`, index, mute); err != nil {
+		return err
+	}
+
+	return self.UpdateSource(index, func(source *pulse.Source) {
+		source.Muted = mute
+	})
+}
+
+func (self *Server) SetSourcePort(index int, port string) error {
+	if err := self.call(`SetSourcePort`, index, port); err != nil {
+		return err
+	}
+
+	return self.UpdateSource(index, func(source *pulse.Source) {
+		source.ActivePort = port
+	})
+}
+
+func (self *Server) SetSinkInputVolume(index int, factor float64) error {
+	if err := self.call(`SetSinkInputVolume`, index, factor); err != nil {
+		return err
+	}
+
+	return self.UpdateSinkInput(index, func(sinkInput *pulse.SinkInput) {
+		sinkInput.Volume, sinkInput.Channels = setStreamVolume(sinkInput.Channels, factor)
+	})
+}
+
+func (self *Server) SetSinkInputChannelVolumes(index int, factors []float64) error {
+	if err := self.call(`SetSinkInputChannelVolumes`, index, factors); err != nil {
+		return err
+	}
+
+	return self.UpdateSinkInput(index, func(sinkInput *pulse.SinkInput) {
+		sinkInput.Volume, sinkInput.Channels = setChannelVolumes(sinkInput.Channels, factors, pulse.DefaultVolumeStep)
+	})
+}
+
+func (self *Server) SetSinkInputMute(index int, mute bool) error {
+	if err := self.call(`SetSinkInputMute`, index, mute); err != nil {
+		return err
+	}
+
+	return self.UpdateSinkInput(index, func(sinkInput *pulse.SinkInput) {
+		sinkInput.Muted = mute
+	})
+}
+
+func (self *Server) MoveSinkInput(index int, sinkIndex int) error {
+	if err := self.call(`MoveSinkInput`, index, sinkIndex); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	_, ok := self.sinks[sinkIndex]
+	self.lock.Unlock()
+
+	if !ok {
+		return NoSuchEntityErr
+	}
+
+	return self.UpdateSinkInput(index, func(sinkInput *pulse.SinkInput) {
+		sinkInput.SinkIndex = sinkIndex
+	})
+}
+
+func (self *Server) KillSinkInput(index int) error {
+	if err := self.call(`KillSinkInput`, index); err != nil {
+		return err
+	}
+
+	return self.Remove(pulse.SinkInputEvent, index)
+}
+
+func (self *Server) SetSourceOutputVolume(index int, factor float64) error {
+	if err := self.call(`SetSourceOutputVolume`, index, factor); err != nil {
+		return err
+	}
+
+	return self.UpdateSourceOutput(index, func(sourceOutput *pulse.SourceOutput) {
+		sourceOutput.Volume, sourceOutput.Channels = setStreamVolume(sourceOutput.Channels, factor)
+	})
+}
+
+func (self *Server) SetSourceOutputMute(index int, mute bool) error {
+	if err := self.call(`SetSourceOutputMute`, index, mute); err != nil {
+		return err
+	}
+
+	return self.UpdateSourceOutput(index, func(sourceOutput *pulse.SourceOutput) {
+		sourceOutput.Muted = mute
+	})
+}
+
+func (self *Server) MoveSourceOutput(index int, sourceIndex int) error {
+	if err := self.call(`MoveSourceOutput`, index, sourceIndex); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	_, ok := self.sources[sourceIndex]
+	self.lock.Unlock()
+
+	if !ok {
+		return NoSuchEntityErr
+	}
+
+	return self.UpdateSourceOutput(index, func(sourceOutput *pulse.SourceOutput) {
+		sourceOutput.SourceIndex = sourceIndex
+	})
+}
+
+func (self *Server) KillSourceOutput(index int) error {
+	if err := self.call(`KillSourceOutput`, index); err != nil {
+		return err
+	}
+
+	return self.Remove(pulse.SourceOutputEvent, index)
+}
+
+func (self *Server) LoadModule(name string, arguments string) error {
+	if err := self.call(`LoadModule`, name, arguments); err != nil {
+		return err
+	}
+
+	module := self.AddModule(pulse.Module{
+		Name:     name,
+		Argument: arguments,
+	})
+
+	if self.OnLoadModule != nil {
+		if err := self.OnLoadModule(module); err != nil {
+			self.Remove(pulse.ModuleEvent, int(module.Index))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *Server) UnloadModule(index int) error {
+	if err := self.call(`UnloadModule`, index); err != nil {
+		return err
+	}
+
+	return self.Remove(pulse.ModuleEvent, index)
+}
+
+func (self *Server) SubscribeEvents(types ...pulse.EventType) (*pulse.Subscription, error) {
+	if err := self.call(`SubscribeEvents`, types); err != nil {
+		return nil, err
+	}
+
+	return self.events.Subscribe(types...), nil
+}
+
+// record a call and return any error scripted for it
+func (self *Server) call(method string, args ...interface{}) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.calls = append(self.calls, Call{
+		Method: method,
+		Args:   args,
+	})
+
+	if queued := self.failNext[method]; len(queued) > 0 {
+		self.failNext[method] = queued[1:]
+		return queued[0]
+	}
+
+	return self.errors[method]
+}
+
+func (self *Server) query(method string, filters []string) (*pulse.Matcher, error) {
+	args := make([]interface{}, len(filters))
+
+	for i, filter := range filters {
+		args[i] = filter
+	}
+
+	if err := self.call(method, args...); err != nil {
+		return nil, err
+	}
+
+	return pulse.CompileFilter(filters...)
+}
+
+// emit a change event if the object was found
+func (self *Server) changed(found bool, facility pulse.EventType, index int) error {
+	if !found {
+		return NoSuchEntityErr
+	}
+
+	self.Emit(facility, pulse.EventChange, index)
+	return nil
+}
+
+// return the next index for a kind of object; indices are never reused, just
+// like on a real server
+func (self *Server) allocate(facility pulse.EventType) int {
+	index := self.nextIndex[facility]
+	self.nextIndex[facility] = index + 1
+	return index
+}
+
+// fill in the Properties map from the PropList, as a real connection does
+func properties(existing map[string]interface{}, propList pulse.PropList) map[string]interface{} {
+	if existing != nil || len(propList) == 0 {
+		return existing
+	}
+
+	flat := make(map[string]interface{}, len(propList))
+
+	for key, value := range propList {
+		flat[key] = value
+	}
+
+	out, _ := maputil.DiffuseMap(flat, `.`)
+	return out
+}
+
+// give a stream two channels at its current volume if it has none
+func streamVolume(volume pulse.Volume, channels []pulse.Volume) (pulse.Volume, []pulse.Volume) {
+	if len(channels) > 0 {
+		return volume, channels
+	}
+
+	factor := volume.Value / pulse.DefaultVolumeStep
+
+	if volume.Value == 0 {
+		factor = 1
+	}
+
+	return setStreamVolume([]pulse.Volume{{Name: `front-left`}, {Name: `front-right`}}, factor)
+}
+
+func setStreamVolume(channels []pulse.Volume, factor float64) (pulse.Volume, []pulse.Volume) {
+	value := float64(uint(factor * pulse.DefaultVolumeStep))
+	out := make([]pulse.Volume, len(channels))
+
+	for i, channel := range channels {
+		out[i] = pulse.Volume{
+			Name:  channel.Name,
+			Value: value,
+		}
+	}
+
+	return pulse.Volume{Name: `mean`, Value: value}, out
+}
+
+// set each channel to a factor of the given number of volume steps, keeping the
+// names of existing channels, and return the channels along with their mean
+func setChannelVolumes(channels []pulse.Volume, factors []float64, steps int) (pulse.Volume, []pulse.Volume) {
+	out := make([]pulse.Volume, len(factors))
+	total := 0.0
+
+	for i, factor := range factors {
+		if i < len(channels) {
+			out[i].Name = channels[i].Name
+		}
+
+		out[i].Value = float64(uint(factor * float64(steps)))
+		total += out[i].Value
+	}
+
+	mean := 0.0
+
+	if len(out) > 0 {
+		mean = float64(uint(total / float64(len(out))))
+	}
+
+	return pulse.Volume{Name: `mean`, Value: mean}, out
+}
+
+func sortedIndices(objects interface{}) []int {
+	indices := make([]int, 0)
+
+	switch objects := objects.(type) {
+	case map[int]*pulse.Sink:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	case map[int]*pulse.Source:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	case map[int]*pulse.SinkInput:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	case map[int]*pulse.SourceOutput:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	case map[int]*pulse.Module:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	case map[int]*pulse.Client:
+		for index := range objects {
+			indices = append(indices, index)
+		}
+	}
+
+	sort.Ints(indices)
+	return indices
+}
@@ -0,0 +1,198 @@
+package pulsefake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvent(t *testing.T, sub *pulse.Subscription) pulse.Event {
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return pulse.Event{}
+	}
+}
+
+func TestServerObjects(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+
+	speakers := server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1})
+	headset := server.AddSink(pulse.Sink{Name: `headset`, VolumeFactor: 0.5})
+	server.AddSource(pulse.Source{Name: `speakers.monitor`, MonitorOfSinkName: `speakers`})
+	mic := server.AddSource(pulse.Source{Name: `mic`})
+
+	assert.Equal(0, speakers.Index)
+	assert.Equal(1, headset.Index)
+
+	info, err := server.GetServerInfo()
+	assert.NoError(err)
+	assert.Equal(`speakers`, info.DefaultSinkName)
+	assert.Equal(`mic`, info.DefaultSourceName)
+
+	server.AddSinkInput(pulse.SinkInput{
+		Name:      `music`,
+		SinkIndex: speakers.Index,
+		PropList:  pulse.PropList{`application.name`: `Player`},
+	})
+
+	server.AddSinkInput(pulse.SinkInput{
+		Name:      `call`,
+		SinkIndex: headset.Index,
+		PropList:  pulse.PropList{`application.name`: `Phone`},
+	})
+
+	sinkInputs, err := server.GetSinkInputs(`@application.name/Phone`)
+	assert.NoError(err)
+	assert.Len(sinkInputs, 1)
+	assert.Equal(`call`, sinkInputs[0].Name)
+	assert.Equal(float64(1), sinkInputs[0].VolumeFactor())
+
+	sinks, err := server.GetSinks(`Name/headset`)
+	assert.NoError(err)
+	assert.Len(sinks, 1)
+
+	// returned objects are copies
+	sinks[0].VolumeFactor = 0.1
+	sinks, err = server.GetSinks(`Name/headset`)
+	assert.NoError(err)
+	assert.Equal(0.5, sinks[0].VolumeFactor)
+
+	assert.NoError(server.SetSinkVolume(headset.Index, 0.75))
+	assert.NoError(server.SetSinkMute(headset.Index, true))
+	assert.NoError(server.SetSourcePort(mic.Index, `analog-input-mic`))
+	assert.NoError(server.SetDefaultSink(`headset`))
+	assert.Equal(NoSuchEntityErr, server.SetDefaultSink(`nonexistent`))
+
+	sinks, err = server.GetSinks(`Name/headset`)
+	assert.NoError(err)
+	assert.Equal(0.75, sinks[0].VolumeFactor)
+	assert.True(sinks[0].Muted)
+
+	sources, err := server.GetSources(`Name/mic`)
+	assert.NoError(err)
+	assert.Equal(`analog-input-mic`, sources[0].ActivePort)
+
+	info, err = server.GetServerInfo()
+	assert.NoError(err)
+	assert.Equal(`headset`, info.DefaultSinkName)
+
+	assert.NoError(server.SetSinkInputVolume(0, 0.5))
+	assert.NoError(server.MoveSinkInput(0, headset.Index))
+	assert.Equal(NoSuchEntityErr, server.MoveSinkInput(0, 42))
+
+	sinkInputs, err = server.GetSinkInputs(`Name/music`)
+	assert.NoError(err)
+	assert.Equal(0.5, sinkInputs[0].VolumeFactor())
+	assert.Equal(headset.Index, sinkInputs[0].SinkIndex)
+
+	for _, channel := range sinkInputs[0].Channels {
+		assert.Equal(0.5*pulse.DefaultVolumeStep, channel.Value)
+	}
+
+	assert.NoError(server.KillSinkInput(0))
+	assert.Equal(NoSuchEntityErr, server.KillSinkInput(0))
+
+	sinkInputs, err = server.GetSinkInputs()
+	assert.NoError(err)
+	assert.Len(sinkInputs, 1)
+}
+
+func TestServerModules(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+
+	server.OnLoadModule = func(module *pulse.Module) error {
+		if module.Name != `module-null-sink` {
+			return fmt.Errorf("Failed to load module")
+		}
+
+		server.AddSink(pulse.Sink{
+			Name:        `null`,
+			ModuleIndex: int(module.Index),
+		})
+
+		return nil
+	}
+
+	assert.NoError(server.LoadModule(`module-null-sink`, `sink_name=null`))
+	assert.Error(server.LoadModule(`module-bogus`, ``))
+
+	modules, err := server.GetModules()
+	assert.NoError(err)
+	assert.Len(modules, 1)
+	assert.Equal(`sink_name=null`, modules[0].Argument)
+
+	sinks, err := server.GetSinks()
+	assert.NoError(err)
+	assert.Len(sinks, 1)
+	assert.Equal(int(modules[0].Index), sinks[0].ModuleIndex)
+
+	assert.NoError(server.UnloadModule(int(modules[0].Index)))
+
+	modules, err = server.GetModules()
+	assert.NoError(err)
+	assert.Empty(modules)
+}
+
+func TestServerEvents(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+
+	sub, err := server.SubscribeEvents(pulse.SinkEvent, pulse.SinkInputEvent)
+	assert.NoError(err)
+	defer sub.Close()
+
+	sink := server.AddSink(pulse.Sink{Name: `speakers`})
+	server.AddClient(pulse.Client{Name: `player`})
+	sinkInput := server.AddSinkInput(pulse.SinkInput{Name: `music`})
+
+	assert.NoError(server.SetSinkInputMute(sinkInput.Index, true))
+	assert.NoError(server.UpdateSink(sink.Index, func(sink *pulse.Sink) {
+		sink.Description = `Speakers`
+	}))
+
+	assert.NoError(server.Remove(pulse.SinkInputEvent, sinkInput.Index))
+
+	assert.Equal(`new sink 0`, nextEvent(t, sub).String())
+	assert.Equal(`new sink-input 0`, nextEvent(t, sub).String())
+	assert.Equal(`change sink-input 0`, nextEvent(t, sub).String())
+	assert.Equal(`change sink 0`, nextEvent(t, sub).String())
+	assert.Equal(`remove sink-input 0`, nextEvent(t, sub).String())
+}
+
+func TestServerFailures(t *testing.T) {
+	assert := require.New(t)
+	server := New()
+	timeout := fmt.Errorf("Timeout")
+
+	sink := server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1})
+
+	server.FailNext(`SetSinkVolume`, timeout)
+	assert.Equal(timeout, server.SetSinkVolume(sink.Index, 0.5))
+	assert.NoError(server.SetSinkVolume(sink.Index, 0.5))
+
+	server.Fail(`GetSinks`, timeout)
+	_, err := server.GetSinks()
+	assert.Equal(timeout, err)
+	_, err = server.GetSinks()
+	assert.Equal(timeout, err)
+
+	server.Fail(`GetSinks`, nil)
+	_, err = server.GetSinks()
+	assert.NoError(err)
+
+	calls := server.Calls()
+	assert.Len(calls, 5)
+	assert.Equal(`SetSinkVolume`, calls[0].Method)
+	assert.Equal([]interface{}{0, 0.5}, calls[0].Args)
+
+	server.ClearCalls()
+	assert.Empty(server.Calls())
+}
@@ -0,0 +1,270 @@
+// Package pulsetest runs private PulseAudio daemons for integration tests.
+//
+// Each daemon runs in its own temporary runtime directory, listens only on a
+// private socket, and starts with nothing but a null sink, so tests can change
+// volumes, load modules, and play audio without touching the developer's real
+// sound setup.  Daemons and connections are torn down when the test finishes.
+//
+//	func TestSomething(t *testing.T) {
+//		conn := pulsetest.NewConn(t)
+//		conn.SetSinkVolume(0, 0.5)
+//	}
+package pulsetest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+)
+
+const (
+	DEFAULT_DAEMON_BINARY   = `pulseaudio`
+	DEFAULT_STARTUP_TIMEOUT = 10 * time.Second
+	DEFAULT_STOP_TIMEOUT    = 5 * time.Second
+	DEFAULT_NULL_SINK_NAME  = `null`
+)
+
+// A Module is loaded when a daemon starts, after the null sink.
+type Module struct {
+	Name      string
+	Arguments string
+}
+
+// A Daemon is a private PulseAudio daemon owned by a single test.
+type Daemon struct {
+	// The temporary directory holding the daemon's runtime, state, and
+	// configuration files.
+	Dir string
+
+	// The path of the daemon's native protocol socket.
+	Socket string
+
+	// The address to pass to pulse.NewWithServer.
+	Server string
+
+	t       testing.TB
+	cmd     *exec.Cmd
+	log     *daemonLog
+	exited  chan error
+	control *pulse.Conn
+	conns   []*pulse.Conn
+	lock    sync.Mutex
+}
+
+// the daemon's output, safe to read while the daemon writes to it
+type daemonLog struct {
+	buffer bytes.Buffer
+	lock   sync.Mutex
+}
+
+func (self *daemonLog) Write(p []byte) (int, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.buffer.Write(p)
+}
+
+func (self *daemonLog) String() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.buffer.String()
+}
+
+// Start a private daemon for the duration of the test, loading any given
+// modules after the null sink.  The test is skipped if PulseAudio is not
+// installed, and fails if the daemon cannot be started.
+func Start(t testing.TB, modules ...Module) *Daemon {
+	t.Helper()
+
+	binary, err := exec.LookPath(DEFAULT_DAEMON_BINARY)
+
+	if err != nil {
+		t.Skipf("PulseAudio daemon not available: %v", err)
+	}
+
+	dir, err := ioutil.TempDir(``, `pulsetest`)
+
+	if err != nil {
+		t.Fatalf("Failed to create runtime directory: %v", err)
+	}
+
+	daemon := &Daemon{
+		Dir:    dir,
+		Socket: filepath.Join(dir, `native`),
+		t:      t,
+		log:    &daemonLog{},
+		exited: make(chan error, 1),
+	}
+
+	daemon.Server = `unix:` + daemon.Socket
+
+	t.Cleanup(daemon.stop)
+
+	script := filepath.Join(dir, `default.pa`)
+
+	if err := ioutil.WriteFile(script, []byte(daemon.script(modules)), 0644); err != nil {
+		t.Fatalf("Failed to write daemon configuration: %v", err)
+	}
+
+	daemon.cmd = exec.Command(
+		binary,
+		`-n`,
+		`--daemonize=no`,
+		`--system=no`,
+		`--use-pid-file=no`,
+		`--exit-idle-time=-1`,
+		`--log-target=stderr`,
+		`--file=`+script,
+	)
+
+	// keep the daemon away from the user's configuration, cookie, and session
+	daemon.cmd.Env = append(
+		os.Environ(),
+		`HOME=`+dir,
+		`XDG_RUNTIME_DIR=`+dir,
+		`XDG_CONFIG_HOME=`+dir,
+		`PULSE_RUNTIME_PATH=`+dir,
+		`PULSE_STATE_PATH=`+dir,
+		`DBUS_SESSION_BUS_ADDRESS=disabled:`,
+	)
+
+	daemon.cmd.Stdout = daemon.log
+	daemon.cmd.Stderr = daemon.log
+
+	if err := daemon.cmd.Start(); err != nil {
+		t.Fatalf("Failed to start PulseAudio daemon: %v", err)
+	}
+
+	go func() {
+		daemon.exited <- daemon.cmd.Wait()
+	}()
+
+	if err := daemon.waitReady(); err != nil {
+		t.Fatalf("PulseAudio daemon did not start: %v\n%s", err, daemon.log)
+	}
+
+	return daemon
+}
+
+// Start a private daemon for the duration of the test and return a connection
+// to it.
+func NewConn(t testing.TB, modules ...Module) *pulse.Conn {
+	t.Helper()
+
+	return Start(t, modules...).Connect(t.Name())
+}
+
+// Open another connection to the daemon, closed when the test finishes.
+func (self *Daemon) Connect(name string) *pulse.Conn {
+	self.t.Helper()
+
+	conn, err := pulse.NewWithServer(name, self.Server)
+
+	if err != nil {
+		self.t.Fatalf("Failed to connect to PulseAudio daemon: %v\n%s", err, self.log)
+	}
+
+	self.lock.Lock()
+	self.conns = append(self.conns, conn)
+	self.lock.Unlock()
+
+	return conn
+}
+
+// Load a module into the running daemon, failing the test if it cannot be
+// loaded.
+func (self *Daemon) LoadModule(name string, arguments string) {
+	self.t.Helper()
+
+	if self.control == nil {
+		self.control = self.Connect(`pulsetest`)
+	}
+
+	if err := self.control.LoadModule(name, arguments); err != nil {
+		self.t.Fatalf("Failed to load module %s: %v\n%s", name, err, self.log)
+	}
+}
+
+// Return everything the daemon has logged so far.
+func (self *Daemon) Log() string {
+	return self.log.String()
+}
+
+func (self *Daemon) script(modules []Module) string {
+	lines := []string{
+		fmt.Sprintf("load-module module-native-protocol-unix socket=%s auth-anonymous=1", self.Socket),
+		fmt.Sprintf("load-module module-null-sink sink_name=%s", DEFAULT_NULL_SINK_NAME),
+		fmt.Sprintf("set-default-sink %s", DEFAULT_NULL_SINK_NAME),
+	}
+
+	for _, module := range modules {
+		lines = append(lines, strings.TrimSpace(`load-module `+module.Name+` `+module.Arguments))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// wait until the daemon accepts connections, or exits, or the startup timeout
+// passes
+func (self *Daemon) waitReady() error {
+	deadline := time.Now().Add(DEFAULT_STARTUP_TIMEOUT)
+
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-self.exited:
+			self.exited <- err
+			return fmt.Errorf("Daemon exited: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		if _, err := os.Stat(self.Socket); err != nil {
+			continue
+		}
+
+		if conn, err := pulse.NewWithServer(`pulsetest-probe`, self.Server); err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Timed out after %v", DEFAULT_STARTUP_TIMEOUT)
+}
+
+func (self *Daemon) stop() {
+	self.lock.Lock()
+	conns := self.conns
+	self.conns = nil
+	self.lock.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	if self.cmd != nil && self.cmd.Process != nil {
+		self.cmd.Process.Signal(syscall.SIGTERM)
+
+		select {
+		case <-self.exited:
+		case <-time.After(DEFAULT_STOP_TIMEOUT):
+			self.cmd.Process.Kill()
+			<-self.exited
+		}
+	}
+
+	if self.t.Failed() {
+		self.t.Logf("PulseAudio daemon log:\n%s", self.log)
+	}
+
+	os.RemoveAll(self.Dir)
+}
@@ -0,0 +1,37 @@
+package pulsetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemon(t *testing.T) {
+	assert := require.New(t)
+	daemon := Start(t, Module{
+		Name:      `module-null-sink`,
+		Arguments: `sink_name=second`,
+	})
+
+	conn := daemon.Connect(`pulsetest-daemon`)
+
+	info, err := conn.GetServerInfo()
+	assert.NoError(err)
+	assert.Equal(DEFAULT_NULL_SINK_NAME, info.DefaultSinkName)
+
+	sinks, err := conn.GetSinks()
+	assert.NoError(err)
+	assert.Len(sinks, 2)
+
+	daemon.LoadModule(`module-null-sink`, `sink_name=third`)
+
+	sinks, err = conn.GetSinks(`Name/third`)
+	assert.NoError(err)
+	assert.Len(sinks, 1)
+
+	assert.NoError(conn.SetSinkVolume(sinks[0].Index, 0.5))
+
+	sinks, err = conn.GetSinks(`Name/third`)
+	assert.NoError(err)
+	assert.InDelta(0.5, sinks[0].VolumeFactor, 0.01)
+}
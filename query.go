@@ -0,0 +1,286 @@
+package pulse
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A SortKey orders results by a single field, which may use the @ property
+// prefix just like a filter clause.
+type SortKey struct {
+	Field      string
+	Descending bool
+	path       []string
+}
+
+// Parse a sort key of the form "Field", "Field asc", "Field desc", or "-Field"
+// (descending).
+func ParseSortKey(spec string) (SortKey, error) {
+	parts := strings.Fields(spec)
+
+	if len(parts) == 0 || len(parts) > 2 {
+		return SortKey{}, fmt.Errorf("Invalid sort key %q", spec)
+	}
+
+	key := SortKey{
+		Field: parts[0],
+	}
+
+	if strings.HasPrefix(key.Field, `-`) {
+		key.Field = key.Field[1:]
+		key.Descending = true
+	}
+
+	if len(parts) == 2 {
+		switch strings.ToLower(parts[1]) {
+		case `asc`:
+			key.Descending = false
+		case `desc`:
+			key.Descending = true
+		default:
+			return SortKey{}, fmt.Errorf("Invalid sort direction %q in sort key %q", parts[1], spec)
+		}
+	}
+
+	field := key.Field
+
+	if strings.HasPrefix(field, `@`) {
+		field = `Properties.` + field[1:]
+	}
+
+	if field == `` || field == `Properties.` {
+		return SortKey{}, fmt.Errorf("Invalid sort key %q: missing field name", spec)
+	}
+
+	key.path = strings.Split(field, `.`)
+
+	return key, nil
+}
+
+func (self SortKey) String() string {
+	if self.Descending {
+		return self.Field + ` desc`
+	}
+
+	return self.Field
+}
+
+// A Query selects, orders, and pages through a list of objects such as those
+// returned by GetSinks, GetSources, GetSinkInputs, GetModules, and GetClients.
+type Query struct {
+	// Filters that objects must match (any of), as accepted by CompileFilter.
+	Filters []string
+
+	// Sort keys, applied in order, e.g.: "VolumeFactor desc", "@application.name".
+	Sort []string
+
+	// Fields to include when projecting results with Project.  If empty, all
+	// fields are included.
+	Fields []string
+
+	// The maximum number of results to return (zero for no limit), and the
+	// number of results to skip before returning any.
+	Limit  int
+	Offset int
+}
+
+// Filter, sort, and paginate the given slice, returning a new slice of the same
+// type.  Sorting is stable: objects that compare equal keep their original
+// relative order.  Objects missing a sort field are placed last.
+func (self *Query) Select(items interface{}) (interface{}, error) {
+	list := reflect.ValueOf(items)
+
+	if list.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("Query requires a slice, got %T", items)
+	} else if self.Limit < 0 || self.Offset < 0 {
+		return nil, fmt.Errorf("Query limit and offset cannot be negative")
+	}
+
+	matcher, err := CompileFilter(self.Filters...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]SortKey, 0)
+
+	for _, spec := range self.Sort {
+		if key, err := ParseSortKey(spec); err == nil {
+			keys = append(keys, key)
+		} else {
+			return nil, err
+		}
+	}
+
+	selected := make([]reflect.Value, 0)
+
+	for i := 0; i < list.Len(); i++ {
+		if item := list.Index(i); matcher.Match(item.Interface()) {
+			selected = append(selected, item)
+		}
+	}
+
+	if len(keys) > 0 {
+		sort.SliceStable(selected, func(i int, j int) bool {
+			for _, key := range keys {
+				if c := compareSortValues(selected[i], selected[j], key); c != 0 {
+					return c < 0
+				}
+			}
+
+			return false
+		})
+	}
+
+	if self.Offset >= len(selected) {
+		selected = selected[:0]
+	} else {
+		selected = selected[self.Offset:]
+	}
+
+	if self.Limit > 0 && self.Limit < len(selected) {
+		selected = selected[:self.Limit]
+	}
+
+	out := reflect.MakeSlice(list.Type(), len(selected), len(selected))
+
+	for i, item := range selected {
+		out.Index(i).Set(item)
+	}
+
+	return out.Interface(), nil
+}
+
+// Select from the given slice, then reduce each result to a map containing only
+// the query's Fields.  Fields may name a leaf value (e.g.: "Name",
+// "@application.name") or a whole subtree (e.g.: "Properties").
+func (self *Query) Project(items interface{}) ([]map[string]interface{}, error) {
+	selected, err := self.Select(items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	projection := make(Filter, 0)
+
+	for _, field := range self.Fields {
+		if clause, err := ParseFilterClause(field + FieldValueSeparator + `exists`); err == nil {
+			projection = append(projection, clause.String())
+		} else {
+			return nil, err
+		}
+	}
+
+	list := reflect.ValueOf(selected)
+	out := make([]map[string]interface{}, list.Len())
+
+	for i := range out {
+		data := nativeMap(list.Index(i).Interface())
+
+		if len(projection) > 0 {
+			data = projection.Apply(data)
+		}
+
+		out[i] = data
+	}
+
+	return out, nil
+}
+
+// compare the values of a sort key on two objects, returning -1, 0, or 1
+func compareSortValues(a reflect.Value, b reflect.Value, key SortKey) int {
+	av, aok := lookupFieldPath(a, key.path)
+	bv, bok := lookupFieldPath(b, key.path)
+
+	// missing values always sort last, regardless of direction
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return 1
+	case !bok:
+		return -1
+	}
+
+	c := compareValueStrings(fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
+
+	if key.Descending {
+		return -c
+	}
+
+	return c
+}
+
+// compare two values numerically if both are numbers, otherwise as strings
+func compareValueStrings(a string, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+func runQuery(query *Query, fetch func() (interface{}, error)) (interface{}, error) {
+	if items, err := fetch(); err == nil {
+		return query.Select(items)
+	} else {
+		return nil, err
+	}
+}
+
+// Retrieve the sinks selected by the given query.
+func (self *Conn) QuerySinks(query *Query) ([]*Sink, error) {
+	if result, err := runQuery(query, func() (interface{}, error) { return self.GetSinks() }); err == nil {
+		return result.([]*Sink), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Retrieve the sources selected by the given query.
+func (self *Conn) QuerySources(query *Query) ([]*Source, error) {
+	if result, err := runQuery(query, func() (interface{}, error) { return self.GetSources() }); err == nil {
+		return result.([]*Source), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Retrieve the sink inputs selected by the given query.
+func (self *Conn) QuerySinkInputs(query *Query) ([]SinkInput, error) {
+	if result, err := runQuery(query, func() (interface{}, error) { return self.GetSinkInputs() }); err == nil {
+		return result.([]SinkInput), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Retrieve the modules selected by the given query.
+func (self *Conn) QueryModules(query *Query) ([]*Module, error) {
+	if result, err := runQuery(query, func() (interface{}, error) { return self.GetModules() }); err == nil {
+		return result.([]*Module), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Retrieve the clients selected by the given query.
+func (self *Conn) QueryClients(query *Query) ([]*Client, error) {
+	if result, err := runQuery(query, func() (interface{}, error) { return self.GetClients() }); err == nil {
+		return result.([]*Client), nil
+	} else {
+		return nil, err
+	}
+}
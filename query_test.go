@@ -0,0 +1,118 @@
+package pulse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func querySinks() []*Sink {
+	return []*Sink{
+		{Index: 0, Name: `a`, VolumeFactor: 0.5, Properties: map[string]interface{}{`application`: map[string]interface{}{`name`: `zeta`}}},
+		{Index: 1, Name: `b`, VolumeFactor: 1.0, Properties: map[string]interface{}{`application`: map[string]interface{}{`name`: `alpha`}}},
+		{Index: 2, Name: `c`, VolumeFactor: 0.5},
+		{Index: 3, Name: `d`, VolumeFactor: 0.25, Properties: map[string]interface{}{`application`: map[string]interface{}{`name`: `alpha`}}},
+	}
+}
+
+func sinkNames(t *testing.T, result interface{}) []string {
+	sinks, ok := result.([]*Sink)
+	require.True(t, ok)
+
+	names := make([]string, len(sinks))
+
+	for i, sink := range sinks {
+		names[i] = sink.Name
+	}
+
+	return names
+}
+
+func TestParseSortKey(t *testing.T) {
+	assert := require.New(t)
+
+	key, err := ParseSortKey(`VolumeFactor desc`)
+	assert.NoError(err)
+	assert.Equal(`VolumeFactor`, key.Field)
+	assert.True(key.Descending)
+
+	key, err = ParseSortKey(`-@application.name`)
+	assert.NoError(err)
+	assert.Equal([]string{`Properties`, `application`, `name`}, key.path)
+	assert.True(key.Descending)
+
+	_, err = ParseSortKey(`Name sideways`)
+	assert.Error(err)
+
+	_, err = ParseSortKey(``)
+	assert.Error(err)
+}
+
+func TestQuerySelect(t *testing.T) {
+	assert := require.New(t)
+
+	query := &Query{
+		Sort: []string{`VolumeFactor desc`},
+	}
+
+	result, err := query.Select(querySinks())
+	assert.NoError(err)
+	assert.Equal([]string{`b`, `a`, `c`, `d`}, sinkNames(t, result))
+
+	// missing values sort last, ties keep their original order
+	query.Sort = []string{`@application.name`}
+	result, err = query.Select(querySinks())
+	assert.NoError(err)
+	assert.Equal([]string{`b`, `d`, `a`, `c`}, sinkNames(t, result))
+
+	query = &Query{
+		Filters: []string{`VolumeFactor/lt:1`},
+		Sort:    []string{`VolumeFactor`, `Name desc`},
+		Offset:  1,
+		Limit:   1,
+	}
+
+	result, err = query.Select(querySinks())
+	assert.NoError(err)
+	assert.Equal([]string{`c`}, sinkNames(t, result))
+
+	query.Offset = 10
+	result, err = query.Select(querySinks())
+	assert.NoError(err)
+	assert.Empty(sinkNames(t, result))
+
+	_, err = (&Query{Filters: []string{`Name`}}).Select(querySinks())
+	assert.True(IsFilterSyntaxErr(err))
+
+	_, err = (&Query{}).Select(querySinks()[0])
+	assert.Error(err)
+}
+
+func TestQueryProject(t *testing.T) {
+	assert := require.New(t)
+
+	query := &Query{
+		Filters: []string{`Index/1`},
+		Fields:  []string{`Name`, `@application.name`},
+	}
+
+	result, err := query.Project(querySinks())
+	assert.NoError(err)
+	assert.Equal([]map[string]interface{}{
+		{
+			`Name`: `b`,
+			`Properties`: map[string]interface{}{
+				`application`: map[string]interface{}{
+					`name`: `alpha`,
+				},
+			},
+		},
+	}, result)
+
+	query.Fields = []string{`Properties`}
+	result, err = query.Project(querySinks())
+	assert.NoError(err)
+	assert.Len(result, 1)
+	assert.NotContains(result[0], `Name`)
+	assert.Contains(result[0], `Properties`)
+}
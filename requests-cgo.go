@@ -0,0 +1,397 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"unsafe"
+)
+
+// The requests below start the given operation against libpulse; their results
+// arrive through the callbacks in conn.c, which populate and complete the
+// operation.
+
+func (self *Conn) getServerInfo(operation *Operation) {
+	operation.paOper = C.pa_context_get_server_info(
+		self.context,
+		(C.pa_server_info_cb_t)(unsafe.Pointer(C.pulse_get_server_info_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSinkInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_sink_info_list(
+		self.context,
+		(C.pa_sink_info_cb_t)(unsafe.Pointer(C.pulse_get_sink_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSinkInfoByIndex(operation *Operation, index int) {
+	operation.paOper = C.pa_context_get_sink_info_by_index(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_sink_info_cb_t)(C.pulse_get_sink_info_by_index_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSourceInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_source_info_list(
+		self.context,
+		(C.pa_source_info_cb_t)(unsafe.Pointer(C.pulse_get_source_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSourceInfoByIndex(operation *Operation, index int) {
+	operation.paOper = C.pa_context_get_source_info_by_index(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_source_info_cb_t)(C.pulse_get_source_info_by_index_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSinkInputInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_sink_input_info_list(
+		self.context,
+		(C.pa_sink_input_info_cb_t)(unsafe.Pointer(C.pulse_get_sink_input_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSinkInputInfo(operation *Operation, index int) {
+	operation.paOper = C.pa_context_get_sink_input_info(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_sink_input_info_cb_t)(C.pulse_get_sink_input_info_by_index_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSourceOutputInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_source_output_info_list(
+		self.context,
+		(C.pa_source_output_info_cb_t)(unsafe.Pointer(C.pulse_get_source_output_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getSourceOutputInfo(operation *Operation, index int) {
+	operation.paOper = C.pa_context_get_source_output_info(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_source_output_info_cb_t)(C.pulse_get_source_output_info_by_index_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getModuleInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_module_info_list(
+		self.context,
+		(C.pa_module_info_cb_t)(unsafe.Pointer(C.pulse_get_module_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getModuleInfo(operation *Operation, index uint) {
+	operation.paOper = C.pa_context_get_module_info(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_module_info_cb_t)(unsafe.Pointer(C.pulse_get_module_info_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getClientInfoList(operation *Operation) {
+	operation.paOper = C.pa_context_get_client_info_list(
+		self.context,
+		(C.pa_client_info_cb_t)(unsafe.Pointer(C.pulse_get_client_info_list_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) getClientInfo(operation *Operation, index int) {
+	operation.paOper = C.pa_context_get_client_info(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_client_info_cb_t)(C.pulse_get_client_info_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setName(operation *Operation, name string) {
+	operation.paOper = C.pa_context_set_name(
+		self.context,
+		C.CString(name),
+		(C.pa_context_success_cb_t)(unsafe.Pointer(C.pulse_generic_success_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setDefaultSink(operation *Operation, name string) {
+	operation.paOper = C.pa_context_set_default_sink(
+		self.context,
+		C.CString(name),
+		(C.pa_context_success_cb_t)(unsafe.Pointer(C.pulse_generic_success_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setDefaultSource(operation *Operation, name string) {
+	operation.paOper = C.pa_context_set_default_source(
+		self.context,
+		C.CString(name),
+		(C.pa_context_success_cb_t)(unsafe.Pointer(C.pulse_generic_success_callback)),
+		operation.Userdata(),
+	)
+}
+
+// build a volume setting every channel to the same value
+func newNativeVolume(channels int, volume uint32) *C.pa_cvolume {
+	newVolume := &C.pa_cvolume{}
+	newVolume = C.pa_cvolume_init(newVolume)
+
+	// prepare newVolume for its journey into PulseAudio
+	C.pa_cvolume_set(newVolume, C.uint(channels), C.pa_volume_t(C.uint32_t(volume)))
+
+	return newVolume
+}
+
+// build a volume with one value per channel
+func newNativeChannelVolume(volumes []uint32) *C.pa_cvolume {
+	newVolume := &C.pa_cvolume{}
+	newVolume = C.pa_cvolume_init(newVolume)
+	newVolume.channels = C.uint8_t(len(volumes))
+
+	for i, volume := range volumes {
+		newVolume.values[i] = C.pa_volume_t(C.uint32_t(volume))
+	}
+
+	return newVolume
+}
+
+func nativeBool(v bool) C.int {
+	if v {
+		return C.int(1)
+	} else {
+		return C.int(0)
+	}
+}
+
+func (self *Conn) setSinkVolumeByIndex(operation *Operation, index int, channels int, volume uint32) {
+	operation.paOper = C.pa_context_set_sink_volume_by_index(
+		self.context,
+		C.uint32_t(index),
+		newNativeVolume(channels, volume),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkChannelVolumesByIndex(operation *Operation, index int, volumes []uint32) {
+	operation.paOper = C.pa_context_set_sink_volume_by_index(
+		self.context,
+		C.uint32_t(index),
+		newNativeChannelVolume(volumes),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkMuteByIndex(operation *Operation, index int, mute bool) {
+	operation.paOper = C.pa_context_set_sink_mute_by_index(
+		self.context,
+		C.uint32_t(index),
+		nativeBool(mute),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkPortByIndex(operation *Operation, index int, port string) {
+	cName := C.CString(port)
+	defer C.free(unsafe.Pointer(cName))
+
+	operation.paOper = C.pa_context_set_sink_port_by_index(
+		self.context,
+		C.uint32_t(index),
+		cName,
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSourceVolumeByIndex(operation *Operation, index int, channels int, volume uint32) {
+	operation.paOper = C.pa_context_set_source_volume_by_index(
+		self.context,
+		C.uint32_t(index),
+		newNativeVolume(channels, volume),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSourceMuteByIndex(operation *Operation, index int, mute bool) {
+	operation.paOper = C.pa_context_set_source_mute_by_index(
+		self.context,
+		C.uint32_t(index),
+		nativeBool(mute),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSourcePortByIndex(operation *Operation, index int, port string) {
+	cName := C.CString(port)
+	defer C.free(unsafe.Pointer(cName))
+
+	operation.paOper = C.pa_context_set_source_port_by_index(
+		self.context,
+		C.uint32_t(index),
+		cName,
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkInputVolume(operation *Operation, index int, channels int, volume uint32) {
+	operation.paOper = C.pa_context_set_sink_input_volume(
+		self.context,
+		C.uint32_t(index),
+		newNativeVolume(channels, volume),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkInputChannelVolumes(operation *Operation, index int, volumes []uint32) {
+	operation.paOper = C.pa_context_set_sink_input_volume(
+		self.context,
+		C.uint32_t(index),
+		newNativeChannelVolume(volumes),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSinkInputMute(operation *Operation, index int, mute bool) {
+	operation.paOper = C.pa_context_set_sink_input_mute(
+		self.context,
+		C.uint32_t(index),
+		nativeBool(mute),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) moveSinkInputByIndex(operation *Operation, index int, sinkIndex int) {
+	operation.paOper = C.pa_context_move_sink_input_by_index(
+		self.context,
+		C.uint32_t(index),
+		C.uint32_t(sinkIndex),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) killSinkInput(operation *Operation, index int) {
+	operation.paOper = C.pa_context_kill_sink_input(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSourceOutputVolume(operation *Operation, index int, channels int, volume uint32) {
+	operation.paOper = C.pa_context_set_source_output_volume(
+		self.context,
+		C.uint32_t(index),
+		newNativeVolume(channels, volume),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) setSourceOutputMute(operation *Operation, index int, mute bool) {
+	operation.paOper = C.pa_context_set_source_output_mute(
+		self.context,
+		C.uint32_t(index),
+		nativeBool(mute),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) moveSourceOutputByIndex(operation *Operation, index int, sourceIndex int) {
+	operation.paOper = C.pa_context_move_source_output_by_index(
+		self.context,
+		C.uint32_t(index),
+		C.uint32_t(sourceIndex),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) killSourceOutput(operation *Operation, index int) {
+	operation.paOper = C.pa_context_kill_source_output(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) killClient(operation *Operation, index int) {
+	operation.paOper = C.pa_context_kill_client(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) loadModule(operation *Operation, name string, argument string) {
+	operation.paOper = C.pa_context_load_module(
+		self.context,
+		C.CString(name),
+		C.CString(argument),
+		(C.pa_context_index_cb_t)(unsafe.Pointer(C.pulse_generic_index_callback)),
+		operation.Userdata(),
+	)
+}
+
+func (self *Conn) unloadModule(operation *Operation, index uint) {
+	operation.paOper = C.pa_context_unload_module(
+		self.context,
+		C.uint32_t(index),
+		(C.pa_context_success_cb_t)(unsafe.Pointer(C.pulse_generic_success_callback)),
+		operation.Userdata(),
+	)
+}
+
+// Route the server's subscription events to this connection's EventHub.  Must
+// be called with the mainloop locked.
+func (self *Conn) setSubscribeCallback() {
+	C.pa_context_set_subscribe_callback(
+		self.context,
+		(C.pa_context_subscribe_cb_t)(unsafe.Pointer(C.pulse_subscription_event_callback)),
+		self.Userdata(),
+	)
+}
+
+func (self *Conn) subscribe(operation *Operation, mask int) {
+	operation.paOper = C.pa_context_subscribe(
+		self.context,
+		(C.pa_subscription_mask_t)(C.int(mask)),
+		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
+		operation.Userdata(),
+	)
+}
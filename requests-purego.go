@@ -0,0 +1,569 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/auroralaboratories/pulse/proto"
+)
+
+// The requests below send the given operation's command to the server.  Their
+// replies are decoded into the operation's payloads using the same properties
+// the libpulse callbacks in conn.c set, so that both backends populate objects
+// identically.
+
+// Send a command on behalf of the given operation.  The reply (or error) is
+// passed to handle with the connection locked, and whatever it returns
+// completes the operation.  The connection must be locked.
+func (self *Conn) send(operation *Operation, command proto.Command, args *proto.TagStruct, handle func(reply *proto.TagStruct, err error) error) {
+	tag, err := self.client.Request(command, args, func(reply *proto.TagStruct, err error) {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+
+		// the operation has already timed out
+		if operation.finished {
+			return
+		}
+
+		self.finish(operation, handle(reply, err))
+	})
+
+	if err == nil {
+		operation.tag = tag
+	} else {
+		self.finish(operation, handle(nil, err))
+	}
+}
+
+// Send a command on behalf of the given operation, decoding a successful
+// reply with the given function (if any).
+func (self *Conn) request(operation *Operation, command proto.Command, args *proto.TagStruct, decode func(reply *proto.TagStruct) error) {
+	self.send(operation, command, args, func(reply *proto.TagStruct, err error) error {
+		if err == nil && decode != nil {
+			return decode(reply)
+		}
+
+		return err
+	})
+}
+
+func (self *Conn) finish(operation *Operation, err error) {
+	if err == nil {
+		operation.complete()
+	} else {
+		operation.SetError(err)
+		self.lastError = err
+	}
+
+	operation.Done()
+}
+
+// Return the arguments of a request addressing an object by index.
+func indexArgs(index int) *proto.TagStruct {
+	t := proto.NewTagStruct()
+	t.PutU32(uint32(index))
+	return t
+}
+
+// Return the arguments of a request addressing a device by index rather than
+// by name.
+func deviceArgs(index int) *proto.TagStruct {
+	t := indexArgs(index)
+	t.PutNullableString(``)
+	return t
+}
+
+// Format an index as libpulse's callbacks do, such that PA_INVALID_INDEX
+// becomes -1.
+func formatIndex(v uint32) string {
+	return strconv.Itoa(int(int32(v)))
+}
+
+func formatBool(v bool) string {
+	return strconv.FormatBool(v)
+}
+
+func setVolumeProperties(operation *Operation, volume proto.CVolume, steps uint32) {
+	if len(volume) > 0 {
+		operation.setProperty(`Channels`, strconv.Itoa(len(volume)), `int`)
+
+		aggregate := volume.Max()
+
+		if volume.Min() != aggregate {
+			aggregate = volume.Avg()
+		}
+
+		operation.setProperty(`CurrentVolumeStep`, strconv.Itoa(int(aggregate)), `int`)
+		operation.setProperty(`VolumeFactor`, fmt.Sprintf("%.4f", float64(aggregate)/float64(steps)), `float`)
+	}
+}
+
+func setChannelProperties(operation *Operation, volume proto.CVolume, channelMap proto.ChannelMap) {
+	operation.setProperty(`Volume.Name`, `mean`, `int`)
+	operation.setProperty(`Volume.Value`, strconv.Itoa(int(volume.Avg())), `int`)
+
+	setChannelVolumeProperties(operation, `Channels`, volume, channelMap)
+}
+
+func setChannelVolumeProperties(operation *Operation, field string, volume proto.CVolume, channelMap proto.ChannelMap) {
+	for i, value := range volume {
+		position := ChannelInvalid
+
+		if i < len(channelMap) {
+			position = ChannelPosition(channelMap[i])
+		}
+
+		operation.setProperty(fmt.Sprintf("%s.%d.Name", field, i), position.String(), `str`)
+		operation.setProperty(fmt.Sprintf("%s.%d.Value", field, i), strconv.Itoa(int(value)), `int`)
+	}
+}
+
+func setPropListProperties(operation *Operation, props proto.PropList) {
+	keys := make([]string, 0, len(props))
+
+	for key := range props {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		operation.setProperty(key, props[key], `prop`)
+	}
+}
+
+// Return the number of formats libpulse reports for a device; servers too old
+// to list them are assumed to support PCM only.
+func (self *Conn) numFormats(formats []proto.FormatInfo) int {
+	if self.version < 21 {
+		return 1
+	}
+
+	return len(formats)
+}
+
+func (self *Conn) getServerInfo(operation *Operation) {
+	self.request(operation, proto.CommandGetServerInfo, nil, func(reply *proto.TagStruct) error {
+		var info proto.ServerInfo
+
+		if err := info.Get(reply, self.version); err != nil {
+			return err
+		}
+
+		operation.setProperty(`ServerString`, self.address, `str`)
+		operation.setProperty(`DaemonUser`, info.UserName, `str`)
+		operation.setProperty(`DaemonHostname`, info.HostName, `str`)
+		operation.setProperty(`Version`, info.PackageVersion, `str`)
+		operation.setProperty(`Name`, info.PackageName, `str`)
+		operation.setProperty(`DefaultSinkName`, info.DefaultSinkName, `str`)
+		operation.setProperty(`DefaultSourceName`, info.DefaultSourceName, `str`)
+
+		if format := SampleFormat(info.SampleSpec.Format); format.PCM().Valid() {
+			operation.setProperty(`SampleFormat`, format.String(), `str`)
+		}
+
+		operation.setProperty(`ProtocolVersion`, formatIndex(self.client.ServerVersion), `int`)
+		operation.setProperty(`LibraryProtocolVersion`, formatIndex(proto.ProtocolVersion), `int`)
+		operation.setProperty(`Cookie`, formatIndex(info.Cookie), `int`)
+		operation.setProperty(`SampleRate`, formatIndex(info.SampleSpec.Rate), `int`)
+		operation.setProperty(`Channels`, strconv.Itoa(int(info.SampleSpec.Channels)), `int`)
+
+		return nil
+	})
+}
+
+func (self *Conn) decodeSinkInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.SinkInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Description`, info.Description, `str`)
+			operation.setProperty(`MonitorSourceName`, info.MonitorSourceName, `str`)
+
+			if info.ActivePort != `` {
+				operation.setProperty(`ActivePort`, info.ActivePort, `str`)
+			}
+
+			operation.setProperty(`DriverName`, info.Driver, `str`)
+			operation.setProperty(`Muted`, formatBool(info.Mute), `bool`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`ModuleIndex`, formatIndex(info.OwnerModule), `int`)
+			operation.setProperty(`MonitorSourceIndex`, formatIndex(info.MonitorSource), `int`)
+			operation.setProperty(`CardIndex`, formatIndex(info.Card), `int`)
+			operation.setProperty(`NumPorts`, strconv.Itoa(len(info.Ports)), `int`)
+			operation.setProperty(`NumFormats`, strconv.Itoa(self.numFormats(info.Formats)), `int`)
+			operation.setProperty(`NumVolumeSteps`, formatIndex(info.NumVolumeSteps), `int`)
+			operation.setProperty(`_state`, formatIndex(info.State), `int`)
+
+			setVolumeProperties(operation, info.Volume, info.NumVolumeSteps)
+			setChannelVolumeProperties(operation, `ChannelVolumes`, info.Volume, info.ChannelMap)
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getSinkInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetSinkInfoList, nil, self.decodeSinkInfo(operation))
+}
+
+func (self *Conn) getSinkInfoByIndex(operation *Operation, index int) {
+	self.request(operation, proto.CommandGetSinkInfo, deviceArgs(index), self.decodeSinkInfo(operation))
+}
+
+func (self *Conn) decodeSourceInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.SourceInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Description`, info.Description, `str`)
+			operation.setProperty(`DriverName`, info.Driver, `str`)
+			operation.setProperty(`MonitorOfSinkName`, info.MonitorOfSinkName, `str`)
+
+			if info.ActivePort != `` {
+				operation.setProperty(`ActivePort`, info.ActivePort, `str`)
+			}
+
+			operation.setProperty(`Muted`, formatBool(info.Mute), `bool`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`ModuleIndex`, formatIndex(info.OwnerModule), `int`)
+			operation.setProperty(`MonitorOfSinkIndex`, formatIndex(info.MonitorOfSink), `int`)
+			operation.setProperty(`CardIndex`, formatIndex(info.Card), `int`)
+			operation.setProperty(`NumPorts`, strconv.Itoa(len(info.Ports)), `int`)
+			operation.setProperty(`NumFormats`, strconv.Itoa(self.numFormats(info.Formats)), `int`)
+			operation.setProperty(`NumVolumeSteps`, formatIndex(info.NumVolumeSteps), `int`)
+			operation.setProperty(`_state`, formatIndex(info.State), `int`)
+
+			setVolumeProperties(operation, info.Volume, info.NumVolumeSteps)
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getSourceInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetSourceInfoList, nil, self.decodeSourceInfo(operation))
+}
+
+func (self *Conn) getSourceInfoByIndex(operation *Operation, index int) {
+	self.request(operation, proto.CommandGetSourceInfo, deviceArgs(index), self.decodeSourceInfo(operation))
+}
+
+func (self *Conn) decodeSinkInputInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.SinkInputInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Muted`, formatBool(info.Mute), `bool`)
+			operation.setProperty(`Corked`, formatBool(info.Corked), `bool`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`ModuleIndex`, formatIndex(info.OwnerModule), `int`)
+			operation.setProperty(`ClientIndex`, formatIndex(info.Client), `int`)
+			operation.setProperty(`SinkIndex`, formatIndex(info.Sink), `int`)
+
+			setChannelProperties(operation, info.Volume, info.ChannelMap)
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getSinkInputInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetSinkInputInfoList, nil, self.decodeSinkInputInfo(operation))
+}
+
+func (self *Conn) getSinkInputInfo(operation *Operation, index int) {
+	self.request(operation, proto.CommandGetSinkInputInfo, indexArgs(index), self.decodeSinkInputInfo(operation))
+}
+
+func (self *Conn) decodeSourceOutputInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.SourceOutputInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Muted`, formatBool(info.Mute), `bool`)
+			operation.setProperty(`Corked`, formatBool(info.Corked), `bool`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`ModuleIndex`, formatIndex(info.OwnerModule), `int`)
+			operation.setProperty(`ClientIndex`, formatIndex(info.Client), `int`)
+			operation.setProperty(`SourceIndex`, formatIndex(info.Source), `int`)
+
+			setChannelProperties(operation, info.Volume, info.ChannelMap)
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getSourceOutputInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetSourceOutputInfoList, nil, self.decodeSourceOutputInfo(operation))
+}
+
+func (self *Conn) getSourceOutputInfo(operation *Operation, index int) {
+	self.request(operation, proto.CommandGetSourceOutputInfo, indexArgs(index), self.decodeSourceOutputInfo(operation))
+}
+
+func (self *Conn) decodeModuleInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.ModuleInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Argument`, info.Argument, `str`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`NumUsed`, formatIndex(info.NumUsed), `int`)
+
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getModuleInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetModuleInfoList, nil, self.decodeModuleInfo(operation))
+}
+
+func (self *Conn) getModuleInfo(operation *Operation, index uint) {
+	self.request(operation, proto.CommandGetModuleInfo, indexArgs(int(index)), self.decodeModuleInfo(operation))
+}
+
+func (self *Conn) decodeClientInfo(operation *Operation) func(reply *proto.TagStruct) error {
+	return func(reply *proto.TagStruct) error {
+		for !reply.Eof() {
+			var info proto.ClientInfo
+
+			if err := info.Get(reply, self.version); err != nil {
+				return err
+			}
+
+			operation.setProperty(`Name`, info.Name, `str`)
+			operation.setProperty(`Driver`, info.Driver, `str`)
+			operation.setProperty(`Index`, formatIndex(info.Index), `int`)
+			operation.setProperty(`OwnerModuleIndex`, formatIndex(info.OwnerModule), `int`)
+
+			setPropListProperties(operation, info.PropList)
+
+			operation.createPayload()
+		}
+
+		return nil
+	}
+}
+
+func (self *Conn) getClientInfoList(operation *Operation) {
+	self.request(operation, proto.CommandGetClientInfoList, nil, self.decodeClientInfo(operation))
+}
+
+func (self *Conn) getClientInfo(operation *Operation, index int) {
+	self.request(operation, proto.CommandGetClientInfo, indexArgs(index), self.decodeClientInfo(operation))
+}
+
+func (self *Conn) setName(operation *Operation, name string) {
+	t := proto.NewTagStruct()
+	t.PutPropList(proto.PropList{
+		`application.name`: name,
+	})
+
+	self.request(operation, proto.CommandSetClientName, t, nil)
+}
+
+func (self *Conn) setDefaultSink(operation *Operation, name string) {
+	t := proto.NewTagStruct()
+	t.PutNullableString(name)
+
+	self.request(operation, proto.CommandSetDefaultSink, t, nil)
+}
+
+func (self *Conn) setDefaultSource(operation *Operation, name string) {
+	t := proto.NewTagStruct()
+	t.PutNullableString(name)
+
+	self.request(operation, proto.CommandSetDefaultSource, t, nil)
+}
+
+func (self *Conn) setSinkVolumeByIndex(operation *Operation, index int, channels int, volume uint32) {
+	t := deviceArgs(index)
+	t.PutCVolume(proto.NewCVolume(channels, volume))
+
+	self.request(operation, proto.CommandSetSinkVolume, t, nil)
+}
+
+func (self *Conn) setSinkChannelVolumesByIndex(operation *Operation, index int, volumes []uint32) {
+	t := deviceArgs(index)
+	t.PutCVolume(proto.CVolume(volumes))
+
+	self.request(operation, proto.CommandSetSinkVolume, t, nil)
+}
+
+func (self *Conn) setSinkMuteByIndex(operation *Operation, index int, mute bool) {
+	t := deviceArgs(index)
+	t.PutBool(mute)
+
+	self.request(operation, proto.CommandSetSinkMute, t, nil)
+}
+
+func (self *Conn) setSinkPortByIndex(operation *Operation, index int, port string) {
+	t := deviceArgs(index)
+	t.PutString(port)
+
+	self.request(operation, proto.CommandSetSinkPort, t, nil)
+}
+
+func (self *Conn) setSourceVolumeByIndex(operation *Operation, index int, channels int, volume uint32) {
+	t := deviceArgs(index)
+	t.PutCVolume(proto.NewCVolume(channels, volume))
+
+	self.request(operation, proto.CommandSetSourceVolume, t, nil)
+}
+
+func (self *Conn) setSourceMuteByIndex(operation *Operation, index int, mute bool) {
+	t := deviceArgs(index)
+	t.PutBool(mute)
+
+	self.request(operation, proto.CommandSetSourceMute, t, nil)
+}
+
+func (self *Conn) setSourcePortByIndex(operation *Operation, index int, port string) {
+	t := deviceArgs(index)
+	t.PutString(port)
+
+	self.request(operation, proto.CommandSetSourcePort, t, nil)
+}
+
+func (self *Conn) setSinkInputVolume(operation *Operation, index int, channels int, volume uint32) {
+	t := indexArgs(index)
+	t.PutCVolume(proto.NewCVolume(channels, volume))
+
+	self.request(operation, proto.CommandSetSinkInputVolume, t, nil)
+}
+
+func (self *Conn) setSinkInputChannelVolumes(operation *Operation, index int, volumes []uint32) {
+	t := indexArgs(index)
+	t.PutCVolume(proto.CVolume(volumes))
+
+	self.request(operation, proto.CommandSetSinkInputVolume, t, nil)
+}
+
+func (self *Conn) setSinkInputMute(operation *Operation, index int, mute bool) {
+	t := indexArgs(index)
+	t.PutBool(mute)
+
+	self.request(operation, proto.CommandSetSinkInputMute, t, nil)
+}
+
+func (self *Conn) moveSinkInputByIndex(operation *Operation, index int, sinkIndex int) {
+	t := indexArgs(index)
+	t.PutU32(uint32(sinkIndex))
+	t.PutNullableString(``)
+
+	self.request(operation, proto.CommandMoveSinkInput, t, nil)
+}
+
+func (self *Conn) killSinkInput(operation *Operation, index int) {
+	self.request(operation, proto.CommandKillSinkInput, indexArgs(index), nil)
+}
+
+func (self *Conn) setSourceOutputVolume(operation *Operation, index int, channels int, volume uint32) {
+	t := indexArgs(index)
+	t.PutCVolume(proto.NewCVolume(channels, volume))
+
+	self.request(operation, proto.CommandSetSourceOutputVolume, t, nil)
+}
+
+func (self *Conn) setSourceOutputMute(operation *Operation, index int, mute bool) {
+	t := indexArgs(index)
+	t.PutBool(mute)
+
+	self.request(operation, proto.CommandSetSourceOutputMute, t, nil)
+}
+
+func (self *Conn) moveSourceOutputByIndex(operation *Operation, index int, sourceIndex int) {
+	t := indexArgs(index)
+	t.PutU32(uint32(sourceIndex))
+	t.PutNullableString(``)
+
+	self.request(operation, proto.CommandMoveSourceOutput, t, nil)
+}
+
+func (self *Conn) killSourceOutput(operation *Operation, index int) {
+	self.request(operation, proto.CommandKillSourceOutput, indexArgs(index), nil)
+}
+
+func (self *Conn) killClient(operation *Operation, index int) {
+	self.request(operation, proto.CommandKillClient, indexArgs(index), nil)
+}
+
+func (self *Conn) loadModule(operation *Operation, name string, argument string) {
+	t := proto.NewTagStruct()
+	t.PutString(name)
+	t.PutNullableString(argument)
+
+	self.request(operation, proto.CommandLoadModule, t, func(reply *proto.TagStruct) error {
+		if index, err := reply.GetU32(); err != nil {
+			return err
+		} else if index == proto.InvalidIndex {
+			return proto.ErrModInitFailed
+		} else {
+			operation.setProperty(`Index`, formatIndex(index), `int`)
+			return nil
+		}
+	})
+}
+
+func (self *Conn) unloadModule(operation *Operation, index uint) {
+	self.request(operation, proto.CommandUnloadModule, indexArgs(int(index)), nil)
+}
+
+// Subscription events are always passed to this connection's EventHub as they
+// arrive, so there is nothing to set up.
+func (self *Conn) setSubscribeCallback() {}
+
+func (self *Conn) subscribe(operation *Operation, mask int) {
+	t := proto.NewTagStruct()
+	t.PutU32(uint32(mask))
+
+	self.request(operation, proto.CommandSubscribe, t, nil)
+}
@@ -0,0 +1,49 @@
+package pulse
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/auroralaboratories/pulse/pcm"
+)
+
+// ResampleQuality selects the interpolation method used when resampling.
+type ResampleQuality = pcm.Quality
+
+const (
+	ResampleLinear   ResampleQuality = pcm.Linear
+	ResampleSincFast ResampleQuality = pcm.SincFast
+	ResampleSinc     ResampleQuality = pcm.Sinc
+)
+
+const DEFAULT_RESAMPLE_QUALITY = ResampleSincFast
+
+// Wrap the given reader so that audio data in the input spec is converted to
+// the output spec's sample rate and format as it is read.  Both specs must have
+// the same number of channels.  The result can be used directly as a
+// PlaybackStream's Source.
+func NewResampler(source io.Reader, in SampleSpec, out SampleSpec, quality ResampleQuality) (io.Reader, error) {
+	if in.NumChannels != out.NumChannels {
+		return nil, fmt.Errorf("Cannot resample from %d to %d channels", in.NumChannels, out.NumChannels)
+	} else if in.NumChannels <= 0 || in.SampleRate == 0 || out.SampleRate == 0 {
+		return nil, fmt.Errorf("Invalid sample spec for resampling: %v -> %v", in, out)
+	} else if !in.Format.PCM().Valid() {
+		return nil, fmt.Errorf("Cannot resample unsupported sample format %v", in.Format)
+	} else if !out.Format.PCM().Valid() {
+		return nil, fmt.Errorf("Cannot resample to unsupported sample format %v", out.Format)
+	}
+
+	if in.SampleRate == out.SampleRate {
+		return NewFormatConverter(source, in.Format, out.Format), nil
+	}
+
+	return pcm.NewResamplingReader(
+		source,
+		in.NumChannels,
+		in.Format.PCM(),
+		int(in.SampleRate),
+		out.Format.PCM(),
+		int(out.SampleRate),
+		quality,
+	), nil
+}
@@ -0,0 +1,34 @@
+package pulse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResampler(t *testing.T) {
+	assert := require.New(t)
+
+	in := SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 2}
+	out := SampleSpec{Format: FormatIEEEFloat32LE, SampleRate: 16000, NumChannels: 2}
+
+	_, err := NewResampler(nil, in, SampleSpec{Format: FormatPcmS16LE, SampleRate: 16000, NumChannels: 1}, ResampleLinear)
+	assert.Error(err)
+
+	reader, err := NewResampler(bytes.NewReader(make([]byte, 800*in.FrameSize())), in, out, ResampleLinear)
+	assert.NoError(err)
+
+	data, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Len(data, 1600*out.FrameSize())
+
+	// matching rates only convert the format
+	reader, err = NewResampler(bytes.NewReader([]byte{0x00, 0x40}), SampleSpec{Format: FormatPcmS16LE, SampleRate: 8000, NumChannels: 1}, SampleSpec{Format: FormatPcmS16BE, SampleRate: 8000, NumChannels: 1}, ResampleSinc)
+	assert.NoError(err)
+
+	data, err = ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal([]byte{0x40, 0x00}, data)
+}
@@ -0,0 +1,433 @@
+package pulse
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const DEFAULT_ROUTER_LOG_SIZE = 1000
+
+// The actions a Router can take on a stream.
+const (
+	RouteMove   = `move`
+	RouteVolume = `volume`
+	RouteMute   = `mute`
+)
+
+// A RouteRule sends streams matching a filter to a particular device, and
+// optionally sets their volume and mute state.  A rule with a Sink applies only
+// to playback streams (sink inputs), a rule with a Source only to recording
+// streams (source outputs); a rule with neither applies to both.
+type RouteRule struct {
+	// A name for the rule, used in the action log.
+	Name string
+
+	// A filter that streams must match, as accepted by CompileFilter, e.g.:
+	// "@application.name/contains:zoom".
+	Filter string
+
+	// The name of the sink or source that matching streams are moved to.
+	Sink   string
+	Source string
+
+	// If set, the volume (as a factor of the normal volume) and mute state given
+	// to matching streams when they first appear.
+	Volume *float64
+	Mute   *bool
+
+	matcher *Matcher
+}
+
+func (self *RouteRule) String() string {
+	if self.Name != `` {
+		return self.Name
+	}
+
+	return self.Filter
+}
+
+func (self *RouteRule) appliesTo(kind string) bool {
+	switch kind {
+	case ObjectSinkInput:
+		return self.Source == ``
+	case ObjectSourceOutput:
+		return self.Sink == ``
+	default:
+		return false
+	}
+}
+
+// A RouteAction records something a Router did (or, in dry-run mode, would have
+// done) to a stream.  Error is the message of the error returned by the server,
+// if any.
+type RouteAction struct {
+	Time   time.Time
+	Rule   string
+	Kind   string
+	Index  int
+	Stream string
+	Action string
+	Target string
+	DryRun bool
+	Error  string `json:",omitempty"`
+	apply  func() error
+}
+
+func (self RouteAction) String() string {
+	var out string
+
+	if self.DryRun {
+		out = `(dry run) `
+	}
+
+	out += fmt.Sprintf("%s %d (%s): %s %s [rule %s]", self.Kind, self.Index, self.Stream, self.Action, self.Target, self.Rule)
+
+	if self.Error != `` {
+		out += `: ` + self.Error
+	}
+
+	return out
+}
+
+// A Router moves streams to devices according to an ordered list of rules.  Each
+// stream is handled by the first rule it matches.  Streams are routed when they
+// appear, and again whenever the device a rule targets appears (e.g.: a headset
+// being plugged in).  Volume and mute settings are only applied to new streams,
+// so later changes made by the user are left alone.
+type Router struct {
+	Rules   []*RouteRule
+	DryRun  bool
+	server  Server
+	sub     *Subscription
+	log     []RouteAction
+	planned map[string]string
+	lock    sync.Mutex
+}
+
+// Create a Router with the given rules.  An error is returned if any rule's
+// filter is invalid or a rule targets both a sink and a source.
+func NewRouter(server Server, rules ...RouteRule) (*Router, error) {
+	router := &Router{
+		Rules:   make([]*RouteRule, 0),
+		server:  server,
+		log:     make([]RouteAction, 0),
+		planned: make(map[string]string),
+	}
+
+	for i := range rules {
+		rule := rules[i]
+
+		if rule.Sink != `` && rule.Source != `` {
+			return nil, fmt.Errorf("Route rule %q cannot target both a sink and a source", rule.String())
+		}
+
+		if matcher, err := CompileFilter(rule.Filter); err == nil {
+			rule.matcher = matcher
+		} else {
+			return nil, err
+		}
+
+		router.Rules = append(router.Rules, &rule)
+	}
+
+	return router, nil
+}
+
+// Route all existing streams once, then keep routing as streams and devices
+// appear until Stop is called.
+func (self *Router) Start() error {
+	if sub, err := self.server.SubscribeEvents(SinkEvent, SourceEvent, SinkInputEvent, SourceOutputEvent); err == nil {
+		self.sub = sub
+	} else {
+		return err
+	}
+
+	if _, err := self.Apply(); err != nil {
+		self.sub.Close()
+		return err
+	}
+
+	go self.run()
+
+	return nil
+}
+
+// Stop watching for new streams and devices.
+func (self *Router) Stop() error {
+	if self.sub != nil {
+		return self.sub.Close()
+	}
+
+	return nil
+}
+
+// Route every existing stream now, returning the actions taken.  Only moves
+// are performed; volume and mute are reserved for new streams.
+func (self *Router) Apply() ([]RouteAction, error) {
+	actions := make([]RouteAction, 0)
+
+	if sinks, err := self.server.GetSinks(); err == nil {
+		if sinkInputs, err := self.server.GetSinkInputs(); err == nil {
+			for i := range sinkInputs {
+				actions = append(actions, self.planSinkInput(&sinkInputs[i], sinks, false)...)
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	if sources, err := self.server.GetSources(); err == nil {
+		if sourceOutputs, err := self.server.GetSourceOutputs(); err == nil {
+			for i := range sourceOutputs {
+				actions = append(actions, self.planSourceOutput(&sourceOutputs[i], sources, false)...)
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	return self.execute(actions), nil
+}
+
+// Return the actions taken so far, oldest first.  At most
+// DEFAULT_ROUTER_LOG_SIZE actions are kept.
+func (self *Router) Log() []RouteAction {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	out := make([]RouteAction, len(self.log))
+	copy(out, self.log)
+
+	return out
+}
+
+func (self *Router) run() {
+	for event := range self.sub.Events() {
+		if event.Kind == EventRemove {
+			switch event.Facility {
+			case SinkInputEvent:
+				self.forget(ObjectSinkInput, event.Index)
+			case SourceOutputEvent:
+				self.forget(ObjectSourceOutput, event.Index)
+			}
+
+			continue
+		} else if event.Kind != EventNew {
+			continue
+		}
+
+		switch event.Facility {
+		case SinkInputEvent:
+			if sinkInput, err := getSinkInput(self.server, event.Index); err == nil {
+				if sinks, err := self.server.GetSinks(); err == nil {
+					self.execute(self.planSinkInput(sinkInput, sinks, true))
+				}
+			}
+
+		case SourceOutputEvent:
+			if sourceOutput, err := getSourceOutput(self.server, event.Index); err == nil {
+				if sources, err := self.server.GetSources(); err == nil {
+					self.execute(self.planSourceOutput(sourceOutput, sources, true))
+				}
+			}
+
+		case SinkEvent, SourceEvent:
+			// a device appeared; streams whose rules target it can now be moved
+			self.Apply()
+		}
+	}
+}
+
+// return the first rule matching the given stream
+func (self *Router) ruleFor(kind string, stream interface{}) *RouteRule {
+	for _, rule := range self.Rules {
+		if rule.appliesTo(kind) && rule.matcher.Match(stream) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+func (self *Router) planSinkInput(sinkInput *SinkInput, sinks []*Sink, isNew bool) []RouteAction {
+	actions := make([]RouteAction, 0)
+	rule := self.ruleFor(ObjectSinkInput, sinkInput)
+
+	if rule == nil {
+		return actions
+	}
+
+	action := RouteAction{
+		Rule:   rule.String(),
+		Kind:   ObjectSinkInput,
+		Index:  sinkInput.Index,
+		Stream: sinkInput.Name,
+	}
+
+	if rule.Sink != `` {
+		for _, sink := range sinks {
+			if sink.Name == rule.Sink {
+				if sink.Index != sinkInput.SinkIndex {
+					index := sink.Index
+					move := action
+					move.Action = RouteMove
+					move.Target = sink.Name
+					move.apply = func() error {
+						return self.server.MoveSinkInput(sinkInput.Index, index)
+					}
+
+					actions = append(actions, move)
+				}
+
+				break
+			}
+		}
+	}
+
+	if isNew {
+		if factor := rule.Volume; factor != nil && *factor != sinkInput.VolumeFactor() {
+			volume := action
+			volume.Action = RouteVolume
+			volume.Target = fmt.Sprintf("%g", *factor)
+			volume.apply = func() error {
+				return self.server.SetSinkInputVolume(sinkInput.Index, *factor)
+			}
+
+			actions = append(actions, volume)
+		}
+
+		if mute := rule.Mute; mute != nil && *mute != sinkInput.Muted {
+			muting := action
+			muting.Action = RouteMute
+			muting.Target = fmt.Sprintf("%v", *mute)
+			muting.apply = func() error {
+				return self.server.SetSinkInputMute(sinkInput.Index, *mute)
+			}
+
+			actions = append(actions, muting)
+		}
+	}
+
+	return actions
+}
+
+func (self *Router) planSourceOutput(sourceOutput *SourceOutput, sources []*Source, isNew bool) []RouteAction {
+	actions := make([]RouteAction, 0)
+	rule := self.ruleFor(ObjectSourceOutput, sourceOutput)
+
+	if rule == nil {
+		return actions
+	}
+
+	action := RouteAction{
+		Rule:   rule.String(),
+		Kind:   ObjectSourceOutput,
+		Index:  sourceOutput.Index,
+		Stream: sourceOutput.Name,
+	}
+
+	if rule.Source != `` {
+		for _, source := range sources {
+			if source.Name == rule.Source {
+				if source.Index != sourceOutput.SourceIndex {
+					index := source.Index
+					move := action
+					move.Action = RouteMove
+					move.Target = source.Name
+					move.apply = func() error {
+						return self.server.MoveSourceOutput(sourceOutput.Index, index)
+					}
+
+					actions = append(actions, move)
+				}
+
+				break
+			}
+		}
+	}
+
+	if isNew {
+		if factor := rule.Volume; factor != nil && *factor != sourceOutput.VolumeFactor() {
+			volume := action
+			volume.Action = RouteVolume
+			volume.Target = fmt.Sprintf("%g", *factor)
+			volume.apply = func() error {
+				return self.server.SetSourceOutputVolume(sourceOutput.Index, *factor)
+			}
+
+			actions = append(actions, volume)
+		}
+
+		if mute := rule.Mute; mute != nil && *mute != sourceOutput.Muted {
+			muting := action
+			muting.Action = RouteMute
+			muting.Target = fmt.Sprintf("%v", *mute)
+			muting.apply = func() error {
+				return self.server.SetSourceOutputMute(sourceOutput.Index, *mute)
+			}
+
+			actions = append(actions, muting)
+		}
+	}
+
+	return actions
+}
+
+// perform (unless in dry-run mode) and log the given actions.  In dry-run mode
+// nothing changes on the server, so every device event would plan the same
+// actions again; those already logged with the same target are left out of the
+// log.
+func (self *Router) execute(actions []RouteAction) []RouteAction {
+	for i := range actions {
+		actions[i].Time = time.Now()
+		actions[i].DryRun = self.DryRun
+
+		if !self.DryRun && actions[i].apply != nil {
+			if err := actions[i].apply(); err != nil {
+				actions[i].Error = err.Error()
+			}
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, action := range actions {
+		if self.DryRun {
+			key := plannedKey(action.Kind, action.Index, action.Action)
+
+			if target, ok := self.planned[key]; ok && target == action.Target {
+				continue
+			}
+
+			self.planned[key] = action.Target
+		}
+
+		self.log = append(self.log, action)
+	}
+
+	if overflow := len(self.log) - DEFAULT_ROUTER_LOG_SIZE; overflow > 0 {
+		self.log = append(self.log[:0:0], self.log[overflow:]...)
+	}
+
+	return actions
+}
+
+// drop the dry-run actions planned for a stream that has gone away
+func (self *Router) forget(kind string, index int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, action := range []string{RouteMove, RouteVolume, RouteMute} {
+		delete(self.planned, plannedKey(kind, index, action))
+	}
+}
+
+func plannedKey(kind string, index int, action string) string {
+	return fmt.Sprintf("%s/%d/%s", kind, index, action)
+}
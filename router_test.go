@@ -0,0 +1,128 @@
+package pulse
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterRules(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := NewRouter(nil, RouteRule{Filter: `@application.name/re:(`, Sink: `headset`})
+	assert.True(IsFilterSyntaxErr(err))
+
+	_, err = NewRouter(nil, RouteRule{Filter: `Name/x`, Sink: `headset`, Source: `mic`})
+	assert.Error(err)
+
+	router, err := NewRouter(nil,
+		RouteRule{Name: `zoom`, Filter: `@application.name/contains:zoom`, Sink: `headset`, Volume: profileFloat(0.5)},
+		RouteRule{Name: `zoom-mic`, Filter: `@application.name/contains:zoom`, Source: `headset-mic`, Mute: profileBool(false)},
+		RouteRule{Name: `everything`, Filter: `Name/exists`, Sink: `speakers`},
+	)
+	assert.NoError(err)
+	assert.Len(router.Rules, 3)
+
+	sinks := []*Sink{
+		{Index: 0, Name: `speakers`},
+		{Index: 1, Name: `headset`},
+	}
+
+	zoom := &SinkInput{
+		Index:      12,
+		Name:       `Call`,
+		SinkIndex:  0,
+		Volume:     Volume{Value: DefaultVolumeStep},
+		Properties: map[string]interface{}{`application`: map[string]interface{}{`name`: `zoom.us`}},
+	}
+
+	// existing streams are only moved
+	actions := router.planSinkInput(zoom, sinks, false)
+	assert.Len(actions, 1)
+	assert.Equal(RouteMove, actions[0].Action)
+	assert.Equal(`headset`, actions[0].Target)
+	assert.Equal(`zoom`, actions[0].Rule)
+
+	// new streams also get the rule's volume and mute settings
+	actions = router.planSinkInput(zoom, sinks, true)
+	assert.Len(actions, 2)
+	assert.Equal(RouteVolume, actions[1].Action)
+	assert.Equal(`0.5`, actions[1].Target)
+
+	// nothing to do for a stream already on its target
+	other := &SinkInput{Index: 13, Name: `Music`, SinkIndex: 0}
+	assert.Empty(router.planSinkInput(other, sinks, true))
+
+	// targets that are not present are skipped until they appear
+	assert.Empty(router.planSinkInput(zoom, sinks[:1], false))
+
+	// sink rules never apply to source outputs
+	recording := &SourceOutput{
+		Index:       4,
+		Name:        `Mic`,
+		SourceIndex: 0,
+		Muted:       true,
+		Properties:  map[string]interface{}{`application`: map[string]interface{}{`name`: `zoom.us`}},
+	}
+
+	actions = router.planSourceOutput(recording, []*Source{{Index: 0, Name: `mic`}, {Index: 3, Name: `headset-mic`}}, true)
+	assert.Len(actions, 2)
+	assert.Equal(ObjectSourceOutput, actions[0].Kind)
+	assert.Equal(RouteMove, actions[0].Action)
+	assert.Equal(`headset-mic`, actions[0].Target)
+	assert.Equal(RouteMute, actions[1].Action)
+	assert.Equal(`false`, actions[1].Target)
+}
+
+func TestRouterDryRun(t *testing.T) {
+	assert := require.New(t)
+
+	router, err := NewRouter(nil, RouteRule{Filter: `Name/Call`, Sink: `headset`})
+	assert.NoError(err)
+	router.DryRun = true
+
+	called := false
+	actions := router.planSinkInput(&SinkInput{Index: 1, Name: `Call`}, []*Sink{{Index: 5, Name: `headset`}}, false)
+	actions[0].apply = func() error {
+		called = true
+		return nil
+	}
+
+	router.execute(actions)
+	assert.False(called)
+
+	log := router.Log()
+	assert.Len(log, 1)
+	assert.True(log[0].DryRun)
+	assert.False(log[0].Time.IsZero())
+	assert.Equal(`(dry run) sink-input 1 (Call): move headset [rule Name/Call]`, log[0].String())
+
+	// planning the same move again (e.g.: another device appeared) is not logged
+	router.execute(router.planSinkInput(&SinkInput{Index: 1, Name: `Call`}, []*Sink{{Index: 5, Name: `headset`}}, false))
+	assert.Len(router.Log(), 1)
+
+	// ...unless the stream went away and its index is logged afresh
+	router.forget(ObjectSinkInput, 1)
+	router.execute(router.planSinkInput(&SinkInput{Index: 1, Name: `Call`}, []*Sink{{Index: 5, Name: `headset`}}, false))
+	assert.Len(router.Log(), 2)
+
+	router.DryRun = false
+	actions[0].apply = func() error {
+		called = true
+		return fmt.Errorf("No such entity")
+	}
+
+	router.execute(actions)
+	assert.True(called)
+
+	log = router.Log()
+	assert.Len(log, 3)
+	assert.Equal(`No such entity`, log[2].Error)
+	assert.Equal(`sink-input 1 (Call): move headset [rule Name/Call]: No such entity`, log[2].String())
+
+	data, err := json.Marshal(log[2])
+	assert.NoError(err)
+	assert.Contains(string(data), `"Error":"No such entity"`)
+}
@@ -0,0 +1,60 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"unsafe"
+)
+
+// The highest sample rate that the linked libpulse accepts (PA_RATE_MAX).
+const MAX_SAMPLE_RATE = C.PA_RATE_MAX
+
+func sampleFormatName(format SampleFormat) string {
+	return C.GoString(C.pa_sample_format_to_string(C.pa_sample_format_t(format)))
+}
+
+func parseSampleFormat(name string) SampleFormat {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	return GetSampleFormat(int(C.pa_parse_sample_format(cName)))
+}
+
+func sampleSpecValid(spec SampleSpec) bool {
+	return int(C.pa_sample_spec_valid(spec.toNative())) != 0
+}
+
+func frameSize(spec SampleSpec) int {
+	return int(C.pa_frame_size(spec.toNative()))
+}
+
+func bytesPerSecond(spec SampleSpec) int {
+	return int(C.pa_bytes_per_second(spec.toNative()))
+}
+
+func bytesToUsec(length uint64, spec SampleSpec) uint64 {
+	return uint64(C.pa_bytes_to_usec(C.uint64_t(length), spec.toNative()))
+}
+
+func usecToBytes(usec uint64, spec SampleSpec) uint64 {
+	return uint64(C.pa_usec_to_bytes(C.pa_usec_t(usec), spec.toNative()))
+}
+
+func (self *SampleSpec) toNative() *C.pa_sample_spec {
+	rv := C.pulse_new_sample_spec((C.pa_sample_format_t)(self.Format), C.uint32_t(self.SampleRate), C.uint8_t(self.NumChannels))
+	return &rv
+}
+
+func sampleSpecFromNative(native *C.pa_sample_spec) SampleSpec {
+	return SampleSpec{
+		Format:      GetSampleFormat(int(native.format)),
+		SampleRate:  uint32(native.rate),
+		NumChannels: int(native.channels),
+	}
+}
@@ -0,0 +1,97 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+import (
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// The highest sample rate accepted by current daemons, whose PA_RATE_MAX is
+// 768 kHz.  Older daemons refuse rates above their own (lower) limit when the
+// stream is created, so checking against the newer one only defers the error
+// rather than rejecting rates that a current daemon supports.
+const MAX_SAMPLE_RATE = 48000 * 16
+
+// names accepted by ParseSampleFormat, as understood by pa_parse_sample_format,
+// with the format each means on little and big endian hosts (respectively)
+var sampleFormatNames = map[string][2]SampleFormat{
+	`u8`:        {FormatPcmU8, FormatPcmU8},
+	`8`:         {FormatPcmU8, FormatPcmU8},
+	`alaw`:      {FormatALaw8, FormatALaw8},
+	`ulaw`:      {FormatMuLaw8, FormatMuLaw8},
+	`mulaw`:     {FormatMuLaw8, FormatMuLaw8},
+	`s16le`:     {FormatPcmS16LE, FormatPcmS16LE},
+	`s16be`:     {FormatPcmS16BE, FormatPcmS16BE},
+	`s16ne`:     {FormatPcmS16LE, FormatPcmS16BE},
+	`s16`:       {FormatPcmS16LE, FormatPcmS16BE},
+	`16`:        {FormatPcmS16LE, FormatPcmS16BE},
+	`s16re`:     {FormatPcmS16BE, FormatPcmS16LE},
+	`float32le`: {FormatIEEEFloat32LE, FormatIEEEFloat32LE},
+	`float32be`: {FormatIEEEFloat32BE, FormatIEEEFloat32BE},
+	`float32ne`: {FormatIEEEFloat32LE, FormatIEEEFloat32BE},
+	`float32`:   {FormatIEEEFloat32LE, FormatIEEEFloat32BE},
+	`float`:     {FormatIEEEFloat32LE, FormatIEEEFloat32BE},
+	`float32re`: {FormatIEEEFloat32BE, FormatIEEEFloat32LE},
+	`s32le`:     {FormatPcmS32LE, FormatPcmS32LE},
+	`s32be`:     {FormatPcmS32BE, FormatPcmS32BE},
+	`s32ne`:     {FormatPcmS32LE, FormatPcmS32BE},
+	`s32`:       {FormatPcmS32LE, FormatPcmS32BE},
+	`32`:        {FormatPcmS32LE, FormatPcmS32BE},
+	`s32re`:     {FormatPcmS32BE, FormatPcmS32LE},
+	`s24le`:     {FormatPcmS24PackedLE, FormatPcmS24PackedLE},
+	`s24be`:     {FormatPcmS24PackedBE, FormatPcmS24PackedBE},
+	`s24ne`:     {FormatPcmS24PackedLE, FormatPcmS24PackedBE},
+	`s24`:       {FormatPcmS24PackedLE, FormatPcmS24PackedBE},
+	`24`:        {FormatPcmS24PackedLE, FormatPcmS24PackedBE},
+	`s24re`:     {FormatPcmS24PackedBE, FormatPcmS24PackedLE},
+	`s24-32le`:  {FormatPcmS24Lsb32LE, FormatPcmS24Lsb32LE},
+	`s24-32be`:  {FormatPcmS24Lsb32BE, FormatPcmS24Lsb32BE},
+	`s24-32ne`:  {FormatPcmS24Lsb32LE, FormatPcmS24Lsb32BE},
+	`s24-32`:    {FormatPcmS24Lsb32LE, FormatPcmS24Lsb32BE},
+	`s24-32re`:  {FormatPcmS24Lsb32BE, FormatPcmS24Lsb32LE},
+}
+
+// whether this host stores integers least significant byte first
+func isLittleEndian() bool {
+	var probe uint16 = 1
+	return *(*byte)(unsafe.Pointer(&probe)) == 1
+}
+
+func sampleFormatName(format SampleFormat) string {
+	return format.PCM().String()
+}
+
+func parseSampleFormat(name string) SampleFormat {
+	if formats, ok := sampleFormatNames[strings.ToLower(name)]; ok {
+		if isLittleEndian() {
+			return formats[0]
+		} else {
+			return formats[1]
+		}
+	}
+
+	return FormatInvalid
+}
+
+func sampleSpecValid(spec SampleSpec) bool {
+	return spec.Format.PCM().Valid() && spec.SampleRate > 0 && spec.SampleRate <= MAX_SAMPLE_RATE
+}
+
+func frameSize(spec SampleSpec) int {
+	return spec.Format.PCM().SampleSize() * spec.NumChannels
+}
+
+func bytesPerSecond(spec SampleSpec) int {
+	return int(spec.SampleRate) * frameSize(spec)
+}
+
+func bytesToUsec(length uint64, spec SampleSpec) uint64 {
+	return (length / uint64(frameSize(spec))) * uint64(time.Second/time.Microsecond) / uint64(spec.SampleRate)
+}
+
+func usecToBytes(usec uint64, spec SampleSpec) uint64 {
+	return (usec * uint64(spec.SampleRate) / uint64(time.Second/time.Microsecond)) * uint64(frameSize(spec))
+}
@@ -1,101 +1,213 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const (
 	DEFAULT_SAMPLE_RATE  = 44100
 	DEFAULT_NUM_CHANNELS = 2
+	MAX_CHANNELS         = 32
 )
 
 type SampleFormat int
 
 const (
-	FormatInvalid        SampleFormat = C.PA_SAMPLE_INVALID   // An invalid value.
-	FormatPcmU8                       = C.PA_SAMPLE_U8        // Unsigned 8 Bit PCM.
-	FormatALaw8                       = C.PA_SAMPLE_ALAW      // 8 Bit a-Law
-	FormatMuLaw8                      = C.PA_SAMPLE_ULAW      // 8 Bit mu-Law
-	FormatPcmS16LE                    = C.PA_SAMPLE_S16LE     // Signed 16 Bit PCM, little endian (PC)
-	FormatPcmS16BE                    = C.PA_SAMPLE_S16BE     // Signed 16 Bit PCM, big endian.
-	FormatIEEEFloat32LE               = C.PA_SAMPLE_FLOAT32LE // 32 Bit IEEE floating point, little endian (PC), range -1.0 to 1.0
-	FormatIEEEFloat32BE               = C.PA_SAMPLE_FLOAT32BE // 32 Bit IEEE floating point, big endian, range -1.0 to 1.0
-	FormatPcmS32LE                    = C.PA_SAMPLE_S32LE     // Signed 32 Bit PCM, little endian (PC)
-	FormatPcmS32BE                    = C.PA_SAMPLE_S32BE     // Signed 32 Bit PCM, big endian.
-	FormatPcmS24PackedLE              = C.PA_SAMPLE_S24LE     // Signed 24 Bit PCM packed, little endian (PC).
-	FormatPcmS24PackedBE              = C.PA_SAMPLE_S24BE     // Signed 24 Bit PCM packed, big endian. (Since 0.9.15)
-	FormatPcmS24Lsb32LE               = C.PA_SAMPLE_S24_32LE  // Signed 24 Bit PCM in LSB of 32 Bit words, little endian (PC). (Since 0.9.15)
-	FormatPcmS24Lsb32BE               = C.PA_SAMPLE_S24_32BE  // Signed 24 Bit PCM in LSB of 32 Bit words, big endian. (Since 0.9.15)
-	FormatMax                         = C.PA_SAMPLE_MAX       // Upper limit of valid sample types. (Since 0.9.15)
+	FormatInvalid        SampleFormat = -1 // An invalid value.
+	FormatPcmU8          SampleFormat = 0  // Unsigned 8 Bit PCM.
+	FormatALaw8          SampleFormat = 1  // 8 Bit a-Law
+	FormatMuLaw8         SampleFormat = 2  // 8 Bit mu-Law
+	FormatPcmS16LE       SampleFormat = 3  // Signed 16 Bit PCM, little endian (PC)
+	FormatPcmS16BE       SampleFormat = 4  // Signed 16 Bit PCM, big endian.
+	FormatIEEEFloat32LE  SampleFormat = 5  // 32 Bit IEEE floating point, little endian (PC), range -1.0 to 1.0
+	FormatIEEEFloat32BE  SampleFormat = 6  // 32 Bit IEEE floating point, big endian, range -1.0 to 1.0
+	FormatPcmS32LE       SampleFormat = 7  // Signed 32 Bit PCM, little endian (PC)
+	FormatPcmS32BE       SampleFormat = 8  // Signed 32 Bit PCM, big endian.
+	FormatPcmS24PackedLE SampleFormat = 9  // Signed 24 Bit PCM packed, little endian (PC).
+	FormatPcmS24PackedBE SampleFormat = 10 // Signed 24 Bit PCM packed, big endian. (Since 0.9.15)
+	FormatPcmS24Lsb32LE  SampleFormat = 11 // Signed 24 Bit PCM in LSB of 32 Bit words, little endian (PC). (Since 0.9.15)
+	FormatPcmS24Lsb32BE  SampleFormat = 12 // Signed 24 Bit PCM in LSB of 32 Bit words, big endian. (Since 0.9.15)
+	FormatMax            SampleFormat = 13 // Upper limit of valid sample types. (Since 0.9.15)
 )
 
+// Return the PulseAudio name for this format (e.g.: "s16le", "float32be").
+func (self SampleFormat) String() string {
+	if self == FormatInvalid || self == FormatMax {
+		return `invalid`
+	}
+
+	return sampleFormatName(self)
+}
+
+func (self SampleFormat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(self.String())
+}
+
+func (self *SampleFormat) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	if format, err := ParseSampleFormat(name); err == nil {
+		*self = format
+		return nil
+	} else {
+		return err
+	}
+}
+
+// Parse a sample format name as understood by PulseAudio (e.g.: "s16le",
+// "s16ne", "float32", "ulaw").
+func ParseSampleFormat(name string) (SampleFormat, error) {
+	if format := parseSampleFormat(name); format != FormatInvalid && format != FormatMax {
+		return format, nil
+	}
+
+	return FormatInvalid, fmt.Errorf("Invalid sample format %q", name)
+}
+
 // A SampleSpec describes an audio sampling format
-//
 type SampleSpec struct {
 	Format      SampleFormat
 	SampleRate  uint32
 	NumChannels int
 }
 
-func (self *SampleSpec) toNative() *C.pa_sample_spec {
-	rv := C.pulse_new_sample_spec((C.pa_sample_format_t)(self.Format), C.uint32_t(self.SampleRate), C.uint8_t(self.NumChannels))
-	return &rv
+// Parse a sample spec in the form PulseAudio prints them (e.g.: "s16le 2ch 48000Hz").
+// The format, channel count, and sample rate may appear in any order.
+func ParseSampleSpec(spec string) (SampleSpec, error) {
+	var rv SampleSpec
+	var hasFormat, hasChannels, hasRate bool
+
+	for _, part := range strings.Fields(spec) {
+		lower := strings.ToLower(part)
+
+		switch {
+		case strings.HasSuffix(lower, `ch`):
+			if n, err := strconv.Atoi(strings.TrimSuffix(lower, `ch`)); err == nil {
+				rv.NumChannels = n
+				hasChannels = true
+			} else {
+				return SampleSpec{}, fmt.Errorf("Invalid channel count %q", part)
+			}
+
+		case strings.HasSuffix(lower, `hz`):
+			if n, err := strconv.ParseUint(strings.TrimSuffix(lower, `hz`), 10, 32); err == nil {
+				rv.SampleRate = uint32(n)
+				hasRate = true
+			} else {
+				return SampleSpec{}, fmt.Errorf("Invalid sample rate %q", part)
+			}
+
+		default:
+			if format, err := ParseSampleFormat(part); err == nil {
+				rv.Format = format
+				hasFormat = true
+			} else {
+				return SampleSpec{}, err
+			}
+		}
+	}
+
+	if !hasFormat || !hasChannels || !hasRate {
+		return SampleSpec{}, fmt.Errorf("Incomplete sample spec %q: expected format, channels, and rate", spec)
+	}
+
+	return rv, rv.Validate()
 }
 
-func DefaultSampleSpec() SampleSpec {
-	return SampleSpec{
-		Format:      FormatPcmS16LE,
-		SampleRate:  DEFAULT_SAMPLE_RATE,
-		NumChannels: DEFAULT_NUM_CHANNELS,
+// Return an error if the sample spec is not something PulseAudio would accept.
+func (self SampleSpec) Validate() error {
+	if self.NumChannels <= 0 || self.NumChannels > MAX_CHANNELS {
+		return fmt.Errorf("Invalid sample spec: channel count %d out of range", self.NumChannels)
 	}
+
+	if !sampleSpecValid(self) {
+		return fmt.Errorf("Invalid sample spec: %s", self.String())
+	}
+
+	return nil
 }
 
-func GetSampleFormat(cvalue int) SampleFormat {
-	switch cvalue {
-	case int(C.PA_SAMPLE_U8):
-		return FormatPcmU8
+// Return the size (in bytes) of a single frame (one sample for every channel).
+func (self SampleSpec) FrameSize() int {
+	if self.Validate() != nil {
+		return 0
+	}
 
-	case int(C.PA_SAMPLE_ALAW):
-		return FormatALaw8
+	return frameSize(self)
+}
 
-	case int(C.PA_SAMPLE_ULAW):
-		return FormatMuLaw8
+// Return the number of bytes consumed by one second of audio in this spec.
+func (self SampleSpec) BytesPerSecond() int {
+	if self.Validate() != nil {
+		return 0
+	}
 
-	case int(C.PA_SAMPLE_S16LE):
-		return FormatPcmS16LE
+	return bytesPerSecond(self)
+}
 
-	case int(C.PA_SAMPLE_S16BE):
-		return FormatPcmS16BE
+// Return the amount of playback time represented by the given number of bytes.
+func (self SampleSpec) BytesToDuration(length int64) time.Duration {
+	if self.Validate() != nil || length <= 0 {
+		return 0
+	}
 
-	case int(C.PA_SAMPLE_FLOAT32LE):
-		return FormatIEEEFloat32LE
+	return time.Duration(bytesToUsec(uint64(length), self)) * time.Microsecond
+}
 
-	case int(C.PA_SAMPLE_FLOAT32BE):
-		return FormatIEEEFloat32BE
+// Return the number of bytes needed to represent the given duration of audio,
+// rounded down to a whole number of frames.
+func (self SampleSpec) DurationToBytes(duration time.Duration) int64 {
+	if self.Validate() != nil || duration <= 0 {
+		return 0
+	}
 
-	case int(C.PA_SAMPLE_S32LE):
-		return FormatPcmS32LE
+	return int64(usecToBytes(uint64(duration/time.Microsecond), self))
+}
 
-	case int(C.PA_SAMPLE_S32BE):
-		return FormatPcmS32BE
+// Return the sample spec in the form PulseAudio prints them (e.g.: "s16le 2ch 44100Hz").
+func (self SampleSpec) String() string {
+	return fmt.Sprintf("%v %dch %dHz", self.Format, self.NumChannels, self.SampleRate)
+}
 
-	case int(C.PA_SAMPLE_S24LE):
-		return FormatPcmS24PackedLE
+func (self SampleSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(self.String())
+}
 
-	case int(C.PA_SAMPLE_S24BE):
-		return FormatPcmS24PackedBE
+func (self *SampleSpec) UnmarshalJSON(data []byte) error {
+	var str string
 
-	case int(C.PA_SAMPLE_S24_32LE):
-		return FormatPcmS24Lsb32LE
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
 
-	case int(C.PA_SAMPLE_S24_32BE):
-		return FormatPcmS24Lsb32BE
+	if spec, err := ParseSampleSpec(str); err == nil {
+		*self = spec
+		return nil
+	} else {
+		return err
+	}
+}
 
-	case int(C.PA_SAMPLE_MAX):
-		return FormatMax
+func DefaultSampleSpec() SampleSpec {
+	return SampleSpec{
+		Format:      FormatPcmS16LE,
+		SampleRate:  DEFAULT_SAMPLE_RATE,
+		NumChannels: DEFAULT_NUM_CHANNELS,
+	}
+}
 
-	default:
-		return FormatInvalid
+func GetSampleFormat(cvalue int) SampleFormat {
+	if format := SampleFormat(cvalue); format.PCM().Valid() || format == FormatMax {
+		return format
 	}
+
+	return FormatInvalid
 }
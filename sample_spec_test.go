@@ -0,0 +1,96 @@
+package pulse
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse/pcm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleSpecArithmetic(t *testing.T) {
+	assert := require.New(t)
+	spec := SampleSpec{
+		Format:      FormatPcmS16LE,
+		SampleRate:  48000,
+		NumChannels: 2,
+	}
+
+	assert.NoError(spec.Validate())
+	assert.Equal(4, spec.FrameSize())
+	assert.Equal(192000, spec.BytesPerSecond())
+	assert.Equal(time.Second, spec.BytesToDuration(192000))
+	assert.Equal(int64(19200), spec.DurationToBytes(100*time.Millisecond))
+	assert.Equal(`s16le 2ch 48000Hz`, spec.String())
+
+	assert.Error(SampleSpec{Format: FormatPcmS16LE, SampleRate: 0, NumChannels: 2}.Validate())
+	assert.Error(SampleSpec{Format: FormatInvalid, SampleRate: 44100, NumChannels: 2}.Validate())
+	assert.NoError(SampleSpec{Format: FormatPcmS16LE, SampleRate: MAX_SAMPLE_RATE, NumChannels: 2}.Validate())
+	assert.Error(SampleSpec{Format: FormatPcmS16LE, SampleRate: MAX_SAMPLE_RATE + 1, NumChannels: 2}.Validate())
+	assert.Zero(SampleSpec{}.FrameSize())
+}
+
+func TestParseSampleSpec(t *testing.T) {
+	assert := require.New(t)
+
+	spec, err := ParseSampleSpec(`s16le 2ch 48000Hz`)
+	assert.NoError(err)
+	assert.Equal(SampleSpec{Format: FormatPcmS16LE, SampleRate: 48000, NumChannels: 2}, spec)
+
+	spec, err = ParseSampleSpec(`1ch float32be 8000hz`)
+	assert.NoError(err)
+	assert.Equal(SampleSpec{Format: FormatIEEEFloat32BE, SampleRate: 8000, NumChannels: 1}, spec)
+
+	_, err = ParseSampleSpec(`s16le 2ch`)
+	assert.Error(err)
+
+	_, err = ParseSampleSpec(`s17le 2ch 48000Hz`)
+	assert.Error(err)
+
+	format, err := ParseSampleFormat(`float32be`)
+	assert.NoError(err)
+	assert.Equal(FormatIEEEFloat32BE, format)
+	assert.Equal(`float32be`, format.String())
+}
+
+func TestSampleSpecJSON(t *testing.T) {
+	assert := require.New(t)
+	spec := SampleSpec{
+		Format:      FormatPcmS24PackedLE,
+		SampleRate:  96000,
+		NumChannels: 6,
+	}
+
+	data, err := json.Marshal(spec)
+	assert.NoError(err)
+	assert.Equal(`"s24le 6ch 96000Hz"`, string(data))
+
+	var out SampleSpec
+	assert.NoError(json.Unmarshal(data, &out))
+	assert.Equal(spec, out)
+}
+
+func TestSampleFormatMatchesPCM(t *testing.T) {
+	assert := require.New(t)
+
+	for _, format := range []SampleFormat{
+		FormatPcmU8,
+		FormatALaw8,
+		FormatMuLaw8,
+		FormatPcmS16LE,
+		FormatPcmS16BE,
+		FormatIEEEFloat32LE,
+		FormatIEEEFloat32BE,
+		FormatPcmS32LE,
+		FormatPcmS32BE,
+		FormatPcmS24PackedLE,
+		FormatPcmS24PackedBE,
+		FormatPcmS24Lsb32LE,
+		FormatPcmS24Lsb32BE,
+	} {
+		assert.Equal(format.String(), format.PCM().String())
+	}
+
+	assert.Equal(pcm.Invalid, FormatInvalid.PCM())
+}
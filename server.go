@@ -0,0 +1,291 @@
+package pulse
+
+import (
+	"fmt"
+)
+
+// A Server is anything that can be inspected and controlled like a PulseAudio
+// daemon.  *Conn is the real implementation; the pulsefake package provides an
+// in-memory one for tests.  Code that only needs these methods should accept a
+// Server rather than a *Conn so that it can be tested without a daemon.
+//
+// Objects returned by a Server other than *Conn are plain data: their own
+// methods (e.g.: Sink.SetVolume) require a connection, so control them through
+// the Server instead (e.g.: SetSinkVolume).
+type Server interface {
+	GetServerInfo() (ServerInfo, error)
+	GetSinks(filters ...string) ([]*Sink, error)
+	GetSources(filters ...string) ([]*Source, error)
+	GetSinkInputs(filters ...string) ([]SinkInput, error)
+	GetSourceOutputs(filters ...string) ([]SourceOutput, error)
+	GetModules(filters ...string) ([]*Module, error)
+	GetClients(filters ...string) ([]*Client, error)
+
+	SetDefaultSink(name string) error
+	SetDefaultSource(name string) error
+	SetSinkVolume(index int, factor float64) error
+	SetSinkChannelVolumes(index int, factors []float64) error
+	SetSinkMute(index int, mute bool) error
+	SetSinkPort(index int, port string) error
+	SetSourceVolume(index int, factor float64) error
+	SetSourceMute(index int, mute bool) error
+	SetSourcePort(index int, port string) error
+	SetSinkInputVolume(index int, factor float64) error
+	SetSinkInputChannelVolumes(index int, factors []float64) error
+	SetSinkInputMute(index int, mute bool) error
+	MoveSinkInput(index int, sinkIndex int) error
+	KillSinkInput(index int) error
+	SetSourceOutputVolume(index int, factor float64) error
+	SetSourceOutputMute(index int, mute bool) error
+	MoveSourceOutput(index int, sourceIndex int) error
+	KillSourceOutput(index int) error
+	LoadModule(name string, arguments string) error
+	UnloadModule(index int) error
+
+	SubscribeEvents(types ...EventType) (*Subscription, error)
+}
+
+var _ Server = (*Conn)(nil)
+
+func (self *Conn) sink(index int) (*Sink, error) {
+	sink := &Sink{Index: index, conn: self}
+	return sink, sink.Refresh()
+}
+
+func (self *Conn) source(index int) (*Source, error) {
+	source := &Source{Index: index, conn: self}
+	return source, source.Refresh()
+}
+
+func (self *Conn) sinkInput(index int) (*SinkInput, error) {
+	sinkInput := &SinkInput{Index: index, conn: self}
+	return sinkInput, sinkInput.Refresh()
+}
+
+func (self *Conn) sourceOutput(index int) (*SourceOutput, error) {
+	sourceOutput := &SourceOutput{Index: index, conn: self}
+	return sourceOutput, sourceOutput.Refresh()
+}
+
+// Set the volume of the sink with the given index.
+func (self *Conn) SetSinkVolume(index int, factor float64) error {
+	if sink, err := self.sink(index); err == nil {
+		return sink.SetVolume(factor)
+	} else {
+		return err
+	}
+}
+
+// Set the volume of each channel of the sink with the given index.
+func (self *Conn) SetSinkChannelVolumes(index int, factors []float64) error {
+	if sink, err := self.sink(index); err == nil {
+		return sink.SetChannelVolumes(factors)
+	} else {
+		return err
+	}
+}
+
+// Mute or unmute the sink with the given index.
+func (self *Conn) SetSinkMute(index int, mute bool) error {
+	if sink, err := self.sink(index); err == nil {
+		return sink.SetMute(mute)
+	} else {
+		return err
+	}
+}
+
+// Set the active port of the sink with the given index.
+func (self *Conn) SetSinkPort(index int, port string) error {
+	if sink, err := self.sink(index); err == nil {
+		return sink.SetPort(port)
+	} else {
+		return err
+	}
+}
+
+// Set the volume of the source with the given index.
+func (self *Conn) SetSourceVolume(index int, factor float64) error {
+	if source, err := self.source(index); err == nil {
+		return source.SetVolume(factor)
+	} else {
+		return err
+	}
+}
+
+// Mute or unmute the source with the given index.
+func (self *Conn) SetSourceMute(index int, mute bool) error {
+	if source, err := self.source(index); err == nil {
+		return source.SetMute(mute)
+	} else {
+		return err
+	}
+}
+
+// Set the active port of the source with the given index.
+func (self *Conn) SetSourcePort(index int, port string) error {
+	if source, err := self.source(index); err == nil {
+		return source.SetPort(port)
+	} else {
+		return err
+	}
+}
+
+// Set the volume of the sink input with the given index.
+func (self *Conn) SetSinkInputVolume(index int, factor float64) error {
+	if sinkInput, err := self.sinkInput(index); err == nil {
+		return sinkInput.SetVolume(factor)
+	} else {
+		return err
+	}
+}
+
+// Set the volume of each channel of the sink input with the given index.
+func (self *Conn) SetSinkInputChannelVolumes(index int, factors []float64) error {
+	if sinkInput, err := self.sinkInput(index); err == nil {
+		return sinkInput.SetChannelVolumes(factors)
+	} else {
+		return err
+	}
+}
+
+// Mute or unmute the sink input with the given index.
+func (self *Conn) SetSinkInputMute(index int, mute bool) error {
+	if sinkInput, err := self.sinkInput(index); err == nil {
+		return sinkInput.SetMute(mute)
+	} else {
+		return err
+	}
+}
+
+// Move the sink input with the given index to another sink.
+func (self *Conn) MoveSinkInput(index int, sinkIndex int) error {
+	if sinkInput, err := self.sinkInput(index); err == nil {
+		return sinkInput.MoveToSink(sinkIndex)
+	} else {
+		return err
+	}
+}
+
+// Remove the sink input with the given index.
+func (self *Conn) KillSinkInput(index int) error {
+	return (&SinkInput{Index: index, conn: self}).Kill()
+}
+
+// Set the volume of the source output with the given index.
+func (self *Conn) SetSourceOutputVolume(index int, factor float64) error {
+	if sourceOutput, err := self.sourceOutput(index); err == nil {
+		return sourceOutput.SetVolume(factor)
+	} else {
+		return err
+	}
+}
+
+// Mute or unmute the source output with the given index.
+func (self *Conn) SetSourceOutputMute(index int, mute bool) error {
+	if sourceOutput, err := self.sourceOutput(index); err == nil {
+		return sourceOutput.SetMute(mute)
+	} else {
+		return err
+	}
+}
+
+// Move the source output with the given index to another source.
+func (self *Conn) MoveSourceOutput(index int, sourceIndex int) error {
+	if sourceOutput, err := self.sourceOutput(index); err == nil {
+		return sourceOutput.MoveToSource(sourceIndex)
+	} else {
+		return err
+	}
+}
+
+// Remove the source output with the given index.
+func (self *Conn) KillSourceOutput(index int) error {
+	return (&SourceOutput{Index: index, conn: self}).Kill()
+}
+
+// Unload the module with the given index.
+func (self *Conn) UnloadModule(index int) error {
+	return (&Module{Index: uint(index), conn: self}).Unload()
+}
+
+// The functions below retrieve a single object from a Server by its index.  A
+// *Conn asks the daemon for just that object; other Servers are queried with an
+// index filter.
+
+func getSink(server Server, index int) (*Sink, error) {
+	if conn, ok := server.(*Conn); ok {
+		return conn.sink(index)
+	} else if sinks, err := server.GetSinks(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(sinks) == 0 {
+		return nil, fmt.Errorf("No sink with index %d", index)
+	} else {
+		return sinks[0], nil
+	}
+}
+
+func getSource(server Server, index int) (*Source, error) {
+	if conn, ok := server.(*Conn); ok {
+		return conn.source(index)
+	} else if sources, err := server.GetSources(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(sources) == 0 {
+		return nil, fmt.Errorf("No source with index %d", index)
+	} else {
+		return sources[0], nil
+	}
+}
+
+func getSinkInput(server Server, index int) (*SinkInput, error) {
+	if conn, ok := server.(*Conn); ok {
+		return conn.sinkInput(index)
+	} else if sinkInputs, err := server.GetSinkInputs(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(sinkInputs) == 0 {
+		return nil, fmt.Errorf("No sink input with index %d", index)
+	} else {
+		return &sinkInputs[0], nil
+	}
+}
+
+func getSourceOutput(server Server, index int) (*SourceOutput, error) {
+	if conn, ok := server.(*Conn); ok {
+		return conn.sourceOutput(index)
+	} else if sourceOutputs, err := server.GetSourceOutputs(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(sourceOutputs) == 0 {
+		return nil, fmt.Errorf("No source output with index %d", index)
+	} else {
+		return &sourceOutputs[0], nil
+	}
+}
+
+func getClient(server Server, index int) (*Client, error) {
+	if conn, ok := server.(*Conn); ok {
+		client := &Client{Index: index, conn: conn}
+		return client, client.Refresh()
+	} else if clients, err := server.GetClients(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(clients) == 0 {
+		return nil, fmt.Errorf("No client with index %d", index)
+	} else {
+		return clients[0], nil
+	}
+}
+
+func getModule(server Server, index int) (*Module, error) {
+	if conn, ok := server.(*Conn); ok {
+		module := &Module{Index: uint(index), conn: conn}
+		return module, module.Refresh()
+	} else if modules, err := server.GetModules(indexFilter(index)); err != nil {
+		return nil, err
+	} else if len(modules) == 0 {
+		return nil, fmt.Errorf("No module with index %d", index)
+	} else {
+		return modules[0], nil
+	}
+}
+
+func indexFilter(index int) string {
+	return fmt.Sprintf("Index%s%d", FieldValueSeparator, index)
+}
@@ -0,0 +1,280 @@
+package pulse_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/auroralaboratories/pulse"
+	"github.com/auroralaboratories/pulse/pulsefake"
+	"github.com/stretchr/testify/require"
+)
+
+// return the calls made to the fake server so far, as strings
+func fakeCalls(server *pulsefake.Server) []string {
+	calls := make([]string, 0)
+
+	for _, call := range server.Calls() {
+		calls = append(calls, call.String())
+	}
+
+	return calls
+}
+
+// wait for the fake server to receive the given call
+func requireCall(t *testing.T, server *pulsefake.Server, call string) {
+	require.Eventually(t, func() bool {
+		for _, made := range fakeCalls(server) {
+			if made == call {
+				return true
+			}
+		}
+
+		return false
+	}, time.Second, 5*time.Millisecond, "expected call %s, got %v", call, fakeCalls(server))
+}
+
+func TestRouterStart(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+	volume := 0.5
+
+	server.AddSink(pulse.Sink{Name: `speakers`})
+	server.AddSinkInput(pulse.SinkInput{
+		Name:     `Call`,
+		PropList: pulse.PropList{`application.name`: `zoom`},
+	})
+
+	router, err := pulse.NewRouter(server, pulse.RouteRule{
+		Filter: `@application.name/zoom`,
+		Sink:   `headset`,
+		Volume: &volume,
+	})
+
+	assert.NoError(err)
+	assert.NoError(router.Start())
+	defer router.Stop()
+
+	// the headset isn't there yet
+	assert.Empty(router.Log())
+
+	// plugging it in moves the existing stream, but leaves its volume alone
+	server.AddSink(pulse.Sink{Name: `headset`})
+	requireCall(t, server, `MoveSinkInput[0 1]`)
+	assert.NotContains(fakeCalls(server), `SetSinkInputVolume[0 0.5]`)
+
+	// new streams are moved and have their volume set
+	server.AddSinkInput(pulse.SinkInput{
+		Name:     `Screen share`,
+		PropList: pulse.PropList{`application.name`: `zoom`},
+	})
+
+	requireCall(t, server, `MoveSinkInput[1 1]`)
+	requireCall(t, server, `SetSinkInputVolume[1 0.5]`)
+
+	assert.Eventually(func() bool {
+		return len(router.Log()) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	for _, action := range router.Log() {
+		assert.Empty(action.Error)
+	}
+}
+
+func TestDuckerStart(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+
+	server.AddSinkInput(pulse.SinkInput{
+		Name:     `Music`,
+		Volume:   pulse.Volume{Value: 0.8 * pulse.DefaultVolumeStep},
+		PropList: pulse.PropList{`media.role`: `music`},
+	})
+
+	ducker := pulse.NewDucker(server)
+	ducker.Fade = 0
+
+	assert.NoError(ducker.Start())
+	assert.False(ducker.IsDucking())
+
+	call := server.AddSinkInput(pulse.SinkInput{
+		Name:     `Call`,
+		PropList: pulse.PropList{`media.role`: `phone`},
+	})
+
+	assert.Eventually(ducker.IsDucking, time.Second, 5*time.Millisecond)
+
+	assert.Eventually(func() bool {
+		if sinkInputs, err := server.GetSinkInputs(`Name/Music`); err == nil {
+			return sinkInputs[0].VolumeFactor() < 0.8*ducker.Gain()+0.001
+		}
+
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	// the call ending restores the original volume, not the ducked one
+	assert.NoError(server.Remove(pulse.SinkInputEvent, call.Index))
+	assert.Eventually(func() bool { return !ducker.IsDucking() }, time.Second, 5*time.Millisecond)
+
+	assert.Eventually(func() bool {
+		if sinkInputs, err := server.GetSinkInputs(`Name/Music`); err == nil {
+			return sinkInputs[0].VolumeFactor() > 0.799
+		}
+
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(ducker.Stop())
+}
+
+func TestLimiterStart(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+
+	server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1.5})
+
+	limiter, err := pulse.NewLimiter(server)
+	assert.NoError(err)
+
+	assert.NoError(limiter.Start())
+	defer limiter.Stop()
+
+	assert.Contains(fakeCalls(server), `SetSinkVolume[0 1]`)
+}
+
+func TestLimiterUnbalancedStart(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+
+	server.AddSink(pulse.Sink{
+		Name:         `speakers`,
+		VolumeFactor: 1,
+		ChannelVolumes: []pulse.Volume{
+			{Name: `front-left`, Value: 1.5 * pulse.DefaultVolumeStep},
+			{Name: `front-right`, Value: 0.5 * pulse.DefaultVolumeStep},
+		},
+	})
+
+	server.AddSinkInput(pulse.SinkInput{
+		Name:   `Music`,
+		Volume: pulse.Volume{Name: `mean`, Value: pulse.DefaultVolumeStep},
+		Channels: []pulse.Volume{
+			{Name: `front-left`, Value: 0.5 * pulse.DefaultVolumeStep},
+			{Name: `front-right`, Value: 1.5 * pulse.DefaultVolumeStep},
+		},
+	})
+
+	limiter, err := pulse.NewLimiter(server)
+	assert.NoError(err)
+
+	assert.NoError(limiter.Start())
+	defer limiter.Stop()
+
+	sinks, err := server.GetSinks()
+	assert.NoError(err)
+	assert.Equal(pulse.DefaultVolumeStep, int(sinks[0].ChannelVolumes[0].Value))
+	assert.InDelta(pulse.DefaultVolumeStep/3, sinks[0].ChannelVolumes[1].Value, 1)
+	assert.Equal(`front-left`, sinks[0].ChannelVolumes[0].Name)
+
+	sinkInputs, err := server.GetSinkInputs()
+	assert.NoError(err)
+	assert.InDelta(pulse.DefaultVolumeStep/3, sinkInputs[0].Channels[0].Value, 1)
+	assert.Equal(pulse.DefaultVolumeStep, int(sinkInputs[0].Channels[1].Value))
+	assert.NotContains(fakeCalls(server), `SetSinkInputVolume[0 1]`)
+}
+
+func TestLimiterRun(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+	overrides := make(chan pulse.Override, 8)
+
+	sink := server.AddSink(pulse.Sink{Name: `speakers`, VolumeFactor: 1})
+	music := server.AddSinkInput(pulse.SinkInput{Name: `Music`})
+
+	limiter, err := pulse.NewLimiter(server)
+	assert.NoError(err)
+	limiter.OnOverride = func(override pulse.Override) {
+		overrides <- override
+	}
+
+	assert.NoError(limiter.Start())
+	defer limiter.Stop()
+
+	// volumes raised after starting are clamped as their change events arrive
+	assert.NoError(server.SetSinkInputVolume(music.Index, 1.5))
+
+	select {
+	case override := <-overrides:
+		assert.Equal(pulse.ObjectSinkInput, override.Kind)
+		assert.Equal(`Music`, override.Name)
+		assert.Equal(pulse.LimitMaximum, override.Reason)
+		assert.Equal(1.5, override.Requested)
+		assert.Equal(1.0, override.Applied)
+		assert.Empty(override.Error)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for override")
+	}
+
+	requireCall(t, server, `SetSinkInputVolume[0 1]`)
+
+	// errors correcting the volume are reported as messages
+	server.FailNext(`SetSinkVolume`, pulsefake.NoSuchEntityErr)
+
+	assert.NoError(server.UpdateSink(sink.Index, func(sink *pulse.Sink) {
+		sink.VolumeFactor = 2
+	}))
+
+	select {
+	case override := <-overrides:
+		assert.Equal(pulse.ObjectSink, override.Kind)
+		assert.Equal(`No such entity`, override.Error)
+
+		data, err := json.Marshal(override)
+		assert.NoError(err)
+		assert.Contains(string(data), `"Error":"No such entity"`)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for override")
+	}
+}
+
+func TestCacheStart(t *testing.T) {
+	assert := require.New(t)
+	server := pulsefake.New()
+
+	server.AddSink(pulse.Sink{Name: `speakers`})
+
+	cache, err := pulse.NewCache(server)
+	assert.NoError(err)
+	defer cache.Close()
+
+	assert.Len(cache.Sinks(), 1)
+	assert.Equal(`speakers`, cache.ServerInfo().DefaultSinkName)
+
+	headset := server.AddSink(pulse.Sink{Name: `headset`})
+
+	assert.Eventually(func() bool {
+		return len(cache.Sinks()) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(server.UpdateSink(headset.Index, func(sink *pulse.Sink) {
+		sink.Description = `Headset`
+	}))
+
+	assert.Eventually(func() bool {
+		sink, ok := cache.Sink(headset.Index)
+		return ok && sink.Description == `Headset`
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(server.SetDefaultSink(`headset`))
+
+	assert.Eventually(func() bool {
+		return cache.ServerInfo().DefaultSinkName == `headset`
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(server.Remove(pulse.SinkEvent, 0))
+
+	assert.Eventually(func() bool {
+		_, ok := cache.Sink(0)
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+}
@@ -1,9 +1,5 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
 import (
 	"fmt"
 
@@ -24,6 +20,7 @@ type SinkInput struct {
 	Volume      Volume
 	Channels    []Volume
 	Properties  map[string]interface{}
+	PropList    PropList
 	conn        *Conn
 }
 
@@ -42,12 +39,7 @@ func (self *SinkInput) Refresh() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_get_sink_input_info(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_sink_info_cb_t)(C.pulse_get_sink_input_info_by_index_callback),
-		operation.Userdata(),
-	)
+	self.conn.getSinkInputInfo(operation, self.Index)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -57,6 +49,8 @@ func (self *SinkInput) Refresh() error {
 			if err := self.Initialize(payload.Properties); err != nil {
 				return err
 			}
+
+			self.PropList = payload.PropList
 		} else {
 			return fmt.Errorf("Invalid sink response: expected 1 payload, got %d", l)
 		}
@@ -66,18 +60,87 @@ func (self *SinkInput) Refresh() error {
 	})
 }
 
+// Return the average volume of this sink input as a factor of the normal
+// (100%) volume.
+func (self *SinkInput) VolumeFactor() float64 {
+	return self.Volume.Value / DefaultVolumeStep
+}
+
+// Set the volume of all channels of this sink input to a factor of the normal
+// (100%) volume.
+func (self *SinkInput) SetVolume(factor float64) error {
+	if channels := len(self.Channels); channels > 0 {
+		operation := NewOperation(self.conn)
+		defer operation.Destroy()
+
+		if factor < 0 {
+			factor = 0
+		}
+
+		self.conn.setSinkInputVolume(operation, self.Index, channels, uint32(uint(DefaultVolumeStep*factor)))
+
+		// wait for the result, refresh, return any errors
+		if err := operation.Wait(); err == nil {
+			return self.Refresh()
+		} else {
+			return err
+		}
+	} else {
+		return fmt.Errorf("Cannot set volume on sink input %d, no channels defined", self.Index)
+	}
+}
+
+// Set the volume of each channel of this sink input to a factor of the normal
+// (100%) volume, in channel order.
+func (self *SinkInput) SetChannelVolumes(factors []float64) error {
+	if len(factors) > 0 {
+		operation := NewOperation(self.conn)
+		defer operation.Destroy()
+
+		volumes := make([]uint32, len(factors))
+
+		for i, factor := range factors {
+			if factor < 0 {
+				factor = 0
+			}
+
+			volumes[i] = uint32(uint(DefaultVolumeStep * factor))
+		}
+
+		self.conn.setSinkInputChannelVolumes(operation, self.Index, volumes)
+
+		// wait for the result, refresh, return any errors
+		if err := operation.Wait(); err == nil {
+			return self.Refresh()
+		} else {
+			return err
+		}
+	} else {
+		return fmt.Errorf("Cannot set channel volumes on sink input %d, no channels given", self.Index)
+	}
+}
+
+// Explicitly set the muted or unmuted state of the sink input.
+func (self *SinkInput) SetMute(mute bool) error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.setSinkInputMute(operation, self.Index, mute)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
+
 func (self *SinkInput) MoveToSink(sinkIndex int) error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
 	// make the call
-	operation.paOper = C.pa_context_move_sink_input_by_index(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		C.uint32_t(sinkIndex),
-		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-		operation.Userdata(),
-	)
+	self.conn.moveSinkInputByIndex(operation, self.Index, sinkIndex)
 
 	// wait for the result, refresh, return any errors
 	if err := operation.Wait(); err == nil {
@@ -92,12 +155,7 @@ func (self *SinkInput) Kill() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_kill_sink_input(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-		operation.Userdata(),
-	)
+	self.conn.killSinkInput(operation, self.Index)
 
 	// wait for the result, refresh, return any errors
 	if err := operation.Wait(); err == nil {
@@ -1,10 +1,5 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"encoding/json"
 	"fmt"
@@ -16,10 +11,10 @@ import (
 type SinkState int
 
 const (
-	SinkStateInvalid   SinkState = C.PA_SINK_INVALID_STATE
-	SinkStateRunning             = C.PA_SINK_RUNNING
-	SinkStateIdle                = C.PA_SINK_IDLE
-	SinkStateSuspended           = C.PA_SINK_SUSPENDED
+	SinkStateInvalid   SinkState = -1
+	SinkStateRunning             = 0
+	SinkStateIdle                = 1
+	SinkStateSuspended           = 2
 )
 
 func (self SinkState) String() string {
@@ -38,8 +33,10 @@ func (self SinkState) String() string {
 // A Sink represents a logical audio output destination with its own volume control.
 //
 type Sink struct {
+	ActivePort         string
 	CardIndex          int
 	Channels           int
+	ChannelVolumes     []Volume
 	CurrentVolumeStep  int
 	Description        string
 	DriverName         string
@@ -53,6 +50,7 @@ type Sink struct {
 	NumPorts           int
 	NumVolumeSteps     int
 	Properties         map[string]interface{}
+	PropList           PropList
 	State              SinkState
 	VolumeFactor       float64
 	conn               *Conn
@@ -93,11 +91,11 @@ func (self *Sink) loadSinkStateFromProperties() {
 
 	if v := self.P(`_state`); !v.IsNil() {
 		switch int(v.Int()) {
-		case int(C.PA_SINK_RUNNING):
+		case int(SinkStateRunning):
 			state = SinkStateRunning
-		case int(C.PA_SINK_IDLE):
+		case int(SinkStateIdle):
 			state = SinkStateIdle
-		case int(C.PA_SINK_SUSPENDED):
+		case int(SinkStateSuspended):
 			state = SinkStateSuspended
 		}
 
@@ -113,12 +111,7 @@ func (self *Sink) Refresh() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_get_sink_info_by_index(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		(C.pa_sink_info_cb_t)(C.pulse_get_sink_info_by_index_callback),
-		operation.Userdata(),
-	)
+	self.conn.getSinkInfoByIndex(operation, self.Index)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -128,6 +121,8 @@ func (self *Sink) Refresh() error {
 			if err := self.Initialize(payload.Properties); err != nil {
 				return err
 			}
+
+			self.PropList = payload.PropList
 		} else {
 			return fmt.Errorf("Invalid sink response: expected 1 payload, got %d", l)
 		}
@@ -145,23 +140,36 @@ func (self *Sink) SetVolume(factor float64) error {
 	if self.Channels > 0 {
 		operation := NewOperation(self.conn)
 		defer operation.Destroy()
-		newVolume := &C.pa_cvolume{}
 
 		// new volume is the (maximum number of normal volume steps * factor)
-		newVolume = C.pa_cvolume_init(newVolume)
-		newVolumeT := C.pa_volume_t(C.uint32_t(uint(float64(self.NumVolumeSteps) * factor)))
+		self.conn.setSinkVolumeByIndex(operation, self.Index, self.Channels, uint32(uint(float64(self.NumVolumeSteps)*factor)))
 
-		// prepare newVolume for its journey into PulseAudio
-		C.pa_cvolume_set(newVolume, C.uint(self.Channels), newVolumeT)
+		// wait for the result, refresh, return any errors
+		if err := operation.Wait(); err == nil {
+			return self.Refresh()
+		} else {
+			return err
+		}
+	} else {
+		return fmt.Errorf("Cannot set volume on sink %d, no channels defined", self.Index)
+	}
+}
+
+// Set the volume of each channel of this sink to a factor of the maximum
+// volume, in channel order.
+//
+func (self *Sink) SetChannelVolumes(factors []float64) error {
+	if len(factors) > 0 {
+		operation := NewOperation(self.conn)
+		defer operation.Destroy()
+
+		volumes := make([]uint32, len(factors))
+
+		for i, factor := range factors {
+			volumes[i] = uint32(uint(float64(self.NumVolumeSteps) * factor))
+		}
 
-		// make the call
-		operation.paOper = C.pa_context_set_sink_volume_by_index(
-			self.conn.context,
-			C.uint32_t(self.Index),
-			newVolume,
-			(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-			operation.Userdata(),
-		)
+		self.conn.setSinkChannelVolumesByIndex(operation, self.Index, volumes)
 
 		// wait for the result, refresh, return any errors
 		if err := operation.Wait(); err == nil {
@@ -170,7 +178,7 @@ func (self *Sink) SetVolume(factor float64) error {
 			return err
 		}
 	} else {
-		return fmt.Errorf("Cannot set volume on sink %d, no channels defined", self.Index)
+		return fmt.Errorf("Cannot set channel volumes on sink %d, no channels given", self.Index)
 	}
 }
 
@@ -208,21 +216,7 @@ func (self *Sink) SetMute(mute bool) error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	var muting C.int
-
-	if mute {
-		muting = C.int(1)
-	} else {
-		muting = C.int(0)
-	}
-
-	operation.paOper = C.pa_context_set_sink_mute_by_index(
-		self.conn.context,
-		C.uint32_t(self.Index),
-		muting,
-		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-		operation.Userdata(),
-	)
+	self.conn.setSinkMuteByIndex(operation, self.Index, mute)
 
 	// wait for the result, refresh, return any errors
 	if err := operation.Wait(); err == nil {
@@ -254,3 +248,19 @@ func (self *Sink) ToggleMute() error {
 		return err
 	}
 }
+
+// Set the active port of the sink (e.g.: "analog-output-headphones").
+//
+func (self *Sink) SetPort(name string) error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.setSinkPortByIndex(operation, self.Index, name)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
@@ -0,0 +1,136 @@
+package pulse
+
+import (
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+)
+
+// A SourceOutput represents client ends of recording streams inside the server,
+// i.e. they connect one of the global sources to a client stream.
+type SourceOutput struct {
+	ClientIndex int
+	Index       int
+	ModuleIndex int
+	Muted       bool
+	Corked      bool
+	Name        string
+	SourceIndex int
+	Volume      Volume
+	Channels    []Volume
+	Properties  map[string]interface{}
+	PropList    PropList
+	conn        *Conn
+}
+
+// Populate this source output's fields with data in a string-interface{} map.
+func (self *SourceOutput) Initialize(properties map[string]interface{}) error {
+	self.Properties, _ = maputil.DiffuseMap(properties, `.`)
+	return populateStruct(self.Properties, self)
+}
+
+func (self *SourceOutput) P(key string) typeutil.Variant {
+	return maputil.M(self.Properties).Get(key)
+}
+
+// Synchronize this source output's data with the PulseAudio daemon.
+func (self *SourceOutput) Refresh() error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.getSourceOutputInfo(operation, self.Index)
+
+	// wait for the operation to finish and handle success and error cases
+	return operation.WaitSuccess(func(op *Operation) error {
+		if l := len(op.Payloads); l == 1 {
+			payload := operation.Payloads[0]
+
+			if err := self.Initialize(payload.Properties); err != nil {
+				return err
+			}
+
+			self.PropList = payload.PropList
+		} else {
+			return fmt.Errorf("Invalid source output response: expected 1 payload, got %d", l)
+		}
+
+		return nil
+
+	})
+}
+
+// Return the average volume of this source output as a factor of the normal
+// (100%) volume.
+func (self *SourceOutput) VolumeFactor() float64 {
+	return self.Volume.Value / DefaultVolumeStep
+}
+
+// Set the volume of all channels of this source output to a factor of the normal
+// (100%) volume.
+func (self *SourceOutput) SetVolume(factor float64) error {
+	if channels := len(self.Channels); channels > 0 {
+		operation := NewOperation(self.conn)
+		defer operation.Destroy()
+
+		if factor < 0 {
+			factor = 0
+		}
+
+		self.conn.setSourceOutputVolume(operation, self.Index, channels, uint32(uint(DefaultVolumeStep*factor)))
+
+		// wait for the result, refresh, return any errors
+		if err := operation.Wait(); err == nil {
+			return self.Refresh()
+		} else {
+			return err
+		}
+	} else {
+		return fmt.Errorf("Cannot set volume on source output %d, no channels defined", self.Index)
+	}
+}
+
+// Explicitly set the muted or unmuted state of the source output.
+func (self *SourceOutput) SetMute(mute bool) error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.setSourceOutputMute(operation, self.Index, mute)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
+
+func (self *SourceOutput) MoveToSource(sourceIndex int) error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	// make the call
+	self.conn.moveSourceOutputByIndex(operation, self.Index, sourceIndex)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
+
+// Remove this source output.
+func (self *SourceOutput) Kill() error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.killSourceOutput(operation, self.Index)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
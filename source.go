@@ -1,10 +1,5 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"encoding/json"
 	"fmt"
@@ -16,10 +11,10 @@ import (
 type SourceState int
 
 const (
-	SourceStateInvalid   SourceState = C.PA_SOURCE_INVALID_STATE
-	SourceStateRunning               = C.PA_SOURCE_RUNNING
-	SourceStateIdle                  = C.PA_SOURCE_IDLE
-	SourceStateSuspended             = C.PA_SOURCE_SUSPENDED
+	SourceStateInvalid   SourceState = -1
+	SourceStateRunning               = 0
+	SourceStateIdle                  = 1
+	SourceStateSuspended             = 2
 )
 
 func (self SourceState) String() string {
@@ -38,6 +33,7 @@ func (self SourceState) String() string {
 // A Source represents a logical audio input source
 //
 type Source struct {
+	ActivePort         string
 	BaseVolumeStep     int
 	CardIndex          int
 	Channels           int
@@ -56,6 +52,7 @@ type Source struct {
 	State              SourceState
 	VolumeFactor       float64
 	Properties         map[string]interface{}
+	PropList           PropList
 	conn               *Conn
 }
 
@@ -94,11 +91,11 @@ func (self *Source) loadSourceStateFromProperties() {
 
 	if v := self.P(`_state`); !v.IsNil() {
 		switch int(v.Int()) {
-		case int(C.PA_SOURCE_RUNNING):
+		case int(SourceStateRunning):
 			state = SourceStateRunning
-		case int(C.PA_SOURCE_IDLE):
+		case int(SourceStateIdle):
 			state = SourceStateIdle
-		case int(C.PA_SOURCE_SUSPENDED):
+		case int(SourceStateSuspended):
 			state = SourceStateSuspended
 		}
 
@@ -114,7 +111,7 @@ func (self *Source) Refresh() error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	operation.paOper = C.pa_context_get_source_info_by_index(self.conn.context, C.uint32_t(self.Index), (C.pa_source_info_cb_t)(C.pulse_get_source_info_by_index_callback), operation.Userdata())
+	self.conn.getSourceInfoByIndex(operation, self.Index)
 
 	// wait for the operation to finish and handle success and error cases
 	return operation.WaitSuccess(func(op *Operation) error {
@@ -124,6 +121,8 @@ func (self *Source) Refresh() error {
 			if err := self.Initialize(payload.Properties); err != nil {
 				return err
 			}
+
+			self.PropList = payload.PropList
 		} else {
 			return fmt.Errorf("Invalid source response: expected 1 payload, got %d", l)
 		}
@@ -141,17 +140,9 @@ func (self *Source) SetVolume(factor float64) error {
 	if self.Channels > 0 {
 		operation := NewOperation(self.conn)
 		defer operation.Destroy()
-		newVolume := &C.pa_cvolume{}
 
 		// new volume is the (maximum number of normal volume steps * factor)
-		newVolume = C.pa_cvolume_init(newVolume)
-		newVolumeT := C.pa_volume_t(C.uint32_t(uint(float64(self.NumVolumeSteps) * factor)))
-
-		// prepare newVolume for its journey into PulseAudio
-		C.pa_cvolume_set(newVolume, C.uint(self.Channels), newVolumeT)
-
-		// make the call
-		operation.paOper = C.pa_context_set_source_volume_by_index(self.conn.context, C.uint32_t(self.Index), newVolume, (C.pa_context_success_cb_t)(C.pulse_generic_success_callback), operation.Userdata())
+		self.conn.setSourceVolumeByIndex(operation, self.Index, self.Channels, uint32(uint(float64(self.NumVolumeSteps)*factor)))
 
 		// wait for the result, refresh, return any errors
 		if err := operation.Wait(); err == nil {
@@ -198,15 +189,7 @@ func (self *Source) SetMute(mute bool) error {
 	operation := NewOperation(self.conn)
 	defer operation.Destroy()
 
-	var muting C.int
-
-	if mute {
-		muting = C.int(1)
-	} else {
-		muting = C.int(0)
-	}
-
-	operation.paOper = C.pa_context_set_source_mute_by_index(self.conn.context, C.uint32_t(self.Index), muting, (C.pa_context_success_cb_t)(C.pulse_generic_success_callback), operation.Userdata())
+	self.conn.setSourceMuteByIndex(operation, self.Index, mute)
 
 	// wait for the result, refresh, return any errors
 	if err := operation.Wait(); err == nil {
@@ -238,3 +221,19 @@ func (self *Source) ToggleMute() error {
 		return err
 	}
 }
+
+// Set the active port of the source (e.g.: "analog-input-mic").
+//
+func (self *Source) SetPort(name string) error {
+	operation := NewOperation(self.conn)
+	defer operation.Destroy()
+
+	self.conn.setSourcePortByIndex(operation, self.Index, name)
+
+	// wait for the result, refresh, return any errors
+	if err := operation.Wait(); err == nil {
+		return self.Refresh()
+	} else {
+		return err
+	}
+}
@@ -0,0 +1,241 @@
+package pulse
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+)
+
+// The kinds of object captured in a State.
+const (
+	ObjectServer       = `server`
+	ObjectSink         = `sink`
+	ObjectSource       = `source`
+	ObjectSinkInput    = `sink-input`
+	ObjectSourceOutput = `source-output`
+	ObjectClient       = `client`
+	ObjectModule       = `module`
+)
+
+// The ways an object can differ between two States.
+const (
+	ObjectAdded   = `added`
+	ObjectRemoved = `removed`
+	ObjectChanged = `changed`
+)
+
+// A State is a snapshot of every object on a PulseAudio server at one point in
+// time.  States can be saved as JSON, loaded again, and compared with Diff.
+type State struct {
+	Time       time.Time
+	Server     ServerInfo
+	Sinks      []*Sink
+	Sources    []*Source
+	SinkInputs []SinkInput
+	Clients    []*Client
+	Modules    []*Module
+}
+
+// Capture the current state of the server.  Each kind of object is retrieved
+// with a separate request, so objects that change while the snapshot is being
+// taken may be captured before or after the change.
+func (self *Conn) Snapshot() (*State, error) {
+	return snapshot(self)
+}
+
+func snapshot(server Server) (*State, error) {
+	state := &State{
+		Time: time.Now(),
+	}
+
+	var err error
+
+	if state.Server, err = server.GetServerInfo(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve server info: %v", err)
+	} else if state.Sinks, err = server.GetSinks(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve sinks: %v", err)
+	} else if state.Sources, err = server.GetSources(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve sources: %v", err)
+	} else if state.SinkInputs, err = server.GetSinkInputs(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve sink inputs: %v", err)
+	} else if state.Clients, err = server.GetClients(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve clients: %v", err)
+	} else if state.Modules, err = server.GetModules(); err != nil {
+		return nil, fmt.Errorf("Failed to retrieve modules: %v", err)
+	}
+
+	return state, nil
+}
+
+// A FieldChange describes a single field whose value differs between two
+// States.  Nested fields are named with dots, e.g.: "Properties.media.role".
+type FieldChange struct {
+	Field string
+	Old   interface{} `json:",omitempty"`
+	New   interface{} `json:",omitempty"`
+}
+
+// An ObjectChange describes an object that was added, removed, or changed
+// between two States.
+type ObjectChange struct {
+	Kind   string
+	Index  int
+	Name   string
+	Change string
+	Fields []FieldChange `json:",omitempty"`
+}
+
+func (self ObjectChange) String() string {
+	return fmt.Sprintf("%s %s %d (%s)", self.Change, self.Kind, self.Index, self.Name)
+}
+
+// A StateDiff lists every difference between two States, ordered by kind of
+// object and then by index.
+type StateDiff []ObjectChange
+
+// Return whether the two States were equivalent.
+func (self StateDiff) IsEmpty() bool {
+	return len(self) == 0
+}
+
+// Compare this State (before) with another (after), reporting objects that were
+// added, removed, or changed field by field.  Objects are matched by kind and
+// index.  Values are compared in their JSON form, so a State loaded from JSON
+// can be compared with a live one.
+func (self *State) Diff(other *State) StateDiff {
+	diff := make(StateDiff, 0)
+
+	before := self.objects()
+	after := other.objects()
+
+	for _, kind := range []string{ObjectServer, ObjectSink, ObjectSource, ObjectSinkInput, ObjectClient, ObjectModule} {
+		indices := make([]int, 0)
+
+		for index := range before[kind] {
+			indices = append(indices, index)
+		}
+
+		for index := range after[kind] {
+			if _, ok := before[kind][index]; !ok {
+				indices = append(indices, index)
+			}
+		}
+
+		sort.Ints(indices)
+
+		for _, index := range indices {
+			previous, existed := before[kind][index]
+			current, exists := after[kind][index]
+
+			change := ObjectChange{
+				Kind:  kind,
+				Index: index,
+			}
+
+			switch {
+			case !exists:
+				change.Change = ObjectRemoved
+				change.Name = typeutil.String(previous[`Name`])
+			case !existed:
+				change.Change = ObjectAdded
+				change.Name = typeutil.String(current[`Name`])
+			default:
+				change.Change = ObjectChanged
+				change.Name = typeutil.String(current[`Name`])
+				change.Fields = diffFields(previous, current)
+
+				if len(change.Fields) == 0 {
+					continue
+				}
+			}
+
+			diff = append(diff, change)
+		}
+	}
+
+	return diff
+}
+
+// return every object in the state as a flattened map of its JSON fields, keyed
+// by kind and index
+func (self *State) objects() map[string]map[int]map[string]interface{} {
+	objects := make(map[string]map[int]map[string]interface{})
+	empty := (self == nil)
+
+	add := func(kind string, items interface{}) {
+		objects[kind] = make(map[int]map[string]interface{})
+
+		if empty {
+			return
+		}
+
+		list := reflect.ValueOf(items)
+
+		for i := 0; i < list.Len(); i++ {
+			if data, err := flattenJSON(list.Index(i).Interface()); err == nil {
+				objects[kind][int(typeutil.Int(data[`Index`]))] = data
+			}
+		}
+	}
+
+	if self == nil {
+		self = &State{}
+	}
+
+	add(ObjectServer, []ServerInfo{self.Server})
+	add(ObjectSink, self.Sinks)
+	add(ObjectSource, self.Sources)
+	add(ObjectSinkInput, self.SinkInputs)
+	add(ObjectClient, self.Clients)
+	add(ObjectModule, self.Modules)
+
+	return objects
+}
+
+func flattenJSON(item interface{}) (map[string]interface{}, error) {
+	var data map[string]interface{}
+
+	if encoded, err := json.Marshal(item); err == nil {
+		if err := json.Unmarshal(encoded, &data); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	return maputil.CoalesceMap(data, `.`)
+}
+
+func diffFields(previous map[string]interface{}, current map[string]interface{}) []FieldChange {
+	fields := make([]string, 0)
+
+	for field := range previous {
+		fields = append(fields, field)
+	}
+
+	for field := range current {
+		if _, ok := previous[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+	changes := make([]FieldChange, 0)
+
+	for _, field := range fields {
+		if a, b := previous[field], current[field]; !reflect.DeepEqual(a, b) {
+			changes = append(changes, FieldChange{
+				Field: field,
+				Old:   a,
+				New:   b,
+			})
+		}
+	}
+
+	return changes
+}
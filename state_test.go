@@ -0,0 +1,93 @@
+package pulse
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testState() *State {
+	return &State{
+		Server: ServerInfo{
+			Name:            `pulseaudio`,
+			DefaultSinkName: `speakers`,
+		},
+		Sinks: []*Sink{
+			{Index: 0, Name: `speakers`, VolumeFactor: 0.5, Properties: map[string]interface{}{`device`: map[string]interface{}{`bus`: `pci`}}},
+			{Index: 1, Name: `headset`, VolumeFactor: 1, PropList: PropList{PropDeviceBus: `usb`}},
+		},
+		SinkInputs: []SinkInput{
+			{Index: 7, Name: `Playback`, SinkIndex: 0, Channels: []Volume{{Name: `mono`, Value: 1}}},
+		},
+		Modules: []*Module{
+			{Index: 3, Name: `module-null-sink`},
+		},
+	}
+}
+
+func TestStateJSONRoundTrip(t *testing.T) {
+	assert := require.New(t)
+
+	state := testState()
+	data, err := json.Marshal(state)
+	assert.NoError(err)
+
+	var loaded State
+	assert.NoError(json.Unmarshal(data, &loaded))
+
+	assert.Equal(`usb`, loaded.Sinks[1].PropList.DeviceBus())
+	assert.True(state.Diff(&loaded).IsEmpty(), "%v", state.Diff(&loaded))
+	assert.True(loaded.Diff(state).IsEmpty())
+}
+
+func TestStateDiff(t *testing.T) {
+	assert := require.New(t)
+
+	before := testState()
+	after := testState()
+
+	after.Server.DefaultSinkName = `headset`
+	after.Sinks = after.Sinks[1:]
+	after.Sinks[0].VolumeFactor = 0.25
+	after.SinkInputs[0].SinkIndex = 1
+	after.Clients = []*Client{{Index: 12, Name: `firefox`}}
+
+	diff := before.Diff(after)
+
+	assert.Equal(StateDiff{
+		{
+			Kind:   ObjectServer,
+			Name:   `pulseaudio`,
+			Change: ObjectChanged,
+			Fields: []FieldChange{{Field: `DefaultSinkName`, Old: `speakers`, New: `headset`}},
+		}, {
+			Kind:   ObjectSink,
+			Index:  0,
+			Name:   `speakers`,
+			Change: ObjectRemoved,
+		}, {
+			Kind:   ObjectSink,
+			Index:  1,
+			Name:   `headset`,
+			Change: ObjectChanged,
+			Fields: []FieldChange{{Field: `VolumeFactor`, Old: float64(1), New: 0.25}},
+		}, {
+			Kind:   ObjectSinkInput,
+			Index:  7,
+			Name:   `Playback`,
+			Change: ObjectChanged,
+			Fields: []FieldChange{{Field: `SinkIndex`, Old: float64(0), New: float64(1)}},
+		}, {
+			Kind:   ObjectClient,
+			Index:  12,
+			Name:   `firefox`,
+			Change: ObjectAdded,
+		},
+	}, diff)
+
+	_, err := json.Marshal(diff)
+	assert.NoError(err)
+
+	assert.Len((*State)(nil).Diff(before), 5)
+}
@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package pulse
 
 // #cgo CFLAGS: -Wno-implicit-function-declaration
@@ -14,10 +17,17 @@ func go_streamStateChange(streamId *C.char, message *C.char) {
 	v := cgoget(C.GoString(streamId))
 
 	if stream, ok := v.(*Stream); ok {
-		if str := C.GoString(message); str == `` {
-			stream.state <- nil
-		} else {
-			stream.state <- errors.New(str)
+		var err error
+
+		if str := C.GoString(message); str != `` {
+			err = errors.New(str)
+		}
+
+		// only the first state change is waited on; never block the mainloop
+		// on later ones (e.g.: termination after a disconnect)
+		select {
+		case stream.state <- err:
+		default:
 		}
 	}
 }
@@ -28,3 +38,10 @@ func go_streamPerformWrite(streamId *C.char, length C.size_t) {
 		stream.readFromSource(int(length))
 	}
 }
+
+//export go_streamPerformRead
+func go_streamPerformRead(streamId *C.char, length C.size_t) {
+	if stream, ok := cgoget(C.GoString(streamId)).(*Stream); ok {
+		stream.writeToDestination(int(length))
+	}
+}
@@ -0,0 +1,467 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package pulse
+
+// #cgo CFLAGS: -Wno-error=implicit-function-declaration
+// #include "conn.h"
+// #cgo pkg-config: libpulse
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"unsafe"
+)
+
+// A Stream represents a client-side handle for working with audio data going to or coming from PulseAudio
+//
+type Stream struct {
+	BufferSize  int
+	ChannelMap  ChannelMap
+	Destination io.Writer
+	Flags       StreamFlags
+	ID          string
+	Name        string
+	Sampling    SampleSpec
+	Source      io.Reader
+	state       chan error
+	paStream    *C.pa_stream
+	buffer      *bytes.Buffer
+	conn        *Conn
+	nextOffset  int64
+	nextSeek    SeekMode
+	seekLock    sync.Mutex
+}
+
+func (self *Stream) initialize() error {
+	spec := (*C.pa_sample_spec)(self.Sampling.toNative())
+
+	var channelMap *C.pa_channel_map
+
+	if len(self.ChannelMap) > 0 {
+		channelMap = self.ChannelMap.toNative()
+	}
+
+	self.buffer = bytes.NewBuffer(make([]byte, 0, self.BufferSize))
+	cgoregister(self.ID, self)
+
+	if self.Source == nil {
+		self.Source = self.buffer
+	}
+
+	// create the client-side stream object
+	self.paStream = C.pa_stream_new(
+		self.conn.context,
+		C.CString(self.Name),
+		spec,
+		channelMap,
+	)
+
+	return nil
+}
+
+// block until the stream is connected (or fails to), then read back the
+// sample spec and channel map the server actually chose
+func (self *Stream) waitReady() error {
+	select {
+	case err := <-self.state:
+		if err != nil {
+			return err
+		}
+	}
+
+	return self.conn.LockFunc(func() error {
+		if spec, channelMap, err := self.readNegotiatedSpec(); err == nil {
+			self.Sampling = spec
+			self.ChannelMap = channelMap
+			return nil
+		} else {
+			return err
+		}
+	})
+}
+
+func (self *Stream) readNegotiatedSpec() (SampleSpec, ChannelMap, error) {
+	if self.toNative() == nil || C.pa_stream_get_state(self.toNative()) != C.PA_STREAM_READY {
+		return SampleSpec{}, nil, fmt.Errorf("Stream %s is not ready", self.Name)
+	}
+
+	spec := C.pa_stream_get_sample_spec(self.toNative())
+	channelMap := C.pa_stream_get_channel_map(self.toNative())
+
+	if spec == nil || channelMap == nil {
+		return SampleSpec{}, nil, self.conn.GetLastError()
+	}
+
+	return sampleSpecFromNative(spec), channelMapFromNative(channelMap), nil
+}
+
+// Return the sample spec the server negotiated for this stream.  This may
+// differ from the requested spec when the stream was created with the
+// FixFormat, FixRate, or FixChannels flags.
+//
+func (self *Stream) NegotiatedSpec() (SampleSpec, error) {
+	var spec SampleSpec
+
+	err := self.conn.LockFunc(func() error {
+		var err error
+		spec, _, err = self.readNegotiatedSpec()
+		return err
+	})
+
+	return spec, err
+}
+
+// Return whether the current stream is corked (stopped) or not
+//
+func (self *Stream) IsCorked() bool {
+	return (int(C.pa_stream_is_corked(self.toNative())) == 0)
+}
+
+// Uncork (start) the stream
+//
+func (self *Stream) Uncork() error {
+	if self.IsCorked() {
+		operation := NewOperation(self.conn)
+		operation.Timeout = MaxDuration()
+		defer operation.Destroy()
+
+		operation.paOper = C.pa_stream_cork(
+			self.toNative(),
+			C.int(0),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			operation.Userdata(),
+		)
+
+		return operation.WaitSuccess(func(op *Operation) error {
+			log.Printf("Waiting for stream %s uncorked", self.Name)
+			return nil
+		})
+	} else {
+		return nil
+	}
+}
+
+// Cork (stop) the stream
+//
+func (self *Stream) Cork() error {
+	if !self.IsCorked() {
+		operation := NewOperation(self.conn)
+		operation.Timeout = MaxDuration()
+		defer operation.Destroy()
+
+		operation.paOper = C.pa_stream_cork(
+			self.toNative(),
+			C.int(1),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			operation.Userdata(),
+		)
+
+		return operation.WaitSuccess(func(op *Operation) error {
+			log.Printf("Waiting for stream %s corked", self.Name)
+			return nil
+		})
+	} else {
+		return nil
+	}
+}
+
+// Block until the stream's buffer has fully played
+//
+func (self *Stream) Drain() error {
+	operation := NewOperation(self.conn)
+	operation.Timeout = MaxDuration()
+	defer operation.Destroy()
+
+	operation.paOper = C.pa_stream_drain(
+		self.toNative(),
+		(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+		operation.Userdata(),
+	)
+
+	return operation.WaitSuccess(func(op *Operation) error {
+		log.Printf("Waiting for stream %s drained", self.Name)
+		return nil
+	})
+}
+
+// Discard all data currently buffered on the server for this stream, as well
+// as any data waiting in the local write buffer.
+//
+func (self *Stream) Flush() error {
+	if self.buffer != nil {
+		self.buffer.Reset()
+	}
+
+	return self.simpleOperation(`flushed`, func(op *Operation) *C.pa_operation {
+		return C.pa_stream_flush(
+			self.toNative(),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			op.Userdata(),
+		)
+	})
+}
+
+// Reenable prebuffering on the stream; playback will not resume until the
+// prebuffer threshold is reached again.
+//
+func (self *Stream) Prebuf() error {
+	return self.simpleOperation(`prebuffering`, func(op *Operation) *C.pa_operation {
+		return C.pa_stream_prebuf(
+			self.toNative(),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			op.Userdata(),
+		)
+	})
+}
+
+// Start playback immediately, even if the prebuffer threshold has not been
+// reached yet.
+//
+func (self *Stream) Trigger() error {
+	return self.simpleOperation(`triggered`, func(op *Operation) *C.pa_operation {
+		return C.pa_stream_trigger(
+			self.toNative(),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			op.Userdata(),
+		)
+	})
+}
+
+// Change the sample rate of a stream that was created with the VariableRate
+// flag.  On success, the stream's Sampling is updated to reflect the new rate.
+//
+func (self *Stream) UpdateSampleRate(rate uint32) error {
+	if self.Flags&VariableRate == 0 {
+		return NotVariableRateErr{
+			Stream: self.Name,
+		}
+	} else if rate == 0 || rate > MAX_SAMPLE_RATE {
+		return fmt.Errorf("Invalid sample rate %d", rate)
+	}
+
+	if err := self.simpleOperation(`rate updated`, func(op *Operation) *C.pa_operation {
+		return C.pa_stream_update_sample_rate(
+			self.toNative(),
+			C.uint32_t(rate),
+			(C.pa_stream_success_cb_t)(C.pulse_stream_success_callback),
+			op.Userdata(),
+		)
+	}); err == nil {
+		self.Sampling.SampleRate = rate
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (self *Stream) simpleOperation(desc string, fn func(op *Operation) *C.pa_operation) error {
+	operation := NewOperation(self.conn)
+	operation.Timeout = MaxDuration()
+	defer operation.Destroy()
+
+	operation.paOper = fn(operation)
+
+	return operation.WaitSuccess(func(op *Operation) error {
+		log.Printf("Waiting for stream %s %s", self.Name, desc)
+		return nil
+	})
+}
+
+func (self *Stream) Write(data []byte) (int, error) {
+	return self.buffer.Write(data)
+}
+
+// Write data directly to the server-side buffer at the given offset, where the
+// offset is interpreted according to the given seek mode.  This bypasses the
+// local buffer and the stream's Source entirely.
+//
+func (self *Stream) WriteSeek(data []byte, offset int64, seek SeekMode) (int, error) {
+	if self.toNative() == nil {
+		return 0, fmt.Errorf("Cannot write to stream %s: stream is not connected", self.Name)
+	} else if len(data) == 0 {
+		return 0, nil
+	}
+
+	self.conn.Lock()
+	defer self.conn.Unlock()
+
+	// a nil free callback causes PulseAudio to make its own copy of the data
+	if status := int(C.pa_stream_write(
+		self.toNative(),
+		unsafe.Pointer(&data[0]),
+		C.size_t(len(data)),
+		nil,
+		C.int64_t(offset),
+		C.pa_seek_mode_t(seek),
+	)); status < 0 {
+		return 0, self.conn.GetLastError()
+	}
+
+	return len(data), nil
+}
+
+// Return the stream's native C pointer
+//
+func (self *Stream) toNative() *C.pa_stream {
+	return self.paStream
+}
+
+func (self *Stream) Destroy() {
+	if p := self.toNative(); p != nil {
+		self.conn.Lock()
+		C.pa_stream_disconnect(p)
+		self.conn.Unlock()
+	}
+
+	cgounregister(self.ID)
+}
+
+func (self *Stream) Userdata() unsafe.Pointer {
+	return unsafe.Pointer(C.CString(self.ID))
+}
+
+func (self *Stream) readFromSource(length int) {
+	if self.Source != nil {
+		var cData unsafe.Pointer
+		toFill := C.size_t(length)
+
+		// call begin write to determine how much data the server wants
+		if status := int(C.pa_stream_begin_write(self.toNative(), &cData, &toFill)); status < 0 {
+			// return -1, self.conn.GetLastError()
+			log.Printf("Write Prep failed: %d", status)
+			return
+		}
+
+		// allocate local byteslice to the size of the server's choosing
+		data := make([]byte, int(toFill))
+
+		// read data from the source
+		if n, err := self.Source.Read(data); err == nil && n > 0 {
+			c := (*[1 << 30]byte)(cData)[:n:n]
+			copy(c, data)
+
+			// consume any seek that was requested for this write
+			offset, seek := self.takeSeek()
+
+			// perform the PulseAudio write operation
+			if status := int(C.pa_stream_write(self.toNative(), cData, C.size_t(n), nil, C.int64_t(offset), C.pa_seek_mode_t(seek))); status < 0 {
+				// return -1, io.ErrUnexpectedEOF
+				log.Printf("Write failed (%d): %v", status, self.conn.GetLastError())
+				return
+			} else {
+				// log.Printf("pulse.Stream(%s).Write(%d bytes); wrote %d, status=%d\n", self.ID, n, int(toFill), status)
+				return
+			}
+		} else {
+			C.pa_stream_cancel_write(self.toNative())
+		}
+	}
+}
+
+func (self *Stream) writeToDestination(length int) {
+	for C.pa_stream_readable_size(self.toNative()) > 0 {
+		var cData unsafe.Pointer
+		var available C.size_t
+
+		if status := int(C.pa_stream_peek(self.toNative(), &cData, &available)); status < 0 {
+			log.Printf("Read failed (%d): %v", status, self.conn.GetLastError())
+			return
+		} else if available == 0 {
+			return
+		}
+
+		// a nil pointer with a non-zero length indicates a hole in the buffer,
+		// which is dropped without being passed along
+		if cData != nil && self.Destination != nil {
+			if _, err := self.Destination.Write(C.GoBytes(cData, C.int(available))); err != nil {
+				log.Printf("Write to destination failed: %v", err)
+			}
+		}
+
+		C.pa_stream_drop(self.toNative())
+	}
+}
+
+// func (self *Stream) writeNFromBuffer(length int) {
+//    bytes_remaining := length
+//    bytesWritten := 0
+
+//    for bytes_remaining > 0 {
+//        log.Printf("Buffer len %d\n", self.buffer.Len())
+
+//        data := make([]byte, length)
+
+//    // read `length' bytes from the stream buffer
+//        if n, err := self.buffer.Read(data); err == nil {
+//            log.Printf("Write %d/%d bytes from internal buffer %s (size: %d)\n", n, length, self.ID, self.buffer.Len())
+
+//        // only do the complicated stuff if there was any data in there
+//            if n > 0 {
+
+//            }
+//        }else if err == io.EOF {
+//            status <- nil
+//        }else{
+//            time.Sleep(500 * time.Millisecond)
+//        }
+//    }
+// }
+
+func (self *PlaybackStream) initialize() error {
+	if err := self.Stream.initialize(); err != nil {
+		return err
+	}
+
+	C.pa_stream_set_state_callback(self.Stream.toNative(), (C.pa_stream_notify_cb_t)(C.pulse_stream_state_callback), self.Stream.Userdata())
+	C.pa_stream_set_write_callback(self.Stream.toNative(), (C.pa_stream_request_cb_t)(C.pulse_stream_write_callback), self.Stream.Userdata())
+
+	go func() {
+		attr := C.pulse_stream_get_playback_attr(C.int32_t(-1), C.int32_t(-1), C.int32_t(-1), C.int32_t(-1))
+
+		C.pa_stream_connect_playback(self.Stream.toNative(), nil, (*C.pa_buffer_attr)(unsafe.Pointer(&attr)), (C.pa_stream_flags_t)(self.Stream.Flags), nil, nil)
+	}()
+
+	// block until a terminal stream state is reached; successful or otherwise
+	return self.Stream.waitReady()
+}
+
+func (self *RecordStream) initialize() error {
+	if err := self.Stream.initialize(); err != nil {
+		return err
+	}
+
+	if self.Destination == nil {
+		self.Destination = self.Stream.buffer
+	}
+
+	self.Reader = self.Stream.buffer
+
+	if self.MonitorStream >= 0 {
+		if status := int(C.pa_stream_set_monitor_stream(self.Stream.toNative(), C.uint32_t(self.MonitorStream))); status < 0 {
+			return fmt.Errorf("Failed to monitor sink input %d: %v", self.MonitorStream, self.conn.GetLastError())
+		}
+	}
+
+	C.pa_stream_set_state_callback(self.Stream.toNative(), (C.pa_stream_notify_cb_t)(C.pulse_stream_state_callback), self.Stream.Userdata())
+	C.pa_stream_set_read_callback(self.Stream.toNative(), (C.pa_stream_request_cb_t)(C.pulse_stream_read_callback), self.Stream.Userdata())
+
+	go func() {
+		var device *C.char
+		attr := C.pulse_stream_get_record_attr(C.int32_t(-1), C.int32_t(self.FragmentSize))
+
+		if self.Device != `` {
+			device = C.CString(self.Device)
+			defer C.free(unsafe.Pointer(device))
+		}
+
+		C.pa_stream_connect_record(self.Stream.toNative(), device, (*C.pa_buffer_attr)(unsafe.Pointer(&attr)), (C.pa_stream_flags_t)(self.Stream.Flags))
+	}()
+
+	// block until a terminal stream state is reached; successful or otherwise
+	return self.Stream.waitReady()
+}
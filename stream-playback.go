@@ -1,14 +1,8 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"fmt"
 	"io"
-	"unsafe"
 	// "log"
 )
 
@@ -43,27 +37,10 @@ func NewPlaybackStreamFromSource(conn *Conn, name string, sampling *SampleSpec,
 	}
 }
 
-func (self *PlaybackStream) initialize() error {
-	if err := self.Stream.initialize(); err != nil {
-		return err
-	}
-
-	C.pa_stream_set_state_callback(self.Stream.toNative(), (C.pa_stream_notify_cb_t)(C.pulse_stream_state_callback), self.Stream.Userdata())
-	C.pa_stream_set_write_callback(self.Stream.toNative(), (C.pa_stream_request_cb_t)(C.pulse_stream_write_callback), self.Stream.Userdata())
-
-	go func() {
-		attr := C.pulse_stream_get_playback_attr(C.int32_t(-1), C.int32_t(-1), C.int32_t(-1), C.int32_t(-1))
-
-		C.pa_stream_connect_playback(self.Stream.toNative(), nil, (*C.pa_buffer_attr)(unsafe.Pointer(&attr)), (C.pa_stream_flags_t)(self.Stream.Flags), nil, nil)
-	}()
-
-	// block until a terminal stream state is reached; successful or otherwise
-	select {
-	case err := <-self.Stream.state:
-		return err
-	}
-}
-
+// Play audio from the given reader, which contains data in the given sample
+// spec (or the default spec if nil), blocking until playback has finished.  If
+// the server negotiates a different sample format, rate, or number of channels
+// for the stream, the data is converted, resampled, and remixed as it is played.
 func Play(conn *Conn, streamName string, sampling *SampleSpec, data io.Reader, flags ...StreamFlags) error {
 	if stream, err := NewPlaybackStream(
 		conn,
@@ -71,6 +48,34 @@ func Play(conn *Conn, streamName string, sampling *SampleSpec, data io.Reader, f
 		sampling,
 		flags...,
 	); err == nil {
+		requested := DefaultSampleSpec()
+
+		if sampling != nil {
+			requested = *sampling
+		}
+
+		if requested.NumChannels != stream.Sampling.NumChannels && stream.Sampling.NumChannels > 0 {
+			to := stream.ChannelMap
+
+			if len(to) != stream.Sampling.NumChannels {
+				to = DefaultChannelMap(stream.Sampling.NumChannels)
+			}
+
+			if data, err = NewChannelMixer(data, requested.Format, NewChannelMatrix(DefaultChannelMap(requested.NumChannels), to)); err != nil {
+				return err
+			}
+
+			requested.NumChannels = stream.Sampling.NumChannels
+		}
+
+		negotiated := requested
+		negotiated.Format = stream.Sampling.Format
+		negotiated.SampleRate = stream.Sampling.SampleRate
+
+		if data, err = NewResampler(data, requested, negotiated, DEFAULT_RESAMPLE_QUALITY); err != nil {
+			return err
+		}
+
 		if _, err := io.Copy(stream, data); err == nil {
 			if err := stream.Uncork(); err != nil {
 				return fmt.Errorf("Failed to uncork stream: %v", err)
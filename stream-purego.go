@@ -0,0 +1,510 @@
+//go:build purego || !cgo
+// +build purego !cgo
+
+package pulse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"unsafe"
+
+	"github.com/auroralaboratories/pulse/proto"
+)
+
+// A Stream represents a client-side handle for working with audio data going to or coming from PulseAudio
+//
+type Stream struct {
+	BufferSize  int
+	ChannelMap  ChannelMap
+	Destination io.Writer
+	Flags       StreamFlags
+	ID          string
+	Name        string
+	Sampling    SampleSpec
+	Source      io.Reader
+	state       chan error
+	buffer      *bytes.Buffer
+	conn        *Conn
+	nextOffset  int64
+	nextSeek    SeekMode
+	seekLock    sync.Mutex
+	record      bool
+	connected   bool
+	corked      bool
+	channel     uint32
+	requested   int
+}
+
+func (self *Stream) initialize() error {
+	// NewPlaybackStream initializes the stream twice; keep the buffer the
+	// Source was pointed at the first time
+	if self.buffer == nil {
+		self.buffer = bytes.NewBuffer(make([]byte, 0, self.BufferSize))
+	}
+
+	if self.Source == nil {
+		self.Source = self.buffer
+	}
+
+	return nil
+}
+
+// Ask the server to create the stream.  The reply registers the stream with
+// the connection and unblocks waitReady.
+func (self *Stream) connect(device string, attr proto.BufferAttr, directOnInput uint32) {
+	self.conn.Lock()
+	defer self.conn.Unlock()
+
+	channelMap := self.ChannelMap
+
+	if len(channelMap) == 0 {
+		channelMap = DefaultChannelMap(self.Sampling.NumChannels)
+	}
+
+	create := proto.CreateStream{
+		Record: self.record,
+		SampleSpec: proto.SampleSpec{
+			Format:   uint8(self.Sampling.Format),
+			Channels: uint8(self.Sampling.NumChannels),
+			Rate:     self.Sampling.SampleRate,
+		},
+		ChannelMap:    make(proto.ChannelMap, self.Sampling.NumChannels),
+		DeviceIndex:   proto.InvalidIndex,
+		DeviceName:    device,
+		BufferAttr:    attr,
+		Flags:         uint32(self.Flags),
+		SyncID:        self.conn.nextSyncID,
+		Volume:        proto.NewCVolume(self.Sampling.NumChannels, proto.VolumeNorm),
+		PropList:      proto.PropList{`media.name`: self.Name},
+		DirectOnInput: directOnInput,
+	}
+
+	self.conn.nextSyncID++
+
+	// channels without a standard position are auxiliary
+	for i := range create.ChannelMap {
+		if i < len(channelMap) {
+			create.ChannelMap[i] = uint8(channelMap[i])
+		} else {
+			create.ChannelMap[i] = uint8(ChannelAux0 + ChannelPosition(i))
+		}
+	}
+
+	command := proto.CommandCreatePlaybackStream
+
+	if self.record {
+		command = proto.CommandCreateRecordStream
+	}
+
+	args := proto.NewTagStruct()
+	create.Put(args, self.conn.version)
+
+	if _, err := self.conn.client.Request(command, args, func(reply *proto.TagStruct, err error) {
+		self.conn.lock.Lock()
+		defer self.conn.lock.Unlock()
+
+		if err == nil {
+			err = self.created(reply)
+		}
+
+		self.setState(err)
+	}); err != nil {
+		self.setState(err)
+	}
+}
+
+// Read the negotiated parameters of the newly-created stream and start
+// routing the server's data and requests to it.
+func (self *Stream) created(reply *proto.TagStruct) error {
+	created := proto.CreateStreamReply{
+		Record: self.record,
+	}
+
+	if err := created.Get(reply, self.conn.version); err != nil {
+		return err
+	}
+
+	self.channel = created.Channel
+	self.connected = true
+	self.corked = (self.Flags&StartCorked != 0)
+
+	self.Sampling = SampleSpec{
+		Format:      GetSampleFormat(int(created.SampleSpec.Format)),
+		SampleRate:  created.SampleSpec.Rate,
+		NumChannels: int(created.SampleSpec.Channels),
+	}
+
+	self.ChannelMap = make(ChannelMap, len(created.ChannelMap))
+
+	for i, pos := range created.ChannelMap {
+		self.ChannelMap[i] = ChannelPosition(pos)
+	}
+
+	if self.record {
+		self.conn.recordStreams[self.channel] = self
+	} else {
+		self.conn.playbackStreams[self.channel] = self
+		self.requested += int(created.RequestedBytes)
+		self.fill()
+	}
+
+	return nil
+}
+
+func (self *Stream) setState(err error) {
+	select {
+	case self.state <- err:
+	default:
+	}
+}
+
+// Forget a stream the server (or connection) has done away with.  The
+// connection must be locked.
+func (self *Stream) kill(err error) {
+	if self.record {
+		delete(self.conn.recordStreams, self.channel)
+	} else {
+		delete(self.conn.playbackStreams, self.channel)
+	}
+
+	self.connected = false
+	self.setState(err)
+}
+
+// block until the stream is connected (or fails to)
+func (self *Stream) waitReady() error {
+	return <-self.state
+}
+
+// Return the sample spec the server negotiated for this stream.  This may
+// differ from the requested spec when the stream was created with the
+// FixFormat, FixRate, or FixChannels flags.
+//
+func (self *Stream) NegotiatedSpec() (SampleSpec, error) {
+	var spec SampleSpec
+
+	err := self.conn.LockFunc(func() error {
+		if !self.connected {
+			return fmt.Errorf("Stream %s is not ready", self.Name)
+		}
+
+		spec = self.Sampling
+		return nil
+	})
+
+	return spec, err
+}
+
+// Return whether the current stream is corked (stopped) or not
+//
+func (self *Stream) IsCorked() bool {
+	return self.corked
+}
+
+// Uncork (start) the stream
+//
+func (self *Stream) Uncork() error {
+	if self.IsCorked() {
+		return self.simpleOperation(`uncorked`, func(op *Operation) {
+			// hand over whatever was written while the stream was corked
+			self.fill()
+
+			self.cork(op, false)
+		})
+	} else {
+		return nil
+	}
+}
+
+// Cork (stop) the stream
+//
+func (self *Stream) Cork() error {
+	if !self.IsCorked() {
+		return self.simpleOperation(`corked`, func(op *Operation) {
+			self.cork(op, true)
+		})
+	} else {
+		return nil
+	}
+}
+
+func (self *Stream) cork(operation *Operation, cork bool) {
+	command := proto.CommandCorkPlaybackStream
+
+	if self.record {
+		command = proto.CommandCorkRecordStream
+	}
+
+	self.request(operation, command, func(t *proto.TagStruct) {
+		t.PutBool(cork)
+	}, func() {
+		self.corked = cork
+	})
+}
+
+// Block until the stream's buffer has fully played
+//
+func (self *Stream) Drain() error {
+	return self.simpleOperation(`drained`, func(op *Operation) {
+		self.fill()
+		self.playbackRequest(op, proto.CommandDrainPlaybackStream)
+	})
+}
+
+// Discard all data currently buffered on the server for this stream, as well
+// as any data waiting in the local write buffer.
+//
+func (self *Stream) Flush() error {
+	return self.simpleOperation(`flushed`, func(op *Operation) {
+		if self.buffer != nil {
+			self.buffer.Reset()
+		}
+
+		command := proto.CommandFlushPlaybackStream
+
+		if self.record {
+			command = proto.CommandFlushRecordStream
+		}
+
+		self.request(op, command, nil, nil)
+	})
+}
+
+// Reenable prebuffering on the stream; playback will not resume until the
+// prebuffer threshold is reached again.
+//
+func (self *Stream) Prebuf() error {
+	return self.simpleOperation(`prebuffering`, func(op *Operation) {
+		self.playbackRequest(op, proto.CommandPrebufPlaybackStream)
+	})
+}
+
+// Start playback immediately, even if the prebuffer threshold has not been
+// reached yet.
+//
+func (self *Stream) Trigger() error {
+	return self.simpleOperation(`triggered`, func(op *Operation) {
+		self.playbackRequest(op, proto.CommandTriggerPlaybackStream)
+	})
+}
+
+// Change the sample rate of a stream that was created with the VariableRate
+// flag.  On success, the stream's Sampling is updated to reflect the new rate.
+//
+func (self *Stream) UpdateSampleRate(rate uint32) error {
+	if self.Flags&VariableRate == 0 {
+		return NotVariableRateErr{
+			Stream: self.Name,
+		}
+	} else if rate == 0 || rate > MAX_SAMPLE_RATE {
+		return fmt.Errorf("Invalid sample rate %d", rate)
+	}
+
+	return self.simpleOperation(`rate updated`, func(op *Operation) {
+		command := proto.CommandUpdatePlaybackStreamRate
+
+		if self.record {
+			command = proto.CommandUpdateRecordStreamRate
+		}
+
+		self.request(op, command, func(t *proto.TagStruct) {
+			t.PutU32(rate)
+		}, func() {
+			self.Sampling.SampleRate = rate
+		})
+	})
+}
+
+func (self *Stream) simpleOperation(desc string, fn func(op *Operation)) error {
+	operation := NewOperation(self.conn)
+	operation.Timeout = MaxDuration()
+	defer operation.Destroy()
+
+	fn(operation)
+
+	return operation.WaitSuccess(func(op *Operation) error {
+		log.Printf("Waiting for stream %s %s", self.Name, desc)
+		return nil
+	})
+}
+
+// Send a command concerning this stream on behalf of the given operation.  Any
+// arguments beyond the stream's channel are added by args, and onSuccess is
+// called (with the connection locked) once the server has accepted it.
+func (self *Stream) request(operation *Operation, command proto.Command, args func(t *proto.TagStruct), onSuccess func()) {
+	if !self.connected {
+		self.conn.finish(operation, fmt.Errorf("Stream operation failed: %v", proto.ErrBadState))
+		return
+	}
+
+	t := proto.NewTagStruct()
+	t.PutU32(self.channel)
+
+	if args != nil {
+		args(t)
+	}
+
+	self.conn.send(operation, command, t, func(reply *proto.TagStruct, err error) error {
+		if err != nil {
+			return fmt.Errorf("Stream operation failed: %v", err)
+		} else if onSuccess != nil {
+			onSuccess()
+		}
+
+		return nil
+	})
+}
+
+// Send a command that only applies to playback streams.
+func (self *Stream) playbackRequest(operation *Operation, command proto.Command) {
+	if self.record {
+		self.conn.finish(operation, fmt.Errorf("Stream operation failed: %v", proto.ErrBadState))
+	} else {
+		self.request(operation, command, nil, nil)
+	}
+}
+
+func (self *Stream) Write(data []byte) (int, error) {
+	self.conn.lock.Lock()
+	defer self.conn.lock.Unlock()
+
+	n, err := self.buffer.Write(data)
+
+	// the server may already be waiting for this data
+	self.fill()
+
+	return n, err
+}
+
+// Write data directly to the server-side buffer at the given offset, where the
+// offset is interpreted according to the given seek mode.  This bypasses the
+// local buffer and the stream's Source entirely.
+//
+func (self *Stream) WriteSeek(data []byte, offset int64, seek SeekMode) (int, error) {
+	self.conn.Lock()
+	defer self.conn.Unlock()
+
+	if !self.connected || self.record {
+		return 0, fmt.Errorf("Cannot write to stream %s: stream is not connected", self.Name)
+	} else if len(data) == 0 {
+		return 0, nil
+	}
+
+	if err := self.send(data, offset, seek); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+func (self *Stream) send(data []byte, offset int64, seek SeekMode) error {
+	self.requested -= len(data)
+
+	return self.conn.client.Send(&proto.Packet{
+		Channel: self.channel,
+		Offset:  offset,
+		Flags:   uint32(seek) & proto.SeekMask,
+		Data:    data,
+	})
+}
+
+func (self *Stream) Userdata() unsafe.Pointer {
+	return userdata(self.ID)
+}
+
+func (self *Stream) Destroy() {
+	self.conn.Lock()
+	defer self.conn.Unlock()
+
+	if self.connected {
+		command := proto.CommandDeletePlaybackStream
+
+		if self.record {
+			command = proto.CommandDeleteRecordStream
+		}
+
+		args := proto.NewTagStruct()
+		args.PutU32(self.channel)
+
+		self.conn.client.Request(command, args, nil)
+		self.kill(nil)
+	}
+}
+
+// Send the server as much of the data it has asked for as the stream's Source
+// can provide.  The connection must be locked.
+func (self *Stream) fill() {
+	if !self.connected || self.Source == nil {
+		return
+	}
+
+	for self.requested > 0 {
+		data := make([]byte, self.requested)
+
+		n, err := self.Source.Read(data)
+
+		if n > 0 {
+			// consume any seek that was requested for this write
+			offset, seek := self.takeSeek()
+
+			if err := self.send(data[:n], offset, seek); err != nil {
+				log.Printf("Write failed: %v", err)
+				return
+			}
+		}
+
+		if err != nil || n == 0 {
+			return
+		}
+	}
+}
+
+// Pass recorded data to the stream's Destination.  The connection must be
+// locked.
+func (self *Stream) writeToDestination(data []byte) {
+	if self.Destination != nil {
+		if _, err := self.Destination.Write(data); err != nil {
+			log.Printf("Write to destination failed: %v", err)
+		}
+	}
+}
+
+func (self *PlaybackStream) initialize() error {
+	if err := self.Stream.initialize(); err != nil {
+		return err
+	}
+
+	self.Stream.connect(``, proto.DefaultBufferAttr(), proto.InvalidIndex)
+
+	// block until the stream is ready (or has failed)
+	return self.Stream.waitReady()
+}
+
+func (self *RecordStream) initialize() error {
+	if err := self.Stream.initialize(); err != nil {
+		return err
+	}
+
+	if self.Destination == nil {
+		self.Destination = self.Stream.buffer
+	}
+
+	self.Reader = self.Stream.buffer
+	self.Stream.record = true
+
+	attr := proto.DefaultBufferAttr()
+	attr.FragSize = uint32(int32(self.FragmentSize))
+	directOnInput := proto.InvalidIndex
+
+	if self.MonitorStream >= 0 {
+		directOnInput = uint32(self.MonitorStream)
+	}
+
+	self.Stream.connect(self.Device, attr, directOnInput)
+
+	// block until the stream is ready (or has failed)
+	return self.Stream.waitReady()
+}
@@ -1,15 +1,47 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
 	"io"
 )
 
+// A RecordStream receives audio data from a PulseAudio source (or the monitor
+// source of a sink).  Recorded data is written to the stream's Destination, or
+// can be read directly from the RecordStream if no Destination was given.
 type RecordStream struct {
 	*Stream
 	io.Reader
+	Device        string
+	FragmentSize  int
+	MonitorStream int
+}
+
+func newRecordStream(conn *Conn, name string, device string, sampling *SampleSpec, flags ...StreamFlags) *RecordStream {
+	rv := &RecordStream{
+		Stream:        NewStream(conn, name, flags...),
+		Device:        device,
+		FragmentSize:  -1,
+		MonitorStream: -1,
+	}
+
+	if sampling != nil {
+		rv.Sampling = *sampling
+	}
+
+	return rv
+}
+
+// Create a new stream that records from the named source device.  If device is
+// empty, the default source is used.
+func NewRecordStream(conn *Conn, name string, device string, sampling *SampleSpec, flags ...StreamFlags) (*RecordStream, error) {
+	rv := newRecordStream(conn, name, device, sampling, flags...)
+	return rv, rv.initialize()
+}
+
+// Create a new stream that records from the named source device, writing all
+// received data to the given destination.
+func NewRecordStreamToDestination(conn *Conn, name string, device string, sampling *SampleSpec, destination io.Writer, flags ...StreamFlags) (*RecordStream, error) {
+	rv := newRecordStream(conn, name, device, sampling, flags...)
+	rv.Destination = destination
+
+	return rv, rv.initialize()
 }
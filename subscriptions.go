@@ -1,29 +1,24 @@
 // Golang bindings for PulseAudio 8.x+
 package pulse
 
-// #cgo CFLAGS: -Wno-implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 import (
-	"unsafe"
+	"fmt"
 )
 
 type EventType int
 
 const (
-	NullEvent         EventType = C.PA_SUBSCRIPTION_MASK_NULL          // No events.
-	SinkEvent                   = C.PA_SUBSCRIPTION_MASK_SINK          // Sink events.
-	SourceEvent                 = C.PA_SUBSCRIPTION_MASK_SOURCE        // Source events.
-	SinkInputEvent              = C.PA_SUBSCRIPTION_MASK_SINK_INPUT    // Sink input events.
-	SourceOutputEvent           = C.PA_SUBSCRIPTION_MASK_SOURCE_OUTPUT // Source output events.
-	ModuleEvent                 = C.PA_SUBSCRIPTION_MASK_MODULE        // Module events.
-	ClientEvent                 = C.PA_SUBSCRIPTION_MASK_CLIENT        // Client events.
-	SampleCacheEvent            = C.PA_SUBSCRIPTION_MASK_SAMPLE_CACHE  // Sample cache events.
-	ServerEvent                 = C.PA_SUBSCRIPTION_MASK_SERVER        // Other global server changes.
-	CardEvent                   = C.PA_SUBSCRIPTION_MASK_CARD          // Card events.
-	AllEvent                    = C.PA_SUBSCRIPTION_MASK_ALL           // Catch all events.
+	NullEvent         EventType = 0x0000 // No events.
+	SinkEvent                   = 0x0001 // Sink events.
+	SourceEvent                 = 0x0002 // Source events.
+	SinkInputEvent              = 0x0004 // Sink input events.
+	SourceOutputEvent           = 0x0008 // Source output events.
+	ModuleEvent                 = 0x0010 // Module events.
+	ClientEvent                 = 0x0020 // Client events.
+	SampleCacheEvent            = 0x0040 // Sample cache events.
+	ServerEvent                 = 0x0080 // Other global server changes.
+	CardEvent                   = 0x0200 // Card events.
+	AllEvent                    = 0x02ff // Catch all events.
 )
 
 func ExtractEvents(combined int) []EventType {
@@ -73,55 +68,98 @@ func (self EventType) String() string {
 	}
 }
 
-var eventTypes chan EventType
+type EventKind int
 
-// Subscribe to event notifications and emit the type of event as it occurs.
-func (self *Conn) Subscribe(types ...EventType) <-chan EventType {
-	eventTypes = make(chan EventType)
+const (
+	eventFacilityMask = 0x000F
+	eventTypeMask     = 0x0030
+)
 
-	// subscribe to event types
-	operation := NewOperation(self)
-	defer operation.Destroy()
+const (
+	EventNew    EventKind = 0x0000 // An object was created.
+	EventChange EventKind = 0x0010 // An object was changed.
+	EventRemove EventKind = 0x0020 // An object was removed.
+)
+
+func (self EventKind) String() string {
+	switch self {
+	case EventNew:
+		return `new`
+	case EventChange:
+		return `change`
+	case EventRemove:
+		return `remove`
+	default:
+		return `unknown`
+	}
+}
 
-	typeMask := 0
+// An Event is a notification that an object on the server was created, changed,
+// or removed.
+type Event struct {
+	Facility EventType
+	Kind     EventKind
+	Index    int
+}
 
-	if len(types) == 0 {
-		typeMask = AllEvent
-	} else {
-		for _, tm := range types {
-			typeMask |= int(tm)
-		}
+// Decode an event code, as passed to subscription callbacks, into the facility
+// (the kind of object affected) and the kind of event.
+func ParseEvent(code int, index int) Event {
+	return Event{
+		Facility: EventType(1 << uint(code&eventFacilityMask)),
+		Kind:     EventKind(code & eventTypeMask),
+		Index:    index,
 	}
+}
 
-	operation.paOper = C.pa_context_subscribe(
-		self.context,
-		(C.pa_subscription_mask_t)(C.int(typeMask)),
-		(C.pa_context_success_cb_t)(C.pulse_generic_success_callback),
-		operation.Userdata(),
-	)
+func (self Event) String() string {
+	return fmt.Sprintf("%v %v %d", self.Kind, self.Facility, self.Index)
+}
 
-	// wait for the result
-	err := operation.Wait()
+// Subscribe to notifications of objects being created, changed, or removed.  If
+// no types are given, all events are delivered.
+func (self *Conn) SubscribeEvents(types ...EventType) (*Subscription, error) {
+	self.subscriptionLock.Lock()
+	defer self.subscriptionLock.Unlock()
 
-	if err != nil {
-		close(eventTypes)
-	}
+	// the server only keeps one mask per connection, so subscribe to the union of
+	// what every Subscription wants
+	if mask := self.subscriptionMask | eventMask(types); mask != self.subscriptionMask {
+		self.LockFunc(func() error {
+			self.setSubscribeCallback()
+			return nil
+		})
 
-	// set subscription callback
-	C.pa_context_set_subscribe_callback(
-		self.context,
-		(C.pa_context_subscribe_cb_t)(unsafe.Pointer(C.pulse_subscription_event_callback)),
-		self.Userdata(),
-	)
+		operation := NewOperation(self)
+		defer operation.Destroy()
 
-	return eventTypes
-}
+		self.subscribe(operation, mask)
 
-//export go_clientEventCallback
-func go_clientEventCallback(types C.pa_subscription_event_type_t, index C.uint32_t, connID *C.char) {
-	if _, ok := cgoget(C.GoString(connID)).(*Conn); ok {
-		for _, eventType := range ExtractEvents(int(types)) {
-			eventTypes <- eventType
+		if err := operation.Wait(); err != nil {
+			return nil, err
 		}
+
+		self.subscriptionMask = mask
 	}
+
+	return self.events.Subscribe(types...), nil
+}
+
+// Subscribe to event notifications and emit the type of event as it occurs.
+func (self *Conn) Subscribe(types ...EventType) <-chan EventType {
+	eventTypes := make(chan EventType)
+
+	if sub, err := self.SubscribeEvents(types...); err == nil {
+		go func() {
+			for event := range sub.Events() {
+				eventTypes <- event.Facility
+			}
+
+			close(eventTypes)
+		}()
+	} else {
+		close(eventTypes)
+	}
+
+	return eventTypes
 }
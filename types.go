@@ -1,10 +1,5 @@
 package pulse
 
-// #cgo CFLAGS: -Wno-error=implicit-function-declaration
-// #include "conn.h"
-// #cgo pkg-config: libpulse
-import "C"
-
 const DefaultVolumeStep = 65536
 
 type ServerInfo struct {
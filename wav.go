@@ -0,0 +1,269 @@
+package pulse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatALaw       = 0x0006
+	wavFormatMuLaw      = 0x0007
+	wavFormatExtensible = 0xFFFE
+	wavUnknownLength    = 0xFFFFFFFF
+)
+
+type wavDecoder struct{}
+
+func (wavDecoder) Sniff(header []byte) bool {
+	return len(header) >= 12 && string(header[0:4]) == `RIFF` && string(header[8:12]) == `WAVE`
+}
+
+func (wavDecoder) Decode(r io.Reader) (SampleSpec, io.Reader, error) {
+	var spec SampleSpec
+	var hasFormat bool
+	var riff [12]byte
+
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return SampleSpec{}, nil, err
+	} else if string(riff[0:4]) != `RIFF` || string(riff[8:12]) != `WAVE` {
+		return SampleSpec{}, nil, fmt.Errorf("Not a WAV file")
+	}
+
+	for {
+		var chunk [8]byte
+
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return SampleSpec{}, nil, fmt.Errorf("WAV file has no data chunk")
+		}
+
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch string(chunk[0:4]) {
+		case `fmt `:
+			body := make([]byte, size)
+
+			if _, err := io.ReadFull(r, body); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+			if s, err := parseWAVFormat(body); err == nil {
+				spec = s
+				hasFormat = true
+			} else {
+				return SampleSpec{}, nil, err
+			}
+
+			if err := skipBytes(r, int64(size&1)); err != nil {
+				return SampleSpec{}, nil, err
+			}
+
+		case `data`:
+			if !hasFormat {
+				return SampleSpec{}, nil, fmt.Errorf("WAV data chunk precedes fmt chunk")
+			}
+
+			// streamed WAVs may not know their own length
+			if size == wavUnknownLength {
+				return spec, r, nil
+			}
+
+			return spec, dataReader(r, int64(size)), nil
+
+		default:
+			if err := skipBytes(r, int64(size)+int64(size&1)); err != nil {
+				return SampleSpec{}, nil, err
+			}
+		}
+	}
+}
+
+func parseWAVFormat(body []byte) (SampleSpec, error) {
+	if len(body) < 16 {
+		return SampleSpec{}, fmt.Errorf("WAV fmt chunk is too short")
+	}
+
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	channels := int(binary.LittleEndian.Uint16(body[2:4]))
+	rate := binary.LittleEndian.Uint32(body[4:8])
+	blockAlign := int(binary.LittleEndian.Uint16(body[12:14]))
+
+	if tag == wavFormatExtensible {
+		if len(body) < 40 {
+			return SampleSpec{}, fmt.Errorf("WAVE_FORMAT_EXTENSIBLE fmt chunk is too short")
+		}
+
+		// the first two bytes of the SubFormat GUID hold the actual format tag
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	if channels <= 0 || blockAlign%channels != 0 {
+		return SampleSpec{}, fmt.Errorf("Invalid WAV channel layout: %d channels, block align %d", channels, blockAlign)
+	}
+
+	spec := SampleSpec{
+		Format:      FormatInvalid,
+		SampleRate:  rate,
+		NumChannels: channels,
+	}
+
+	// samples are sized by their container; any unused low-order bits in
+	// (e.g.) 24-in-32 bit audio are zero, so reading the full container is exact
+	switch size := blockAlign / channels; tag {
+	case wavFormatPCM:
+		switch size {
+		case 1:
+			spec.Format = FormatPcmU8
+		case 2:
+			spec.Format = FormatPcmS16LE
+		case 3:
+			spec.Format = FormatPcmS24PackedLE
+		case 4:
+			spec.Format = FormatPcmS32LE
+		}
+	case wavFormatIEEEFloat:
+		if size == 4 {
+			spec.Format = FormatIEEEFloat32LE
+		}
+	case wavFormatALaw:
+		spec.Format = FormatALaw8
+	case wavFormatMuLaw:
+		spec.Format = FormatMuLaw8
+	}
+
+	if spec.Format == FormatInvalid {
+		return SampleSpec{}, fmt.Errorf("Unsupported WAV format %#04x with %d-byte samples", tag, blockAlign/channels)
+	}
+
+	return spec, nil
+}
+
+func wavTarget(format SampleFormat) (SampleFormat, *byteTransform, error) {
+	switch format {
+	case FormatPcmU8, FormatALaw8, FormatMuLaw8, FormatPcmS16LE, FormatPcmS24PackedLE, FormatPcmS32LE, FormatIEEEFloat32LE:
+		return format, nil, nil
+	case FormatPcmS16BE:
+		return FormatPcmS16LE, swap16, nil
+	case FormatPcmS24PackedBE:
+		return FormatPcmS24PackedLE, swap24, nil
+	case FormatPcmS32BE:
+		return FormatPcmS32LE, swap32, nil
+	case FormatIEEEFloat32BE:
+		return FormatIEEEFloat32LE, swap32, nil
+	case FormatPcmS24Lsb32LE:
+		return FormatPcmS24PackedLE, s24_32leToLE, nil
+	case FormatPcmS24Lsb32BE:
+		return FormatPcmS24PackedLE, s24_32beToLE, nil
+	default:
+		return FormatInvalid, nil, fmt.Errorf("Cannot write sample format %v to a WAV file", format)
+	}
+}
+
+func wavHeader(spec SampleSpec, dataLength int64) ([]byte, error) {
+	var tag uint16
+	var buf bytes.Buffer
+
+	le := func(v interface{}) {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	switch spec.Format {
+	case FormatIEEEFloat32LE:
+		tag = wavFormatIEEEFloat
+	case FormatALaw8:
+		tag = wavFormatALaw
+	case FormatMuLaw8:
+		tag = wavFormatMuLaw
+	default:
+		tag = wavFormatPCM
+	}
+
+	frameSize := frameSizeOf(spec)
+	bits := 8 * spec.Format.PCM().SampleSize()
+	fmtSize := uint32(16)
+
+	// WAVE_FORMAT_EXTENSIBLE is required for anything but mono or stereo
+	// audio of up to 16 bits
+	extensible := spec.NumChannels > 2 || bits > 16
+
+	if extensible {
+		fmtSize = 40
+	} else if tag != wavFormatPCM {
+		fmtSize = 18
+	}
+
+	// everything but integer PCM must state its length in frames
+	hasFact := tag != wavFormatPCM
+
+	riffSize := uint32(wavUnknownLength)
+	dataSize := uint32(wavUnknownLength)
+	frames := uint32(wavUnknownLength)
+
+	if dataLength >= 0 {
+		size := 4 + (8 + int64(fmtSize)) + 8 + dataLength + dataLength%2
+
+		if hasFact {
+			size += 12
+		}
+
+		if size >= wavUnknownLength {
+			return nil, fmt.Errorf("Cannot write %d bytes of audio to a WAV file: too long", dataLength)
+		}
+
+		riffSize = uint32(size)
+		dataSize = uint32(dataLength)
+		frames = uint32(dataLength / int64(frameSize))
+	}
+
+	buf.WriteString(`RIFF`)
+	le(riffSize)
+	buf.WriteString(`WAVE`)
+
+	buf.WriteString(`fmt `)
+	le(fmtSize)
+
+	if extensible {
+		le(uint16(wavFormatExtensible))
+	} else {
+		le(tag)
+	}
+
+	le(uint16(spec.NumChannels))
+	le(spec.SampleRate)
+	le(uint32(frameSize) * spec.SampleRate)
+	le(uint16(frameSize))
+	le(uint16(bits))
+
+	if extensible {
+		le(uint16(22))
+		le(uint16(bits))
+
+		// the speaker layout is left unspecified
+		le(uint32(0))
+
+		// the SubFormat GUID is the format tag followed by a fixed suffix
+		le(tag)
+		buf.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71})
+	} else if fmtSize > 16 {
+		le(uint16(0))
+	}
+
+	if hasFact {
+		buf.WriteString(`fact`)
+		le(uint32(4))
+		le(frames)
+	}
+
+	buf.WriteString(`data`)
+	le(dataSize)
+
+	return buf.Bytes(), nil
+}
+
+// Create a writer that encodes audio in the given spec as a WAV file.
+func NewWAVWriter(w io.Writer, spec SampleSpec) (*AudioWriter, error) {
+	return newAudioWriter(w, spec, wavTarget, wavHeader, true)
+}